@@ -0,0 +1,126 @@
+package adminauth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+
+	"go.uber.org/zap"
+)
+
+// Networks admin and debug routes are restricted to when neither networks nor credentials are configured
+var LOOPBACK_NETWORKS = []netip.Prefix{netip.MustParsePrefix("127.0.0.1/32"), netip.MustParsePrefix("::1/128")}
+
+// Restricts admin and debug routes to allowed networks and / or basic auth credentials
+type Guard interface {
+	Protect(handler http.Handler) http.Handler
+	LoopbackOnly() bool
+	RequiresCredentials() bool
+}
+
+type guard struct {
+	allowedNetworks []netip.Prefix // empty allows every address
+	username        string         // empty disables basic auth
+	passwordHash    [32]byte
+	loopbackOnly    bool
+	logger          *zap.Logger
+}
+
+// Get new Guard, with no allowed networks and no username only loopback addresses are let through, so admin routes are
+// never exposed by omission
+func NewGuard(allowedNetworks []netip.Prefix, username string, password string, logger *zap.Logger) Guard {
+	loopbackOnly := len(allowedNetworks) == 0 && username == ""
+	if loopbackOnly {
+		allowedNetworks = LOOPBACK_NETWORKS
+	}
+
+	return &guard{
+		allowedNetworks: allowedNetworks,
+		username:        username,
+		passwordHash:    sha256.Sum256([]byte(password)),
+		loopbackOnly:    loopbackOnly,
+		logger:          logger,
+	}
+}
+
+// Determines if requests are restricted to loopback addresses, because neither networks nor credentials were configured
+func (g *guard) LoopbackOnly() bool {
+	return g.loopbackOnly
+}
+
+// Determines if requests must carry the basic auth credentials, which take their Authorization header
+func (g *guard) RequiresCredentials() bool {
+	return g.username != ""
+}
+
+// Wraps a handler so only allowed requests reach it. Requests from outside the allowed networks are answered 403,
+// requests without valid credentials 401 with a Basic challenge
+func (g *guard) Protect(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !g.allowedAddress(r.RemoteAddr) {
+			g.logger.Warn("Admin request from a disallowed address", zap.String("path", r.URL.Path), zap.String("remote addr", r.RemoteAddr))
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		if !g.validCredentials(r) {
+			g.logger.Warn("Admin request with missing or invalid credentials", zap.String("path", r.URL.Path), zap.String("remote addr", r.RemoteAddr))
+			w.Header().Set("WWW-Authenticate", `Basic realm="admin", charset="UTF-8"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		// the credentials are only for this service, like the authorizer's
+		if g.username != "" {
+			r.Header.Del("Authorization")
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// Get whether the connection's address is in an allowed network. Only the peer address is checked, X-Forwarded-For can be
+// set by anyone, so behind a proxy the proxy's address is what's allowed
+func (g *guard) allowedAddress(remoteAddr string) bool {
+	if len(g.allowedNetworks) == 0 {
+		return true
+	}
+
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return false
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+
+	// IPv4 clients of a dual stack listener show up as IPv4-mapped IPv6 addresses
+	addr = addr.Unmap()
+
+	return slices.ContainsFunc(g.allowedNetworks, func(network netip.Prefix) bool { return network.Contains(addr) })
+}
+
+// Get whether the request carries the configured basic auth credentials, compared in constant time
+func (g *guard) validCredentials(r *http.Request) bool {
+	if g.username == "" {
+		return true
+	}
+
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+
+	usernameHash, expectedUsernameHash := sha256.Sum256([]byte(username)), sha256.Sum256([]byte(g.username))
+	passwordHash := sha256.Sum256([]byte(password))
+
+	usernameMatches := subtle.ConstantTimeCompare(usernameHash[:], expectedUsernameHash[:]) == 1
+	passwordMatches := subtle.ConstantTimeCompare(passwordHash[:], g.passwordHash[:]) == 1
+
+	return usernameMatches && passwordMatches
+}
@@ -0,0 +1,140 @@
+package adminauth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/auth"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/metrics"
+	"go.uber.org/zap"
+)
+
+// Allows requests carrying X-API-Key: test-api-key
+type testAuthorizer struct{}
+
+func (testAuthorizer) AllowRequest(r *http.Request) (bool, string) {
+	if r.Header.Get("X-API-Key") != "test-api-key" {
+		return false, "invalid API key"
+	}
+	return true, ""
+}
+
+func TestGuard(t *testing.T) {
+	networks := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("192.168.1.7/32")}
+
+	tests := []struct {
+		name       string
+		networks   []netip.Prefix
+		username   string
+		remoteAddr string
+		basicAuth  []string // username and password, none when nil
+		status     int
+	}{
+		{name: "loopback only by default, IPv4 loopback", remoteAddr: "127.0.0.1:5000", status: http.StatusOK},
+		{name: "loopback only by default, IPv6 loopback", remoteAddr: "[::1]:5000", status: http.StatusOK},
+		{name: "loopback only by default, IPv4-mapped loopback", remoteAddr: "[::ffff:127.0.0.1]:5000", status: http.StatusOK},
+		{name: "loopback only by default, other address", remoteAddr: "10.1.2.3:5000", status: http.StatusForbidden},
+		{name: "inside a CIDR", networks: networks, remoteAddr: "10.1.2.3:5000", status: http.StatusOK},
+		{name: "single address", networks: networks, remoteAddr: "192.168.1.7:5000", status: http.StatusOK},
+		{name: "next to a single address", networks: networks, remoteAddr: "192.168.1.8:5000", status: http.StatusForbidden},
+		{name: "outside every CIDR", networks: networks, remoteAddr: "11.0.0.1:5000", status: http.StatusForbidden},
+		{name: "loopback isn't allowed once networks are configured", networks: networks, remoteAddr: "127.0.0.1:5000", status: http.StatusForbidden},
+		{name: "IPv4-mapped IPv6 peer inside a CIDR", networks: networks, remoteAddr: "[::ffff:10.1.2.3]:5000", status: http.StatusOK},
+		{name: "IPv4-mapped IPv6 peer outside every CIDR", networks: networks, remoteAddr: "[::ffff:11.0.0.1]:5000", status: http.StatusForbidden},
+		{name: "unparsable address", networks: networks, remoteAddr: "pipe", status: http.StatusForbidden},
+		{name: "credentials from anywhere", username: "ops", remoteAddr: "11.0.0.1:5000", basicAuth: []string{"ops", "secret"}, status: http.StatusOK},
+		{name: "missing credentials", username: "ops", remoteAddr: "11.0.0.1:5000", status: http.StatusUnauthorized},
+		{name: "wrong password", username: "ops", remoteAddr: "11.0.0.1:5000", basicAuth: []string{"ops", "wrong"}, status: http.StatusUnauthorized},
+		{name: "wrong username", username: "ops", remoteAddr: "11.0.0.1:5000", basicAuth: []string{"admin", "secret"}, status: http.StatusUnauthorized},
+		{name: "credentials and networks", networks: networks, username: "ops", remoteAddr: "10.1.2.3:5000", basicAuth: []string{"ops", "secret"}, status: http.StatusOK},
+		{name: "credentials outside the networks", networks: networks, username: "ops", remoteAddr: "11.0.0.1:5000", basicAuth: []string{"ops", "secret"}, status: http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			guard := NewGuard(test.networks, test.username, "secret", zap.NewNop())
+
+			var authorization string
+			handler := guard.Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				authorization = r.Header.Get("Authorization")
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/maintenance", nil)
+			req.RemoteAddr = test.remoteAddr
+			if test.basicAuth != nil {
+				req.SetBasicAuth(test.basicAuth[0], test.basicAuth[1])
+			}
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+
+			if recorder.Code != test.status {
+				t.Fatalf("status = %d, want %d", recorder.Code, test.status)
+			}
+			if test.status == http.StatusUnauthorized && recorder.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without a Basic challenge")
+			}
+			if test.status == http.StatusOK && authorization != "" {
+				t.Errorf("Authorization reached the handler as %q", authorization)
+			}
+		})
+	}
+}
+
+func TestLoopbackOnly(t *testing.T) {
+	if !NewGuard(nil, "", "", zap.NewNop()).LoopbackOnly() {
+		t.Error("Expected a guard without networks or credentials to be loopback only")
+	}
+	if NewGuard(LOOPBACK_NETWORKS, "", "", zap.NewNop()).LoopbackOnly() || NewGuard(nil, "ops", "secret", zap.NewNop()).LoopbackOnly() {
+		t.Error("Expected a guard with networks or credentials not to be loopback only")
+	}
+}
+
+// Admin routes are served behind the guard, and behind the request authorizer too unless the guard requires credentials,
+// which take the Authorization header a bearer token would
+func TestGuardWithAuthorizer(t *testing.T) {
+	tests := []struct {
+		name      string
+		username  string
+		apiKey    string
+		basicAuth bool
+		status    int
+	}{
+		{name: "credentials without an API key", username: "ops", basicAuth: true, status: http.StatusOK},
+		{name: "credentials with an API key", username: "ops", basicAuth: true, apiKey: "test-api-key", status: http.StatusOK},
+		{name: "API key without credentials", username: "ops", apiKey: "test-api-key", status: http.StatusUnauthorized},
+		{name: "networks only, with an API key", apiKey: "test-api-key", status: http.StatusOK},
+		{name: "networks only, without an API key", status: http.StatusUnauthorized},
+		{name: "networks only, with a wrong API key", apiKey: "wrong", status: http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			guard := NewGuard([]netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}, test.username, "secret", zap.NewNop())
+
+			served := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+			handler := guard.Protect(served)
+			if !guard.RequiresCredentials() {
+				handler = guard.Protect(auth.Protect(testAuthorizer{}, zap.NewNop(), metrics.NewRegistry(), served))
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/maintenance", nil)
+			req.RemoteAddr = "10.1.2.3:5000"
+			if test.basicAuth {
+				req.SetBasicAuth("ops", "secret")
+			}
+			if test.apiKey != "" {
+				req.Header.Set("X-API-Key", test.apiKey)
+			}
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+
+			if recorder.Code != test.status {
+				t.Errorf("status = %d, want %d", recorder.Code, test.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/config"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/metrics"
+	"go.uber.org/zap"
+)
+
+// Events alerts are raised for
+const (
+	EVENT_SYNC_FAILURE    string = "sync_failure"    // a dataset failed to sync --alert-sync-failures times in a row
+	EVENT_BACKOFF_ENTERED string = "backoff_entered" // GitHub rate limited the service, requests are refused until the quota resets
+	EVENT_PROXY_ERROR     string = "proxy_error"     // a proxied request failed to reach GitHub, or GitHub failed it
+)
+
+// Severities of alerts, as PagerDuty names them
+const (
+	SEVERITY_ERROR   string = "error"
+	SEVERITY_WARNING string = "warning"
+)
+
+// alerts raised while the previous ones are still being delivered, alerts past it are dropped
+const ALERT_QUEUE_SIZE int = 32
+
+// Notified of failures operators should learn about before users do. Hooks must not block, they're called from the sync
+// loop and the request path
+type Hooks interface {
+	// Called every time a dataset fails to sync, failures counts the consecutive failed syncs of the dataset
+	OnSyncFailure(dataset string, failures int, statusCode int, err error)
+	// Called when GitHub rate limits the service, requests aren't sent again until resetTime
+	OnBackoffEntered(resetTime time.Time)
+	// Called when a proxied request fails, err is nil if GitHub responded with statusCode itself
+	OnProxyError(path string, statusCode int, err error)
+}
+
+// Hooks that ignore every failure, used when no alert channel is configured
+type NopHooks struct{}
+
+func (NopHooks) OnSyncFailure(dataset string, failures int, statusCode int, err error) {}
+func (NopHooks) OnBackoffEntered(resetTime time.Time)                                  {}
+func (NopHooks) OnProxyError(path string, statusCode int, err error)                   {}
+
+// Hooks that raise alerts on the configured channels (Slack, PagerDuty), delivered in the background until stopped
+type Alerter interface {
+	Hooks
+	Start(ctx context.Context, fail func(error)) error
+	Stop(ctx context.Context) error
+}
+
+// A failure worth an operator's attention
+type Alert struct {
+	Event    string                 `json:"event"`
+	Key      string                 `json:"key"` // identifies the failure, alerts with the same event and key are muted for --alert-cooldown
+	Severity string                 `json:"severity"`
+	Summary  string                 `json:"summary"`
+	Details  map[string]interface{} `json:"details,omitempty"`
+	At       time.Time              `json:"at"`
+}
+
+type alerter struct {
+	ctx          context.Context // cancelled once Stop times out, aborting in-flight deliveries
+	cancel       context.CancelFunc
+	stopping     chan struct{} // closed by Stop
+	done         chan struct{} // closed once the delivery thread has exited
+	queue        chan Alert
+	targets      []target
+	syncFailures int
+	cooldown     time.Duration
+	lock         sync.Mutex
+	lastRaised   map[string]time.Time // by event and key
+	logger       *zap.Logger
+	raised       metrics.Counter
+	delivered    metrics.Counter
+}
+
+// Get new Alerter for the configured alert channels, nil if none are configured
+func NewAlerter(cfg config.Configuration, logger *zap.Logger, registry metrics.Registry) Alerter {
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+	source, _ := os.Hostname()
+
+	var targets []target
+	if cfg.GetAlertSlackWebhookUrl() != "" {
+		targets = append(targets, &slackTarget{webhookUrl: cfg.GetAlertSlackWebhookUrl(), httpClient: httpClient})
+	}
+	if cfg.GetAlertPagerDutyKey() != "" {
+		targets = append(targets, &pagerDutyTarget{eventsUrl: PAGERDUTY_EVENTS_URL, routingKey: cfg.GetAlertPagerDutyKey(), source: source, httpClient: httpClient})
+	}
+
+	if len(targets) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &alerter{
+		ctx:          ctx,
+		cancel:       cancel,
+		stopping:     make(chan struct{}),
+		done:         make(chan struct{}),
+		queue:        make(chan Alert, ALERT_QUEUE_SIZE),
+		targets:      targets,
+		syncFailures: cfg.GetAlertSyncFailures(),
+		cooldown:     cfg.GetAlertCooldown(),
+		lastRaised:   map[string]time.Time{},
+		logger:       logger,
+		raised:       registry.Counter("alerts_raised_total", "Number of alerts raised, by event and whether they were queued, muted by the cooldown, or dropped"),
+		delivered:    registry.Counter("alerts_delivered_total", "Number of alert deliveries, by event, channel, and result"),
+	}
+}
+
+func (a *alerter) OnSyncFailure(dataset string, failures int, statusCode int, err error) {
+	// a single failed sync is retried on the next tick, only persistent failures are worth an alert
+	if failures < a.syncFailures {
+		return
+	}
+
+	a.raise(Alert{
+		Event:    EVENT_SYNC_FAILURE,
+		Key:      dataset,
+		Severity: SEVERITY_ERROR,
+		Summary:  fmt.Sprintf("Cache dataset %s failed to sync %d times in a row (status %d): %v", dataset, failures, statusCode, err),
+		Details:  map[string]interface{}{"dataset": dataset, "failures": failures, "status": statusCode, "error": err.Error()},
+	})
+}
+
+func (a *alerter) OnBackoffEntered(resetTime time.Time) {
+	a.raise(Alert{
+		Event:    EVENT_BACKOFF_ENTERED,
+		Severity: SEVERITY_WARNING,
+		Summary:  fmt.Sprintf("Rate limited by GitHub, requests to GitHub are refused until %s", resetTime.UTC().Format(time.RFC3339)),
+		Details:  map[string]interface{}{"reset_time": resetTime.UTC()},
+	})
+}
+
+func (a *alerter) OnProxyError(path string, statusCode int, err error) {
+	summary := fmt.Sprintf("Proxied request to %s failed with status %d", path, statusCode)
+	details := map[string]interface{}{"path": path, "status": statusCode}
+	if err != nil {
+		summary += ": " + err.Error()
+		details["error"] = err.Error()
+	}
+
+	// keyed by status rather than path, so failures across every path are muted together
+	a.raise(Alert{Event: EVENT_PROXY_ERROR, Key: fmt.Sprint(statusCode), Severity: SEVERITY_ERROR, Summary: summary, Details: details})
+}
+
+// Queues alert for delivery, unless the same alert was raised within the cooldown. Never blocks, the alert is dropped if
+// the queue is full
+func (a *alerter) raise(alert Alert) {
+	alert.At = time.Now().UTC()
+	id := alert.Event + "/" + alert.Key
+
+	a.lock.Lock()
+	if last, ok := a.lastRaised[id]; ok && alert.At.Sub(last) < a.cooldown {
+		a.lock.Unlock()
+		a.raised.Add(1, "event", alert.Event, "result", "muted")
+		return
+	}
+	a.lastRaised[id] = alert.At
+	a.lock.Unlock()
+
+	select {
+	case a.queue <- alert:
+		a.raised.Add(1, "event", alert.Event, "result", "queued")
+	default:
+		a.raised.Add(1, "event", alert.Event, "result", "dropped")
+		a.logger.Warn("Alert queue is full, dropping alert", zap.String("event", alert.Event), zap.String("summary", alert.Summary))
+	}
+}
+
+// Starts thread that delivers raised alerts to every channel, until stopped
+func (a *alerter) Start(ctx context.Context, fail func(error)) error {
+	go func() {
+		defer close(a.done)
+
+		for {
+			select {
+			case alert := <-a.queue:
+				a.deliver(alert)
+			case <-a.stopping:
+				// alerts raised before stopping, e.g. by the cache shutting down, are still delivered
+				for {
+					select {
+					case alert := <-a.queue:
+						a.deliver(alert)
+					default:
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Stops delivering alerts once the ones already raised were delivered, abandoning them if ctx is done first
+func (a *alerter) Stop(ctx context.Context) error {
+	close(a.stopping)
+
+	stop := context.AfterFunc(ctx, a.cancel)
+	defer stop()
+
+	select {
+	case <-a.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends alert to every channel, a channel failing doesn't keep it from the others
+func (a *alerter) deliver(alert Alert) {
+	for _, target := range a.targets {
+		result := "ok"
+
+		if err := target.send(a.ctx, alert); err != nil {
+			result = "error"
+			a.logger.Warn("Failed to deliver alert", zap.String("channel", target.name()), zap.String("event", alert.Event), zap.Error(err))
+		}
+
+		a.delivered.Add(1, "event", alert.Event, "channel", target.name(), "result", result)
+	}
+}
@@ -0,0 +1,112 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// PagerDuty's Events API v2 endpoint, docs: https://developer.pagerduty.com/docs/events-api-v2/trigger-events/
+const PAGERDUTY_EVENTS_URL string = "https://events.pagerduty.com/v2/enqueue"
+
+// Channel alerts are delivered to
+type target interface {
+	name() string
+	send(ctx context.Context, alert Alert) error
+}
+
+// Posts alerts to a Slack incoming webhook, as a message along with the alert itself
+type slackTarget struct {
+	webhookUrl string
+	httpClient *http.Client
+}
+
+// Body posted to the Slack webhook, text is understood by most chat webhooks
+type slackPayload struct {
+	Text  string `json:"text"`
+	Alert Alert  `json:"alert"`
+}
+
+func (t *slackTarget) name() string {
+	return "slack"
+}
+
+func (t *slackTarget) send(ctx context.Context, alert Alert) error {
+	return postJson(ctx, t.httpClient, t.webhookUrl, slackPayload{Text: fmt.Sprintf("[%s] %s", alert.Severity, alert.Summary), Alert: alert})
+}
+
+// Triggers PagerDuty incidents through the Events API, deduplicated by event and key so a failure that persists past the
+// cooldown updates its open incident instead of opening another
+type pagerDutyTarget struct {
+	eventsUrl  string
+	routingKey string
+	source     string // host the alert was raised on
+	httpClient *http.Client
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string           `json:"routing_key"`
+	EventAction string           `json:"event_action"`
+	DedupKey    string           `json:"dedup_key"`
+	Payload     pagerDutyPayload `json:"payload"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string                 `json:"summary"`
+	Source        string                 `json:"source"`
+	Severity      string                 `json:"severity"`
+	Timestamp     string                 `json:"timestamp"`
+	Component     string                 `json:"component"`
+	Class         string                 `json:"class"`
+	CustomDetails map[string]interface{} `json:"custom_details,omitempty"`
+}
+
+func (t *pagerDutyTarget) name() string {
+	return "pagerduty"
+}
+
+func (t *pagerDutyTarget) send(ctx context.Context, alert Alert) error {
+	return postJson(ctx, t.httpClient, t.eventsUrl, pagerDutyEvent{
+		RoutingKey:  t.routingKey,
+		EventAction: "trigger",
+		DedupKey:    "github-api-read-cache-service/" + alert.Event + "/" + alert.Key,
+		Payload: pagerDutyPayload{
+			Summary:       alert.Summary,
+			Source:        t.source,
+			Severity:      alert.Severity,
+			Timestamp:     alert.At.Format(time.RFC3339),
+			Component:     "github-api-read-cache-service",
+			Class:         alert.Event,
+			CustomDetails: alert.Details,
+		},
+	})
+}
+
+// Posts payload as JSON to url, failing unless it's answered with a 2xx
+func postJson(ctx context.Context, httpClient *http.Client, url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("Failed to create alert request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("Failed to send alert: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("Alert was rejected with status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/config"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/metrics"
+	"go.uber.org/zap"
+)
+
+const (
+	AUTH_MODE_NONE        string = "none"        // every request is allowed
+	AUTH_MODE_STATIC_KEYS string = "static-keys" // requests must carry one of the configured API keys
+	AUTH_MODE_JWT         string = "jwt"         // requests must carry a JWT signed by a key of the configured JWKS
+	AUTH_MODE_OIDC        string = "oidc"        // requests must carry a token issued by the configured OIDC issuer
+)
+
+// Decides whether an inbound request may be served. When it may not, the reason is returned to the client and recorded as
+// a metric label, so it must not be derived from request data
+type Authorizer interface {
+	AllowRequest(r *http.Request) (bool, string)
+}
+
+// Get the Authorizer selected by the configured auth mode
+func NewAuthorizer(cfg config.Configuration, logger *zap.Logger) (Authorizer, error) {
+	switch cfg.GetAuthMode() {
+	case AUTH_MODE_NONE:
+		return noneAuthorizer{}, nil
+	case AUTH_MODE_STATIC_KEYS:
+		return newStaticKeysAuthorizer(cfg.GetAuthApiKeys()), nil
+	case AUTH_MODE_JWT:
+		return newJwtAuthorizer(cfg.GetAuthJwksUrl(), cfg.GetAuthJwtIssuer(), cfg.GetAuthJwtAudience(), cfg.GetAuthLogClaims(), logger), nil
+	case AUTH_MODE_OIDC:
+		return newOidcAuthorizer(cfg.GetAuthOidcIssuer(), cfg.GetAuthJwtAudience(), cfg.GetAuthLogClaims(), logger), nil
+	default:
+		return nil, fmt.Errorf("Unknown auth mode %q", cfg.GetAuthMode())
+	}
+}
+
+// Wraps handler so only requests authorizer allows reach it, without their credentials. Others are answered 401
+// with the reason they were denied
+func Protect(authorizer Authorizer, logger *zap.Logger, registry metrics.Registry, handler http.Handler) http.Handler {
+	if _, ok := authorizer.(noneAuthorizer); ok {
+		return handler
+	}
+
+	denied := registry.Counter("auth_requests_denied_total", "Number of requests answered 401 by the authorizer, by reason")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(context.WithValue(r.Context(), identityKey{}, new(string)))
+
+		allowed, reason := authorizer.AllowRequest(r)
+		if !allowed {
+			denied.Add(1, "reason", reason)
+			logger.Debug("Denied unauthorized request", zap.String("path", r.URL.Path), zap.String("remote addr", r.RemoteAddr), zap.String("reason", reason))
+
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+			http.Error(w, "Unauthorized: "+reason, http.StatusUnauthorized)
+			return
+		}
+
+		// the credential is only for this service, it must never be forwarded upstream, e.g. by the proxy
+		r.Header.Del("Authorization")
+		r.Header.Del("X-API-Key")
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+type identityKey struct{}
+
+// Get who made the request, as identified by the authorizer that allowed it, e.g. a token's subject. Empty if the request
+// wasn't authorized, or the authorizer can't tell callers apart
+func Identity(ctx context.Context) string {
+	identity, _ := ctx.Value(identityKey{}).(*string)
+	if identity == nil {
+		return ""
+	}
+
+	return *identity
+}
+
+// Records who made the request, must be called by authorizers as they allow it
+func setIdentity(r *http.Request, identity string) {
+	if holder, ok := r.Context().Value(identityKey{}).(*string); ok {
+		*holder = identity
+	}
+}
+
+// Get the bearer token of the request's Authorization header, empty if it has none
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+
+	return strings.TrimSpace(token)
+}
+
+// Allows every request
+type noneAuthorizer struct{}
+
+func (noneAuthorizer) AllowRequest(r *http.Request) (bool, string) {
+	return true, ""
+}
@@ -0,0 +1,412 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/metrics"
+	"go.uber.org/zap"
+)
+
+const (
+	testKid      string = "test-key"
+	testAudience string = "github-cache"
+	testIssuer   string = "https://idp.example.com"
+)
+
+// Serves a JWKS with the public half of key under testKid
+func newTestJwks(t *testing.T, key *rsa.PrivateKey) *httptest.Server {
+	t.Helper()
+
+	return newGatedTestJwks(t, key, nil)
+}
+
+// Serves a JWKS with the public half of key under testKid, each response held until gate yields, unless gate is nil
+func newGatedTestJwks(t *testing.T, key *rsa.PrivateKey, gate <-chan struct{}) *httptest.Server {
+	t.Helper()
+
+	jwks := map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": testKid,
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if gate != nil {
+			<-gate
+		}
+		json.NewEncoder(w).Encode(jwks)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// Encodes a JWT of header and claims, signed by sign over its first two segments
+func encodeToken(t *testing.T, header map[string]interface{}, claims map[string]interface{}, sign func(signed []byte) []byte) string {
+	t.Helper()
+
+	encode := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+
+	signed := encode(header) + "." + encode(claims)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sign([]byte(signed)))
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey) func([]byte) []byte {
+	return func(signed []byte) []byte {
+		digest := sha256.Sum256(signed)
+		signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signature
+	}
+}
+
+func TestJwtAuthorizer(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwks := newTestJwks(t, key)
+	now := time.Now().Unix()
+
+	validClaims := func() map[string]interface{} {
+		return map[string]interface{}{"sub": "alice", "iss": testIssuer, "aud": testAudience, "exp": now + 300}
+	}
+	withClaim := func(name string, value interface{}) map[string]interface{} {
+		claims := validClaims()
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+		return claims
+	}
+	rs256 := map[string]interface{}{"alg": "RS256", "kid": testKid}
+
+	// the RSA public key's encoding, what an HMAC verifier confused into using it as a secret would check against
+	publicKeyBytes := key.N.Bytes()
+
+	tests := []struct {
+		name     string
+		token    string
+		allowed  bool
+		reason   string
+		identity string
+	}{
+		{
+			name:     "valid",
+			token:    encodeToken(t, rs256, validClaims(), signRS256(t, key)),
+			allowed:  true,
+			identity: "alice",
+		},
+		{
+			name:     "without kid",
+			token:    encodeToken(t, map[string]interface{}{"alg": "RS256"}, validClaims(), signRS256(t, key)),
+			allowed:  true,
+			identity: "alice",
+		},
+		{
+			name:   "missing token",
+			token:  "",
+			reason: "missing token",
+		},
+		{
+			name:   "malformed",
+			token:  "not.a-jwt",
+			reason: "malformed token",
+		},
+		{
+			name:   "alg none",
+			token:  encodeToken(t, map[string]interface{}{"alg": "none", "kid": testKid}, validClaims(), func([]byte) []byte { return nil }),
+			reason: "unsupported algorithm",
+		},
+		{
+			name: "alg confusion HS256 with the public key",
+			token: encodeToken(t, map[string]interface{}{"alg": "HS256", "kid": testKid}, validClaims(), func(signed []byte) []byte {
+				mac := hmac.New(sha256.New, publicKeyBytes)
+				mac.Write(signed)
+				return mac.Sum(nil)
+			}),
+			reason: "unsupported algorithm",
+		},
+		{
+			name: "alg confusion ES256 against an RSA key",
+			token: encodeToken(t, map[string]interface{}{"alg": "ES256", "kid": testKid}, validClaims(), func(signed []byte) []byte {
+				digest := sha256.Sum256(signed)
+				r, s, err := ecdsa.Sign(rand.Reader, ecKey, digest[:])
+				if err != nil {
+					t.Fatal(err)
+				}
+				return append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+			}),
+			reason: "invalid signature",
+		},
+		{
+			name:   "signed by another key",
+			token:  encodeToken(t, rs256, validClaims(), signRS256(t, otherKey)),
+			reason: "invalid signature",
+		},
+		{
+			name:   "unknown kid",
+			token:  encodeToken(t, map[string]interface{}{"alg": "RS256", "kid": "rotated-away"}, validClaims(), signRS256(t, key)),
+			reason: "unknown signing key",
+		},
+		{
+			name:   "missing exp",
+			token:  encodeToken(t, rs256, withClaim("exp", nil), signRS256(t, key)),
+			reason: "token without expiry",
+		},
+		{
+			name:   "expired",
+			token:  encodeToken(t, rs256, withClaim("exp", now-int64(JWT_CLOCK_SKEW.Seconds())-60), signRS256(t, key)),
+			reason: "token expired",
+		},
+		{
+			name:     "expired within clock skew",
+			token:    encodeToken(t, rs256, withClaim("exp", now-5), signRS256(t, key)),
+			allowed:  true,
+			identity: "alice",
+		},
+		{
+			name:   "not yet valid",
+			token:  encodeToken(t, rs256, withClaim("nbf", now+int64(JWT_CLOCK_SKEW.Seconds())+60), signRS256(t, key)),
+			reason: "token not yet valid",
+		},
+		{
+			name:     "nbf in the past",
+			token:    encodeToken(t, rs256, withClaim("nbf", now-60), signRS256(t, key)),
+			allowed:  true,
+			identity: "alice",
+		},
+		{
+			name:   "wrong issuer",
+			token:  encodeToken(t, rs256, withClaim("iss", "https://evil.example.com"), signRS256(t, key)),
+			reason: "wrong issuer",
+		},
+		{
+			name:   "wrong audience string",
+			token:  encodeToken(t, rs256, withClaim("aud", "another-app"), signRS256(t, key)),
+			reason: "wrong audience",
+		},
+		{
+			name:     "audience list",
+			token:    encodeToken(t, rs256, withClaim("aud", []string{"another-app", testAudience}), signRS256(t, key)),
+			allowed:  true,
+			identity: "alice",
+		},
+		{
+			name:   "audience list without the audience",
+			token:  encodeToken(t, rs256, withClaim("aud", []string{"another-app"}), signRS256(t, key)),
+			reason: "wrong audience",
+		},
+		{
+			name:   "missing audience",
+			token:  encodeToken(t, rs256, withClaim("aud", nil), signRS256(t, key)),
+			reason: "wrong audience",
+		},
+	}
+
+	authorizer := newJwtAuthorizer(jwks.URL, testIssuer, testAudience, nil, zap.NewNop())
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var identity string
+			handler := Protect(authorizer, zap.NewNop(), metrics.NewRegistry(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				identity = Identity(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/orgs/Netflix", nil)
+			if test.token != "" {
+				req.Header.Set("Authorization", "Bearer "+test.token)
+			}
+
+			allowed, reason := authorizer.AllowRequest(req)
+			if allowed != test.allowed || reason != test.reason {
+				t.Fatalf("AllowRequest = (%v, %q), want (%v, %q)", allowed, reason, test.allowed, test.reason)
+			}
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+
+			if test.allowed && identity != test.identity {
+				t.Errorf("identity = %q, want %q", identity, test.identity)
+			}
+			if !test.allowed && recorder.Code != http.StatusUnauthorized {
+				t.Errorf("status = %d, want 401", recorder.Code)
+			}
+		})
+	}
+}
+
+// A request that goes away while the JWKS is first fetched doesn't leave the authorizer without keys
+func TestJwtAuthorizerCanceledFetch(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gate := make(chan struct{})
+	jwks := newGatedTestJwks(t, key, gate)
+	authorizer := newJwtAuthorizer(jwks.URL, testIssuer, testAudience, nil, zap.NewNop())
+	token := encodeToken(t, map[string]interface{}{"alg": "RS256", "kid": testKid}, map[string]interface{}{"sub": "alice", "iss": testIssuer, "aud": testAudience, "exp": time.Now().Unix() + 300}, signRS256(t, key))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	req := httptest.NewRequest(http.MethodGet, "/orgs/Netflix", nil).WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+token)
+	if allowed, reason := authorizer.AllowRequest(req); allowed || reason != "request canceled" {
+		t.Errorf("AllowRequest of a canceled request = (%v, %q)", allowed, reason)
+	}
+
+	close(gate)
+
+	req = httptest.NewRequest(http.MethodGet, "/orgs/Netflix", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	if allowed, reason := authorizer.AllowRequest(req); !allowed {
+		t.Errorf("AllowRequest after the fetch = (%v, %q), want it allowed", allowed, reason)
+	}
+}
+
+// Refreshing a stale JWKS doesn't hold up requests the current keys can verify
+func TestJwtAuthorizerRefreshInBackground(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gate := make(chan struct{}, 1)
+	gate <- struct{}{}
+	jwks := newGatedTestJwks(t, key, gate)
+	defer close(gate)
+
+	authorizer := newJwtAuthorizer(jwks.URL, testIssuer, testAudience, nil, zap.NewNop())
+	token := encodeToken(t, map[string]interface{}{"alg": "RS256", "kid": testKid}, map[string]interface{}{"sub": "alice", "iss": testIssuer, "aud": testAudience, "exp": time.Now().Unix() + 300}, signRS256(t, key))
+
+	allow := func() (bool, string) {
+		req := httptest.NewRequest(http.MethodGet, "/orgs/Netflix", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		return authorizer.AllowRequest(req)
+	}
+
+	if allowed, reason := allow(); !allowed {
+		t.Fatalf("AllowRequest = (%v, %q), want it allowed", allowed, reason)
+	}
+
+	// the refresh is held by the gate until the test ends
+	authorizer.lock.Lock()
+	authorizer.fetchedAt = time.Now().Add(-JWKS_REFRESH_INTERVAL - time.Minute)
+	authorizer.lock.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 3; i++ {
+			if allowed, reason := allow(); !allowed {
+				t.Errorf("AllowRequest during a refresh = (%v, %q), want it allowed", allowed, reason)
+			}
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Requests waited on the JWKS refresh")
+	}
+}
+
+func TestStaticKeysAuthorizer(t *testing.T) {
+	authorizer := newStaticKeysAuthorizer([]string{"first-key", "second-key"})
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		allowed bool
+		reason  string
+	}{
+		{name: "X-API-Key", headers: map[string]string{"X-API-Key": "first-key"}, allowed: true},
+		{name: "bearer token", headers: map[string]string{"Authorization": "Bearer second-key"}, allowed: true},
+		{name: "lowercase bearer scheme", headers: map[string]string{"Authorization": "bearer second-key"}, allowed: true},
+		{name: "missing", headers: map[string]string{}, reason: "missing API key"},
+		{name: "basic auth isn't a bearer token", headers: map[string]string{"Authorization": "Basic first-key"}, reason: "missing API key"},
+		{name: "mismatch", headers: map[string]string{"X-API-Key": "third-key"}, reason: "invalid API key"},
+		{name: "prefix of a key", headers: map[string]string{"X-API-Key": "first"}, reason: "invalid API key"},
+		{name: "X-API-Key takes precedence", headers: map[string]string{"X-API-Key": "wrong", "Authorization": "Bearer first-key"}, reason: "invalid API key"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/orgs/Netflix", nil)
+			for name, value := range test.headers {
+				req.Header.Set(name, value)
+			}
+
+			allowed, reason := authorizer.AllowRequest(req)
+			if allowed != test.allowed || reason != test.reason {
+				t.Fatalf("AllowRequest = (%v, %q), want (%v, %q)", allowed, reason, test.allowed, test.reason)
+			}
+		})
+	}
+}
+
+func TestProtectStripsCredentials(t *testing.T) {
+	authorizer := newStaticKeysAuthorizer([]string{"secret-key"})
+
+	var forwarded http.Header
+	handler := Protect(authorizer, zap.NewNop(), metrics.NewRegistry(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = r.Header.Clone()
+	}))
+
+	for _, header := range []string{"Authorization", "X-API-Key"} {
+		req := httptest.NewRequest(http.MethodGet, "/repos/Netflix/zuul", nil)
+		if header == "Authorization" {
+			req.Header.Set(header, "Bearer secret-key")
+		} else {
+			req.Header.Set(header, "secret-key")
+		}
+
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		if forwarded == nil {
+			t.Fatalf("request with %s wasn't allowed", header)
+		}
+		if value := forwarded.Get(header); value != "" {
+			t.Errorf("%s reached the handler as %q", header, value)
+		}
+		forwarded = nil
+	}
+}
@@ -0,0 +1,461 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/logging"
+	"go.uber.org/zap"
+)
+
+const (
+	JWKS_REFRESH_INTERVAL time.Duration = time.Hour        // how long fetched keys are used before the JWKS is fetched again
+	JWKS_MIN_REFETCH      time.Duration = time.Minute      // least time between fetches for tokens signed by an unknown key
+	JWKS_FETCH_TIMEOUT    time.Duration = 10 * time.Second // longest a single JWKS or discovery request may take
+	JWT_CLOCK_SKEW        time.Duration = 30 * time.Second // leeway on exp and nbf for clocks that drift apart
+)
+
+var errJwksUnavailable = errors.New("JWKS hasn't been fetched")
+
+// How a JWS algorithm verifies signatures
+type jwtAlgorithm struct {
+	hash  crypto.Hash
+	kind  string         // RSA or EC, the kty of keys it verifies with
+	pss   bool           // RSASSA-PSS rather than PKCS #1 v1.5
+	curve elliptic.Curve // EC only
+}
+
+// Asymmetric algorithms only, HMAC tokens would need a shared secret, and unsigned tokens are never accepted
+var JWT_ALGORITHMS = map[string]jwtAlgorithm{
+	"RS256": {hash: crypto.SHA256, kind: "RSA"},
+	"RS384": {hash: crypto.SHA384, kind: "RSA"},
+	"RS512": {hash: crypto.SHA512, kind: "RSA"},
+	"PS256": {hash: crypto.SHA256, kind: "RSA", pss: true},
+	"PS384": {hash: crypto.SHA384, kind: "RSA", pss: true},
+	"PS512": {hash: crypto.SHA512, kind: "RSA", pss: true},
+	"ES256": {hash: crypto.SHA256, kind: "EC", curve: elliptic.P256()},
+	"ES384": {hash: crypto.SHA384, kind: "EC", curve: elliptic.P384()},
+	"ES512": {hash: crypto.SHA512, kind: "EC", curve: elliptic.P521()},
+}
+
+// Allows requests carrying a bearer JWT that's signed by a key of a JSON Web Key Set, unexpired, and issued by and for the
+// configured issuer and audience
+type jwtAuthorizer struct {
+	jwksUrl   string   // empty until discovered for OIDC issuers
+	issuer    string   // empty accepts any issuer
+	audience  string   // empty accepts any audience
+	logClaims []string // claims of allowed tokens added to the access log
+	client    *http.Client
+	logger    *zap.Logger
+	lock      sync.Mutex
+	keys      map[string]crypto.PublicKey // by kid
+	fetchedAt time.Time                   // of the last fetch attempt, successful or not
+	fetching  chan struct{}               // closed once the fetch in progress finishes, nil while none is
+}
+
+// Get new jwtAuthorizer, the JWKS is fetched on the first request
+func newJwtAuthorizer(jwksUrl string, issuer string, audience string, logClaims []string, logger *zap.Logger) *jwtAuthorizer {
+	return &jwtAuthorizer{
+		jwksUrl:   jwksUrl,
+		issuer:    issuer,
+		audience:  audience,
+		logClaims: logClaims,
+		client:    &http.Client{Timeout: JWKS_FETCH_TIMEOUT},
+		logger:    logger,
+	}
+}
+
+// Header and claims of a JWT the authorizer looks at
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jwtClaims struct {
+	Subject   string          `json:"sub"`
+	Issuer    string          `json:"iss"`
+	Audience  json.RawMessage `json:"aud"` // a single string, or a list of them
+	ExpiresAt *float64        `json:"exp"`
+	NotBefore *float64        `json:"nbf"`
+}
+
+// Allows the request if it carries a valid JWT
+func (authorizer *jwtAuthorizer) AllowRequest(r *http.Request) (bool, string) {
+	token := bearerToken(r)
+	if token == "" {
+		return false, "missing token"
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return false, "malformed token"
+	}
+
+	var header jwtHeader
+	var claims jwtClaims
+	if decodeSegment(parts[0], &header) != nil || decodeSegment(parts[1], &claims) != nil {
+		return false, "malformed token"
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false, "malformed token"
+	}
+
+	algorithm, ok := JWT_ALGORITHMS[header.Alg]
+	if !ok {
+		return false, "unsupported algorithm"
+	}
+
+	keys, err := authorizer.keysFor(r.Context(), header.Kid)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false, "request canceled"
+	}
+	if err != nil {
+		return false, "signing keys unavailable"
+	}
+	if len(keys) == 0 {
+		return false, "unknown signing key"
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	if !slices.ContainsFunc(keys, func(key crypto.PublicKey) bool { return verifySignature(algorithm, key, signed, signature) }) {
+		return false, "invalid signature"
+	}
+
+	allowed, reason := authorizer.validClaims(claims)
+	if allowed {
+		setIdentity(r, claims.Subject)
+		authorizer.logAllowedClaims(r, parts[1])
+	}
+
+	return allowed, reason
+}
+
+// Adds the configured claims of an allowed token to the request's access log entry, e.g. so requests can be traced to users
+func (authorizer *jwtAuthorizer) logAllowedClaims(r *http.Request, encodedClaims string) {
+	if len(authorizer.logClaims) == 0 {
+		return
+	}
+
+	var claims map[string]interface{}
+	if decodeSegment(encodedClaims, &claims) != nil {
+		return
+	}
+
+	fields := make([]zap.Field, 0, len(authorizer.logClaims))
+	for _, claim := range authorizer.logClaims {
+		if value, ok := claims[claim]; ok {
+			fields = append(fields, zap.Any("claim "+claim, value))
+		}
+	}
+
+	logging.AddAccessLogFields(r.Context(), fields...)
+}
+
+// Checks the token is within its validity period, and was issued by and for the configured issuer and audience
+func (authorizer *jwtAuthorizer) validClaims(claims jwtClaims) (bool, string) {
+	now := time.Now()
+
+	if claims.ExpiresAt == nil {
+		return false, "token without expiry"
+	}
+	if now.After(time.Unix(int64(*claims.ExpiresAt), 0).Add(JWT_CLOCK_SKEW)) {
+		return false, "token expired"
+	}
+	if claims.NotBefore != nil && now.Add(JWT_CLOCK_SKEW).Before(time.Unix(int64(*claims.NotBefore), 0)) {
+		return false, "token not yet valid"
+	}
+
+	if authorizer.issuer != "" && claims.Issuer != authorizer.issuer {
+		return false, "wrong issuer"
+	}
+
+	if authorizer.audience != "" && !containsAudience(claims.Audience, authorizer.audience) {
+		return false, "wrong audience"
+	}
+
+	return true, ""
+}
+
+// Get the keys a token with kid may be signed by, every key when it has no kid. The JWKS is fetched again once it's stale,
+// or when kid isn't in it, at most once a minute so tokens with made up kids can't hammer the JWKS endpoint. Fetches run in
+// the background, one at a time, requests the current keys can serve keep using them meanwhile, and the others wait on
+// the fetch until ctx is done
+func (authorizer *jwtAuthorizer) keysFor(ctx context.Context, kid string) ([]crypto.PublicKey, error) {
+	authorizer.lock.Lock()
+
+	if authorizer.missingKey(kid) {
+		authorizer.startFetch(JWKS_MIN_REFETCH)
+
+		if fetching := authorizer.fetching; fetching != nil {
+			authorizer.lock.Unlock()
+
+			select {
+			case <-fetching:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+
+			authorizer.lock.Lock()
+		}
+	} else {
+		authorizer.startFetch(JWKS_REFRESH_INTERVAL)
+	}
+
+	defer authorizer.lock.Unlock()
+
+	if authorizer.keys == nil {
+		return nil, errJwksUnavailable
+	}
+
+	if kid != "" {
+		if key, ok := authorizer.keys[kid]; ok {
+			return []crypto.PublicKey{key}, nil
+		}
+		return nil, nil
+	}
+
+	keys := make([]crypto.PublicKey, 0, len(authorizer.keys))
+	for _, key := range authorizer.keys {
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// Determines if the current keys can't verify a token with kid, because none were fetched yet or kid isn't one of them.
+// Must be called with the lock held
+func (authorizer *jwtAuthorizer) missingKey(kid string) bool {
+	_, known := authorizer.keys[kid]
+	return authorizer.keys == nil || (kid != "" && !known)
+}
+
+// Starts fetching the JWKS in the background if the last fetch was longer than after ago and none is in progress. Must be
+// called with the lock held
+func (authorizer *jwtAuthorizer) startFetch(after time.Duration) {
+	if authorizer.fetching != nil || time.Since(authorizer.fetchedAt) <= after {
+		return
+	}
+
+	fetching := make(chan struct{})
+	authorizer.fetching = fetching
+
+	// not tied to the request that started it, which may go away before the fetch finishes
+	go func() {
+		defer close(fetching)
+
+		keys, err := authorizer.fetchKeys(context.Background())
+
+		authorizer.lock.Lock()
+		defer authorizer.lock.Unlock()
+
+		authorizer.fetchedAt = time.Now()
+		authorizer.fetching = nil
+
+		if err != nil {
+			// the last fetched keys are kept if the JWKS endpoint is having trouble
+			authorizer.logger.Warn("Failed to fetch the JWKS", zap.String("url", authorizer.jwksUrl), zap.String("issuer", authorizer.issuer), zap.Bool("has previous keys", authorizer.keys != nil), zap.Error(err))
+			return
+		}
+
+		authorizer.keys = keys
+	}()
+}
+
+// Single key of a JSON Web Key Set, RSA or EC
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// Fetches the JWKS, keys that aren't for signatures or of an unsupported type are left out. Must be called without the lock
+// held, by the only fetch in progress
+func (authorizer *jwtAuthorizer) fetchKeys(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	authorizer.lock.Lock()
+	jwksUrl := authorizer.jwksUrl
+	authorizer.lock.Unlock()
+
+	// OIDC issuers point at their JWKS from their discovery document
+	if jwksUrl == "" {
+		discovered, err := discoverJwksUrl(ctx, authorizer.client, authorizer.issuer)
+		if err != nil {
+			return nil, err
+		}
+		jwksUrl = discovered
+
+		authorizer.lock.Lock()
+		authorizer.jwksUrl = jwksUrl
+		authorizer.lock.Unlock()
+	}
+
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := getJson(ctx, authorizer.client, jwksUrl, &jwks); err != nil {
+		return nil, fmt.Errorf("Failed to fetch the JWKS: %w", err)
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := parseJsonWebKey(jwk)
+		if err != nil {
+			authorizer.logger.Warn("Skipping JWKS key", zap.String("kid", jwk.Kid), zap.Error(err))
+			continue
+		}
+
+		keys[jwk.Kid] = key
+	}
+
+	return keys, nil
+}
+
+// Get the public key of a JWK
+func parseJsonWebKey(jwk jsonWebKey) (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, errN := decodeBigInt(jwk.N)
+		e, errE := decodeBigInt(jwk.E)
+		if errN != nil || errE != nil || !e.IsInt64() || e.Int64() < 2 || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA key")
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+
+		x, errX := decodeBigInt(jwk.X)
+		y, errY := decodeBigInt(jwk.Y)
+		if errX != nil || errY != nil || !curve.IsOnCurve(x, y) {
+			return nil, errors.New("invalid EC key")
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+	}
+}
+
+// Get whether signature is algorithm's signature of signed by key
+func verifySignature(algorithm jwtAlgorithm, key crypto.PublicKey, signed []byte, signature []byte) bool {
+	hasher := algorithm.hash.New()
+	hasher.Write(signed)
+	digest := hasher.Sum(nil)
+
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if algorithm.kind != "RSA" {
+			return false
+		}
+		if algorithm.pss {
+			return rsa.VerifyPSS(key, algorithm.hash, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}) == nil
+		}
+		return rsa.VerifyPKCS1v15(key, algorithm.hash, digest, signature) == nil
+
+	case *ecdsa.PublicKey:
+		// JWS EC signatures are r and s concatenated, each padded to the curve's size
+		size := (algorithm.curve.Params().BitSize + 7) / 8
+		if algorithm.kind != "EC" || key.Curve != algorithm.curve || len(signature) != 2*size {
+			return false
+		}
+		r, s := new(big.Int).SetBytes(signature[:size]), new(big.Int).SetBytes(signature[size:])
+		return ecdsa.Verify(key, digest, r, s)
+	}
+
+	return false
+}
+
+// Decodes a base64url encoded JSON segment of a JWT
+func decodeSegment(segment string, v interface{}) error {
+	decoded, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(decoded, v)
+}
+
+// Decodes a base64url encoded big-endian integer of a JWK
+func decodeBigInt(encoded string) (*big.Int, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(decoded) == 0 {
+		return nil, errors.New("invalid integer")
+	}
+
+	return new(big.Int).SetBytes(decoded), nil
+}
+
+// Get whether the aud claim, a string or a list of them, contains audience
+func containsAudience(aud json.RawMessage, audience string) bool {
+	var single string
+	if json.Unmarshal(aud, &single) == nil {
+		return single == audience
+	}
+
+	var list []string
+	if json.Unmarshal(aud, &list) == nil {
+		return slices.Contains(list, audience)
+	}
+
+	return false
+}
+
+// Gets url and decodes its JSON body into v, bounded by the JWKS fetch timeout
+func getJson(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, JWKS_FETCH_TIMEOUT)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s responded %d", url, resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
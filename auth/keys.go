@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+)
+
+// Allows requests carrying one of a fixed set of API keys, as a bearer token or in the X-API-Key header
+type staticKeysAuthorizer struct {
+	keyHashes [][32]byte
+}
+
+// Get new staticKeysAuthorizer accepting keys, only their hashes are kept
+func newStaticKeysAuthorizer(keys []string) *staticKeysAuthorizer {
+	authorizer := &staticKeysAuthorizer{}
+	for _, key := range keys {
+		authorizer.keyHashes = append(authorizer.keyHashes, sha256.Sum256([]byte(key)))
+	}
+
+	return authorizer
+}
+
+// Allows the request if it carries an accepted key. Every key is compared in constant time, so timing doesn't reveal
+// how close a guess was, or which key it matched
+func (authorizer *staticKeysAuthorizer) AllowRequest(r *http.Request) (bool, string) {
+	key := r.Header.Get("X-API-Key")
+	if key == "" {
+		key = bearerToken(r)
+	}
+
+	if key == "" {
+		return false, "missing API key"
+	}
+
+	keyHash := sha256.Sum256([]byte(key))
+
+	matched := 0
+	for _, accepted := range authorizer.keyHashes {
+		matched |= subtle.ConstantTimeCompare(keyHash[:], accepted[:])
+	}
+
+	if matched != 1 {
+		return false, "invalid API key"
+	}
+
+	// keys are identified by a prefix of their hash, which can't be used to recover them
+	setIdentity(r, "key:"+hex.EncodeToString(keyHash[:6]))
+
+	return true, ""
+}
@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Get new jwtAuthorizer for bearer tokens an OIDC issuer (e.g. the SSO) issued for audience. The issuer's JWKS is found
+// through its discovery document, on the first request
+func newOidcAuthorizer(issuer string, audience string, logClaims []string, logger *zap.Logger) *jwtAuthorizer {
+	return newJwtAuthorizer("", issuer, audience, logClaims, logger)
+}
+
+// Get the JWKS URL of an OIDC issuer from its discovery document. The document must name the same issuer, so a
+// misconfigured or spoofed endpoint can't hand out another issuer's keys
+func discoverJwksUrl(ctx context.Context, client *http.Client, issuer string) (string, error) {
+	var discovery struct {
+		Issuer  string `json:"issuer"`
+		JwksUri string `json:"jwks_uri"`
+	}
+
+	if err := getJson(ctx, client, strings.TrimSuffix(issuer, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+		return "", fmt.Errorf("Failed to fetch the OIDC discovery document: %w", err)
+	}
+
+	if discovery.Issuer != issuer {
+		return "", fmt.Errorf("OIDC discovery document is for issuer %q, not %q", discovery.Issuer, issuer)
+	}
+
+	parsed, err := url.Parse(discovery.JwksUri)
+	if err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+		return "", fmt.Errorf("OIDC discovery document has an invalid jwks_uri %q", discovery.JwksUri)
+	}
+
+	return discovery.JwksUri, nil
+}
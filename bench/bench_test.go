@@ -0,0 +1,107 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/cache"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/handlers"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/internal/testutil"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/metrics"
+	"go.uber.org/zap"
+)
+
+var repoCounts = []int{100, 1000, 10000}
+
+// Get a cache hydrated from a fake client with repoCount repos, and handlers serving it
+func newHydratedCache(b *testing.B, repoCount int, args ...string) (cache.Cache, handlers.HttpHandlers) {
+	b.Helper()
+
+	cfg, err := testutil.NewConfiguration(args...)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	client := testutil.NewFakeGithubClient(repoCount/10, repoCount)
+	registry := metrics.NewRegistry()
+	dataCache := cache.NewCache(cfg, client, context.Background(), zap.NewNop(), registry)
+
+	if _, err := dataCache.HydrateCache(context.Background()); err != nil {
+		b.Fatal(err)
+	}
+
+	return dataCache, handlers.NewHttpHandlers(context.Background(), cfg, dataCache, zap.NewNop(), zap.NewAtomicLevel(), client, registry, zap.NewNop())
+}
+
+func BenchmarkHydrateCache(b *testing.B) {
+	for _, repoCount := range repoCounts {
+		b.Run(fmt.Sprintf("repos-%d", repoCount), func(b *testing.B) {
+			dataCache, _ := newHydratedCache(b, repoCount)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := dataCache.HydrateCache(context.Background()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// Serves handler b.N times, failing the benchmark on a non 200 response
+func benchmarkHandler(b *testing.B, handler http.Handler, newRequest func() *http.Request) {
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, newRequest())
+
+		if recorder.Code != http.StatusOK {
+			b.Fatalf("unexpected status code %d", recorder.Code)
+		}
+	}
+}
+
+func BenchmarkReposEncoding(b *testing.B) {
+	for _, repoCount := range repoCounts {
+		b.Run(fmt.Sprintf("repos-%d", repoCount), func(b *testing.B) {
+			_, httpHandlers := newHydratedCache(b, repoCount)
+
+			benchmarkHandler(b, httpHandlers.GetCachedNetflixOrgRepos(), func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/orgs/Netflix/repos", nil)
+			})
+		})
+	}
+}
+
+func BenchmarkBottomViewEncoding(b *testing.B) {
+	cases := []struct {
+		name  string
+		n     string
+		query string
+		args  []string
+	}{
+		{"json-precomputed", "10", "", nil},
+		{"json", "10", "", []string{"--precomputed-view-sizes="}},
+		{"json-large-n", "500", "", nil},
+		{"csv", "10", "?format=csv", nil},
+	}
+
+	for _, c := range cases {
+		b.Run(c.name, func(b *testing.B) {
+			_, httpHandlers := newHydratedCache(b, 1000, c.args...)
+
+			benchmarkHandler(b, httpHandlers.GetCachedBottomNNetflixReposByStars(), func() *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/view/bottom/"+c.n+"/stars"+c.query, nil)
+				r.SetPathValue("n", c.n)
+				return r
+			})
+		})
+	}
+}
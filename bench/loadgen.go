@@ -0,0 +1,181 @@
+package bench
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// paths requested by default, the cached endpoints under the most load
+const DEFAULT_LOAD_TEST_PATHS string = "/orgs/Netflix,/orgs/Netflix/members,/orgs/Netflix/repos,/view/bottom/10/stars,/view/bottom/10/forks"
+
+// Outcome of a single load test request
+type loadTestResult struct {
+	status  int // 0 if the request failed
+	latency time.Duration
+	bytes   int64
+}
+
+// Runs a load test against a running server, cycling through paths from concurrent workers, then prints throughput,
+// status codes, and latency percentiles to out. e.g. loadtest --url http://localhost:7101 --concurrency 50 --duration 30s
+func RunLoadTest(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("loadtest", flag.ExitOnError)
+	baseUrl := flags.String("url", "http://localhost:7101", "Base URL of the server under test")
+	pathList := flags.String("paths", DEFAULT_LOAD_TEST_PATHS, "Comma separated paths to request, in round robin")
+	concurrency := flags.Int("concurrency", 10, "Number of concurrent workers")
+	duration := flags.Duration("duration", 10*time.Second, "How long to generate load for")
+	rate := flags.Int("rate", 0, "Max requests per second across all workers, 0 for unlimited")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var paths []string
+	for _, path := range strings.Split(*pathList, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+
+	if len(paths) == 0 {
+		return fmt.Errorf("paths must not be empty")
+	}
+
+	if *concurrency <= 0 || *duration <= 0 || *rate < 0 {
+		return fmt.Errorf("concurrency and duration must be positive, and rate must not be negative")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(ctx, *duration)
+	defer cancel()
+
+	// a nil channel never fires, so requests aren't throttled without a rate
+	var ticks <-chan time.Time
+	if *rate > 0 {
+		ticker := time.NewTicker(time.Second / time.Duration(*rate))
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	client := &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: &http.Transport{MaxIdleConnsPerHost: *concurrency},
+	}
+
+	results := make([][]loadTestResult, *concurrency)
+	var wg sync.WaitGroup
+
+	start := time.Now()
+
+	for worker := 0; worker < *concurrency; worker++ {
+		wg.Add(1)
+
+		go func(worker int) {
+			defer wg.Done()
+
+			for i := worker; ; i++ {
+				if ticks != nil {
+					select {
+					case <-ticks:
+					case <-ctx.Done():
+						return
+					}
+				}
+
+				if ctx.Err() != nil {
+					return
+				}
+
+				result, ok := sendLoadTestRequest(ctx, client, *baseUrl+paths[i%len(paths)])
+				if !ok {
+					return
+				}
+
+				results[worker] = append(results[worker], result)
+			}
+		}(worker)
+	}
+
+	wg.Wait()
+
+	printLoadTestReport(out, slices.Concat(results...), time.Since(start))
+
+	return nil
+}
+
+// Sends a single request and reads the full body, returns false if the test ended before the request finished
+func sendLoadTestRequest(ctx context.Context, client *http.Client, url string) (loadTestResult, bool) {
+	start := time.Now()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return loadTestResult{latency: time.Since(start)}, true
+	}
+
+	resp, err := client.Do(req)
+	if ctx.Err() != nil {
+		return loadTestResult{}, false
+	}
+
+	if err != nil {
+		return loadTestResult{latency: time.Since(start)}, true
+	}
+	defer resp.Body.Close()
+
+	bytes, _ := io.Copy(io.Discard, resp.Body)
+
+	return loadTestResult{status: resp.StatusCode, latency: time.Since(start), bytes: bytes}, true
+}
+
+// Prints throughput, status codes, and latency percentiles of a load test
+func printLoadTestReport(out io.Writer, results []loadTestResult, elapsed time.Duration) {
+	if len(results) == 0 {
+		fmt.Fprintln(out, "No requests completed")
+		return
+	}
+
+	statusCounts := map[int]int{}
+	latencies := make([]time.Duration, 0, len(results))
+	var totalBytes int64
+
+	for _, result := range results {
+		statusCounts[result.status]++
+		latencies = append(latencies, result.latency)
+		totalBytes += result.bytes
+	}
+
+	sort.Slice(latencies, func(a int, b int) bool { return latencies[a] < latencies[b] })
+
+	percentile := func(p float64) time.Duration {
+		return latencies[int(float64(len(latencies)-1)*p)]
+	}
+
+	fmt.Fprintf(out, "requests:    %d in %s\n", len(results), elapsed.Round(time.Millisecond))
+	fmt.Fprintf(out, "throughput:  %.1f req/s, %.1f KB/s\n", float64(len(results))/elapsed.Seconds(), float64(totalBytes)/1024/elapsed.Seconds())
+	fmt.Fprintf(out, "latency:     p50 %s  p90 %s  p99 %s  max %s\n", percentile(0.5), percentile(0.9), percentile(0.99), latencies[len(latencies)-1])
+
+	statuses := make([]int, 0, len(statusCounts))
+	for status := range statusCounts {
+		statuses = append(statuses, status)
+	}
+	sort.Ints(statuses)
+
+	for _, status := range statuses {
+		label := fmt.Sprint(status)
+		if status == 0 {
+			label = "errors"
+		}
+
+		fmt.Fprintf(out, "status %-6s %d\n", label+":", statusCounts[status])
+	}
+}
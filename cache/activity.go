@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	githubclient "github.com/adamjeanlaurent/github-api-read-cache-service/github-client"
+	"go.uber.org/zap"
+)
+
+// max repos whose commit activity is fetched at once
+const COMMIT_ACTIVITY_CONCURRENCY int = 8
+
+// Fetches the weekly commit activity of every cached repo. Repos GitHub is still computing statistics for keep their
+// previously cached activity, or are left out until a later sync, as are repos whose activity couldn't be fetched. Fails
+// only if every repo's activity failed
+func (c *cache) fetchCommitActivity(ctx context.Context, report *SyncReport) (datasetUpdate, int, error) {
+	repos := c.GetNetflixOrganizationRepos()
+	if !c.IsHydrated(DATASET_REPOS) {
+		err := fmt.Errorf("Can't fetch commit activity before repos are cached")
+		report.recordDataset(DATASET_COMMIT_ACTIVITY, http.StatusServiceUnavailable, 0, err)
+		return datasetUpdate{}, http.StatusServiceUnavailable, err
+	}
+
+	previous := loadDataset[commitActivityData](c.store, DATASET_COMMIT_ACTIVITY).netflixRepoCommitActivity
+
+	activity, statusCode, err := fetchPerRepo(ctx, c, DATASET_COMMIT_ACTIVITY, repoNames(repos), COMMIT_ACTIVITY_CONCURRENCY, previous,
+		func(ctx context.Context, repo string) ([]githubclient.JsonObject, bool, error, int) {
+			resp, err := c.githubClient.GetNetflixRepoCommitActivity(ctx, repo)
+
+			if resp.StatusCode == http.StatusAccepted {
+				previousActivity, ok := previous[strings.ToLower(repo)]
+				return previousActivity, ok, nil, http.StatusOK
+			}
+
+			// empty repos have no activity, cached as an empty list instead of null
+			repoActivity := resp.Body
+			if err == nil && repoActivity == nil {
+				repoActivity = []githubclient.JsonObject{}
+			}
+
+			return repoActivity, true, err, resp.StatusCode
+		})
+
+	report.recordDataset(DATASET_COMMIT_ACTIVITY, statusCode, len(activity), err)
+	if err != nil {
+		return datasetUpdate{}, statusCode, fmt.Errorf("Failed to fetch commit activity: %w", err)
+	}
+
+	if pending := len(repos) - len(activity); pending > 0 {
+		c.logger.Info("GitHub is still computing commit activity, repos without cached activity are left out until a later sync", zap.Int("repos", pending))
+	}
+
+	return newDatasetUpdate(DATASET_COMMIT_ACTIVITY, activity, commitActivityData{
+		netflixRepoCommitActivity:              activity,
+		viewBottomNetflixReposByCommitActivity: computeBottomCommitActivityView(repos, activity),
+	}), http.StatusOK, nil
+}
+
+// Computes the bottom view of repos by number of commits over the last year
+func computeBottomCommitActivityView(repos []githubclient.JsonObject, activity map[string][]githubclient.JsonObject) []Tuple {
+	view := make([]Tuple, 0, len(activity))
+
+	for _, repo := range repos {
+		name, ok := repo["name"].(string)
+		if !ok {
+			continue
+		}
+
+		weeks, ok := activity[strings.ToLower(name)]
+		if !ok {
+			continue
+		}
+
+		var commits float64
+		for _, week := range weeks {
+			total, _ := week["total"].(float64)
+			commits += total
+		}
+
+		view = append(view, Tuple{fmt.Sprintf("Netflix/%s", name), commits})
+	}
+
+	sortBottomViewByCount(view)
+
+	return view
+}
+
+// Get Bottom Netflix Organization Repos By Commit Activity from Cache, empty unless commit activity is hydrated
+func (c *cache) GetBottomNetflixReposByCommitActivity() []Tuple {
+	return loadDataset[commitActivityData](c.store, DATASET_COMMIT_ACTIVITY).viewBottomNetflixReposByCommitActivity
+}
+
+// Get the weekly commit activity of a single Netflix Organization Repo by name from Cache, accepts either "repo" or "Netflix/repo"
+func (c *cache) GetNetflixRepoCommitActivity(repo string) ([]githubclient.JsonObject, bool) {
+	activity, ok := loadDataset[commitActivityData](c.store, DATASET_COMMIT_ACTIVITY).netflixRepoCommitActivity[strings.TrimPrefix(strings.ToLower(repo), "netflix/")]
+	return activity, ok
+}
@@ -0,0 +1,187 @@
+package cache
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	githubclient "github.com/adamjeanlaurent/github-api-read-cache-service/github-client"
+	"go.uber.org/zap"
+)
+
+// Single event from a GHArchive dump, or a row of a BigQuery export of the githubarchive tables
+type archiveEvent struct {
+	Type string `json:"type"`
+	Repo struct {
+		Name string `json:"name"`
+	} `json:"repo"`
+	RepoName  string `json:"repo_name"` // flattened BigQuery exports
+	CreatedAt string `json:"created_at"`
+	Payload   struct {
+		Action string `json:"action"`
+	} `json:"payload"`
+}
+
+// Approximate repo stats accumulated from archive events
+type archiveRepoStats struct {
+	name       string
+	updatedAt  time.Time
+	stars      float64
+	forks      float64
+	openIssues float64
+}
+
+// Seeds the cache with approximate repo metadata derived from a GHArchive / BigQuery export file (newline delimited JSON, optionally gzipped).
+// Used at first boot when the GitHub API quota is exhausted, the data is marked approximate until the first real hydration replaces it.
+func (c *cache) BootstrapFromArchive(path string) error {
+	report := newSyncReport()
+	report.Approximate = true
+
+	repos, err := readArchiveRepos(path)
+	if err != nil {
+		report.recordDataset(DATASET_REPOS, http.StatusInternalServerError, 0, err)
+		report.finish(http.StatusInternalServerError, err)
+		return err
+	}
+	report.recordDataset(DATASET_REPOS, http.StatusOK, len(repos), nil)
+
+	views, err := computeBottomViews(repos, c.views)
+	if err != nil {
+		report.recordDataset(DATASET_VIEWS, http.StatusInternalServerError, 0, err)
+		report.finish(http.StatusInternalServerError, err)
+		return err
+	}
+	report.recordDataset(DATASET_VIEWS, http.StatusOK, len(repos), nil)
+	report.finish(http.StatusOK, nil)
+	c.precomputeBottomViews(&views)
+	views.breakdowns = computeRepoBreakdowns(repos)
+
+	netflixOrg := githubclient.JsonObject{"login": "Netflix"}
+	reposUpdate := newDatasetUpdate(DATASET_REPOS, repos, reposData{
+		netflixOrganizationRepos:       repos,
+		netflixOrganizationReposByName: indexReposByName(repos),
+		excludedRepos:                  indexExcludedRepos(repos),
+		reposByType:                    indexReposByType(repos),
+	})
+	reposUpdate.derived = map[string]interface{}{DATASET_VIEWS: views}
+
+	c.replaceDatasets(&report, time.Now().UTC(), true, newDatasetUpdate(DATASET_ORGANIZATION, netflixOrg, netflixOrg), reposUpdate)
+
+	return nil
+}
+
+// Reads an archive export, and derives approximate repo objects for every Netflix repo that appears in it
+func readArchiveRepos(path string) ([]githubclient.JsonObject, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open archive file: %w", err)
+	}
+	defer file.Close()
+
+	buffered := bufio.NewReader(file)
+
+	var in io.Reader = buffered
+
+	// gzip magic bytes, GHArchive hourly dumps are gzipped
+	if magic, err := buffered.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gzipReader, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read gzipped archive file: %w", err)
+		}
+		defer gzipReader.Close()
+
+		in = gzipReader
+	}
+
+	stats := map[string]*archiveRepoStats{}
+	decoder := json.NewDecoder(in)
+
+	for {
+		var event archiveEvent
+		if err := decoder.Decode(&event); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("Failed to parse archive event: %w", err)
+		}
+
+		fullName := event.Repo.Name
+		if fullName == "" {
+			fullName = event.RepoName
+		}
+
+		owner, name, found := strings.Cut(fullName, "/")
+		if !found || !strings.EqualFold(owner, "Netflix") {
+			continue
+		}
+
+		repo, ok := stats[strings.ToLower(name)]
+		if !ok {
+			repo = &archiveRepoStats{name: name}
+			stats[strings.ToLower(name)] = repo
+		}
+
+		if createdAt, err := time.Parse(time.RFC3339, event.CreatedAt); err == nil && createdAt.After(repo.updatedAt) {
+			repo.updatedAt = createdAt
+		}
+
+		switch event.Type {
+		case "WatchEvent":
+			repo.stars++
+		case "ForkEvent":
+			repo.forks++
+		case "IssuesEvent", "PullRequestEvent":
+			// open_issues_count includes pull requests
+			if event.Payload.Action == "opened" || event.Payload.Action == "reopened" {
+				repo.openIssues++
+			} else if event.Payload.Action == "closed" && repo.openIssues > 0 {
+				repo.openIssues--
+			}
+		}
+	}
+
+	repos := make([]githubclient.JsonObject, 0, len(stats))
+
+	for _, repo := range stats {
+		repos = append(repos, githubclient.JsonObject{
+			"name":              repo.name,
+			"full_name":         "Netflix/" + repo.name,
+			"updated_at":        repo.updatedAt.Format(time.RFC3339),
+			"stargazers_count":  repo.stars,
+			"forks_count":       repo.forks,
+			"open_issues_count": repo.openIssues,
+			"approximate":       true,
+		})
+	}
+
+	return repos, nil
+}
+
+// Determines if a failed hydration was caused by an exhausted GitHub API quota: GitHub answered 429, or the client is in
+// backoff because GitHub reported no requests left. Other 403s, e.g. a missing permission or SAML enforcement, are token or
+// config errors to report rather than wait out
+func isQuotaExhausted(statusCode int, backoffResetTime time.Time) bool {
+	return statusCode == http.StatusTooManyRequests || !backoffResetTime.IsZero()
+}
+
+// Seeds the cache from the configured archive file if the initial hydration failed because the quota is exhausted, returns true if the cache was seeded
+func (c *cache) bootstrapOnQuotaExhausted(statusCode int) bool {
+	if c.bootstrapArchiveFile == "" || !isQuotaExhausted(statusCode, c.githubClient.GetBackoffResetTime()) {
+		return false
+	}
+
+	c.logger.Warn("GitHub API quota exhausted at startup, seeding cache with approximate data from archive", zap.String("file", c.bootstrapArchiveFile))
+
+	if err := c.BootstrapFromArchive(c.bootstrapArchiveFile); err != nil {
+		c.logger.Error("Failed to seed cache from archive", zap.Error(err))
+		return false
+	}
+
+	c.logger.Info("Seeded cache with approximate data from archive")
+	return true
+}
@@ -0,0 +1,30 @@
+package cache
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestIsQuotaExhausted(t *testing.T) {
+	backoffResetTime := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name             string
+		statusCode       int
+		backoffResetTime time.Time
+		exhausted        bool
+	}{
+		{name: "429", statusCode: http.StatusTooManyRequests, exhausted: true},
+		{name: "403 with no requests left", statusCode: http.StatusForbidden, backoffResetTime: backoffResetTime, exhausted: true},
+		{name: "403 without the quota exhausted", statusCode: http.StatusForbidden},
+		{name: "401", statusCode: http.StatusUnauthorized},
+		{name: "500", statusCode: http.StatusInternalServerError},
+	}
+
+	for _, test := range tests {
+		if exhausted := isQuotaExhausted(test.statusCode, test.backoffResetTime); exhausted != test.exhausted {
+			t.Errorf("%s: isQuotaExhausted = %v, want %v", test.name, exhausted, test.exhausted)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"slices"
+
+	githubclient "github.com/adamjeanlaurent/github-api-read-cache-service/github-client"
+)
+
+// Names of the breakdowns of repos, counted every time repos are hydrated
+const (
+	BREAKDOWN_LICENSES string = "licenses" // repos per license SPDX ID
+	BREAKDOWN_ARCHIVED string = "archived" // archived vs active repos
+)
+
+// Every breakdown computed by the cache
+var breakdowns = []string{BREAKDOWN_LICENSES, BREAKDOWN_ARCHIVED}
+
+// Labels of repos without a license, and of archived and active repos
+const (
+	LICENSE_NONE   string = "none"
+	LABEL_ARCHIVED string = "archived"
+	LABEL_ACTIVE   string = "active"
+)
+
+// Counts repos per license and archived vs active, as lists of [label, count] tuples with the largest count first, by breakdown name.
+// Repos with a license GitHub doesn't recognize are counted under its NOASSERTION SPDX ID
+func computeRepoBreakdowns(netflixOrgRepos []githubclient.JsonObject) map[string][]Tuple {
+	licenses := map[string]float64{}
+	archived := map[string]float64{LABEL_ARCHIVED: 0, LABEL_ACTIVE: 0}
+
+	for _, repo := range netflixOrgRepos {
+		license := LICENSE_NONE
+		if details, ok := repo["license"].(map[string]interface{}); ok {
+			if spdxId, ok := details["spdx_id"].(string); ok && spdxId != "" {
+				license = spdxId
+			}
+		}
+		licenses[license]++
+
+		if isArchived, _ := repo["archived"].(bool); isArchived {
+			archived[LABEL_ARCHIVED]++
+		} else {
+			archived[LABEL_ACTIVE]++
+		}
+	}
+
+	return map[string][]Tuple{
+		BREAKDOWN_LICENSES: countTuples(licenses),
+		BREAKDOWN_ARCHIVED: countTuples(archived),
+	}
+}
+
+// Get counts as [label, count] tuples, largest count first
+func countTuples(counts map[string]float64) []Tuple {
+	tuples := make([]Tuple, 0, len(counts))
+
+	for label, count := range counts {
+		tuples = append(tuples, Tuple{label, count})
+	}
+
+	sortBottomViewByCount(tuples)
+
+	return tuples
+}
+
+// Get a breakdown of the repos, false if the breakdown isn't computed by the cache
+func (c *cache) GetRepoBreakdown(breakdown string) ([]Tuple, bool) {
+	return c.Snapshot().RepoBreakdown(breakdown)
+}
+
+// Get a breakdown of the repos, false if the breakdown isn't computed by the cache
+func (snapshot Snapshot) RepoBreakdown(breakdown string) ([]Tuple, bool) {
+	if !slices.Contains(breakdowns, breakdown) {
+		return nil, false
+	}
+
+	return snapshotDataset[viewsData](snapshot, DATASET_VIEWS).breakdowns[breakdown], true
+}
@@ -2,96 +2,503 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/adamjeanlaurent/github-api-read-cache-service/alerts"
 	"github.com/adamjeanlaurent/github-api-read-cache-service/config"
 	githubclient "github.com/adamjeanlaurent/github-api-read-cache-service/github-client"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/metrics"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/replication"
+	"github.com/klauspost/compress/zstd"
 	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
 )
 
 type Cache interface {
 	StartSyncLoop()
+	SyncLoopDone() <-chan error
 	GetNetflixOrganization() githubclient.JsonObject
 	GetNetflixOrganizationMembers() []githubclient.JsonObject
 	GetNetflixOrganizationRepos() []githubclient.JsonObject
+	GetNetflixOrganizationRepo(name string) (githubclient.JsonObject, bool)
+	GetNetflixOrganizationMember(login string) (githubclient.JsonObject, bool)
 	GetBottomNetflixReposByForks() []Tuple
 	GetBottomNetflixReposByUpdateTime() []Tuple
 	GetBottomNetflixReposByOpenIssues() []Tuple
 	GetBottomNetflixReposByStars() []Tuple
-	GetLastCacheSyncStatus() int
-	HydrateCache() (int, error)
+	GetBottomNetflixReposByContributors() []Tuple
+	GetView(view string) ([]Tuple, bool)
+	GetViewDefinitions() []ViewDefinition
+	GetRepoBreakdown(breakdown string) ([]Tuple, bool)
+	Snapshot() Snapshot
+	GetNetflixRepoContributors(repo string) ([]githubclient.JsonObject, bool)
+	GetNetflixRepoCommitActivity(repo string) ([]githubclient.JsonObject, bool)
+	GetRecentNetflixReleases() []Tuple
+	GetBottomNetflixReposByCommitActivity() []Tuple
+	GetNetflixRepoLatestRelease(repo string) (githubclient.JsonObject, bool)
+	GetNetflixTeams() []githubclient.JsonObject
+	GetBottomNetflixReposByIssues() []Tuple
+	GetBottomNetflixReposByPullRequests() []Tuple
+	GetNetflixTeamRepos(team string) ([]githubclient.JsonObject, bool)
+	GetNetflixRepoReadme(repo string) (*githubclient.Readme, bool)
+	GetNetflixRepoLanguages(repo string) (githubclient.JsonObject, bool)
+	GetExtraEndpoint(path string) (githubclient.RawResponse, bool)
+	GetPrecomputedBottomView(format string, view string, n int) ([]byte, bool)
+	GetLastSyncReport() SyncReport
+	GetLastHydrationTime() time.Time
+	GetDatasetHydrationTime(dataset string) time.Time
+	GetNextSyncTime(dataset string) time.Time
+	SetAlertHooks(hooks alerts.Hooks)
+	IsHydrated(dataset string) bool
+	IsApproximate() bool
+	IsSyncing() bool
+	GetETag(dataset string) string
+	GetStats() CacheStats
+	Subscribe() (<-chan DatasetsUpdated, func())
+	RequestRefresh(dataset string)
+	Status() Status
+	BootstrapFromArchive(path string) error
+	HydrateCache(ctx context.Context) (int, error)
+	HydrateDataset(ctx context.Context, dataset string) (int, error)
+	HydrateOrg(ctx context.Context) (int, error)
+	HydrateMembers(ctx context.Context) (int, error)
+	HydrateRepos(ctx context.Context) (int, error)
+	HydratableDatasets() []string
+	Export() CacheExport
+	Import(export CacheExport) error
+	WriteSnapshot(path string) error
+	LoadSnapshot(path string) error
+	ProbePersistence(ctx context.Context) error
 }
 
 type Tuple = [2]interface{}
 
-// Stores In-memory cache of netflix github data, re-hydrates the cache on a fixed interval
-type cacheData struct {
-	netflixOrganization                githubclient.JsonObject
-	netflixOrganizationMembers         []githubclient.JsonObject
-	netflixOrganizationRepos           []githubclient.JsonObject
-	viewBottomNetflixReposByForks      []Tuple
-	viewBottomNetflixReposByUpdateTime []Tuple
-	viewBottomNetflixReposByOpenIssues []Tuple
-	viewBottomNetflixReposByStars      []Tuple
+// Cached Netflix organization members, along with a lookup by lower-cased login
+type membersData struct {
+	netflixOrganizationMembers        []githubclient.JsonObject
+	netflixOrganizationMembersByLogin map[string]githubclient.JsonObject
+}
+
+// Cached Netflix organization repos, along with a lookup by lower-cased name
+type reposData struct {
+	netflixOrganizationRepos       []githubclient.JsonObject
+	netflixOrganizationReposByName map[string]githubclient.JsonObject
+	excludedRepos                  map[string]map[string]bool // lower-cased names of the repos each exclusion filters out, by exclusion
+	removedRepos                   []RemovedRepo              // repos that disappeared from the org within the retention, most recent first
+	viewRemovedRepos               []Tuple
+	reposByType                    map[string][]githubclient.JsonObject // repos of each type other than all, by type
+}
+
+// Sorted views of the repos, computed along with them
+type viewsData struct {
+	views                  map[string][]Tuple                   // sorted from top to bottom, by view name
+	precomputedBottomViews map[string]map[string]map[int][]byte // encoded bottom N of each view, by format, then view, then N
+	breakdowns             map[string][]Tuple                   // counts of repos by license and by archived, by breakdown name
+}
+
+// Cached contributors of every repo, only when contributors are hydrated
+type contributorsData struct {
+	netflixRepoContributors              map[string][]githubclient.JsonObject // by lower-cased repo name
+	viewBottomNetflixReposByContributors []Tuple
+}
+
+// Cached latest releases of the configured repos, only when releases are hydrated
+type releasesData struct {
+	netflixRepoLatestReleases map[string]githubclient.JsonObject // by lower-cased repo name, nil for tracked repos without releases
+	viewRecentNetflixReleases []Tuple
+}
+
+// Cached weekly commit activity of every repo, only when commit activity is hydrated
+type commitActivityData struct {
+	netflixRepoCommitActivity              map[string][]githubclient.JsonObject // by lower-cased repo name, repos GitHub is still computing are missing
+	viewBottomNetflixReposByCommitActivity []Tuple
+}
+
+// Cached teams of the org and the repos of each, only when teams are hydrated
+type teamsData struct {
+	netflixTeams     []githubclient.JsonObject
+	netflixTeamRepos map[string][]githubclient.JsonObject // by lower-cased team slug
+}
+
+// Cached open issue and pull request counts of every repo, only when issue counts are hydrated
+type issueCountsData struct {
+	netflixRepoIssueCounts               map[string]githubclient.IssueCounts // by lower-cased repo name
+	viewBottomNetflixReposByIssues       []Tuple
+	viewBottomNetflixReposByPullRequests []Tuple
+}
+
+// Cached READMEs of every repo, only when READMEs are hydrated
+type readmesData struct {
+	netflixRepoReadmes map[string]*githubclient.Readme // by lower-cased repo name, nil for repos without a README
+}
+
+// Cached bytes of code per language of every repo, only when languages are hydrated
+type languagesData struct {
+	netflixRepoLanguages map[string]githubclient.JsonObject // by lower-cased repo name
+	netflixOrgLanguages  []Tuple                            // [language, bytes] summed over every repo, most bytes first
+}
+
+// Cached verbatim responses of the configured extra endpoints, only when extra endpoints are configured
+type extraEndpointsData struct {
+	extraEndpoints map[string]githubclient.RawResponse // by path
 }
 
 type cache struct {
-	ttl                 time.Duration
-	lock                sync.RWMutex
-	githubClient        githubclient.GithubClient
-	ctx                 context.Context
-	data                *cacheData
-	logger              *zap.Logger
-	lastCacheSyncStatus int
+	orgTTL                  time.Duration
+	membersTTL              time.Duration
+	reposTTL                time.Duration
+	lock                    sync.RWMutex
+	githubClient            githubclient.GithubClient
+	ctx                     context.Context
+	store                   Store // each dataset is re-hydrated on its own fixed interval
+	logger                  *zap.Logger
+	lastSyncReport          SyncReport
+	waitForCache            bool
+	waitForCacheTimeout     time.Duration
+	bootstrapArchiveFile    string
+	snapshotFile            string
+	zstdLevel               zstd.EncoderLevel
+	precomputedViewSizes    []int
+	views                   []ViewDefinition // computed over the repos every time they're hydrated
+	strictHydration         bool
+	snapshotReplicator      replication.Replicator // nil unless snapshots are replicated
+	preferFreshestSnapshot  bool
+	seedFile                string
+	seedDownloader          replication.Replicator // nil unless the cache is seeded from a URL
+	replicationLock         sync.Mutex
+	hydrateContributors     bool
+	contributorsTTL         time.Duration
+	contributorsConcurrency int
+	releaseRepos            []string // empty unless releases are hydrated
+	releasesTTL             time.Duration
+	hydrateCommitActivity   bool
+	commitActivityTTL       time.Duration
+	hydrateTeams            bool
+	teamsTTL                time.Duration
+	hydrateIssueCounts      bool
+	issueCountsTTL          time.Duration
+	issueCountsConcurrency  int
+	hydrateReadmes          bool
+	readmesTTL              time.Duration
+	readmesConcurrency      int
+	hydrateLanguages        bool
+	languagesTTL            time.Duration
+	languagesConcurrency    int
+	extraEndpoints          []string
+	extraEndpointsTTL       time.Duration
+	incrementalRepoSync     bool
+	reposFullSyncInterval   time.Duration
+	removedReposRetention   time.Duration
+	lastFullRepoSync        time.Time // guarded by lock, zero until repos are fully synced
+	maxCacheBytes           int       // 0 for no limit
+	memoryLimitAction       string
+	trimmed                 []string // optional data dropped to stay under the memory limit
+	subscribers             subscribers
+	statsLock               sync.Mutex
+	sizeHistory             map[string][]DatasetSizeSample
+	datasetBytesGauge       metrics.Gauge
+	totalBytesGauge         metrics.Gauge
+	overLimitGauge          metrics.Gauge
+	syncDurations           metrics.Histogram
+	upstreamRequests        metrics.Counter
+	upstreamCost            metrics.Counter
+	syncLoopDone            chan error    // receives why the sync loop stopped, nil once the context is done, then is closed
+	refreshOnMutationDelay  time.Duration // 0 unless datasets are re-hydrated after proxied writes
+	refreshes               pendingRefreshes
+	syncFailures            map[string]int // consecutive failed syncs by dataset, guarded by statsLock
+	alertHooks              alerts.Hooks
+	syncSchedule            map[string]time.Time // when the ticker of each dataset the sync loop hydrates started, guarded by statsLock
+	skipUnchangedSyncs      bool
+	skipUnchangedMaxAge     time.Duration
+	eventsETags             map[string]string // ETag of the org events when each dataset was last synced, guarded by statsLock
+	consistencyCheck        bool
+	consistencyInterval     time.Duration
+	consistencyCheckSample  int
+	consistencyChecks       metrics.Counter
+	perRepoFailures         metrics.Counter
+	driftedReposGauge       metrics.Gauge
+	fullHydrations          atomic.Int32 // full hydrations in progress, at startup or forced by cache misses
+}
+
+// Get New Cache, held in memory
+func NewCache(cfg config.Configuration, client githubclient.GithubClient, context context.Context, logger *zap.Logger, registry metrics.Registry) Cache {
+	return NewCacheWithStore(cfg, client, context, logger, registry, NewMemoryStore())
+}
+
+// Get the Replicator snapshots are replicated with, to the snapshot bucket or the snapshot replica URL, nil unless either is configured
+func NewSnapshotReplicator(cfg config.Configuration) replication.Replicator {
+	if bucket := cfg.GetSnapshotBucket(); bucket.Name != "" {
+		// the bucket is validated along with the configuration
+		replicator, _ := replication.NewBucketReplicator(bucket)
+		return replicator
+	}
+
+	if cfg.GetSnapshotReplicaUrl() != "" {
+		return replication.NewHttpReplicator(cfg.GetSnapshotReplicaUrl())
+	}
+
+	return nil
 }
 
-// Get New Cache
-func NewCache(cfg config.Configuration, client githubclient.GithubClient, context context.Context, logger *zap.Logger) Cache {
-	return &cache{ttl: time.Duration(cfg.GetCacheTTL()), githubClient: client, ctx: context, logger: logger, lastCacheSyncStatus: http.StatusOK, data: &cacheData{}}
+// Get New Cache, held in store
+func NewCacheWithStore(cfg config.Configuration, client githubclient.GithubClient, context context.Context, logger *zap.Logger, registry metrics.Registry, store Store) Cache {
+	snapshotReplicator := NewSnapshotReplicator(cfg)
+
+	var seedDownloader replication.Replicator
+	if cfg.GetSeedUrl() != "" {
+		seedDownloader = replication.NewHttpReplicator(cfg.GetSeedUrl())
+	}
+
+	c := &cache{
+		orgTTL:                  cfg.GetOrgTTL(),
+		membersTTL:              cfg.GetMembersTTL(),
+		reposTTL:                cfg.GetReposTTL(),
+		githubClient:            client,
+		ctx:                     context,
+		logger:                  logger,
+		lastSyncReport:          SyncReport{Status: http.StatusOK},
+		store:                   store,
+		waitForCache:            cfg.GetWaitForCache(),
+		waitForCacheTimeout:     cfg.GetWaitForCacheTimeout(),
+		bootstrapArchiveFile:    cfg.GetBootstrapArchiveFile(),
+		snapshotFile:            cfg.GetSnapshotFile(),
+		zstdLevel:               cfg.GetZstdLevel(),
+		precomputedViewSizes:    cfg.GetPrecomputedViewSizes(),
+		views:                   EnabledViews(cfg.GetExtraViews()),
+		strictHydration:         cfg.GetStrictHydration(),
+		snapshotReplicator:      snapshotReplicator,
+		preferFreshestSnapshot:  cfg.GetPreferFreshestSnapshot(),
+		seedFile:                cfg.GetSeedFile(),
+		seedDownloader:          seedDownloader,
+		sizeHistory:             map[string][]DatasetSizeSample{},
+		datasetBytesGauge:       registry.Gauge("cache_dataset_bytes", "Size of each cached dataset's JSON encoding in bytes"),
+		totalBytesGauge:         registry.Gauge("cache_total_bytes", "Approximate memory footprint of the cache in bytes"),
+		overLimitGauge:          registry.Gauge("cache_memory_limit_exceeded", "Whether the cache is over its configured memory limit (1) or not (0)"),
+		syncLoopDone:            make(chan error, 1),
+		syncDurations:           registry.Histogram("cache_sync_duration_seconds", "Time taken to sync the cache with GitHub in seconds, by dataset (all for a full hydration) and result", metrics.DURATION_BUCKETS),
+		upstreamRequests:        registry.Counter("cache_sync_github_requests_total", "Number of GitHub requests made by cache syncs, by dataset (all for a full hydration)"),
+		upstreamCost:            registry.Counter("cache_sync_github_quota_cost_total", "Number of GitHub requests made by cache syncs counted against the rate limit, by dataset (all for a full hydration) and rate limit resource"),
+		hydrateContributors:     cfg.GetHydrateContributors(),
+		contributorsTTL:         cfg.GetContributorsTTL(),
+		contributorsConcurrency: cfg.GetContributorsConcurrency(),
+		releaseRepos:            cfg.GetReleaseRepos(),
+		releasesTTL:             cfg.GetReleasesTTL(),
+		hydrateCommitActivity:   cfg.GetHydrateCommitActivity(),
+		commitActivityTTL:       cfg.GetCommitActivityTTL(),
+		hydrateTeams:            cfg.GetHydrateTeams(),
+		teamsTTL:                cfg.GetTeamsTTL(),
+		hydrateIssueCounts:      cfg.GetHydrateIssueCounts(),
+		issueCountsTTL:          cfg.GetIssueCountsTTL(),
+		issueCountsConcurrency:  cfg.GetIssueCountsConcurrency(),
+		hydrateReadmes:          cfg.GetHydrateReadmes(),
+		readmesTTL:              cfg.GetReadmesTTL(),
+		readmesConcurrency:      cfg.GetReadmesConcurrency(),
+		hydrateLanguages:        cfg.GetHydrateLanguages(),
+		languagesTTL:            cfg.GetLanguagesTTL(),
+		languagesConcurrency:    cfg.GetLanguagesConcurrency(),
+		extraEndpoints:          cfg.GetExtraEndpoints(),
+		extraEndpointsTTL:       cfg.GetExtraEndpointsTTL(),
+		incrementalRepoSync:     cfg.GetIncrementalRepoSync(),
+		reposFullSyncInterval:   cfg.GetReposFullSyncInterval(),
+		removedReposRetention:   cfg.GetRemovedReposRetention(),
+		maxCacheBytes:           cfg.GetMaxCacheBytes(),
+		syncFailures:            map[string]int{},
+		alertHooks:              alerts.NopHooks{},
+		syncSchedule:            map[string]time.Time{},
+		memoryLimitAction:       cfg.GetMemoryLimitAction(),
+		refreshOnMutationDelay:  cfg.GetRefreshOnMutationDelay(),
+		refreshes:               pendingRefreshes{datasets: map[string]bool{}, signal: make(chan struct{}, 1)},
+		skipUnchangedSyncs:      cfg.GetSkipUnchangedSyncs(),
+		skipUnchangedMaxAge:     cfg.GetSkipUnchangedMaxAge(),
+		eventsETags:             map[string]string{},
+		consistencyCheck:        cfg.GetConsistencyCheck(),
+		consistencyInterval:     cfg.GetConsistencyCheckInterval(),
+		consistencyCheckSample:  cfg.GetConsistencyCheckSample(),
+		perRepoFailures:         registry.Counter("cache_per_repo_fetch_failures_total", "Number of repos (or teams) whose value couldn't be fetched by syncs of per-repo datasets, which keep their previous value, by dataset"),
+		consistencyChecks:       registry.Counter("cache_consistency_checks_total", "Number of cached repos compared against GitHub by consistency checks, by result"),
+		driftedReposGauge:       registry.Gauge("cache_consistency_drifted_repos", "Number of cached repos that drifted from GitHub in the last consistency check"),
+	}
+
+	// stores that keep datasets as bytes get values encoded and decoded for them
+	if !store.HoldsValues() {
+		c.store = newSerializingStore(store, c)
+	}
+
+	return c
 }
 
-// Starts thread that on a fixed interval, makes requests to the GitHub API, computes views, and updates the cache
+// Hydrates the cache for server startup, then starts thread that on a fixed interval per dataset, makes requests to the GitHub API, computes views, and updates the cache
 func (c *cache) StartSyncLoop() {
-	ticker := time.NewTicker(c.ttl)
+	orgTicker := time.NewTicker(c.orgTTL)
+	membersTicker := time.NewTicker(c.membersTTL)
+	reposTicker := time.NewTicker(c.reposTTL)
+	c.scheduleSyncs(DATASET_ORGANIZATION, DATASET_MEMBERS, DATASET_REPOS)
+
+	// serve the last persisted data if every startup attempt fails
+	c.loadSnapshotOnStartup()
+
+	// a seeded cache serves the seed right away, and is hydrated in the background instead
+	seeded := c.loadSeedOnStartup()
+	if !seeded && !c.hydrateForStartup() {
+		orgTicker.Stop()
+		membersTicker.Stop()
+		reposTicker.Stop()
+		c.finishSyncLoop(nil)
+		return
+	}
 
-	// Try 5 times to initially hydrate the cache
-	retriesLeft := 5
-	for retriesLeft > 0 {
-		c.logger.Info("Hydrating cache for server startup", zap.Int("attempts left", retriesLeft))
+	// contributors take a request per repo, so they're optional, and hydrated in the background instead of delaying startup
+	contributorsTicker := time.NewTicker(c.contributorsTTL)
+	if !c.hydrateContributors {
+		contributorsTicker.Stop()
+	}
 
-		statusCode, err := c.HydrateCache()
-		c.lastCacheSyncStatus = statusCode
+	releasesTicker := time.NewTicker(c.releasesTTL)
+	if len(c.releaseRepos) == 0 {
+		releasesTicker.Stop()
+	}
 
-		if err == nil {
-			c.logger.Info("Successfully hydrated cache")
-			break
-		}
+	commitActivityTicker := time.NewTicker(c.commitActivityTTL)
+	if !c.hydrateCommitActivity {
+		commitActivityTicker.Stop()
+	}
 
-		c.logger.Warn(fmt.Sprintf("Attempt %d failed backing off for %d seconds", 5-retriesLeft, 5), zap.Error(err), zap.Int("Http status code", statusCode))
+	teamsTicker := time.NewTicker(c.teamsTTL)
+	if !c.hydrateTeams {
+		teamsTicker.Stop()
+	}
 
-		time.Sleep(5 * time.Second)
-		retriesLeft--
+	issueCountsTicker := time.NewTicker(c.issueCountsTTL)
+	if !c.hydrateIssueCounts {
+		issueCountsTicker.Stop()
+	}
+
+	readmesTicker := time.NewTicker(c.readmesTTL)
+	if !c.hydrateReadmes {
+		readmesTicker.Stop()
+	}
+
+	languagesTicker := time.NewTicker(c.languagesTTL)
+	if !c.hydrateLanguages {
+		languagesTicker.Stop()
+	}
+
+	extraEndpointsTicker := time.NewTicker(c.extraEndpointsTTL)
+	if len(c.extraEndpoints) == 0 {
+		extraEndpointsTicker.Stop()
 	}
 
+	c.scheduleSyncs(c.optionalDatasets()...)
+
+	consistencyTicker := time.NewTicker(c.consistencyInterval)
+	if !c.consistencyCheck {
+		consistencyTicker.Stop()
+	}
+
+	// each dataset is re-hydrated on its own schedule
 	go func() {
-		defer ticker.Stop()
+		// a panicking sync is reported instead of crashing the process, so the server can shut down gracefully
+		defer func() {
+			var err error
+			if r := recover(); r != nil {
+				err = fmt.Errorf("Cache sync loop panicked: %v", r)
+				c.logger.Error("Cache sync loop panicked", zap.Any("panic", r), zap.Stack("stack"))
+			}
+			c.finishSyncLoop(err)
+		}()
+		defer orgTicker.Stop()
+		defer membersTicker.Stop()
+		defer reposTicker.Stop()
+		defer contributorsTicker.Stop()
+		defer releasesTicker.Stop()
+		defer commitActivityTicker.Stop()
+		defer teamsTicker.Stop()
+		defer issueCountsTicker.Stop()
+		defer readmesTicker.Stop()
+		defer languagesTicker.Stop()
+		defer extraEndpointsTicker.Stop()
+		defer consistencyTicker.Stop()
+
+		if seeded && !c.hydrateForStartup() {
+			return
+		}
+
+		if len(c.releaseRepos) > 0 {
+			c.syncDataset(DATASET_RELEASES, c.fetchReleases)
+		}
+
+		if c.hydrateContributors {
+			c.syncDataset(DATASET_CONTRIBUTORS, c.fetchContributors)
+		}
+
+		if c.hydrateCommitActivity {
+			c.syncDataset(DATASET_COMMIT_ACTIVITY, c.fetchCommitActivity)
+		}
+
+		if c.hydrateTeams {
+			c.syncDataset(DATASET_TEAMS, c.fetchTeams)
+		}
+
+		if c.hydrateIssueCounts {
+			c.syncDataset(DATASET_ISSUE_COUNTS, c.fetchIssueCounts)
+		}
+
+		if c.hydrateReadmes {
+			c.syncDataset(DATASET_READMES, c.fetchReadmes)
+		}
+
+		if c.hydrateLanguages {
+			c.syncDataset(DATASET_LANGUAGES, c.fetchLanguages)
+		}
+
+		if len(c.extraEndpoints) > 0 {
+			c.syncDataset(DATASET_EXTRA_ENDPOINTS, c.fetchExtraEndpoints)
+		}
+
+		// started by the first proxied write after the last refresh, so later writes are coalesced into the same re-hydration
+		var refreshTimer *time.Timer
+
 		for {
 			select {
-			case <-ticker.C:
-				c.logger.Info("Attempting to re-Hydrate cache")
-				statusCode, err := c.HydrateCache()
-
-				if err != nil {
-					c.logger.Error("Failed to hydrate cache", zap.Error(err), zap.Int("Http status code", statusCode))
-				} else {
-					c.logger.Info("Successfully re-hydrated cache")
+			case <-orgTicker.C:
+				c.syncDataset(DATASET_ORGANIZATION, c.fetchOrg)
+			case <-membersTicker.C:
+				c.syncUnlessUnchanged(DATASET_MEMBERS, c.fetchMembers)
+			case <-reposTicker.C:
+				c.syncUnlessUnchanged(DATASET_REPOS, c.fetchRepos)
+			case <-contributorsTicker.C:
+				c.syncDataset(DATASET_CONTRIBUTORS, c.fetchContributors)
+			case <-releasesTicker.C:
+				c.syncDataset(DATASET_RELEASES, c.fetchReleases)
+			case <-commitActivityTicker.C:
+				c.syncDataset(DATASET_COMMIT_ACTIVITY, c.fetchCommitActivity)
+			case <-teamsTicker.C:
+				c.syncDataset(DATASET_TEAMS, c.fetchTeams)
+			case <-issueCountsTicker.C:
+				c.syncDataset(DATASET_ISSUE_COUNTS, c.fetchIssueCounts)
+			case <-readmesTicker.C:
+				c.syncDataset(DATASET_READMES, c.fetchReadmes)
+			case <-languagesTicker.C:
+				c.syncDataset(DATASET_LANGUAGES, c.fetchLanguages)
+			case <-extraEndpointsTicker.C:
+				c.syncDataset(DATASET_EXTRA_ENDPOINTS, c.fetchExtraEndpoints)
+			case <-consistencyTicker.C:
+				c.checkConsistency()
+			case <-c.refreshes.signal:
+				if refreshTimer == nil {
+					refreshTimer = time.NewTimer(c.refreshOnMutationDelay)
 				}
-
-				c.lastCacheSyncStatus = statusCode
+			case <-refreshTimerChannel(refreshTimer):
+				refreshTimer = nil
+				c.refreshMutatedDatasets()
 			case <-c.ctx.Done():
 				c.logger.Info("Cache Ticker Stopped")
 				return
@@ -100,83 +507,171 @@ func (c *cache) StartSyncLoop() {
 	}()
 }
 
-// Makes requests to the GitHub API, computes views, and updates the cache
-func (c *cache) HydrateCache() (int, error) {
-	// fetch new data
-	netflixOrgMembers, err, statusCode := c.githubClient.GetNetflixOrgMembers(c.ctx)
-	if err != nil {
-		return statusCode, fmt.Errorf("Failed to fetch netflix organization members: %s", err.Error())
-	}
+// Get a channel that receives why the sync loop stopped once it has, nil if it stopped because the context is done
+func (c *cache) SyncLoopDone() <-chan error {
+	return c.syncLoopDone
+}
 
-	netflixOrgRepos, err, statusCode := c.githubClient.GetNetflixRepos(c.ctx)
-	if err != nil {
-		return statusCode, fmt.Errorf("Failed to fetch netflix organization repositories: %s", err.Error())
-	}
+// Reports why the sync loop stopped
+func (c *cache) finishSyncLoop(err error) {
+	c.syncLoopDone <- err
+	close(c.syncLoopDone)
+}
 
-	netflixOrg, err, statusCode := c.githubClient.GetNetflixOrg(c.ctx)
-	if err != nil {
-		return statusCode, fmt.Errorf("Failed to fetch netflix organization: %s", err.Error())
-	}
+// Hydrates the cache for server startup, retrying failed attempts. Returns false if the cache was stopped while backing off
+func (c *cache) hydrateForStartup() bool {
+	// Try 5 times to initially hydrate the cache, or until the timeout elapses when waiting for the cache before serving traffic
+	retriesLeft := 5
+	waitDeadline := time.Now().Add(c.waitForCacheTimeout)
+	for attempt := 1; ; attempt++ {
+		c.logger.Info("Hydrating cache for server startup", zap.Int("attempt", attempt))
+
+		statusCode, err := c.HydrateCache(c.ctx)
 
-	// compute views
-	var bottomNetflixReposByForks []Tuple
-	var bottomNetflixReposByUpdateTime []Tuple
-	var bottomNetflixReposByOpenIssues []Tuple
-	var bottomNetflixReposByStars []Tuple
+		if err == nil {
+			c.logger.Info("Successfully hydrated cache")
+			return true
+		}
+
+		retriesLeft--
 
-	for _, repo := range netflixOrgRepos {
-		repoName, ok := repo["name"].(string)
-		if !ok {
-			return http.StatusInternalServerError, fmt.Errorf("Missing repository name")
+		// retrying won't help until the quota resets, serve approximate data in the meantime
+		if c.bootstrapOnQuotaExhausted(statusCode) {
+			return true
 		}
-		repoName = fmt.Sprintf("Netflix/%s", repoName)
 
-		updatedTime, ok := repo["updated_at"].(string)
-		if !ok {
-			return http.StatusInternalServerError, fmt.Errorf("Missing Updated time for repository")
+		if c.waitForCache && time.Now().Add(5*time.Second).After(waitDeadline) {
+			c.logger.Warn("Timed out waiting for initial cache hydration, starting with an empty cache", zap.Error(err), zap.Int("Http status code", statusCode))
+			return true
 		}
 
-		openIssuesCount, ok := repo["open_issues_count"].(float64)
-		if !ok {
-			return http.StatusInternalServerError, fmt.Errorf("Missing issue count for repository")
+		if !c.waitForCache && retriesLeft == 0 {
+			c.logger.Warn("Failed to hydrate cache for server startup, starting with an empty cache", zap.Error(err), zap.Int("Http status code", statusCode))
+			return true
 		}
 
-		starCount, ok := repo["stargazers_count"].(float64)
-		if !ok {
-			return http.StatusInternalServerError, fmt.Errorf("Missing star count for repository")
+		c.logger.Warn(fmt.Sprintf("Attempt %d failed backing off for %d seconds", attempt, 5), zap.Error(err), zap.Int("Http status code", statusCode))
+
+		select {
+		case <-time.After(5 * time.Second):
+		case <-c.ctx.Done():
+			return false
+		}
+	}
+}
+
+// Makes requests to the GitHub API, computes views, and updates the cache. The outcome is recorded as the last sync report.
+// The hydration is abandoned once ctx is done, or the cache is stopped, and datasets not yet fetched aren't published
+func (c *cache) HydrateCache(ctx context.Context) (int, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(c.ctx, cancel)
+	defer stop()
+
+	c.fullHydrations.Add(1)
+	defer c.fullHydrations.Add(-1)
+
+	report := newSyncReport()
+	ctx, account := githubclient.CountRequests(ctx)
+
+	updates, statusCode, err := c.hydrate(ctx, &report)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		statusCode = http.StatusGatewayTimeout
+	}
+
+	report.finish(statusCode, err)
+	report.Upstream = account.Usage()
+	c.observeSyncDuration("all", report, err)
+	c.observeUpstreamUsage("all", report)
+	c.observeSyncFailures(report)
+
+	c.applySync(report, false, updates...)
+
+	if err == nil {
+		c.persistSnapshot()
+	}
+
+	return statusCode, err
+}
+
+// Fetches every dataset concurrently and computes views, recording the outcome of each dataset in report. When hydration is
+// strict, the first failure abandons the other datasets and no updates are returned, so nothing is published. Otherwise the
+// datasets that were fetched are returned along with the first failure, the others keep their previously cached data
+func (c *cache) hydrate(ctx context.Context, report *SyncReport) ([]datasetUpdate, int, error) {
+	fetchers := []datasetFetcher{c.fetchMembers, c.fetchRepos, c.fetchOrg}
+
+	group := &errgroup.Group{}
+	if c.strictHydration {
+		group, ctx = errgroup.WithContext(ctx)
+	}
+
+	// each dataset is recorded in its own report, merged once they're all done
+	updates := make([]datasetUpdate, len(fetchers))
+	statusCodes := make([]int, len(fetchers))
+	errs := make([]error, len(fetchers))
+	reports := make([]SyncReport, len(fetchers))
+
+	for i, fetch := range fetchers {
+		reports[i] = newSyncReport()
+
+		group.Go(func() error {
+			updates[i], statusCodes[i], errs[i] = fetch(ctx, &reports[i])
+			return errs[i]
+		})
+	}
+	err := group.Wait()
+
+	for _, datasetReport := range reports {
+		for dataset, outcome := range datasetReport.Datasets {
+			report.Datasets[dataset] = outcome
 		}
+	}
 
-		forksCount, ok := repo["forks_count"].(float64)
-		if !ok {
-			return http.StatusInternalServerError, fmt.Errorf("Missing forks count for repository")
+	if err == nil {
+		return updates, http.StatusOK, nil
+	}
+
+	var fetched []datasetUpdate
+	statusCode := http.StatusInternalServerError
+	for i := range fetchers {
+		if errs[i] == nil {
+			fetched = append(fetched, updates[i])
+		} else if errs[i] == err {
+			statusCode = statusCodes[i]
 		}
+	}
 
-		bottomNetflixReposByForks = append(bottomNetflixReposByForks, Tuple{repoName, forksCount})
-		bottomNetflixReposByUpdateTime = append(bottomNetflixReposByUpdateTime, Tuple{repoName, updatedTime})
-		bottomNetflixReposByOpenIssues = append(bottomNetflixReposByOpenIssues, Tuple{repoName, openIssuesCount})
-		bottomNetflixReposByStars = append(bottomNetflixReposByStars, Tuple{repoName, starCount})
+	if c.strictHydration {
+		return nil, statusCode, err
 	}
 
-	sortBottomViewByTimestamp(bottomNetflixReposByUpdateTime)
-	sortBottomViewByCount(bottomNetflixReposByForks)
-	sortBottomViewByCount(bottomNetflixReposByOpenIssues)
-	sortBottomViewByCount(bottomNetflixReposByStars)
-
-	c.lock.Lock()
-
-	c.data = &cacheData{
-		netflixOrganization:                netflixOrg,
-		netflixOrganizationMembers:         netflixOrgMembers,
-		netflixOrganizationRepos:           netflixOrgRepos,
-		viewBottomNetflixReposByForks:      bottomNetflixReposByForks,
-		viewBottomNetflixReposByStars:      bottomNetflixReposByStars,
-		viewBottomNetflixReposByUpdateTime: bottomNetflixReposByUpdateTime,
-		viewBottomNetflixReposByOpenIssues: bottomNetflixReposByOpenIssues,
+	return fetched, statusCode, err
+}
+
+// Builds a lookup of repos by lower-cased name, GitHub repository names are case-insensitive
+func indexReposByName(repos []githubclient.JsonObject) map[string]githubclient.JsonObject {
+	index := make(map[string]githubclient.JsonObject, len(repos))
+
+	for _, repo := range repos {
+		if name, ok := repo["name"].(string); ok {
+			index[strings.ToLower(name)] = repo
+		}
 	}
 
-	c.lock.Unlock()
+	return index
+}
+
+// Builds a lookup of members by lower-cased login, GitHub logins are case-insensitive
+func indexMembersByLogin(members []githubclient.JsonObject) map[string]githubclient.JsonObject {
+	index := make(map[string]githubclient.JsonObject, len(members))
+
+	for _, member := range members {
+		if login, ok := member["login"].(string); ok {
+			index[strings.ToLower(login)] = member
+		}
+	}
 
-	return http.StatusOK, nil
+	return index
 }
 
 // Sorts list of [name: string, count: float] tuples by count descending, when count values are the same, uses the name value alphabetically
@@ -196,73 +691,103 @@ func sortBottomViewByCount(tuples []Tuple) {
 	})
 }
 
-// Sorts list of [name: string, timestamp: string] tuples by timestamp value descending
+// Sorts list of [name: string, timestamp: string] tuples by timestamp value descending, when timestamp values are the same,
+// uses the name value alphabetically. Timestamps that aren't RFC 3339 are ranked below every other, as if they were the oldest
 func sortBottomViewByTimestamp(tuples []Tuple) {
-	sort.Slice(tuples, func(a int, b int) bool {
-		timeA, _ := time.Parse(time.RFC3339, tuples[a][1].(string))
-		timeB, _ := time.Parse(time.RFC3339, tuples[b][1].(string))
+	// by timestamp, so each is parsed once instead of on every comparison. Unparsable timestamps are zero
+	parsed := make(map[string]time.Time, len(tuples))
+	for _, tuple := range tuples {
+		timestamp := tuple[1].(string)
+		if _, ok := parsed[timestamp]; !ok {
+			parsed[timestamp], _ = time.Parse(time.RFC3339, timestamp)
+		}
+	}
 
-		return timeA.After(timeB)
+	slices.SortFunc(tuples, func(a Tuple, b Tuple) int {
+		if cmp := parsed[b[1].(string)].Compare(parsed[a[1].(string)]); cmp != 0 {
+			return cmp
+		}
+		return strings.Compare(a[0].(string), b[0].(string))
 	})
 }
 
 // Get Netflix Organization from Cache
 func (c *cache) GetNetflixOrganization() githubclient.JsonObject {
-	defer c.lock.RUnlock()
-	c.lock.RLock()
-
-	return c.data.netflixOrganization
+	return loadDataset[githubclient.JsonObject](c.store, DATASET_ORGANIZATION)
 }
 
 // Get Netflix Organization Members from Cache
 func (c *cache) GetNetflixOrganizationMembers() []githubclient.JsonObject {
-	defer c.lock.RUnlock()
-	c.lock.RLock()
-
-	return c.data.netflixOrganizationMembers
+	return loadDataset[membersData](c.store, DATASET_MEMBERS).netflixOrganizationMembers
 }
 
 // Get Netflix Organization Repos from Cache
 func (c *cache) GetNetflixOrganizationRepos() []githubclient.JsonObject {
-	defer c.lock.RUnlock()
-	c.lock.RLock()
+	return loadDataset[reposData](c.store, DATASET_REPOS).netflixOrganizationRepos
+}
 
-	return c.data.netflixOrganizationRepos
+// Get a single Netflix Organization Repo by name from Cache, accepts either "repo" or "Netflix/repo"
+func (c *cache) GetNetflixOrganizationRepo(name string) (githubclient.JsonObject, bool) {
+	name = strings.TrimPrefix(strings.ToLower(name), "netflix/")
+
+	repo, ok := loadDataset[reposData](c.store, DATASET_REPOS).netflixOrganizationReposByName[name]
+	return repo, ok
+}
+
+// Get a single Netflix Organization Member by login from Cache
+func (c *cache) GetNetflixOrganizationMember(login string) (githubclient.JsonObject, bool) {
+	member, ok := loadDataset[membersData](c.store, DATASET_MEMBERS).netflixOrganizationMembersByLogin[strings.ToLower(login)]
+	return member, ok
 }
 
 // Get Bottom Netflix Organization Repos By Forks from Cache
 func (c *cache) GetBottomNetflixReposByForks() []Tuple {
-	defer c.lock.RUnlock()
-	c.lock.RLock()
-
-	return c.data.viewBottomNetflixReposByForks
+	return loadDataset[viewsData](c.store, DATASET_VIEWS).views[VIEW_BOTTOM_FORKS]
 }
 
 // Get Bottom Netflix Organization Repos By Last Updated Time from Cache
 func (c *cache) GetBottomNetflixReposByUpdateTime() []Tuple {
-	defer c.lock.RUnlock()
-	c.lock.RLock()
-
-	return c.data.viewBottomNetflixReposByUpdateTime
+	return loadDataset[viewsData](c.store, DATASET_VIEWS).views[VIEW_BOTTOM_LAST_UPDATED]
 }
 
 // Get Bottom Netflix Organization Repos By Open Issues from Cache
 func (c *cache) GetBottomNetflixReposByOpenIssues() []Tuple {
-	defer c.lock.RUnlock()
-	c.lock.RLock()
-
-	return c.data.viewBottomNetflixReposByOpenIssues
+	return loadDataset[viewsData](c.store, DATASET_VIEWS).views[VIEW_BOTTOM_OPEN_ISSUES]
 }
 
 // Get Bottom Netflix Organization Repos By Stars from Cache
 func (c *cache) GetBottomNetflixReposByStars() []Tuple {
-	defer c.lock.RUnlock()
-	c.lock.RLock()
+	return loadDataset[viewsData](c.store, DATASET_VIEWS).views[VIEW_BOTTOM_STARS]
+}
+
+// Get the time of the last successful hydration of any dataset, zero if the cache has never been hydrated
+func (c *cache) GetLastHydrationTime() time.Time {
+	return c.Snapshot().LastHydrationTime()
+}
+
+// Determines if a full hydration is in progress, e.g. the one at startup, which fills every dataset missing from the cache
+func (c *cache) IsSyncing() bool {
+	return c.fullHydrations.Load() > 0
+}
+
+// Get the time dataset was last hydrated, zero if it has never been hydrated
+func (c *cache) GetDatasetHydrationTime(dataset string) time.Time {
+	stored, _ := c.store.Get(dataset)
+	return stored.HydratedAt
+}
+
+// Sets the hooks notified of failures, must be called before the cache is hydrated
+func (c *cache) SetAlertHooks(hooks alerts.Hooks) {
+	c.alertHooks = hooks
+}
 
-	return c.data.viewBottomNetflixReposByStars
+// Determines if dataset has been hydrated, even if it holds nothing, e.g. an org without public repos
+func (c *cache) IsHydrated(dataset string) bool {
+	_, hydrated := c.store.Get(dataset)
+	return hydrated
 }
 
-// Get the HTTP status of the last attempted cache sync
-func (c *cache) GetLastCacheSyncStatus() int {
-	return c.lastCacheSyncStatus
+// Determines if the cached data is approximate, i.e. seeded from an archive and not yet replaced by a real hydration
+func (c *cache) IsApproximate() bool {
+	return c.Snapshot().IsApproximate()
 }
@@ -0,0 +1,178 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+
+	githubclient "github.com/adamjeanlaurent/github-api-read-cache-service/github-client"
+)
+
+// Encoding of the Payload of every stored dataset, the dataset's fields as JSON. Bumped whenever a dataset's payload changes shape
+const DATASET_ENCODING_JSON string = "json/v1"
+
+// Converts a dataset's stored value to and from its payload. Payloads only carry what was fetched or computed from other
+// datasets, lookups and encodings derived from the dataset itself are rebuilt when decoding
+type datasetCodec struct {
+	encode func(value interface{}) ([]byte, error)
+	decode func(c *cache, data []byte) (interface{}, error)
+}
+
+// Get a codec for datasets stored as V, encoded as the JSON of P
+func newDatasetCodec[V any, P any](toPayload func(value V) P, fromPayload func(c *cache, payload P) V) datasetCodec {
+	return datasetCodec{
+		encode: func(value interface{}) ([]byte, error) {
+			typed, ok := value.(V)
+			if !ok {
+				return nil, fmt.Errorf("Unexpected value of type %T", value)
+			}
+			return json.Marshal(toPayload(typed))
+		},
+		decode: func(c *cache, data []byte) (interface{}, error) {
+			var payload P
+			if err := json.Unmarshal(data, &payload); err != nil {
+				return nil, err
+			}
+			return fromPayload(c, payload), nil
+		},
+	}
+}
+
+type reposPayload struct {
+	Repos   []githubclient.JsonObject `json:"repos"`
+	Removed []RemovedRepo             `json:"removed,omitempty"`
+}
+
+type viewsPayload struct {
+	Views       map[string][]Tuple `json:"views"`
+	Breakdowns  map[string][]Tuple `json:"breakdowns"`
+	Precomputed bool               `json:"precomputed,omitempty"` // false once trimmed to stay under the memory limit
+}
+
+type contributorsPayload struct {
+	Contributors map[string][]githubclient.JsonObject `json:"contributors"`
+	View         []Tuple                              `json:"view"`
+}
+
+type releasesPayload struct {
+	Releases map[string]githubclient.JsonObject `json:"releases"`
+	View     []Tuple                            `json:"view"`
+}
+
+type commitActivityPayload struct {
+	Activity map[string][]githubclient.JsonObject `json:"activity"`
+	View     []Tuple                              `json:"view"`
+}
+
+type teamsPayload struct {
+	Teams     []githubclient.JsonObject            `json:"teams"`
+	TeamRepos map[string][]githubclient.JsonObject `json:"team_repos"`
+}
+
+type issueCountsPayload struct {
+	Counts           map[string]githubclient.IssueCounts `json:"counts"`
+	ViewIssues       []Tuple                             `json:"view_issues"`
+	ViewPullRequests []Tuple                             `json:"view_pull_requests"`
+}
+
+// Codec of every dataset, by dataset
+var datasetCodecs = map[string]datasetCodec{
+	DATASET_ORGANIZATION: newDatasetCodec(
+		func(org githubclient.JsonObject) githubclient.JsonObject { return org },
+		func(c *cache, org githubclient.JsonObject) githubclient.JsonObject { return org },
+	),
+	DATASET_MEMBERS: newDatasetCodec(
+		func(members membersData) []githubclient.JsonObject { return members.netflixOrganizationMembers },
+		func(c *cache, members []githubclient.JsonObject) membersData {
+			return membersData{netflixOrganizationMembers: members, netflixOrganizationMembersByLogin: indexMembersByLogin(members)}
+		},
+	),
+	DATASET_REPOS: newDatasetCodec(
+		func(repos reposData) reposPayload {
+			return reposPayload{Repos: repos.netflixOrganizationRepos, Removed: repos.removedRepos}
+		},
+		func(c *cache, payload reposPayload) reposData {
+			return reposData{
+				netflixOrganizationRepos:       payload.Repos,
+				netflixOrganizationReposByName: indexReposByName(payload.Repos),
+				excludedRepos:                  indexExcludedRepos(payload.Repos),
+				reposByType:                    indexReposByType(payload.Repos),
+				removedRepos:                   payload.Removed,
+				viewRemovedRepos:               computeRemovedReposView(payload.Removed),
+			}
+		},
+	),
+	DATASET_VIEWS: newDatasetCodec(
+		func(views viewsData) viewsPayload {
+			return viewsPayload{Views: views.views, Breakdowns: views.breakdowns, Precomputed: views.precomputedBottomViews != nil}
+		},
+		func(c *cache, payload viewsPayload) viewsData {
+			views := viewsData{views: payload.Views, breakdowns: payload.Breakdowns}
+			if payload.Precomputed {
+				c.precomputeBottomViews(&views)
+			}
+			return views
+		},
+	),
+	DATASET_CONTRIBUTORS: newDatasetCodec(
+		func(contributors contributorsData) contributorsPayload {
+			return contributorsPayload{Contributors: contributors.netflixRepoContributors, View: contributors.viewBottomNetflixReposByContributors}
+		},
+		func(c *cache, payload contributorsPayload) contributorsData {
+			return contributorsData{netflixRepoContributors: payload.Contributors, viewBottomNetflixReposByContributors: payload.View}
+		},
+	),
+	DATASET_RELEASES: newDatasetCodec(
+		func(releases releasesData) releasesPayload {
+			return releasesPayload{Releases: releases.netflixRepoLatestReleases, View: releases.viewRecentNetflixReleases}
+		},
+		func(c *cache, payload releasesPayload) releasesData {
+			return releasesData{netflixRepoLatestReleases: payload.Releases, viewRecentNetflixReleases: payload.View}
+		},
+	),
+	DATASET_COMMIT_ACTIVITY: newDatasetCodec(
+		func(activity commitActivityData) commitActivityPayload {
+			return commitActivityPayload{Activity: activity.netflixRepoCommitActivity, View: activity.viewBottomNetflixReposByCommitActivity}
+		},
+		func(c *cache, payload commitActivityPayload) commitActivityData {
+			return commitActivityData{netflixRepoCommitActivity: payload.Activity, viewBottomNetflixReposByCommitActivity: payload.View}
+		},
+	),
+	DATASET_TEAMS: newDatasetCodec(
+		func(teams teamsData) teamsPayload {
+			return teamsPayload{Teams: teams.netflixTeams, TeamRepos: teams.netflixTeamRepos}
+		},
+		func(c *cache, payload teamsPayload) teamsData {
+			return teamsData{netflixTeams: payload.Teams, netflixTeamRepos: payload.TeamRepos}
+		},
+	),
+	DATASET_ISSUE_COUNTS: newDatasetCodec(
+		func(counts issueCountsData) issueCountsPayload {
+			return issueCountsPayload{Counts: counts.netflixRepoIssueCounts, ViewIssues: counts.viewBottomNetflixReposByIssues, ViewPullRequests: counts.viewBottomNetflixReposByPullRequests}
+		},
+		func(c *cache, payload issueCountsPayload) issueCountsData {
+			return issueCountsData{netflixRepoIssueCounts: payload.Counts, viewBottomNetflixReposByIssues: payload.ViewIssues, viewBottomNetflixReposByPullRequests: payload.ViewPullRequests}
+		},
+	),
+	DATASET_READMES: newDatasetCodec(
+		func(readmes readmesData) map[string]*githubclient.Readme { return readmes.netflixRepoReadmes },
+		func(c *cache, readmes map[string]*githubclient.Readme) readmesData {
+			return readmesData{netflixRepoReadmes: readmes}
+		},
+	),
+	DATASET_LANGUAGES: newDatasetCodec(
+		func(languages languagesData) map[string]githubclient.JsonObject {
+			return languages.netflixRepoLanguages
+		},
+		func(c *cache, languages map[string]githubclient.JsonObject) languagesData {
+			return languagesData{netflixRepoLanguages: languages, netflixOrgLanguages: computeOrgLanguages(languages)}
+		},
+	),
+	DATASET_EXTRA_ENDPOINTS: newDatasetCodec(
+		func(endpoints extraEndpointsData) map[string]githubclient.RawResponse {
+			return endpoints.extraEndpoints
+		},
+		func(c *cache, endpoints map[string]githubclient.RawResponse) extraEndpointsData {
+			return extraEndpointsData{extraEndpoints: endpoints}
+		},
+	),
+}
@@ -0,0 +1,192 @@
+package cache
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	githubclient "github.com/adamjeanlaurent/github-api-read-cache-service/github-client"
+)
+
+// Every cached dataset as of a single point in time. Reading through a Snapshot never mixes data from different syncs,
+// e.g. a view and the ETag of the repos it was computed from. Snapshots are shared, callers must not modify what they return
+type Snapshot struct {
+	datasets map[string]StoredDataset
+	views    []ViewDefinition
+	lastSync SyncReport
+}
+
+// Get every cached dataset, consistent with each other and with the report of the last sync, in a single read of the store
+func (c *cache) Snapshot() Snapshot {
+	// syncs publish their data and report under the lock
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return Snapshot{datasets: c.store.GetAll(), views: c.views, lastSync: c.lastSyncReport}
+}
+
+// Get the version of the store the snapshot was taken at, 0 if nothing was cached yet. The version is the cache's generation,
+// it increases every time any dataset is updated, so clients can tell whether data rolled over between two requests
+func (snapshot Snapshot) Version() uint64 {
+	var version uint64
+
+	for _, stored := range snapshot.datasets {
+		version = max(version, stored.Version)
+	}
+
+	return version
+}
+
+// Get the value of a dataset in the snapshot, the zero value if it was never set
+func snapshotDataset[T any](snapshot Snapshot, dataset string) T {
+	value, _ := snapshot.datasets[dataset].Value.(T)
+	return value
+}
+
+// Get the report of the last attempted sync as of the snapshot
+func (snapshot Snapshot) LastSyncReport() SyncReport {
+	return snapshot.lastSync
+}
+
+// Get the Netflix Organization
+func (snapshot Snapshot) NetflixOrganization() githubclient.JsonObject {
+	return snapshotDataset[githubclient.JsonObject](snapshot, DATASET_ORGANIZATION)
+}
+
+// Get the Netflix Organization Members
+func (snapshot Snapshot) NetflixOrganizationMembers() []githubclient.JsonObject {
+	return snapshotDataset[membersData](snapshot, DATASET_MEMBERS).netflixOrganizationMembers
+}
+
+// Get the Netflix Organization Repos
+func (snapshot Snapshot) NetflixOrganizationRepos() []githubclient.JsonObject {
+	return snapshotDataset[reposData](snapshot, DATASET_REPOS).netflixOrganizationRepos
+}
+
+// Get a single Netflix Organization Repo by name, accepts either "repo" or "Netflix/repo"
+func (snapshot Snapshot) NetflixOrganizationRepo(name string) (githubclient.JsonObject, bool) {
+	repo, ok := snapshotDataset[reposData](snapshot, DATASET_REPOS).netflixOrganizationReposByName[strings.TrimPrefix(strings.ToLower(name), "netflix/")]
+	return repo, ok
+}
+
+// Get a view sorted from top to bottom, false if the view isn't computed by the cache
+func (snapshot Snapshot) View(view string) ([]Tuple, bool) {
+	if !slices.ContainsFunc(snapshot.views, func(definition ViewDefinition) bool { return definition.Name == view }) {
+		return nil, false
+	}
+
+	return snapshotDataset[viewsData](snapshot, DATASET_VIEWS).views[view], true
+}
+
+// Get the precomputed encoding of the bottom n repos of view in format, false if n isn't one of the precomputed sizes
+func (snapshot Snapshot) PrecomputedBottomView(format string, view string, n int) ([]byte, bool) {
+	encoded, ok := snapshotDataset[viewsData](snapshot, DATASET_VIEWS).precomputedBottomViews[format][view][n]
+	return encoded, ok
+}
+
+// Get the Netflix Repos sorted by contributors, empty unless contributors are hydrated
+func (snapshot Snapshot) BottomNetflixReposByContributors() []Tuple {
+	return snapshotDataset[contributorsData](snapshot, DATASET_CONTRIBUTORS).viewBottomNetflixReposByContributors
+}
+
+// Get the latest releases of the configured repos, most recently released first, empty unless releases are hydrated
+func (snapshot Snapshot) RecentNetflixReleases() []Tuple {
+	return snapshotDataset[releasesData](snapshot, DATASET_RELEASES).viewRecentNetflixReleases
+}
+
+// Get the Netflix Repos sorted by commits over the last year, empty unless commit activity is hydrated
+func (snapshot Snapshot) BottomNetflixReposByCommitActivity() []Tuple {
+	return snapshotDataset[commitActivityData](snapshot, DATASET_COMMIT_ACTIVITY).viewBottomNetflixReposByCommitActivity
+}
+
+// Get the weekly commit activity of a single repo by name, accepts either "repo" or "Netflix/repo". False unless the repo's activity is cached
+func (snapshot Snapshot) NetflixRepoCommitActivity(name string) ([]githubclient.JsonObject, bool) {
+	activity, ok := snapshotDataset[commitActivityData](snapshot, DATASET_COMMIT_ACTIVITY).netflixRepoCommitActivity[strings.TrimPrefix(strings.ToLower(name), "netflix/")]
+	return activity, ok
+}
+
+// Get the Netflix Repos sorted by open issues, not counting pull requests, empty unless issue counts are hydrated
+func (snapshot Snapshot) BottomNetflixReposByIssues() []Tuple {
+	return snapshotDataset[issueCountsData](snapshot, DATASET_ISSUE_COUNTS).viewBottomNetflixReposByIssues
+}
+
+// Get the Netflix Repos sorted by open pull requests, empty unless issue counts are hydrated
+func (snapshot Snapshot) BottomNetflixReposByPullRequests() []Tuple {
+	return snapshotDataset[issueCountsData](snapshot, DATASET_ISSUE_COUNTS).viewBottomNetflixReposByPullRequests
+}
+
+// Get the Netflix Organization Teams, empty unless teams are hydrated
+func (snapshot Snapshot) NetflixTeams() []githubclient.JsonObject {
+	return snapshotDataset[teamsData](snapshot, DATASET_TEAMS).netflixTeams
+}
+
+// Get the repos of a single team by slug, false unless the team is cached
+func (snapshot Snapshot) NetflixTeamRepos(team string) ([]githubclient.JsonObject, bool) {
+	repos, ok := snapshotDataset[teamsData](snapshot, DATASET_TEAMS).netflixTeamRepos[strings.ToLower(team)]
+	return repos, ok
+}
+
+// Get the README of a single repo by name, accepts either "repo" or "Netflix/repo". False unless the repo's README is cached,
+// nil if the repo has none
+func (snapshot Snapshot) NetflixRepoReadme(name string) (*githubclient.Readme, bool) {
+	readme, ok := snapshotDataset[readmesData](snapshot, DATASET_READMES).netflixRepoReadmes[strings.TrimPrefix(strings.ToLower(name), "netflix/")]
+	return readme, ok
+}
+
+// Get the bytes of code per language of a single repo by name, accepts either "repo" or "Netflix/repo". False unless the
+// repo's languages are cached
+func (snapshot Snapshot) NetflixRepoLanguages(name string) (githubclient.JsonObject, bool) {
+	languages, ok := snapshotDataset[languagesData](snapshot, DATASET_LANGUAGES).netflixRepoLanguages[strings.TrimPrefix(strings.ToLower(name), "netflix/")]
+	return languages, ok
+}
+
+// Get the bytes of code per language summed over every repo, most bytes first, empty unless languages are hydrated
+func (snapshot Snapshot) NetflixOrgLanguages() []Tuple {
+	return snapshotDataset[languagesData](snapshot, DATASET_LANGUAGES).netflixOrgLanguages
+}
+
+// Get the cached response of an extra endpoint by path, false unless the path is a configured extra endpoint and cached
+func (snapshot Snapshot) ExtraEndpoint(path string) (githubclient.RawResponse, bool) {
+	response, ok := snapshotDataset[extraEndpointsData](snapshot, DATASET_EXTRA_ENDPOINTS).extraEndpoints[path]
+	return response, ok
+}
+
+// Get the ETag of a dataset, empty if it was never hydrated
+func (snapshot Snapshot) ETag(dataset string) string {
+	return snapshot.datasets[dataset].ETag
+}
+
+// Determines if a dataset was hydrated, even if it holds nothing
+func (snapshot Snapshot) IsHydrated(dataset string) bool {
+	_, hydrated := snapshot.datasets[dataset]
+	return hydrated
+}
+
+// Get the time a dataset was last hydrated, zero if it was never hydrated
+func (snapshot Snapshot) DatasetHydrationTime(dataset string) time.Time {
+	return snapshot.datasets[dataset].HydratedAt
+}
+
+// Get the time any dataset was last hydrated, zero if none were
+func (snapshot Snapshot) LastHydrationTime() time.Time {
+	var hydratedAt time.Time
+
+	for _, stored := range snapshot.datasets {
+		if stored.HydratedAt.After(hydratedAt) {
+			hydratedAt = stored.HydratedAt
+		}
+	}
+
+	return hydratedAt
+}
+
+// Determines if any of the data is approximate, i.e. seeded from an archive and not yet replaced by a real hydration
+func (snapshot Snapshot) IsApproximate() bool {
+	for _, stored := range snapshot.datasets {
+		if stored.Approximate {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	githubclient "github.com/adamjeanlaurent/github-api-read-cache-service/github-client"
+)
+
+// Fetches the contributors of every cached repo, at most contributorsConcurrency at a time. Repos whose contributors
+// couldn't be fetched keep their previously cached contributors, fails only if every repo failed
+func (c *cache) fetchContributors(ctx context.Context, report *SyncReport) (datasetUpdate, int, error) {
+	repos := c.GetNetflixOrganizationRepos()
+	if !c.IsHydrated(DATASET_REPOS) {
+		err := fmt.Errorf("Can't fetch contributors before repos are cached")
+		report.recordDataset(DATASET_CONTRIBUTORS, http.StatusServiceUnavailable, 0, err)
+		return datasetUpdate{}, http.StatusServiceUnavailable, err
+	}
+
+	previous := loadDataset[contributorsData](c.store, DATASET_CONTRIBUTORS).netflixRepoContributors
+
+	contributors, statusCode, err := fetchPerRepo(ctx, c, DATASET_CONTRIBUTORS, repoNames(repos), c.contributorsConcurrency, previous,
+		func(ctx context.Context, repo string) ([]githubclient.JsonObject, bool, error, int) {
+			resp, err := c.githubClient.GetNetflixRepoContributors(ctx, repo)
+
+			// empty repos have no contributors, cached as an empty list instead of null
+			repoContributors := resp.Body
+			if err == nil && repoContributors == nil {
+				repoContributors = []githubclient.JsonObject{}
+			}
+
+			return repoContributors, true, err, resp.StatusCode
+		})
+
+	report.recordDataset(DATASET_CONTRIBUTORS, statusCode, len(contributors), err)
+	if err != nil {
+		return datasetUpdate{}, statusCode, fmt.Errorf("Failed to fetch contributors: %w", err)
+	}
+
+	return newDatasetUpdate(DATASET_CONTRIBUTORS, contributors, contributorsData{
+		netflixRepoContributors:              contributors,
+		viewBottomNetflixReposByContributors: computeBottomContributorsView(repos, contributors),
+	}), http.StatusOK, nil
+}
+
+// Computes the bottom view of repos by number of contributors
+func computeBottomContributorsView(repos []githubclient.JsonObject, contributors map[string][]githubclient.JsonObject) []Tuple {
+	view := make([]Tuple, 0, len(contributors))
+
+	for _, repo := range repos {
+		name, ok := repo["name"].(string)
+		if !ok {
+			continue
+		}
+
+		if repoContributors, ok := contributors[strings.ToLower(name)]; ok {
+			view = append(view, Tuple{fmt.Sprintf("Netflix/%s", name), float64(len(repoContributors))})
+		}
+	}
+
+	sortBottomViewByCount(view)
+
+	return view
+}
+
+// Get Bottom Netflix Organization Repos By Contributors from Cache, empty unless contributors are hydrated
+func (c *cache) GetBottomNetflixReposByContributors() []Tuple {
+	return loadDataset[contributorsData](c.store, DATASET_CONTRIBUTORS).viewBottomNetflixReposByContributors
+}
+
+// Get the contributors of a single Netflix Organization Repo by name from Cache, accepts either "repo" or "Netflix/repo"
+func (c *cache) GetNetflixRepoContributors(repo string) ([]githubclient.JsonObject, bool) {
+	contributors, ok := loadDataset[contributorsData](c.store, DATASET_CONTRIBUTORS).netflixRepoContributors[strings.TrimPrefix(strings.ToLower(repo), "netflix/")]
+	return contributors, ok
+}
@@ -0,0 +1,284 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	githubclient "github.com/adamjeanlaurent/github-api-read-cache-service/github-client"
+	"go.uber.org/zap"
+)
+
+// Freshly fetched dataset, set in the store
+type datasetUpdate struct {
+	dataset string
+	etag    string
+	bytes   int                    // size of the dataset's JSON encoding
+	value   interface{}            // stored value of the dataset
+	derived map[string]interface{} // stored values of datasets computed from this one, e.g. views from repos
+}
+
+// Get a new update for dataset, fingerprinting its payload
+func newDatasetUpdate(dataset string, payload interface{}, value interface{}) datasetUpdate {
+	etag, bytes := fingerprintDataset(payload)
+
+	return datasetUpdate{dataset: dataset, etag: etag, bytes: bytes, value: value}
+}
+
+// Fetches a single dataset from the GitHub API, recording its outcome in report
+type datasetFetcher func(ctx context.Context, report *SyncReport) (datasetUpdate, int, error)
+
+// Fetches the Netflix organization
+func (c *cache) fetchOrg(ctx context.Context, report *SyncReport) (datasetUpdate, int, error) {
+	resp, err := c.githubClient.GetNetflixOrg(ctx)
+	netflixOrg, statusCode := resp.Body, resp.StatusCode
+
+	orgItems := 0
+	if netflixOrg != nil {
+		orgItems = 1
+	}
+	report.recordDataset(DATASET_ORGANIZATION, statusCode, orgItems, err)
+
+	if err != nil {
+		return datasetUpdate{}, statusCode, fmt.Errorf("Failed to fetch netflix organization: %s", err.Error())
+	}
+
+	return newDatasetUpdate(DATASET_ORGANIZATION, netflixOrg, netflixOrg), http.StatusOK, nil
+}
+
+// Fetches the Netflix organization members
+func (c *cache) fetchMembers(ctx context.Context, report *SyncReport) (datasetUpdate, int, error) {
+	resp, err := c.githubClient.GetNetflixOrgMembers(ctx)
+	netflixOrgMembers, statusCode := resp.Body, resp.StatusCode
+	report.recordDataset(DATASET_MEMBERS, statusCode, len(netflixOrgMembers), err)
+
+	if err != nil {
+		return datasetUpdate{}, statusCode, fmt.Errorf("Failed to fetch netflix organization members: %s", err.Error())
+	}
+
+	// an org without public members is cached as an empty list instead of null
+	if netflixOrgMembers == nil {
+		netflixOrgMembers = []githubclient.JsonObject{}
+	}
+
+	return newDatasetUpdate(DATASET_MEMBERS, netflixOrgMembers, membersData{
+		netflixOrganizationMembers:        netflixOrgMembers,
+		netflixOrganizationMembersByLogin: indexMembersByLogin(netflixOrgMembers),
+	}), http.StatusOK, nil
+}
+
+// Fetches the Netflix organization repos, and computes the views over them
+func (c *cache) fetchRepos(ctx context.Context, report *SyncReport) (datasetUpdate, int, error) {
+	resp, err := c.fetchNetflixRepos(ctx)
+	netflixOrgRepos, statusCode := resp.Body, resp.StatusCode
+	report.recordDataset(DATASET_REPOS, statusCode, len(netflixOrgRepos), err)
+
+	if err != nil {
+		return datasetUpdate{}, statusCode, fmt.Errorf("Failed to fetch netflix organization repositories: %s", err.Error())
+	}
+
+	// likewise an org without public repos
+	if netflixOrgRepos == nil {
+		netflixOrgRepos = []githubclient.JsonObject{}
+	}
+
+	// unless hydration is strict, repos missing fields are left out of the views instead of failing the sync
+	rankableRepos, skipped := netflixOrgRepos, []SkippedRecord(nil)
+	if !c.strictHydration {
+		rankableRepos, skipped = partitionRankableRepos(netflixOrgRepos, c.views)
+	}
+
+	views, err := computeBottomViews(rankableRepos, c.views)
+	if err != nil {
+		report.recordDataset(DATASET_VIEWS, http.StatusInternalServerError, 0, err)
+		return datasetUpdate{}, http.StatusInternalServerError, err
+	}
+	report.recordDataset(DATASET_VIEWS, http.StatusOK, len(rankableRepos), nil)
+
+	if len(skipped) > 0 {
+		report.recordSkipped(DATASET_VIEWS, skipped)
+		c.logger.Warn("Repos missing fields were left out of the views", zap.Int("skipped", len(skipped)), zap.Any("repos", skipped))
+	}
+
+	if unparsable := unparsableRepoTimestamps(rankableRepos, c.views); len(unparsable) > 0 {
+		report.recordUnparsable(DATASET_VIEWS, unparsable)
+		c.logger.Warn("Repos with unparsable timestamps were ranked at the bottom of the views", zap.Int("unparsable", len(unparsable)), zap.Any("repos", unparsable))
+	}
+	c.precomputeBottomViews(&views)
+	views.breakdowns = computeRepoBreakdowns(netflixOrgRepos)
+
+	// approximate repos (e.g. from a bootstrap archive) weren't listed by the org at any particular time, so nothing missing
+	// from them was removed since
+	var removed []RemovedRepo
+	if previous, hydrated := c.store.Get(DATASET_REPOS); hydrated && !previous.Approximate {
+		previousRepos, _ := previous.Value.(reposData)
+		removed = trackRemovedRepos(previousRepos, netflixOrgRepos, time.Now().UTC(), c.removedReposRetention)
+	}
+
+	update := newDatasetUpdate(DATASET_REPOS, netflixOrgRepos, reposData{
+		netflixOrganizationRepos:       netflixOrgRepos,
+		netflixOrganizationReposByName: indexReposByName(netflixOrgRepos),
+		excludedRepos:                  indexExcludedRepos(netflixOrgRepos),
+		reposByType:                    indexReposByType(netflixOrgRepos),
+		removedRepos:                   removed,
+		viewRemovedRepos:               computeRemovedReposView(removed),
+	})
+	update.derived = map[string]interface{}{DATASET_VIEWS: views}
+
+	return update, http.StatusOK, nil
+}
+
+// Publishes the dataset updates of a sync to the store in a single swap, along with its report. Readers never see a partially
+// applied update, nor data and a report from different syncs. A partial report (a single dataset's sync) is merged into the
+// last report, keeping the outcome of the other datasets
+func (c *cache) applySync(report SyncReport, partial bool, updates ...datasetUpdate) {
+	// writers are serialized, so the memory limit is enforced against what's actually being replaced
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if len(updates) > 0 {
+		datasets := c.storedDatasets(updates, time.Now().UTC(), false)
+		c.enforceMemoryLimit(datasets, false)
+
+		c.publishUpdate(datasets, c.store.Set(datasets))
+	}
+
+	if partial {
+		for name, datasetReport := range c.lastSyncReport.Datasets {
+			if _, ok := report.Datasets[name]; !ok {
+				report.Datasets[name] = datasetReport
+			}
+		}
+	}
+	c.lastSyncReport = report
+}
+
+// Replaces every dataset in the store with updates in a single swap, e.g. when importing a snapshot, along with the report of
+// the sync that produced them, if any. Datasets that aren't updated (e.g. members when bootstrapping from an archive) are no longer hydrated
+func (c *cache) replaceDatasets(report *SyncReport, hydratedAt time.Time, approximate bool, updates ...datasetUpdate) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	datasets := c.storedDatasets(updates, hydratedAt, approximate)
+	c.enforceMemoryLimit(datasets, true)
+
+	c.publishUpdate(datasets, c.store.Replace(datasets))
+
+	if report != nil {
+		c.lastSyncReport = *report
+	}
+}
+
+// Get the datasets to store for updates, recording their sizes
+func (c *cache) storedDatasets(updates []datasetUpdate, hydratedAt time.Time, approximate bool) map[string]StoredDataset {
+	datasets := map[string]StoredDataset{}
+
+	for _, update := range updates {
+		datasets[update.dataset] = StoredDataset{Value: update.value, ETag: update.etag, Bytes: update.bytes, HydratedAt: hydratedAt, Approximate: approximate}
+
+		for dataset, value := range update.derived {
+			datasets[dataset] = StoredDataset{Value: value, HydratedAt: hydratedAt, Approximate: approximate}
+		}
+
+		c.recordDatasetSize(update.dataset, update.bytes, hydratedAt)
+	}
+
+	return datasets
+}
+
+// Re-fetches a single dataset on its own schedule, merging its outcome into the last sync report. Returns why it failed, if it did
+func (c *cache) syncDataset(dataset string, fetch datasetFetcher) error {
+	_, err := c.hydrateDataset(c.ctx, dataset, fetch)
+	return err
+}
+
+// Re-fetches a single dataset, merging its outcome into the last sync report. The fetch is abandoned once ctx is done, or
+// the cache is stopped. Returns the upstream status code, and why it failed, if it did
+func (c *cache) hydrateDataset(ctx context.Context, dataset string, fetch datasetFetcher) (int, error) {
+	c.logger.Info("Attempting to re-Hydrate cache dataset", zap.String("dataset", dataset))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(c.ctx, cancel)
+	defer stop()
+
+	report := newSyncReport()
+	ctx, account := githubclient.CountRequests(ctx)
+
+	update, statusCode, err := fetch(ctx, &report)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		statusCode = http.StatusGatewayTimeout
+	}
+
+	report.finish(statusCode, err)
+	report.Upstream = account.Usage()
+	c.observeSyncDuration(dataset, report, err)
+	c.observeUpstreamUsage(dataset, report)
+	c.observeSyncFailures(report)
+
+	if err != nil {
+		c.applySync(report, true)
+	} else {
+		c.applySync(report, true, update)
+	}
+
+	if err != nil {
+		c.logger.Error("Failed to hydrate cache dataset", zap.String("dataset", dataset), zap.Error(err), zap.Int("Http status code", statusCode))
+	} else {
+		c.logger.Info("Successfully re-hydrated cache dataset", zap.String("dataset", dataset))
+		c.persistSnapshot()
+	}
+
+	return statusCode, err
+}
+
+// Records how long a sync took, by dataset and whether it succeeded
+func (c *cache) observeSyncDuration(dataset string, report SyncReport, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+
+	c.syncDurations.Observe(report.EndTime.Sub(report.StartTime).Seconds(), "dataset", dataset, "result", result)
+}
+
+// Counts the consecutive failed syncs of every dataset the sync attempted, notifying the alert hooks of each failure
+func (c *cache) observeSyncFailures(report SyncReport) {
+	type failure struct {
+		dataset  string
+		failures int
+		status   int
+		err      error
+	}
+	var failed []failure
+
+	c.statsLock.Lock()
+	for dataset, outcome := range report.Datasets {
+		if outcome.Error == "" {
+			delete(c.syncFailures, dataset)
+			continue
+		}
+
+		c.syncFailures[dataset]++
+		failed = append(failed, failure{dataset, c.syncFailures[dataset], outcome.Status, errors.New(outcome.Error)})
+	}
+	c.statsLock.Unlock()
+
+	for _, f := range failed {
+		c.alertHooks.OnSyncFailure(f.dataset, f.failures, f.status, f.err)
+	}
+}
+
+// Records the GitHub requests a sync made, by dataset, and logs them so quota usage can be told apart per sync
+func (c *cache) observeUpstreamUsage(dataset string, report SyncReport) {
+	c.upstreamRequests.Add(float64(report.Upstream.Requests), "dataset", dataset)
+
+	for resource, cost := range report.Upstream.Cost {
+		c.upstreamCost.Add(float64(cost), "dataset", dataset, "resource", resource)
+	}
+
+	c.logger.Info("GitHub requests made by cache sync", zap.String("dataset", dataset), zap.Int("requests", report.Upstream.Requests),
+		zap.Int("not modified", report.Upstream.NotModified), zap.Any("quota cost", report.Upstream.Cost))
+}
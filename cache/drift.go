@@ -0,0 +1,130 @@
+package cache
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"reflect"
+	"time"
+
+	githubclient "github.com/adamjeanlaurent/github-api-read-cache-service/github-client"
+	"go.uber.org/zap"
+)
+
+// Outcomes of comparing a cached repo against GitHub
+const (
+	CONSISTENCY_RESULT_CONSISTENT string = "consistent"
+	CONSISTENCY_RESULT_DRIFTED    string = "drifted" // didn't change on GitHub since it was cached, yet its fields differ
+	CONSISTENCY_RESULT_CHANGED    string = "changed" // changed on GitHub since it was cached, so differences are expected
+	CONSISTENCY_RESULT_MISSING    string = "missing" // GitHub answers 404, e.g. deleted since it was cached
+	CONSISTENCY_RESULT_ERROR      string = "error"
+)
+
+// Repo fields compared by consistency checks, the ones views and lookups are served from
+var consistencyCheckFields = []string{"stargazers_count", "forks_count", "open_issues_count", "watchers_count", "archived", "default_branch", "updated_at", "pushed_at"}
+
+// Field of a cached repo that doesn't match GitHub, though the repo didn't change since it was cached
+type RepoDrift struct {
+	Repo   string      `json:"repo"`
+	Field  string      `json:"field"`
+	Cached interface{} `json:"cached"`
+	Live   interface{} `json:"live"`
+}
+
+// Fetches a random sample of the cached repos live from GitHub and compares their key fields, counting each repo's outcome
+// and logging drift. Drift means the cache missed an update, e.g. an incremental sync skipped a repo
+func (c *cache) checkConsistency() {
+	stored, hydrated := c.store.Get(DATASET_REPOS)
+	if !hydrated || stored.Approximate {
+		return
+	}
+
+	cachedRepos, _ := stored.Value.(reposData)
+	ctx, account := githubclient.CountRequests(c.ctx)
+
+	// repos updated while they were being listed may have been listed before the update
+	cachedAt := stored.HydratedAt.Add(-INCREMENTAL_SYNC_OVERLAP)
+
+	var drifts []RepoDrift
+	driftedRepos := 0
+
+	for _, cached := range sampleRepos(cachedRepos.netflixOrganizationRepos, c.consistencyCheckSample) {
+		if ctx.Err() != nil {
+			return
+		}
+
+		name, _ := cached["name"].(string)
+		resp, err := c.githubClient.GetNetflixRepo(ctx, name)
+
+		result := CONSISTENCY_RESULT_CONSISTENT
+		switch {
+		case resp.StatusCode == http.StatusNotFound:
+			result = CONSISTENCY_RESULT_MISSING
+		case err != nil:
+			result = CONSISTENCY_RESULT_ERROR
+			c.logger.Warn("Failed to fetch repo for consistency check", zap.String("repo", name), zap.Error(err), zap.Int("Http status code", resp.StatusCode))
+		case changedSince(resp.Body, cachedAt):
+			result = CONSISTENCY_RESULT_CHANGED
+		default:
+			if repoDrifts := compareRepo(name, cached, resp.Body); len(repoDrifts) > 0 {
+				result = CONSISTENCY_RESULT_DRIFTED
+				drifts = append(drifts, repoDrifts...)
+				driftedRepos++
+			}
+		}
+
+		c.consistencyChecks.Add(1, "result", result)
+	}
+
+	c.driftedReposGauge.Set(float64(driftedRepos))
+	c.observeUpstreamUsage("consistency_check", SyncReport{Upstream: account.Usage()})
+
+	if len(drifts) > 0 {
+		c.logger.Warn("Cached repos drifted from GitHub", zap.Int("repos", driftedRepos), zap.Any("drift", drifts), zap.Time("cached at", stored.HydratedAt))
+	} else {
+		c.logger.Info("Cached repos match GitHub")
+	}
+}
+
+// Get up to n repos picked at random
+func sampleRepos(repos []githubclient.JsonObject, n int) []githubclient.JsonObject {
+	if len(repos) <= n {
+		return repos
+	}
+
+	sample := make([]githubclient.JsonObject, 0, n)
+	for _, i := range rand.Perm(len(repos))[:n] {
+		sample = append(sample, repos[i])
+	}
+
+	return sample
+}
+
+// Determines if GitHub updated or pushed to repo after t, repos without parsable timestamps are assumed to have changed
+func changedSince(repo githubclient.JsonObject, t time.Time) bool {
+	for _, field := range []string{"updated_at", "pushed_at"} {
+		value, _ := repo[field].(string)
+		if value == "" {
+			continue
+		}
+
+		changedAt, err := time.Parse(time.RFC3339, value)
+		if err != nil || changedAt.After(t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Get the fields of the cached repo that don't match the live one
+func compareRepo(name string, cached githubclient.JsonObject, live githubclient.JsonObject) []RepoDrift {
+	var drifts []RepoDrift
+
+	for _, field := range consistencyCheckFields {
+		if !reflect.DeepEqual(cached[field], live[field]) {
+			drifts = append(drifts, RepoDrift{Repo: name, Field: field, Cached: cached[field], Live: live[field]})
+		}
+	}
+
+	return drifts
+}
@@ -0,0 +1,35 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	githubclient "github.com/adamjeanlaurent/github-api-read-cache-service/github-client"
+)
+
+// Fetches every configured extra endpoint verbatim, one at a time. Stops at the first failure, nothing is published unless
+// every endpoint was fetched
+func (c *cache) fetchExtraEndpoints(ctx context.Context, report *SyncReport) (datasetUpdate, int, error) {
+	responses := make(map[string]githubclient.RawResponse, len(c.extraEndpoints))
+
+	for _, path := range c.extraEndpoints {
+		resp, err := c.githubClient.GetEndpoint(ctx, path)
+		if err != nil {
+			report.recordDataset(DATASET_EXTRA_ENDPOINTS, resp.StatusCode, len(responses), err)
+			return datasetUpdate{}, resp.StatusCode, fmt.Errorf("Failed to fetch extra endpoint %s: %w", path, err)
+		}
+
+		responses[path] = resp.Body
+	}
+
+	report.recordDataset(DATASET_EXTRA_ENDPOINTS, http.StatusOK, len(responses), nil)
+
+	return newDatasetUpdate(DATASET_EXTRA_ENDPOINTS, responses, extraEndpointsData{extraEndpoints: responses}), http.StatusOK, nil
+}
+
+// Get the cached response of a configured extra endpoint by path from Cache. Returns false if the path isn't cached
+func (c *cache) GetExtraEndpoint(path string) (githubclient.RawResponse, bool) {
+	response, ok := loadDataset[extraEndpointsData](c.store, DATASET_EXTRA_ENDPOINTS).extraEndpoints[path]
+	return response, ok
+}
@@ -0,0 +1,27 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// Computes a strong ETag for a dataset from its JSON encoding, along with the size of the encoding in bytes.
+// The same data always produces the same ETag
+func fingerprintDataset(dataset interface{}) (string, int) {
+	encoded, err := json.Marshal(dataset)
+	if err != nil {
+		return "", 0
+	}
+
+	sum := sha256.Sum256(encoded)
+
+	return `"` + hex.EncodeToString(sum[:16]) + `"`, len(encoded)
+}
+
+// Get the ETag of a cached dataset (organization, members, or repos), computed when the dataset was hydrated.
+// Views are derived from the repos dataset, so they share its ETag
+func (c *cache) GetETag(dataset string) string {
+	stored, _ := c.store.Get(dataset)
+	return stored.ETag
+}
@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"strings"
+
+	githubclient "github.com/adamjeanlaurent/github-api-read-cache-service/github-client"
+)
+
+// Kinds of repos that can be excluded from the repo list and views, e.g. ?exclude=archived,forks
+const (
+	EXCLUDE_ARCHIVED string = "archived"
+	EXCLUDE_FORKS    string = "forks"
+)
+
+// Every kind of repo that can be excluded, along with the repo flag it's identified by
+var exclusionFields = map[string]string{
+	EXCLUDE_ARCHIVED: "archived",
+	EXCLUDE_FORKS:    "fork",
+}
+
+// Whether repos can be excluded by name
+func IsExclusion(exclusion string) bool {
+	_, ok := exclusionFields[exclusion]
+	return ok
+}
+
+// Indexes the lower-cased names of the repos each exclusion filters out, computed along with the repos so excluding repos
+// per request is a lookup
+func indexExcludedRepos(repos []githubclient.JsonObject) map[string]map[string]bool {
+	excluded := make(map[string]map[string]bool, len(exclusionFields))
+
+	for exclusion, field := range exclusionFields {
+		names := map[string]bool{}
+
+		for _, repo := range repos {
+			name, ok := repo["name"].(string)
+			if flagged, _ := repo[field].(bool); ok && flagged {
+				names[strings.ToLower(name)] = true
+			}
+		}
+
+		excluded[exclusion] = names
+	}
+
+	return excluded
+}
+
+// Whether any of the exclusions filters out the repo, accepts either "repo" or "Netflix/repo"
+func (snapshot Snapshot) isRepoExcluded(name string, exclusions []string) bool {
+	excluded := snapshotDataset[reposData](snapshot, DATASET_REPOS).excludedRepos
+	name = strings.TrimPrefix(strings.ToLower(name), "netflix/")
+
+	for _, exclusion := range exclusions {
+		if excluded[exclusion][name] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Get the entries of a view without the repos any of the exclusions filter out, the view is returned as is without exclusions
+func (snapshot Snapshot) ExcludeFromView(view []Tuple, exclusions []string) []Tuple {
+	if len(exclusions) == 0 {
+		return view
+	}
+
+	filtered := make([]Tuple, 0, len(view))
+	for _, entry := range view {
+		if name, _ := entry[0].(string); !snapshot.isRepoExcluded(name, exclusions) {
+			filtered = append(filtered, entry)
+		}
+	}
+
+	return filtered
+}
+
+// Get repos without the ones any of the exclusions filter out, repos are returned as is without exclusions
+func (snapshot Snapshot) ExcludeRepos(repos []githubclient.JsonObject, exclusions []string) []githubclient.JsonObject {
+	if len(exclusions) == 0 {
+		return repos
+	}
+
+	filtered := make([]githubclient.JsonObject, 0, len(repos))
+	for _, repo := range repos {
+		if name, _ := repo["name"].(string); !snapshot.isRepoExcluded(name, exclusions) {
+			filtered = append(filtered, repo)
+		}
+	}
+
+	return filtered
+}
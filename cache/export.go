@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"fmt"
+	"time"
+
+	githubclient "github.com/adamjeanlaurent/github-api-read-cache-service/github-client"
+)
+
+// Serializable copy of the entire cache contents, used to export and import the cache (e.g. to seed test environments)
+type CacheExport struct {
+	Metadata                           CacheExportMetadata                  `json:"metadata"`
+	NetflixOrganization                githubclient.JsonObject              `json:"netflix_organization"`
+	NetflixOrganizationMembers         []githubclient.JsonObject            `json:"netflix_organization_members"`
+	NetflixOrganizationRepos           []githubclient.JsonObject            `json:"netflix_organization_repos"`
+	ViewBottomNetflixReposByForks      []Tuple                              `json:"view_bottom_netflix_repos_by_forks"`
+	ViewBottomNetflixReposByUpdateTime []Tuple                              `json:"view_bottom_netflix_repos_by_update_time"`
+	ViewBottomNetflixReposByOpenIssues []Tuple                              `json:"view_bottom_netflix_repos_by_open_issues"`
+	ViewBottomNetflixReposByStars      []Tuple                              `json:"view_bottom_netflix_repos_by_stars"`
+	NetflixRepoContributors            map[string][]githubclient.JsonObject `json:"netflix_repo_contributors,omitempty"`    // by lower-cased repo name, only when contributors are hydrated
+	NetflixRepoLatestReleases          map[string]githubclient.JsonObject   `json:"netflix_repo_latest_releases,omitempty"` // by lower-cased repo name, only when releases are hydrated
+	NetflixRepoCommitActivity          map[string][]githubclient.JsonObject `json:"netflix_repo_commit_activity,omitempty"` // by lower-cased repo name, only when commit activity is hydrated
+	NetflixTeams                       []githubclient.JsonObject            `json:"netflix_teams,omitempty"`                // only when teams are hydrated
+	NetflixTeamRepos                   map[string][]githubclient.JsonObject `json:"netflix_team_repos,omitempty"`           // by lower-cased team slug, only when teams are hydrated
+	NetflixRepoIssueCounts             map[string]githubclient.IssueCounts  `json:"netflix_repo_issue_counts,omitempty"`    // by lower-cased repo name, only when issue counts are hydrated
+	NetflixRepoReadmes                 map[string]*githubclient.Readme      `json:"netflix_repo_readmes,omitempty"`         // by lower-cased repo name, only when READMEs are hydrated
+	NetflixRepoLanguages               map[string]githubclient.JsonObject   `json:"netflix_repo_languages,omitempty"`       // by lower-cased repo name, only when languages are hydrated
+	RemovedNetflixRepos                []RemovedRepo                        `json:"removed_netflix_repos,omitempty"`        // most recently removed first
+	ExtraEndpoints                     map[string]githubclient.RawResponse  `json:"extra_endpoints,omitempty"`              // by path, only when extra endpoints are configured
+}
+
+// Describes when and how the exported cache data was produced
+type CacheExportMetadata struct {
+	ExportedAt     time.Time `json:"exported_at"`
+	HydratedAt     time.Time `json:"hydrated_at"`
+	LastSyncStatus int       `json:"last_sync_status"`
+	Approximate    bool      `json:"approximate,omitempty"`
+	Generation     uint64    `json:"generation,omitempty"` // generation of the exporting cache, not carried over on import
+}
+
+// Get a copy of the entire cache contents, as of a single snapshot
+func (c *cache) Export() CacheExport {
+	snapshot := c.Snapshot()
+	views := snapshotDataset[viewsData](snapshot, DATASET_VIEWS)
+
+	return CacheExport{
+		Metadata: CacheExportMetadata{
+			ExportedAt:     time.Now().UTC(),
+			HydratedAt:     snapshot.LastHydrationTime(),
+			LastSyncStatus: snapshot.LastSyncReport().Status,
+			Approximate:    snapshot.IsApproximate(),
+			Generation:     snapshot.Version(),
+		},
+		NetflixOrganization:                snapshot.NetflixOrganization(),
+		NetflixOrganizationMembers:         snapshot.NetflixOrganizationMembers(),
+		NetflixOrganizationRepos:           snapshot.NetflixOrganizationRepos(),
+		ViewBottomNetflixReposByForks:      views.views[VIEW_BOTTOM_FORKS],
+		ViewBottomNetflixReposByUpdateTime: views.views[VIEW_BOTTOM_LAST_UPDATED],
+		ViewBottomNetflixReposByOpenIssues: views.views[VIEW_BOTTOM_OPEN_ISSUES],
+		ViewBottomNetflixReposByStars:      views.views[VIEW_BOTTOM_STARS],
+		NetflixRepoContributors:            snapshotDataset[contributorsData](snapshot, DATASET_CONTRIBUTORS).netflixRepoContributors,
+		NetflixRepoLatestReleases:          snapshotDataset[releasesData](snapshot, DATASET_RELEASES).netflixRepoLatestReleases,
+		NetflixRepoCommitActivity:          snapshotDataset[commitActivityData](snapshot, DATASET_COMMIT_ACTIVITY).netflixRepoCommitActivity,
+		NetflixTeams:                       snapshotDataset[teamsData](snapshot, DATASET_TEAMS).netflixTeams,
+		NetflixTeamRepos:                   snapshotDataset[teamsData](snapshot, DATASET_TEAMS).netflixTeamRepos,
+		NetflixRepoIssueCounts:             snapshotDataset[issueCountsData](snapshot, DATASET_ISSUE_COUNTS).netflixRepoIssueCounts,
+		NetflixRepoReadmes:                 snapshotDataset[readmesData](snapshot, DATASET_READMES).netflixRepoReadmes,
+		NetflixRepoLanguages:               snapshotDataset[languagesData](snapshot, DATASET_LANGUAGES).netflixRepoLanguages,
+		RemovedNetflixRepos:                snapshotDataset[reposData](snapshot, DATASET_REPOS).removedRepos,
+		ExtraEndpoints:                     snapshotDataset[extraEndpointsData](snapshot, DATASET_EXTRA_ENDPOINTS).extraEndpoints,
+	}
+}
+
+// Replaces the entire cache contents with previously exported data
+func (c *cache) Import(export CacheExport) error {
+	if export.NetflixOrganization == nil {
+		return fmt.Errorf("Import is missing netflix organization")
+	}
+
+	// exports only carry the always computed views, extra views are computed from the imported repos
+	rankableRepos, _ := partitionRankableRepos(export.NetflixOrganizationRepos, c.views)
+
+	views, err := computeBottomViews(rankableRepos, c.views[len(repoViews):])
+	if err != nil {
+		return fmt.Errorf("Failed to compute views of imported repositories: %w", err)
+	}
+
+	views.views[VIEW_BOTTOM_FORKS] = export.ViewBottomNetflixReposByForks
+	views.views[VIEW_BOTTOM_LAST_UPDATED] = export.ViewBottomNetflixReposByUpdateTime
+	views.views[VIEW_BOTTOM_OPEN_ISSUES] = export.ViewBottomNetflixReposByOpenIssues
+	views.views[VIEW_BOTTOM_STARS] = export.ViewBottomNetflixReposByStars
+
+	// repos missing fields are left out of the views, so views can list fewer repos than there are, but they all list the same ones
+	for _, view := range repoViews {
+		if len(views.views[view.Name]) != len(export.ViewBottomNetflixReposByForks) || len(views.views[view.Name]) > len(export.NetflixOrganizationRepos) {
+			return fmt.Errorf("Import views do not match the number of repositories")
+		}
+	}
+	c.precomputeBottomViews(&views)
+	views.breakdowns = computeRepoBreakdowns(export.NetflixOrganizationRepos)
+
+	reposUpdate := newDatasetUpdate(DATASET_REPOS, export.NetflixOrganizationRepos, reposData{
+		netflixOrganizationRepos:       export.NetflixOrganizationRepos,
+		netflixOrganizationReposByName: indexReposByName(export.NetflixOrganizationRepos),
+		excludedRepos:                  indexExcludedRepos(export.NetflixOrganizationRepos),
+		reposByType:                    indexReposByType(export.NetflixOrganizationRepos),
+		removedRepos:                   export.RemovedNetflixRepos,
+		viewRemovedRepos:               computeRemovedReposView(export.RemovedNetflixRepos),
+	})
+	reposUpdate.derived = map[string]interface{}{DATASET_VIEWS: views}
+
+	updates := []datasetUpdate{
+		newDatasetUpdate(DATASET_ORGANIZATION, export.NetflixOrganization, export.NetflixOrganization),
+		newDatasetUpdate(DATASET_MEMBERS, export.NetflixOrganizationMembers, membersData{
+			netflixOrganizationMembers:        export.NetflixOrganizationMembers,
+			netflixOrganizationMembersByLogin: indexMembersByLogin(export.NetflixOrganizationMembers),
+		}),
+		reposUpdate,
+	}
+
+	// optional datasets are only in exports from servers that hydrate them
+	if export.NetflixRepoContributors != nil {
+		updates = append(updates, newDatasetUpdate(DATASET_CONTRIBUTORS, export.NetflixRepoContributors, contributorsData{
+			netflixRepoContributors:              export.NetflixRepoContributors,
+			viewBottomNetflixReposByContributors: computeBottomContributorsView(export.NetflixOrganizationRepos, export.NetflixRepoContributors),
+		}))
+	}
+
+	if export.NetflixRepoLatestReleases != nil {
+		updates = append(updates, newDatasetUpdate(DATASET_RELEASES, export.NetflixRepoLatestReleases, releasesData{
+			netflixRepoLatestReleases: export.NetflixRepoLatestReleases,
+			viewRecentNetflixReleases: computeRecentReleasesView(export.NetflixRepoLatestReleases),
+		}))
+	}
+
+	if export.NetflixRepoCommitActivity != nil {
+		updates = append(updates, newDatasetUpdate(DATASET_COMMIT_ACTIVITY, export.NetflixRepoCommitActivity, commitActivityData{
+			netflixRepoCommitActivity:              export.NetflixRepoCommitActivity,
+			viewBottomNetflixReposByCommitActivity: computeBottomCommitActivityView(export.NetflixOrganizationRepos, export.NetflixRepoCommitActivity),
+		}))
+	}
+
+	if export.NetflixTeams != nil {
+		updates = append(updates, newTeamsUpdate(export.NetflixTeams, export.NetflixTeamRepos))
+	}
+
+	if export.NetflixRepoIssueCounts != nil {
+		updates = append(updates, newIssueCountsUpdate(export.NetflixOrganizationRepos, export.NetflixRepoIssueCounts))
+	}
+
+	if export.NetflixRepoReadmes != nil {
+		updates = append(updates, newDatasetUpdate(DATASET_READMES, export.NetflixRepoReadmes, readmesData{netflixRepoReadmes: export.NetflixRepoReadmes}))
+	}
+
+	if export.NetflixRepoLanguages != nil {
+		updates = append(updates, newLanguagesUpdate(export.NetflixRepoLanguages))
+	}
+
+	if export.ExtraEndpoints != nil {
+		updates = append(updates, newDatasetUpdate(DATASET_EXTRA_ENDPOINTS, export.ExtraEndpoints, extraEndpointsData{extraEndpoints: export.ExtraEndpoints}))
+	}
+
+	c.replaceDatasets(nil, export.Metadata.HydratedAt, export.Metadata.Approximate, updates...)
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	githubclient "github.com/adamjeanlaurent/github-api-read-cache-service/github-client"
+	"go.uber.org/zap"
+)
+
+// incremental syncs reach back this far past the last sync, so clock skew between GitHub and the service can't drop updates
+const INCREMENTAL_SYNC_OVERLAP time.Duration = time.Minute
+
+// Fetches every Netflix repo. When syncing incrementally, only repos updated since the last sync are fetched and merged into
+// the cached repos, falling back to a full sync every reposFullSyncInterval, or when the cached repos aren't from the GitHub API
+func (c *cache) fetchNetflixRepos(ctx context.Context) (*githubclient.Response[[]githubclient.JsonObject], error) {
+	if !c.incrementalRepoSync {
+		return c.githubClient.GetNetflixRepos(ctx)
+	}
+
+	stored, hydrated := c.store.Get(DATASET_REPOS)
+	now := time.Now().UTC()
+
+	c.lock.RLock()
+	lastFullRepoSync := c.lastFullRepoSync
+	c.lock.RUnlock()
+
+	if !hydrated || stored.Approximate || now.Sub(lastFullRepoSync) >= c.reposFullSyncInterval {
+		resp, err := c.githubClient.GetNetflixRepos(ctx)
+
+		if err == nil {
+			c.lock.Lock()
+			c.lastFullRepoSync = now
+			c.lock.Unlock()
+		}
+
+		return resp, err
+	}
+
+	resp, err := c.githubClient.GetNetflixReposUpdatedSince(ctx, stored.HydratedAt.Add(-INCREMENTAL_SYNC_OVERLAP))
+	if err != nil {
+		return resp, err
+	}
+
+	c.logger.Debug("Incrementally synced netflix organization repositories", zap.Int("updated", len(resp.Body)))
+
+	cached, _ := stored.Value.(reposData)
+	resp.Body = mergeRepos(cached.netflixOrganizationRepos, resp.Body)
+	return resp, nil
+}
+
+// Get repos with updated repos replacing the ones of the same name, repos that weren't cached yet are appended
+func mergeRepos(repos []githubclient.JsonObject, updated []githubclient.JsonObject) []githubclient.JsonObject {
+	updatedByName := indexReposByName(updated)
+	merged := make([]githubclient.JsonObject, 0, len(repos)+len(updated))
+
+	for _, repo := range repos {
+		name, _ := repo["name"].(string)
+
+		if updatedRepo, ok := updatedByName[strings.ToLower(name)]; ok {
+			merged = append(merged, updatedRepo)
+			delete(updatedByName, strings.ToLower(name))
+		} else {
+			merged = append(merged, repo)
+		}
+	}
+
+	// keep the order GitHub listed new repos in
+	for _, repo := range updated {
+		name, _ := repo["name"].(string)
+
+		if _, ok := updatedByName[strings.ToLower(name)]; ok {
+			merged = append(merged, repo)
+		}
+	}
+
+	return merged
+}
@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	githubclient "github.com/adamjeanlaurent/github-api-read-cache-service/github-client"
+)
+
+// Fetches the open issue and pull request counts of every cached repo from the search API, at most issueCountsConcurrency
+// at a time. Repos whose counts couldn't be fetched keep their previously cached counts, fails only if every repo failed
+func (c *cache) fetchIssueCounts(ctx context.Context, report *SyncReport) (datasetUpdate, int, error) {
+	repos := c.GetNetflixOrganizationRepos()
+	if !c.IsHydrated(DATASET_REPOS) {
+		err := fmt.Errorf("Can't fetch issue counts before repos are cached")
+		report.recordDataset(DATASET_ISSUE_COUNTS, http.StatusServiceUnavailable, 0, err)
+		return datasetUpdate{}, http.StatusServiceUnavailable, err
+	}
+
+	previous := loadDataset[issueCountsData](c.store, DATASET_ISSUE_COUNTS).netflixRepoIssueCounts
+
+	counts, statusCode, err := fetchPerRepo(ctx, c, DATASET_ISSUE_COUNTS, repoNames(repos), c.issueCountsConcurrency, previous,
+		func(ctx context.Context, repo string) (githubclient.IssueCounts, bool, error, int) {
+			resp, err := c.githubClient.GetNetflixRepoIssueCounts(ctx, repo)
+			return resp.Body, true, err, resp.StatusCode
+		})
+
+	report.recordDataset(DATASET_ISSUE_COUNTS, statusCode, len(counts), err)
+	if err != nil {
+		return datasetUpdate{}, statusCode, fmt.Errorf("Failed to fetch issue counts: %w", err)
+	}
+
+	return newIssueCountsUpdate(repos, counts), http.StatusOK, nil
+}
+
+// Get an update of the issue counts dataset, computing its views over repos
+func newIssueCountsUpdate(repos []githubclient.JsonObject, counts map[string]githubclient.IssueCounts) datasetUpdate {
+	return newDatasetUpdate(DATASET_ISSUE_COUNTS, counts, issueCountsData{
+		netflixRepoIssueCounts:               counts,
+		viewBottomNetflixReposByIssues:       computeBottomIssueCountsView(repos, counts, func(counts githubclient.IssueCounts) int { return counts.OpenIssues }),
+		viewBottomNetflixReposByPullRequests: computeBottomIssueCountsView(repos, counts, func(counts githubclient.IssueCounts) int { return counts.OpenPullRequests }),
+	})
+}
+
+// Computes a bottom view of repos by one of their issue counts
+func computeBottomIssueCountsView(repos []githubclient.JsonObject, counts map[string]githubclient.IssueCounts, count func(githubclient.IssueCounts) int) []Tuple {
+	view := make([]Tuple, 0, len(counts))
+
+	for _, repo := range repos {
+		name, ok := repo["name"].(string)
+		if !ok {
+			continue
+		}
+
+		if repoCounts, ok := counts[strings.ToLower(name)]; ok {
+			view = append(view, Tuple{fmt.Sprintf("Netflix/%s", name), float64(count(repoCounts))})
+		}
+	}
+
+	sortBottomViewByCount(view)
+
+	return view
+}
+
+// Get Bottom Netflix Organization Repos By open issues, not counting pull requests, from Cache, empty unless issue counts are hydrated
+func (c *cache) GetBottomNetflixReposByIssues() []Tuple {
+	return loadDataset[issueCountsData](c.store, DATASET_ISSUE_COUNTS).viewBottomNetflixReposByIssues
+}
+
+// Get Bottom Netflix Organization Repos By open pull requests from Cache, empty unless issue counts are hydrated
+func (c *cache) GetBottomNetflixReposByPullRequests() []Tuple {
+	return loadDataset[issueCountsData](c.store, DATASET_ISSUE_COUNTS).viewBottomNetflixReposByPullRequests
+}
@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	githubclient "github.com/adamjeanlaurent/github-api-read-cache-service/github-client"
+)
+
+// Fetches the bytes of code per language of every cached repo, at most languagesConcurrency at a time. Repos whose
+// languages couldn't be fetched keep their previously cached languages, fails only if every repo failed
+func (c *cache) fetchLanguages(ctx context.Context, report *SyncReport) (datasetUpdate, int, error) {
+	repos := c.GetNetflixOrganizationRepos()
+	if !c.IsHydrated(DATASET_REPOS) {
+		err := fmt.Errorf("Can't fetch languages before repos are cached")
+		report.recordDataset(DATASET_LANGUAGES, http.StatusServiceUnavailable, 0, err)
+		return datasetUpdate{}, http.StatusServiceUnavailable, err
+	}
+
+	previous := loadDataset[languagesData](c.store, DATASET_LANGUAGES).netflixRepoLanguages
+
+	languages, statusCode, err := fetchPerRepo(ctx, c, DATASET_LANGUAGES, repoNames(repos), c.languagesConcurrency, previous,
+		func(ctx context.Context, repo string) (githubclient.JsonObject, bool, error, int) {
+			resp, err := c.githubClient.GetNetflixRepoLanguages(ctx, repo)
+			return resp.Body, true, err, resp.StatusCode
+		})
+
+	report.recordDataset(DATASET_LANGUAGES, statusCode, len(languages), err)
+	if err != nil {
+		return datasetUpdate{}, statusCode, fmt.Errorf("Failed to fetch languages: %w", err)
+	}
+
+	return newLanguagesUpdate(languages), http.StatusOK, nil
+}
+
+// Get the languages dataset update, along with the org-wide totals computed from it
+func newLanguagesUpdate(languages map[string]githubclient.JsonObject) datasetUpdate {
+	return newDatasetUpdate(DATASET_LANGUAGES, languages, languagesData{
+		netflixRepoLanguages: languages,
+		netflixOrgLanguages:  computeOrgLanguages(languages),
+	})
+}
+
+// Sums the bytes of code per language over every repo, as [language, bytes] tuples with the most bytes first
+func computeOrgLanguages(languages map[string]githubclient.JsonObject) []Tuple {
+	totals := map[string]float64{}
+
+	for _, repoLanguages := range languages {
+		for language, bytes := range repoLanguages {
+			if count, ok := bytes.(float64); ok {
+				totals[language] += count
+			}
+		}
+	}
+
+	return countTuples(totals)
+}
+
+// Get the bytes of code per language of a single Netflix Organization Repo by name from Cache, accepts either "repo" or
+// "Netflix/repo". Returns false if the repo's languages aren't cached
+func (c *cache) GetNetflixRepoLanguages(repo string) (githubclient.JsonObject, bool) {
+	languages, ok := loadDataset[languagesData](c.store, DATASET_LANGUAGES).netflixRepoLanguages[strings.TrimPrefix(strings.ToLower(repo), "netflix/")]
+	return languages, ok
+}
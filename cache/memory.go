@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"maps"
+	"slices"
+
+	"go.uber.org/zap"
+)
+
+// What happens when the cache grows past its memory limit
+const (
+	MEMORY_LIMIT_ACTION_WARN string = "warn" // log a warning, and keep everything
+	MEMORY_LIMIT_ACTION_TRIM string = "trim" // drop optional data until the cache is back under the limit
+)
+
+// Data the cache can serve without, at a cost (e.g. slower responses), kept alongside a dataset. Trimmed in order when the
+// cache is over its memory limit
+type optionalData struct {
+	name    string
+	dataset string
+	bytes   func(stored StoredDataset) int
+	trim    func(stored StoredDataset) StoredDataset
+}
+
+var optionalDatasets = []optionalData{
+	{
+		// views are still served without them, encoded per request
+		name:    "precomputed_views",
+		dataset: DATASET_VIEWS,
+		bytes: func(stored StoredDataset) int {
+			views, _ := stored.Value.(viewsData)
+
+			total := 0
+			for _, formatViews := range views.precomputedBottomViews {
+				for _, sizes := range formatViews {
+					for _, encoded := range sizes {
+						total += len(encoded)
+					}
+				}
+			}
+			return total
+		},
+		trim: func(stored StoredDataset) StoredDataset {
+			views, _ := stored.Value.(viewsData)
+			views.precomputedBottomViews = nil
+			stored.Value = views
+			return stored
+		},
+	},
+}
+
+// Get the approximate memory footprint of datasets, the size of every dataset's JSON encoding plus any optional data
+func footprint(datasets map[string]StoredDataset) int {
+	total := 0
+
+	for _, stored := range datasets {
+		total += stored.Bytes
+	}
+
+	for _, optional := range optionalDatasets {
+		total += optional.bytes(datasets[optional.dataset])
+	}
+
+	return total
+}
+
+// Checks the datasets about to be set (or replace every dataset) against the memory limit, warning when they'd put the cache
+// over the limit, and trimming optional data first when configured to. Records the footprint in metrics either way.
+// Must be called with c.lock held
+func (c *cache) enforceMemoryLimit(datasets map[string]StoredDataset, replace bool) {
+	merged := datasets
+	if !replace {
+		merged = maps.Clone(c.store.GetAll())
+		maps.Copy(merged, datasets)
+	}
+
+	total := footprint(merged)
+
+	// data trimmed from an earlier generation stays trimmed until a sync recomputes it
+	c.trimmed = slices.DeleteFunc(slices.Clone(c.trimmed), func(name string) bool {
+		index := slices.IndexFunc(optionalDatasets, func(optional optionalData) bool { return optional.name == name })
+		return index < 0 || optionalDatasets[index].bytes(merged[optionalDatasets[index].dataset]) > 0
+	})
+
+	if c.maxCacheBytes > 0 && total > c.maxCacheBytes && c.memoryLimitAction == MEMORY_LIMIT_ACTION_TRIM {
+		for _, optional := range optionalDatasets {
+			if total <= c.maxCacheBytes {
+				break
+			}
+
+			stored := merged[optional.dataset]
+			if bytes := optional.bytes(stored); bytes > 0 {
+				// datasets that weren't being set are set again without their optional data
+				datasets[optional.dataset] = optional.trim(stored)
+				total -= bytes
+				c.trimmed = append(c.trimmed, optional.name)
+			}
+		}
+
+		c.logger.Warn("Cache exceeded its memory limit, trimmed optional data", zap.Int("limit bytes", c.maxCacheBytes), zap.Int("bytes", total), zap.Strings("trimmed", c.trimmed))
+	}
+
+	overLimit := c.maxCacheBytes > 0 && total > c.maxCacheBytes
+	if overLimit {
+		c.logger.Warn("Cache is over its memory limit", zap.Int("limit bytes", c.maxCacheBytes), zap.Int("bytes", total))
+	}
+
+	c.totalBytesGauge.Set(float64(total))
+	c.overLimitGauge.Set(boolToFloat(overLimit))
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,21 @@
+package cache
+
+import (
+	"io"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Formats cached responses are served in, precomputed views are encoded in each
+const (
+	FORMAT_JSON    string = "json"
+	FORMAT_MSGPACK string = "msgpack"
+)
+
+// Encodes v as MessagePack, structs are keyed by their JSON field names so both formats describe the same document
+func EncodeMsgpack(w io.Writer, v interface{}) error {
+	encoder := msgpack.NewEncoder(w)
+	encoder.SetCustomStructTag("json")
+
+	return encoder.Encode(v)
+}
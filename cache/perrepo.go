@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	githubclient "github.com/adamjeanlaurent/github-api-read-cache-service/github-client"
+	"go.uber.org/zap"
+)
+
+// Fetches a value of a single repo, returns false if the repo has none (e.g. no releases)
+type repoFetcher[T any] func(ctx context.Context, repo string) (T, bool, error, int)
+
+// Fetches a value for each repo, at most concurrency at a time, keyed by lower-cased repo name. A repo that fails doesn't
+// fail the others: it's counted on the per-repo failures metric, keeps its previous value if it had one, or is left out
+// until a later sync. Only fails if every repo failed, or ctx is done
+func fetchPerRepo[T any](ctx context.Context, c *cache, dataset string, repos []string, concurrency int, previous map[string]T, fetch repoFetcher[T]) (map[string]T, int, error) {
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	var firstErr error
+	failures := 0
+	statusCode := http.StatusOK
+	values := make(map[string]T, len(repos))
+	slots := make(chan struct{}, concurrency)
+
+	for _, repo := range repos {
+		slots <- struct{}{}
+		if ctx.Err() != nil {
+			<-slots
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			value, found, err, status := fetch(ctx, repo)
+
+			lock.Lock()
+			defer lock.Unlock()
+
+			if err != nil {
+				failures++
+				if firstErr == nil {
+					firstErr = fmt.Errorf("Failed to fetch %s: %s", repo, err.Error())
+					statusCode = status
+				}
+
+				c.perRepoFailures.Add(1, "dataset", dataset)
+				c.logger.Debug("Failed to fetch repo", zap.String("dataset", dataset), zap.String("repo", repo), zap.Int("Http status code", status), zap.Error(err))
+
+				if previousValue, ok := previous[strings.ToLower(repo)]; ok {
+					values[strings.ToLower(repo)] = previousValue
+				}
+				return
+			}
+
+			if found {
+				values[strings.ToLower(repo)] = value
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return nil, http.StatusServiceUnavailable, ctx.Err()
+	}
+
+	if failures > 0 && failures == len(repos) {
+		return nil, statusCode, firstErr
+	}
+
+	if failures > 0 {
+		c.logger.Warn("Failed to fetch some repos, publishing the others", zap.String("dataset", dataset), zap.Int("failed", failures), zap.Int("repos", len(repos)), zap.Int("Http status code", statusCode), zap.Error(firstErr))
+	}
+
+	return values, http.StatusOK, nil
+}
+
+// Get the names of repos
+func repoNames(repos []githubclient.JsonObject) []string {
+	names := make([]string, 0, len(repos))
+
+	for _, repo := range repos {
+		if name, ok := repo["name"].(string); ok {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
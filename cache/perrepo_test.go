@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/metrics"
+	"go.uber.org/zap"
+)
+
+func newPerRepoTestCache() *cache {
+	return &cache{
+		logger:          zap.NewNop(),
+		perRepoFailures: metrics.NewRegistry().Counter("failures", ""),
+	}
+}
+
+// Fetches the length of a repo's name, failing for the repos in failing with a 502
+func fetchNameLength(failing ...string) repoFetcher[int] {
+	return func(ctx context.Context, repo string) (int, bool, error, int) {
+		for _, name := range failing {
+			if repo == name {
+				return 0, false, errors.New("bad gateway"), http.StatusBadGateway
+			}
+		}
+		return len(repo), true, nil, http.StatusOK
+	}
+}
+
+func TestFetchPerRepo(t *testing.T) {
+	repos := []string{"zuul", "Eureka", "hystrix"}
+
+	tests := []struct {
+		name       string
+		failing    []string
+		previous   map[string]int
+		want       map[string]int
+		wantStatus int
+		wantErr    bool
+	}{
+		{
+			name:       "every repo fetched",
+			want:       map[string]int{"zuul": 4, "eureka": 6, "hystrix": 7},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "failed repo without a previous value is left out",
+			failing:    []string{"Eureka"},
+			want:       map[string]int{"zuul": 4, "hystrix": 7},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "failed repo keeps its previous value",
+			failing:    []string{"Eureka", "hystrix"},
+			previous:   map[string]int{"eureka": 42, "zuul": 1},
+			want:       map[string]int{"zuul": 4, "eureka": 42},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "every repo failed",
+			failing:    repos,
+			previous:   map[string]int{"eureka": 42},
+			wantStatus: http.StatusBadGateway,
+			wantErr:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			values, status, err := fetchPerRepo(context.Background(), newPerRepoTestCache(), DATASET_LANGUAGES, repos, 2, test.previous, fetchNameLength(test.failing...))
+
+			if (err != nil) != test.wantErr || status != test.wantStatus {
+				t.Fatalf("fetchPerRepo = (%d, %v), want status %d and error %v", status, err, test.wantStatus, test.wantErr)
+			}
+			if test.wantErr {
+				return
+			}
+
+			if len(values) != len(test.want) {
+				t.Errorf("fetchPerRepo = %v, want %v", values, test.want)
+			}
+			for repo, want := range test.want {
+				if got, ok := values[repo]; !ok || got != want {
+					t.Errorf("value of %s = %d (%v), want %d", repo, got, ok, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFetchPerRepoCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, status, err := fetchPerRepo(ctx, newPerRepoTestCache(), DATASET_LANGUAGES, []string{"zuul"}, 1, nil, fetchNameLength())
+	if !errors.Is(err, context.Canceled) || status != http.StatusServiceUnavailable {
+		t.Errorf("fetchPerRepo = (%d, %v), want (503, context canceled)", status, err)
+	}
+}
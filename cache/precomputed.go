@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// Names of the bottom views
+const (
+	VIEW_BOTTOM_FORKS           string = "forks"
+	VIEW_BOTTOM_LAST_UPDATED    string = "last_updated"
+	VIEW_BOTTOM_OPEN_ISSUES     string = "open_issues"
+	VIEW_BOTTOM_STARS           string = "stars"
+	VIEW_BOTTOM_CONTRIBUTORS    string = "contributors"    // computed from the contributors dataset, not precomputed
+	VIEW_RECENT_RELEASES        string = "releases"        // most recently released first, computed from the releases dataset
+	VIEW_BOTTOM_COMMIT_ACTIVITY string = "commit_activity" // commits over the last year, computed from the commit activity dataset
+	VIEW_BOTTOM_ISSUES          string = "issues"          // open issues without pull requests, computed from the issue counts dataset
+	VIEW_BOTTOM_PULL_REQUESTS   string = "pull_requests"   // open pull requests, computed from the issue counts dataset
+)
+
+// Encoders of the formats views are precomputed in, by format
+var precomputedFormats = map[string]func(w io.Writer, v interface{}) error{
+	FORMAT_JSON:    func(w io.Writer, v interface{}) error { return json.NewEncoder(w).Encode(v) },
+	FORMAT_MSGPACK: EncodeMsgpack,
+}
+
+// Serializes the bottom N slice of every view as JSON and MessagePack for each configured N, so the most commonly requested
+// sizes don't need to be sliced and encoded on every request. Sizes larger than the view are stored under the view's length,
+// the same N a request for them is clamped to
+func (c *cache) precomputeBottomViews(views *viewsData) {
+	if len(c.precomputedViewSizes) == 0 {
+		return
+	}
+
+	precomputed := map[string]map[string]map[int][]byte{}
+
+	for format, encode := range precomputedFormats {
+		precomputed[format] = map[string]map[int][]byte{}
+
+		for view, tuples := range views.views {
+			precomputed[format][view] = map[int][]byte{}
+
+			for _, n := range c.precomputedViewSizes {
+				n = min(n, len(tuples))
+
+				// encoded exactly as a per-request response would be, so bodies and ETags match either way
+				var buf bytes.Buffer
+				if err := encode(&buf, tuples[len(tuples)-n:]); err != nil {
+					continue
+				}
+
+				precomputed[format][view][n] = buf.Bytes()
+			}
+		}
+	}
+
+	views.precomputedBottomViews = precomputed
+}
+
+// Get the precomputed encoding of the bottom n repos of view in format, false if n isn't one of the precomputed sizes
+func (c *cache) GetPrecomputedBottomView(format string, view string, n int) ([]byte, bool) {
+	return c.Snapshot().PrecomputedBottomView(format, view, n)
+}
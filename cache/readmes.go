@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	githubclient "github.com/adamjeanlaurent/github-api-read-cache-service/github-client"
+)
+
+// Fetches the README of every cached repo, at most readmesConcurrency at a time. Repos whose README couldn't be fetched
+// keep their previously cached README, fails only if every repo failed
+func (c *cache) fetchReadmes(ctx context.Context, report *SyncReport) (datasetUpdate, int, error) {
+	repos := c.GetNetflixOrganizationRepos()
+	if !c.IsHydrated(DATASET_REPOS) {
+		err := fmt.Errorf("Can't fetch READMEs before repos are cached")
+		report.recordDataset(DATASET_READMES, http.StatusServiceUnavailable, 0, err)
+		return datasetUpdate{}, http.StatusServiceUnavailable, err
+	}
+
+	previous := loadDataset[readmesData](c.store, DATASET_READMES).netflixRepoReadmes
+
+	// repos without a README are cached as null, so they're still known to be tracked
+	readmes, statusCode, err := fetchPerRepo(ctx, c, DATASET_READMES, repoNames(repos), c.readmesConcurrency, previous,
+		func(ctx context.Context, repo string) (*githubclient.Readme, bool, error, int) {
+			resp, err := c.githubClient.GetNetflixRepoReadme(ctx, repo)
+			if resp.StatusCode == http.StatusNotFound {
+				return nil, true, nil, http.StatusOK
+			}
+
+			return &resp.Body, true, err, resp.StatusCode
+		})
+
+	report.recordDataset(DATASET_READMES, statusCode, len(readmes), err)
+	if err != nil {
+		return datasetUpdate{}, statusCode, fmt.Errorf("Failed to fetch READMEs: %w", err)
+	}
+
+	return newDatasetUpdate(DATASET_READMES, readmes, readmesData{netflixRepoReadmes: readmes}), http.StatusOK, nil
+}
+
+// Get the README of a single Netflix Organization Repo by name from Cache, accepts either "repo" or "Netflix/repo".
+// Returns false if the repo's README isn't cached, and a nil README if the repo has none
+func (c *cache) GetNetflixRepoReadme(repo string) (*githubclient.Readme, bool) {
+	readme, ok := loadDataset[readmesData](c.store, DATASET_READMES).netflixRepoReadmes[strings.TrimPrefix(strings.ToLower(repo), "netflix/")]
+	return readme, ok
+}
@@ -0,0 +1,171 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Returned when asked to hydrate a dataset that isn't hydrated on its own
+var ErrUnknownDataset = errors.New("Dataset can't be hydrated on its own")
+
+// Datasets waiting on a re-hydration requested after a proxied write
+type pendingRefreshes struct {
+	lock     sync.Mutex
+	datasets map[string]bool
+	signal   chan struct{} // signals the sync loop that a dataset was added
+}
+
+// Get the dataset a write to path changes, e.g. PATCH /repos/Netflix/zuul changes the repos. Only datasets hydrated from
+// GitHub are considered, paths outside the Netflix org don't change the cache
+func MutatedDataset(path string) (string, bool) {
+	segments := strings.Split(strings.Trim(strings.ToLower(path), "/"), "/")
+
+	switch {
+	// PATCH /orgs/Netflix
+	case len(segments) == 2 && segments[0] == "orgs" && segments[1] == "netflix":
+		return DATASET_ORGANIZATION, true
+
+	// PATCH / DELETE /orgs/Netflix/teams/{team}, and PUT / DELETE /orgs/Netflix/teams/{team}/repos/Netflix/{repo}
+	case len(segments) >= 3 && segments[0] == "orgs" && segments[1] == "netflix" && segments[2] == "teams":
+		return DATASET_TEAMS, true
+
+	// PUT / DELETE /orgs/Netflix/members/{login} or /orgs/Netflix/memberships/{login}
+	case len(segments) >= 3 && segments[0] == "orgs" && segments[1] == "netflix" && (segments[2] == "members" || segments[2] == "memberships"):
+		return DATASET_MEMBERS, true
+
+	// POST /orgs/Netflix/repos
+	case len(segments) == 3 && segments[0] == "orgs" && segments[1] == "netflix" && segments[2] == "repos":
+		return DATASET_REPOS, true
+
+	// POST /repos/Netflix/{repo}/releases and its assets
+	case len(segments) >= 4 && segments[0] == "repos" && segments[1] == "netflix" && segments[3] == "releases":
+		return DATASET_RELEASES, true
+
+	// PATCH / DELETE /repos/Netflix/{repo}, and writes that change its counts, e.g. POST /repos/Netflix/{repo}/issues or /forks
+	case len(segments) >= 3 && segments[0] == "repos" && segments[1] == "netflix":
+		return DATASET_REPOS, true
+
+	// PUT / DELETE /user/starred/Netflix/{repo}
+	case len(segments) == 4 && segments[0] == "user" && segments[1] == "starred" && segments[2] == "netflix":
+		return DATASET_REPOS, true
+	}
+
+	return "", false
+}
+
+// Schedules a re-hydration of dataset after the refresh on mutation delay, so the cache converges soon after a write flows through the
+// proxy. Requests made before the re-hydration runs are coalesced into it. Ignored for datasets that aren't hydrated, or when disabled
+func (c *cache) RequestRefresh(dataset string) {
+	if c.refreshOnMutationDelay == 0 || c.syncDatasetFetcher(dataset) == nil {
+		return
+	}
+
+	c.refreshes.lock.Lock()
+	c.refreshes.datasets[dataset] = true
+	c.refreshes.lock.Unlock()
+
+	select {
+	case c.refreshes.signal <- struct{}{}:
+	default:
+	}
+}
+
+// Takes the datasets waiting on a re-hydration, sorted so they're re-hydrated in a stable order
+func (c *cache) takePendingRefreshes() []string {
+	c.refreshes.lock.Lock()
+	defer c.refreshes.lock.Unlock()
+
+	datasets := make([]string, 0, len(c.refreshes.datasets))
+	for dataset := range c.refreshes.datasets {
+		datasets = append(datasets, dataset)
+	}
+	c.refreshes.datasets = map[string]bool{}
+
+	sort.Strings(datasets)
+	return datasets
+}
+
+// Re-hydrates the datasets written to through the proxy since the last refresh
+func (c *cache) refreshMutatedDatasets() {
+	for _, dataset := range c.takePendingRefreshes() {
+		c.logger.Info("Re-hydrating cache dataset after a proxied write", zap.String("dataset", dataset), zap.Duration("delay", c.refreshOnMutationDelay))
+		c.syncDataset(dataset, c.syncDatasetFetcher(dataset))
+	}
+}
+
+// Re-fetches only dataset, rather than the whole org, e.g. after a webhook says it changed. Returns the upstream status
+// code, and ErrUnknownDataset for datasets that aren't hydrated on their own
+func (c *cache) HydrateDataset(ctx context.Context, dataset string) (int, error) {
+	fetch := c.syncDatasetFetcher(dataset)
+	if fetch == nil {
+		return http.StatusBadRequest, fmt.Errorf("%w: %s", ErrUnknownDataset, dataset)
+	}
+
+	return c.hydrateDataset(ctx, dataset, fetch)
+}
+
+// Re-fetches only the Netflix organization
+func (c *cache) HydrateOrg(ctx context.Context) (int, error) {
+	return c.HydrateDataset(ctx, DATASET_ORGANIZATION)
+}
+
+// Re-fetches only the Netflix organization members
+func (c *cache) HydrateMembers(ctx context.Context) (int, error) {
+	return c.HydrateDataset(ctx, DATASET_MEMBERS)
+}
+
+// Re-fetches only the Netflix organization repos, and the views computed from them
+func (c *cache) HydrateRepos(ctx context.Context) (int, error) {
+	return c.HydrateDataset(ctx, DATASET_REPOS)
+}
+
+// Get the datasets that can be re-fetched on their own, sorted
+func (c *cache) HydratableDatasets() []string {
+	var datasets []string
+	for _, dataset := range []string{DATASET_MEMBERS, DATASET_ORGANIZATION, DATASET_RELEASES, DATASET_REPOS, DATASET_TEAMS} {
+		if c.syncDatasetFetcher(dataset) != nil {
+			datasets = append(datasets, dataset)
+		}
+	}
+
+	return datasets
+}
+
+// Get the fetcher the sync loop re-hydrates dataset with, nil if the dataset isn't hydrated on its own
+func (c *cache) syncDatasetFetcher(dataset string) datasetFetcher {
+	switch dataset {
+	case DATASET_ORGANIZATION:
+		return c.fetchOrg
+	case DATASET_MEMBERS:
+		return c.fetchMembers
+	case DATASET_REPOS:
+		return c.fetchRepos
+	case DATASET_RELEASES:
+		if len(c.releaseRepos) > 0 {
+			return c.fetchReleases
+		}
+	case DATASET_TEAMS:
+		if c.hydrateTeams {
+			return c.fetchTeams
+		}
+	}
+
+	return nil
+}
+
+// Get a channel that fires once the refresh on mutation delay has passed, nil while no timer is running
+func refreshTimerChannel(timer *time.Timer) <-chan time.Time {
+	if timer == nil {
+		return nil
+	}
+
+	return timer.C
+}
@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	githubclient "github.com/adamjeanlaurent/github-api-read-cache-service/github-client"
+)
+
+// max repos whose latest release is fetched at once
+const RELEASES_CONCURRENCY int = 8
+
+// Fetches the latest release of every repo in the configured subset, "*" for every cached repo. Repos whose release
+// couldn't be fetched keep their previously cached release, fails only if every repo failed
+func (c *cache) fetchReleases(ctx context.Context, report *SyncReport) (datasetUpdate, int, error) {
+	repos := c.releaseRepos
+	if slices.Contains(repos, "*") {
+		repos = repoNames(c.GetNetflixOrganizationRepos())
+
+		if !c.IsHydrated(DATASET_REPOS) {
+			err := fmt.Errorf("Can't fetch releases of every repo before repos are cached")
+			report.recordDataset(DATASET_RELEASES, http.StatusServiceUnavailable, 0, err)
+			return datasetUpdate{}, http.StatusServiceUnavailable, err
+		}
+	}
+
+	previous := loadDataset[releasesData](c.store, DATASET_RELEASES).netflixRepoLatestReleases
+
+	// repos without releases are cached as null, so they're still known to be tracked
+	releases, statusCode, err := fetchPerRepo(ctx, c, DATASET_RELEASES, repos, RELEASES_CONCURRENCY, previous,
+		func(ctx context.Context, repo string) (githubclient.JsonObject, bool, error, int) {
+			resp, err := c.githubClient.GetNetflixRepoLatestRelease(ctx, repo)
+			if resp.StatusCode == http.StatusNotFound {
+				return nil, true, nil, http.StatusOK
+			}
+
+			return resp.Body, true, err, resp.StatusCode
+		})
+
+	report.recordDataset(DATASET_RELEASES, statusCode, len(releases), err)
+	if err != nil {
+		return datasetUpdate{}, statusCode, fmt.Errorf("Failed to fetch releases: %w", err)
+	}
+
+	return newDatasetUpdate(DATASET_RELEASES, releases, releasesData{
+		netflixRepoLatestReleases: releases,
+		viewRecentNetflixReleases: computeRecentReleasesView(releases),
+	}), http.StatusOK, nil
+}
+
+// Computes the view of repos by when they were last released, most recent first
+func computeRecentReleasesView(releases map[string]githubclient.JsonObject) []Tuple {
+	view := make([]Tuple, 0, len(releases))
+
+	for repo, release := range releases {
+		if release == nil {
+			continue
+		}
+
+		publishedAt, ok := release["published_at"].(string)
+		if !ok {
+			continue
+		}
+
+		// release URLs carry the repo's canonical casing, keys are lower-cased
+		name := repo
+		if htmlUrl, ok := release["html_url"].(string); ok {
+			if parts := strings.Split(htmlUrl, "/"); len(parts) > 4 {
+				name = parts[4]
+			}
+		}
+
+		view = append(view, Tuple{fmt.Sprintf("Netflix/%s", name), publishedAt})
+	}
+
+	// newest first, ties by name so the view is stable
+	slices.SortFunc(view, func(a Tuple, b Tuple) int {
+		timeA, _ := time.Parse(time.RFC3339, a[1].(string))
+		timeB, _ := time.Parse(time.RFC3339, b[1].(string))
+
+		if cmp := timeB.Compare(timeA); cmp != 0 {
+			return cmp
+		}
+		return strings.Compare(a[0].(string), b[0].(string))
+	})
+
+	return view
+}
+
+// Get Netflix Organization Repos by most recent release from Cache, empty unless releases are hydrated
+func (c *cache) GetRecentNetflixReleases() []Tuple {
+	return loadDataset[releasesData](c.store, DATASET_RELEASES).viewRecentNetflixReleases
+}
+
+// Get the latest release of a single Netflix Organization Repo by name from Cache, accepts either "repo" or "Netflix/repo".
+// Returns false if the repo's releases aren't cached, and a nil release if the repo has none
+func (c *cache) GetNetflixRepoLatestRelease(repo string) (githubclient.JsonObject, bool) {
+	release, ok := loadDataset[releasesData](c.store, DATASET_RELEASES).netflixRepoLatestReleases[strings.TrimPrefix(strings.ToLower(repo), "netflix/")]
+	return release, ok
+}
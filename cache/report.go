@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"time"
+
+	githubclient "github.com/adamjeanlaurent/github-api-read-cache-service/github-client"
+)
+
+const (
+	DATASET_ORGANIZATION    string = "organization"
+	DATASET_MEMBERS         string = "members"
+	DATASET_REPOS           string = "repos"
+	DATASET_VIEWS           string = "views"
+	DATASET_CONTRIBUTORS    string = "contributors"    // optional, see --hydrate-contributors
+	DATASET_RELEASES        string = "releases"        // optional, see --release-repos
+	DATASET_COMMIT_ACTIVITY string = "commit_activity" // optional, see --hydrate-commit-activity
+	DATASET_TEAMS           string = "teams"           // optional, see --hydrate-teams
+	DATASET_ISSUE_COUNTS    string = "issue_counts"    // optional, see --hydrate-issue-counts
+	DATASET_READMES         string = "readmes"         // optional, see --hydrate-readmes
+	DATASET_LANGUAGES       string = "languages"       // optional, see --hydrate-languages
+	DATASET_EXTRA_ENDPOINTS string = "extra_endpoints" // optional, see --extra-endpoints
+)
+
+// Outcome of fetching or computing a single dataset during a cache sync
+type DatasetSyncReport struct {
+	Status     int                `json:"status"`
+	Items      int                `json:"items"`
+	Error      string             `json:"error,omitempty"`
+	Skipped    []SkippedRecord    `json:"skipped,omitempty"`    // records left out of the dataset
+	Unparsable []UnparsableRecord `json:"unparsable,omitempty"` // records kept in the dataset with a value it couldn't parse
+}
+
+// Record left out of a dataset because it's missing fields the dataset needs
+type SkippedRecord struct {
+	Record        string   `json:"record"`
+	MissingFields []string `json:"missing_fields"`
+}
+
+// Record with a field the dataset couldn't parse, e.g. a timestamp that isn't RFC 3339
+type UnparsableRecord struct {
+	Record string `json:"record"`
+	Field  string `json:"field"`
+	Value  string `json:"value"`
+}
+
+// Detailed outcome of a cache sync attempt
+type SyncReport struct {
+	StartTime   time.Time                    `json:"start_time"`
+	EndTime     time.Time                    `json:"end_time"`
+	DurationMs  int64                        `json:"duration_ms"`
+	Status      int                          `json:"status"`
+	Error       string                       `json:"error,omitempty"`
+	Approximate bool                         `json:"approximate,omitempty"`
+	Datasets    map[string]DatasetSyncReport `json:"datasets"`
+	Upstream    githubclient.RequestUsage    `json:"upstream"` // GitHub requests the sync made, to predict quota usage when tuning TTLs
+}
+
+// Get a new report for a sync starting now
+func newSyncReport() SyncReport {
+	return SyncReport{StartTime: time.Now().UTC(), Datasets: map[string]DatasetSyncReport{}}
+}
+
+// Records the outcome of a single dataset
+func (report *SyncReport) recordDataset(dataset string, status int, items int, err error) {
+	datasetReport := DatasetSyncReport{Status: status, Items: items}
+
+	if err != nil {
+		datasetReport.Error = err.Error()
+	}
+
+	report.Datasets[dataset] = datasetReport
+}
+
+// Records the records left out of a dataset, must be called after its outcome is recorded
+func (report *SyncReport) recordSkipped(dataset string, skipped []SkippedRecord) {
+	datasetReport := report.Datasets[dataset]
+	datasetReport.Skipped = skipped
+	report.Datasets[dataset] = datasetReport
+}
+
+// Records the records kept in a dataset with values it couldn't parse, must be called after its outcome is recorded
+func (report *SyncReport) recordUnparsable(dataset string, unparsable []UnparsableRecord) {
+	datasetReport := report.Datasets[dataset]
+	datasetReport.Unparsable = unparsable
+	report.Datasets[dataset] = datasetReport
+}
+
+// Records the overall outcome of the sync, and when it finished
+func (report *SyncReport) finish(status int, err error) {
+	report.EndTime = time.Now().UTC()
+	report.DurationMs = report.EndTime.Sub(report.StartTime).Milliseconds()
+	report.Status = status
+
+	if err != nil {
+		report.Error = err.Error()
+	}
+}
+
+// Get the report of the last attempted cache sync
+func (c *cache) GetLastSyncReport() SyncReport {
+	defer c.lock.RUnlock()
+	c.lock.RLock()
+
+	return c.lastSyncReport
+}
@@ -0,0 +1,72 @@
+package cache
+
+import (
+	githubclient "github.com/adamjeanlaurent/github-api-read-cache-service/github-client"
+)
+
+// Types the repo list can be filtered to, e.g. ?type=sources, matching the GitHub API's own type filter where it has one
+const (
+	REPO_TYPE_ALL      string = "all"
+	REPO_TYPE_SOURCES  string = "sources" // repos that aren't forks
+	REPO_TYPE_FORKS    string = "forks"
+	REPO_TYPE_ARCHIVED string = "archived"
+	REPO_TYPE_MIRRORS  string = "mirrors" // repos mirroring another git host, i.e. with a mirror_url
+)
+
+// Every type the repo list can be filtered to other than all, along with whether a repo is of the type
+var repoTypes = map[string]func(repo githubclient.JsonObject) bool{
+	REPO_TYPE_SOURCES: func(repo githubclient.JsonObject) bool {
+		fork, _ := repo["fork"].(bool)
+		return !fork
+	},
+	REPO_TYPE_FORKS: func(repo githubclient.JsonObject) bool {
+		fork, _ := repo["fork"].(bool)
+		return fork
+	},
+	REPO_TYPE_ARCHIVED: func(repo githubclient.JsonObject) bool {
+		archived, _ := repo["archived"].(bool)
+		return archived
+	},
+	REPO_TYPE_MIRRORS: func(repo githubclient.JsonObject) bool {
+		mirrorUrl, _ := repo["mirror_url"].(string)
+		return mirrorUrl != ""
+	},
+}
+
+// Whether the repo list can be filtered to repoType
+func IsRepoType(repoType string) bool {
+	_, ok := repoTypes[repoType]
+	return ok || repoType == REPO_TYPE_ALL
+}
+
+// Groups repos by each type, in the same order as repos, computed along with the repos so filtering by type per request
+// is a lookup
+func indexReposByType(repos []githubclient.JsonObject) map[string][]githubclient.JsonObject {
+	byType := make(map[string][]githubclient.JsonObject, len(repoTypes))
+
+	for repoType, isType := range repoTypes {
+		// an empty list rather than nil, a type without repos is served as []
+		typed := []githubclient.JsonObject{}
+
+		for _, repo := range repos {
+			if isType(repo) {
+				typed = append(typed, repo)
+			}
+		}
+
+		byType[repoType] = typed
+	}
+
+	return byType
+}
+
+// Get Netflix Organization Repos of repoType, every repo for all
+func (snapshot Snapshot) NetflixOrganizationReposOfType(repoType string) []githubclient.JsonObject {
+	repos := snapshotDataset[reposData](snapshot, DATASET_REPOS)
+
+	if repoType == REPO_TYPE_ALL {
+		return repos.netflixOrganizationRepos
+	}
+
+	return repos.reposByType[repoType]
+}
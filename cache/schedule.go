@@ -0,0 +1,67 @@
+package cache
+
+import "time"
+
+// Records that the tickers of datasets started now, so their next sync can be predicted
+func (c *cache) scheduleSyncs(datasets ...string) {
+	now := time.Now()
+
+	c.statsLock.Lock()
+	defer c.statsLock.Unlock()
+
+	for _, dataset := range datasets {
+		c.syncSchedule[dataset] = now
+	}
+}
+
+// Get the interval the sync loop re-hydrates dataset on
+func (c *cache) datasetTTL(dataset string) time.Duration {
+	switch dataset {
+	case DATASET_ORGANIZATION:
+		return c.orgTTL
+	case DATASET_MEMBERS:
+		return c.membersTTL
+	case DATASET_REPOS:
+		return c.reposTTL
+	case DATASET_CONTRIBUTORS:
+		return c.contributorsTTL
+	case DATASET_RELEASES:
+		return c.releasesTTL
+	case DATASET_COMMIT_ACTIVITY:
+		return c.commitActivityTTL
+	case DATASET_TEAMS:
+		return c.teamsTTL
+	case DATASET_ISSUE_COUNTS:
+		return c.issueCountsTTL
+	case DATASET_READMES:
+		return c.readmesTTL
+	case DATASET_LANGUAGES:
+		return c.languagesTTL
+	case DATASET_EXTRA_ENDPOINTS:
+		return c.extraEndpointsTTL
+	}
+
+	return 0
+}
+
+// Get when the sync loop next re-hydrates dataset, zero if the sync loop isn't running or doesn't hydrate it. Refreshes
+// after proxied writes (--refresh-on-mutation-delay) can re-hydrate it sooner
+func (c *cache) GetNextSyncTime(dataset string) time.Time {
+	// views are re-computed whenever the repos are synced
+	scheduled := dataset
+	if dataset == DATASET_VIEWS {
+		scheduled = DATASET_REPOS
+	}
+
+	c.statsLock.Lock()
+	started, ok := c.syncSchedule[scheduled]
+	c.statsLock.Unlock()
+
+	ttl := c.datasetTTL(scheduled)
+	if !ok || ttl <= 0 {
+		return time.Time{}
+	}
+
+	// tickers fire every ttl from when they started
+	return started.Add((time.Since(started)/ttl + 1) * ttl)
+}
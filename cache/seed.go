@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"go.uber.org/zap"
+)
+
+// Seeds the cache from a previously exported cache at startup, so a cold start serves data before the first GitHub hydration.
+// The seed is only loaded when no snapshot was, a persisted snapshot is at least as fresh. Returns whether the cache was seeded
+func (c *cache) loadSeedOnStartup() bool {
+	if c.seedFile == "" && c.seedDownloader == nil {
+		return false
+	}
+
+	if c.Snapshot().Version() != 0 {
+		c.logger.Info("Skipping cache seed, a snapshot was loaded")
+		return false
+	}
+
+	source := c.seedFile
+	var seed CacheExport
+	var err error
+
+	if c.seedDownloader != nil {
+		source = "seed url"
+		seed, err = c.downloadSeed()
+	} else {
+		seed, err = readSnapshotFile(c.seedFile)
+	}
+
+	if err != nil {
+		c.logger.Error("Failed to read cache seed", zap.String("source", source), zap.Error(err))
+		return false
+	}
+
+	if err := c.Import(seed); err != nil {
+		c.logger.Error("Failed to load cache seed", zap.String("source", source), zap.Error(err))
+		return false
+	}
+
+	c.logger.Info("Seeded cache", zap.String("source", source), zap.Time("hydrated at", seed.Metadata.HydratedAt))
+	return true
+}
+
+// Downloads the seed from the seed URL
+func (c *cache) downloadSeed() (CacheExport, error) {
+	body, err := c.seedDownloader.Download(c.ctx)
+	if err != nil {
+		return CacheExport{}, err
+	}
+	defer body.Close()
+
+	return readSnapshot(body)
+}
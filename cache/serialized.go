@@ -0,0 +1,128 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// Wraps a Store that doesn't hold values (e.g. Redis, disk), encoding every dataset's value into its payload when it's set,
+// and decoding it back when it's read. Decoded values are reused for as long as their dataset's version is current
+type serializingStore struct {
+	backend Store
+	c       *cache
+	lock    sync.Mutex
+	decoded map[string]StoredDataset // last decoded version of each dataset
+}
+
+// Get new Store holding values on top of backend
+func newSerializingStore(backend Store, c *cache) Store {
+	return &serializingStore{backend: backend, c: c, decoded: map[string]StoredDataset{}}
+}
+
+// Get a single dataset, false if it has never been set
+func (store *serializingStore) Get(dataset string) (StoredDataset, bool) {
+	stored, ok := store.backend.Get(dataset)
+	if !ok {
+		return stored, false
+	}
+
+	return store.decode(dataset, stored)
+}
+
+// Get every dataset, consistent with each other
+func (store *serializingStore) GetAll() map[string]StoredDataset {
+	datasets := store.backend.GetAll()
+
+	decoded := make(map[string]StoredDataset, len(datasets))
+	for dataset, stored := range datasets {
+		if value, ok := store.decode(dataset, stored); ok {
+			decoded[dataset] = value
+		}
+	}
+
+	return decoded
+}
+
+// Sets datasets in a single swap, leaving the others as they are. Returns the new version of the store
+func (store *serializingStore) Set(datasets map[string]StoredDataset) uint64 {
+	return store.backend.Set(store.encode(datasets))
+}
+
+// Replaces every dataset in a single swap. Returns the new version of the store
+func (store *serializingStore) Replace(datasets map[string]StoredDataset) uint64 {
+	return store.backend.Replace(store.encode(datasets))
+}
+
+// Get the version of the store, 0 until anything is set
+func (store *serializingStore) Version() uint64 {
+	return store.backend.Version()
+}
+
+// Values are decoded from the backend's payloads
+func (store *serializingStore) HoldsValues() bool {
+	return true
+}
+
+// Get a copy of datasets with their values encoded as payloads, datasets that fail to encode are stored without one
+func (store *serializingStore) encode(datasets map[string]StoredDataset) map[string]StoredDataset {
+	encoded := make(map[string]StoredDataset, len(datasets))
+
+	for dataset, stored := range datasets {
+		payload, err := encodeDataset(dataset, stored.Value)
+		if err != nil {
+			store.c.logger.Error("Failed to encode cache dataset", zap.String("dataset", dataset), zap.Error(err))
+		}
+
+		stored.Value, stored.Payload, stored.Encoding = nil, payload, DATASET_ENCODING_JSON
+		encoded[dataset] = stored
+	}
+
+	return encoded
+}
+
+// Get stored with its value decoded from its payload, false if it fails to decode. A dataset that fails to decode reads
+// as never hydrated until it's set again, so it's hydrated rather than served empty or merged onto
+func (store *serializingStore) decode(dataset string, stored StoredDataset) (StoredDataset, bool) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	if previous, ok := store.decoded[dataset]; ok && previous.Version == stored.Version {
+		return previous, previous.Value != nil
+	}
+
+	value, err := decodeDataset(store.c, dataset, stored)
+	if err != nil {
+		store.c.logger.Error("Failed to decode cache dataset", zap.String("dataset", dataset), zap.Uint64("version", stored.Version), zap.Error(err))
+	}
+
+	stored.Value = value
+	store.decoded[dataset] = stored
+
+	return stored, value != nil
+}
+
+// Get the payload of a dataset's value
+func encodeDataset(dataset string, value interface{}) ([]byte, error) {
+	codec, ok := datasetCodecs[dataset]
+	if !ok {
+		return nil, fmt.Errorf("No codec for dataset %s", dataset)
+	}
+
+	return codec.encode(value)
+}
+
+// Get the value of a stored dataset from its payload
+func decodeDataset(c *cache, dataset string, stored StoredDataset) (interface{}, error) {
+	codec, ok := datasetCodecs[dataset]
+	if !ok {
+		return nil, fmt.Errorf("No codec for dataset %s", dataset)
+	}
+
+	if stored.Encoding != DATASET_ENCODING_JSON {
+		return nil, fmt.Errorf("Unsupported encoding %q", stored.Encoding)
+	}
+
+	return codec.decode(c, stored.Payload)
+}
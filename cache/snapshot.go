@@ -0,0 +1,189 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/compression"
+	"go.uber.org/zap"
+)
+
+// Writes the cache contents to the snapshot file as zstd compressed JSON. The snapshot is written to a temporary file
+// first and renamed into place, so a crash mid-write never leaves a truncated snapshot behind
+func (c *cache) WriteSnapshot(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("Failed to create snapshot file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	// temporary files are created owner-only, snapshots get the usual permissions of a created file
+	if err := tmp.Chmod(0644); err != nil {
+		return fmt.Errorf("Failed to set snapshot file permissions: %w", err)
+	}
+
+	zstdWriter, err := compression.NewZstdWriter(tmp, c.zstdLevel)
+	if err != nil {
+		return fmt.Errorf("Failed to create snapshot encoder: %w", err)
+	}
+
+	if err := json.NewEncoder(zstdWriter).Encode(c.Export()); err != nil {
+		zstdWriter.Close()
+		return fmt.Errorf("Failed to encode snapshot: %w", err)
+	}
+
+	if err := zstdWriter.Close(); err != nil {
+		return fmt.Errorf("Failed to flush snapshot: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("Failed to write snapshot: %w", err)
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// Replaces the cache contents with a snapshot file, zstd, gzip, and uncompressed snapshots are all accepted
+func (c *cache) LoadSnapshot(path string) error {
+	export, err := readSnapshotFile(path)
+	if err != nil {
+		return err
+	}
+
+	return c.Import(export)
+}
+
+// Reads a snapshot file, see readSnapshot
+func readSnapshotFile(path string) (CacheExport, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return CacheExport{}, err
+	}
+	defer file.Close()
+
+	return readSnapshot(file)
+}
+
+// Decodes a snapshot, zstd, gzip, and uncompressed snapshots are all accepted
+func readSnapshot(r io.Reader) (CacheExport, error) {
+	reader, err := compression.NewReader(r)
+	if err != nil {
+		return CacheExport{}, fmt.Errorf("Failed to decompress snapshot: %w", err)
+	}
+	defer reader.Close()
+
+	var export CacheExport
+	if err := json.NewDecoder(reader).Decode(&export); err != nil {
+		return CacheExport{}, fmt.Errorf("Failed to decode snapshot: %w", err)
+	}
+
+	return export, nil
+}
+
+// Seeds the cache from a snapshot at startup. The local snapshot file is used when there is one, the replicated remote snapshot otherwise,
+// or whichever was hydrated most recently when preferring the freshest snapshot. A missing snapshot is expected on first boot
+func (c *cache) loadSnapshotOnStartup() {
+	if c.snapshotFile == "" {
+		return
+	}
+
+	snapshot, localErr := readSnapshotFile(c.snapshotFile)
+	source := c.snapshotFile
+	c.logSnapshotReadError(source, localErr)
+
+	if c.snapshotReplicator != nil && (localErr != nil || c.preferFreshestSnapshot) {
+		remote, remoteErr := c.downloadSnapshot()
+		c.logSnapshotReadError("remote replica", remoteErr)
+
+		if remoteErr == nil && (localErr != nil || remote.Metadata.HydratedAt.After(snapshot.Metadata.HydratedAt)) {
+			snapshot, localErr, source = remote, nil, "remote replica"
+		}
+	}
+
+	if localErr != nil {
+		return
+	}
+
+	if err := c.Import(snapshot); err != nil {
+		c.logger.Error("Failed to load cache snapshot", zap.String("source", source), zap.Error(err))
+		return
+	}
+
+	c.logger.Info("Loaded cache snapshot", zap.String("source", source), zap.Time("hydrated at", snapshot.Metadata.HydratedAt))
+}
+
+// Downloads and decodes the replicated snapshot
+func (c *cache) downloadSnapshot() (CacheExport, error) {
+	body, err := c.snapshotReplicator.Download(c.ctx)
+	if err != nil {
+		return CacheExport{}, err
+	}
+	defer body.Close()
+
+	return readSnapshot(body)
+}
+
+// Logs why a snapshot couldn't be read, a missing snapshot isn't an error
+func (c *cache) logSnapshotReadError(source string, err error) {
+	if errors.Is(err, os.ErrNotExist) {
+		c.logger.Info("No cache snapshot found", zap.String("source", source))
+	} else if err != nil {
+		c.logger.Error("Failed to read cache snapshot", zap.String("source", source), zap.Error(err))
+	}
+}
+
+// Persists the cache to the configured snapshot file after a successful sync, and replicates it in the background.
+// Failures are logged and otherwise ignored
+func (c *cache) persistSnapshot() {
+	if c.snapshotFile == "" {
+		return
+	}
+
+	if err := c.WriteSnapshot(c.snapshotFile); err != nil {
+		c.logger.Error("Failed to persist cache snapshot", zap.String("file", c.snapshotFile), zap.Error(err))
+		return
+	}
+
+	if c.snapshotReplicator != nil {
+		go c.replicateSnapshot()
+	}
+}
+
+// Uploads the snapshot file to the replica, uploads are serialized so an older snapshot never overwrites a newer one
+func (c *cache) replicateSnapshot() {
+	c.replicationLock.Lock()
+	defer c.replicationLock.Unlock()
+
+	if err := c.snapshotReplicator.Upload(c.ctx, c.snapshotFile); err != nil {
+		c.logger.Error("Failed to replicate cache snapshot", zap.Error(err))
+		return
+	}
+
+	c.logger.Debug("Replicated cache snapshot")
+}
+
+// Checks the snapshot file's directory is writable and the snapshot replica, if any, is reachable
+func (c *cache) ProbePersistence(ctx context.Context) error {
+	if c.snapshotFile == "" {
+		return fmt.Errorf("Snapshots are disabled")
+	}
+
+	probe, err := os.CreateTemp(filepath.Dir(c.snapshotFile), filepath.Base(c.snapshotFile)+".probe-*")
+	if err != nil {
+		return fmt.Errorf("Snapshot directory isn't writable: %w", err)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	if c.snapshotReplicator != nil {
+		return c.snapshotReplicator.Probe(ctx)
+	}
+
+	return nil
+}
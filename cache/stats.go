@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"slices"
+	"time"
+)
+
+// number of hydrations of size history kept per dataset
+const MAX_SIZE_HISTORY int = 48
+
+// Size of a dataset's JSON encoding at one hydration
+type DatasetSizeSample struct {
+	HydratedAt time.Time `json:"hydrated_at"`
+	Bytes      int       `json:"bytes"`
+}
+
+// Current size of a dataset, and how it has grown over recent hydrations
+type DatasetStats struct {
+	Bytes   int                 `json:"bytes"`
+	History []DatasetSizeSample `json:"history"`
+}
+
+// Size accounting of the cached datasets
+type CacheStats struct {
+	TotalBytes    int                     `json:"total_bytes"` // datasets plus optional data
+	OptionalBytes map[string]int          `json:"optional_bytes"`
+	MaxBytes      int                     `json:"max_bytes,omitempty"`
+	OverLimit     bool                    `json:"over_limit"`
+	Trimmed       []string                `json:"trimmed,omitempty"`
+	Datasets      map[string]DatasetStats `json:"datasets"`
+}
+
+// Records the size of a freshly hydrated dataset in its history and metrics
+func (c *cache) recordDatasetSize(dataset string, bytes int, hydratedAt time.Time) {
+	c.statsLock.Lock()
+	defer c.statsLock.Unlock()
+
+	history := append(c.sizeHistory[dataset], DatasetSizeSample{HydratedAt: hydratedAt, Bytes: bytes})
+	if len(history) > MAX_SIZE_HISTORY {
+		history = history[len(history)-MAX_SIZE_HISTORY:]
+	}
+	c.sizeHistory[dataset] = history
+
+	c.datasetBytesGauge.Set(float64(bytes), "dataset", dataset)
+}
+
+// Get the size accounting of the cached datasets
+func (c *cache) GetStats() CacheStats {
+	c.lock.RLock()
+	datasets := c.store.GetAll()
+	trimmed := c.trimmed
+	c.lock.RUnlock()
+
+	c.statsLock.Lock()
+	defer c.statsLock.Unlock()
+
+	stats := CacheStats{
+		TotalBytes:    footprint(datasets),
+		OptionalBytes: map[string]int{},
+		MaxBytes:      c.maxCacheBytes,
+		Trimmed:       trimmed,
+		Datasets:      map[string]DatasetStats{},
+	}
+	stats.OverLimit = c.maxCacheBytes > 0 && stats.TotalBytes > c.maxCacheBytes
+
+	for _, optional := range optionalDatasets {
+		stats.OptionalBytes[optional.name] = optional.bytes(datasets[optional.dataset])
+	}
+
+	// views are derived from repos, they're accounted for in the repos dataset
+	for dataset, stored := range datasets {
+		if dataset != DATASET_VIEWS {
+			stats.Datasets[dataset] = DatasetStats{Bytes: stored.Bytes, History: slices.Clone(c.sizeHistory[dataset])}
+		}
+	}
+
+	return stats
+}
@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"net/http"
+	"time"
+)
+
+// Readiness of a single cached dataset
+type DatasetStatus struct {
+	Ready              bool       `json:"ready"`
+	HydratedAt         *time.Time `json:"hydrated_at"` // nil until the dataset is first hydrated
+	Version            uint64     `json:"version"`     // store version the dataset was last set at
+	LastError          string     `json:"last_error,omitempty"`
+	LastUpstreamStatus int        `json:"last_upstream_status"`
+	HttpStatus         int        `json:"http_status"` // status to respond with when the dataset is unavailable
+}
+
+// Readiness of every cached dataset
+type Status struct {
+	Ready    bool                     `json:"ready"`
+	Syncing  bool                     `json:"syncing"` // a full hydration is in progress
+	Datasets map[string]DatasetStatus `json:"datasets"`
+}
+
+// Get the readiness of every cached dataset, along with the last error fetching it, and the HTTP status it maps to
+func (c *cache) Status() Status {
+	defer c.lock.RUnlock()
+	c.lock.RLock()
+
+	status := Status{Ready: true, Syncing: c.IsSyncing(), Datasets: map[string]DatasetStatus{}}
+
+	datasets := append([]string{DATASET_ORGANIZATION, DATASET_MEMBERS, DATASET_REPOS, DATASET_VIEWS}, c.optionalDatasets()...)
+
+	for _, dataset := range datasets {
+		stored, ready := c.store.Get(dataset)
+
+		datasetStatus := DatasetStatus{Ready: ready, Version: stored.Version}
+		if ready {
+			datasetStatus.HydratedAt = &stored.HydratedAt
+		}
+
+		// datasets that weren't attempted in the last sync (it failed on an earlier dataset) take the overall outcome
+		if report, ok := c.lastSyncReport.Datasets[dataset]; ok {
+			datasetStatus.LastError = report.Error
+			datasetStatus.LastUpstreamStatus = report.Status
+		} else {
+			datasetStatus.LastError = c.lastSyncReport.Error
+			datasetStatus.LastUpstreamStatus = c.lastSyncReport.Status
+		}
+
+		datasetStatus.HttpStatus = MapUpstreamStatus(datasetStatus.LastUpstreamStatus)
+
+		// optional datasets are reported, but don't hold up readiness
+		if dataset != DATASET_CONTRIBUTORS && dataset != DATASET_RELEASES && dataset != DATASET_COMMIT_ACTIVITY && dataset != DATASET_TEAMS && dataset != DATASET_ISSUE_COUNTS && dataset != DATASET_READMES && dataset != DATASET_LANGUAGES && dataset != DATASET_EXTRA_ENDPOINTS {
+			status.Ready = status.Ready && ready
+		}
+		status.Datasets[dataset] = datasetStatus
+	}
+
+	return status
+}
+
+// Maps the status of a failed upstream GitHub request to the status the service should respond with
+func MapUpstreamStatus(upstreamStatus int) int {
+	switch {
+	case upstreamStatus == 0 || upstreamStatus == http.StatusOK:
+		return http.StatusOK
+	case upstreamStatus == http.StatusNotModified:
+		// the sync was skipped, the cached data is still current
+		return http.StatusOK
+	case upstreamStatus == http.StatusTooManyRequests || upstreamStatus == http.StatusForbidden:
+		// rate limited, will recover once the quota resets
+		return http.StatusServiceUnavailable
+	case upstreamStatus == http.StatusRequestTimeout || upstreamStatus == http.StatusGatewayTimeout:
+		return http.StatusGatewayTimeout
+	case upstreamStatus == http.StatusInternalServerError:
+		// also used for failures processing responses within the service
+		return http.StatusInternalServerError
+	default:
+		// any other upstream failure means GitHub didn't give us usable data
+		return http.StatusBadGateway
+	}
+}
+
+// Get the optional datasets that are configured to be hydrated
+func (c *cache) optionalDatasets() []string {
+	var datasets []string
+	if c.hydrateContributors {
+		datasets = append(datasets, DATASET_CONTRIBUTORS)
+	}
+	if len(c.releaseRepos) > 0 {
+		datasets = append(datasets, DATASET_RELEASES)
+	}
+	if c.hydrateCommitActivity {
+		datasets = append(datasets, DATASET_COMMIT_ACTIVITY)
+	}
+	if c.hydrateTeams {
+		datasets = append(datasets, DATASET_TEAMS)
+	}
+	if c.hydrateIssueCounts {
+		datasets = append(datasets, DATASET_ISSUE_COUNTS)
+	}
+	if c.hydrateReadmes {
+		datasets = append(datasets, DATASET_READMES)
+	}
+	if c.hydrateLanguages {
+		datasets = append(datasets, DATASET_LANGUAGES)
+	}
+	if len(c.extraEndpoints) > 0 {
+		datasets = append(datasets, DATASET_EXTRA_ENDPOINTS)
+	}
+
+	return datasets
+}
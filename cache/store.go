@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"maps"
+	"sync"
+	"time"
+)
+
+// A cached dataset along with its metadata
+type StoredDataset struct {
+	Value       interface{} // the cache's representation of the dataset, only kept by stores that hold values
+	Payload     []byte      // the dataset encoded as Encoding, set for stores that don't hold values
+	Encoding    string
+	ETag        string
+	Bytes       int // size of the dataset's JSON encoding
+	HydratedAt  time.Time
+	Approximate bool   // seeded from an archive instead of the GitHub API
+	Version     uint64 // assigned by the store, increases every time the dataset is set
+}
+
+// Holds the cached datasets, so the cache can be backed by something other than memory (e.g. Redis, disk) without
+// touching handlers. Every Set bumps the store's version. Stores that hold values return every dataset's Value as it was
+// set, others only need to keep its Payload and Encoding (along with the metadata), the cache encodes and decodes values for them
+type Store interface {
+	Get(dataset string) (StoredDataset, bool)
+	GetAll() map[string]StoredDataset
+	Set(datasets map[string]StoredDataset) uint64
+	Replace(datasets map[string]StoredDataset) uint64
+	Version() uint64
+	HoldsValues() bool
+}
+
+// Default Store, keeps every dataset in memory. Writes swap in a new copy of the datasets, so readers never see a partially applied Set
+type memoryStore struct {
+	lock     sync.RWMutex
+	datasets map[string]StoredDataset
+	version  uint64
+}
+
+// Get new in-memory Store
+func NewMemoryStore() Store {
+	return &memoryStore{datasets: map[string]StoredDataset{}}
+}
+
+// Get a single dataset, false if it has never been set
+func (store *memoryStore) Get(dataset string) (StoredDataset, bool) {
+	defer store.lock.RUnlock()
+	store.lock.RLock()
+
+	stored, ok := store.datasets[dataset]
+	return stored, ok
+}
+
+// Get every dataset, consistent with each other. The map is shared, callers must not modify it
+func (store *memoryStore) GetAll() map[string]StoredDataset {
+	defer store.lock.RUnlock()
+	store.lock.RLock()
+
+	return store.datasets
+}
+
+// Sets datasets in a single swap, leaving the others as they are. Returns the new version of the store
+func (store *memoryStore) Set(datasets map[string]StoredDataset) uint64 {
+	defer store.lock.Unlock()
+	store.lock.Lock()
+
+	store.version++
+
+	updated := maps.Clone(store.datasets)
+	for dataset, stored := range datasets {
+		stored.Version = store.version
+		updated[dataset] = stored
+	}
+	store.datasets = updated
+
+	return store.version
+}
+
+// Replaces every dataset in a single swap, e.g. when importing a snapshot. Returns the new version of the store
+func (store *memoryStore) Replace(datasets map[string]StoredDataset) uint64 {
+	defer store.lock.Unlock()
+	store.lock.Lock()
+
+	store.version++
+
+	replaced := make(map[string]StoredDataset, len(datasets))
+	for dataset, stored := range datasets {
+		stored.Version = store.version
+		replaced[dataset] = stored
+	}
+	store.datasets = replaced
+
+	return store.version
+}
+
+// Get the version of the store, 0 until anything is set
+func (store *memoryStore) Version() uint64 {
+	defer store.lock.RUnlock()
+	store.lock.RLock()
+
+	return store.version
+}
+
+// Values are kept as they are, so datasets are never encoded
+func (store *memoryStore) HoldsValues() bool {
+	return true
+}
+
+// Get the value of a dataset from the store, the zero value if it has never been set
+func loadDataset[T any](store Store, dataset string) T {
+	stored, _ := store.Get(dataset)
+	value, _ := stored.Value.(T)
+	return value
+}
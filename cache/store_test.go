@@ -0,0 +1,122 @@
+package cache
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/internal/testutil"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/metrics"
+	"go.uber.org/zap"
+)
+
+// Store that only keeps what a Redis or disk backend would, dropping values
+type bytesStore struct {
+	Store
+}
+
+func (store bytesStore) Set(datasets map[string]StoredDataset) uint64 {
+	return store.Store.Set(dropValues(datasets))
+}
+
+func (store bytesStore) Replace(datasets map[string]StoredDataset) uint64 {
+	return store.Store.Replace(dropValues(datasets))
+}
+
+func (store bytesStore) HoldsValues() bool {
+	return false
+}
+
+func dropValues(datasets map[string]StoredDataset) map[string]StoredDataset {
+	dropped := make(map[string]StoredDataset, len(datasets))
+	for dataset, stored := range datasets {
+		stored.Value = nil
+		dropped[dataset] = stored
+	}
+	return dropped
+}
+
+func TestStoreWithoutValues(t *testing.T) {
+	cfg, err := testutil.NewConfiguration("--hydrate-contributors", "--release-repos=*", "--hydrate-commit-activity", "--hydrate-teams",
+		"--hydrate-issue-counts", "--hydrate-readmes", "--hydrate-languages", "--extra-endpoints=/orgs/Netflix/events", "--extra-views=size,created")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := testutil.NewFakeGithubClient(10, 50)
+
+	backend := bytesStore{NewMemoryStore()}
+	inMemory := NewCache(cfg, client, context.Background(), zap.NewNop(), metrics.NewRegistry()).(*cache)
+	serialized := NewCacheWithStore(cfg, client, context.Background(), zap.NewNop(), metrics.NewRegistry(), backend).(*cache)
+
+	for _, c := range []*cache{inMemory, serialized} {
+		if _, err := c.HydrateCache(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		optional := map[string]datasetFetcher{
+			DATASET_CONTRIBUTORS:    c.fetchContributors,
+			DATASET_RELEASES:        c.fetchReleases,
+			DATASET_COMMIT_ACTIVITY: c.fetchCommitActivity,
+			DATASET_TEAMS:           c.fetchTeams,
+			DATASET_ISSUE_COUNTS:    c.fetchIssueCounts,
+			DATASET_READMES:         c.fetchReadmes,
+			DATASET_LANGUAGES:       c.fetchLanguages,
+			DATASET_EXTRA_ENDPOINTS: c.fetchExtraEndpoints,
+		}
+		for dataset, fetch := range optional {
+			if err := c.syncDataset(dataset, fetch); err != nil {
+				t.Fatalf("Failed to hydrate %s: %v", dataset, err)
+			}
+		}
+	}
+
+	for dataset, stored := range backend.GetAll() {
+		if stored.Value != nil || len(stored.Payload) == 0 || stored.Encoding != DATASET_ENCODING_JSON {
+			t.Errorf("%s reached the backend as value %T, %d payload bytes encoded as %q", dataset, stored.Value, len(stored.Payload), stored.Encoding)
+		}
+	}
+
+	want, got := inMemory.store.GetAll(), serialized.store.GetAll()
+	if len(want) != len(datasetCodecs) || len(got) != len(want) {
+		t.Fatalf("Expected %d datasets, got %d in memory and %d decoded", len(datasetCodecs), len(want), len(got))
+	}
+
+	for dataset, stored := range want {
+		if !reflect.DeepEqual(got[dataset].Value, stored.Value) {
+			t.Errorf("%s decoded as %+v, want %+v", dataset, got[dataset].Value, stored.Value)
+		}
+		if got[dataset].ETag != stored.ETag {
+			t.Errorf("%s ETag = %q, want %q", dataset, got[dataset].ETag, stored.ETag)
+		}
+	}
+
+	// decoded values are reused until the dataset is set again
+	before, _ := serialized.store.Get(DATASET_REPOS)
+	after, _ := serialized.store.Get(DATASET_REPOS)
+	if reflect.ValueOf(before.Value.(reposData).netflixOrganizationReposByName).Pointer() != reflect.ValueOf(after.Value.(reposData).netflixOrganizationReposByName).Pointer() {
+		t.Error("Expected the repos to be decoded once per version")
+	}
+}
+
+func TestStoreWithoutValuesUnsupportedEncoding(t *testing.T) {
+	cfg, err := testutil.NewConfiguration()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backend := NewMemoryStore()
+	c := NewCacheWithStore(cfg, testutil.NewFakeGithubClient(1, 1), context.Background(), zap.NewNop(), metrics.NewRegistry(), bytesStore{backend}).(*cache)
+
+	backend.Set(map[string]StoredDataset{DATASET_MEMBERS: {Payload: []byte(`[]`), Encoding: "msgpack/v9"}})
+
+	// so it's hydrated again rather than served empty
+	if stored, ok := c.store.Get(DATASET_MEMBERS); ok {
+		t.Errorf("Expected a dataset of an unknown encoding to read as never set, got %+v", stored)
+	}
+	if _, ok := c.store.GetAll()[DATASET_MEMBERS]; ok {
+		t.Error("Expected a dataset of an unknown encoding to be left out of every dataset")
+	}
+	if c.IsHydrated(DATASET_MEMBERS) {
+		t.Error("Expected a dataset of an unknown encoding not to be hydrated")
+	}
+}
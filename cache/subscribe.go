@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// updates buffered per subscriber, updates past it are dropped for that subscriber
+const SUBSCRIBER_BUFFER_SIZE int = 16
+
+// Datasets set in the store together, by a hydration, sync, or import
+type DatasetsUpdated struct {
+	Datasets  []string  `json:"datasets"`
+	Version   uint64    `json:"version"` // version of the store after the update
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Channels of everything subscribed to dataset updates
+type subscribers struct {
+	lock     sync.Mutex
+	channels map[chan DatasetsUpdated]struct{}
+}
+
+// Subscribes to dataset updates, published once they're visible to readers. Call unsubscribe once done, which closes updates
+func (c *cache) Subscribe() (updates <-chan DatasetsUpdated, unsubscribe func()) {
+	channel := make(chan DatasetsUpdated, SUBSCRIBER_BUFFER_SIZE)
+
+	c.subscribers.lock.Lock()
+	if c.subscribers.channels == nil {
+		c.subscribers.channels = map[chan DatasetsUpdated]struct{}{}
+	}
+	c.subscribers.channels[channel] = struct{}{}
+	c.subscribers.lock.Unlock()
+
+	var once sync.Once
+	return channel, func() {
+		once.Do(func() {
+			c.subscribers.lock.Lock()
+			delete(c.subscribers.channels, channel)
+			c.subscribers.lock.Unlock()
+
+			close(channel)
+		})
+	}
+}
+
+// Publishes an update of datasets to every subscriber, never blocks on a slow subscriber
+func (c *cache) publishUpdate(datasets map[string]StoredDataset, version uint64) {
+	update := DatasetsUpdated{Version: version, UpdatedAt: time.Now().UTC()}
+	for dataset := range datasets {
+		update.Datasets = append(update.Datasets, dataset)
+	}
+	slices.Sort(update.Datasets)
+
+	c.subscribers.lock.Lock()
+	defer c.subscribers.lock.Unlock()
+
+	for channel := range c.subscribers.channels {
+		select {
+		case channel <- update:
+		default:
+			c.logger.Warn("Subscriber isn't keeping up with dataset updates, dropping update", zap.Uint64("version", version))
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	githubclient "github.com/adamjeanlaurent/github-api-read-cache-service/github-client"
+	"go.uber.org/zap"
+)
+
+// max teams whose repos are fetched at once
+const TEAMS_CONCURRENCY int = 8
+
+// Fetches the org's teams and the repos of each. Secret teams are only visible to org members, so they're left out rather than
+// served publicly. Teams whose repos couldn't be fetched keep their previously cached repos, fails only if every team failed
+func (c *cache) fetchTeams(ctx context.Context, report *SyncReport) (datasetUpdate, int, error) {
+	teamsResp, err := c.githubClient.GetNetflixTeams(ctx)
+	teams, statusCode := teamsResp.Body, teamsResp.StatusCode
+	if err != nil {
+		// the token can't list the org's teams, e.g. it isn't an org member's or lacks the read:org scope
+		if statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden || statusCode == http.StatusNotFound {
+			c.logger.Warn("Token can't list the org's teams, teams aren't cached", zap.Int("Http status code", statusCode))
+		}
+
+		report.recordDataset(DATASET_TEAMS, statusCode, 0, err)
+		return datasetUpdate{}, statusCode, fmt.Errorf("Failed to fetch netflix organization teams: %s", err.Error())
+	}
+
+	visibleTeams := make([]githubclient.JsonObject, 0, len(teams))
+	slugs := make([]string, 0, len(teams))
+	for _, team := range teams {
+		slug, ok := team["slug"].(string)
+		if !ok || team["privacy"] == "secret" {
+			continue
+		}
+
+		visibleTeams = append(visibleTeams, team)
+		slugs = append(slugs, slug)
+	}
+
+	previous := loadDataset[teamsData](c.store, DATASET_TEAMS).netflixTeamRepos
+
+	teamRepos, statusCode, err := fetchPerRepo(ctx, c, DATASET_TEAMS, slugs, TEAMS_CONCURRENCY, previous,
+		func(ctx context.Context, team string) ([]githubclient.JsonObject, bool, error, int) {
+			resp, err := c.githubClient.GetNetflixTeamRepos(ctx, team)
+
+			// teams without repos are cached as an empty list instead of null
+			repos := resp.Body
+			if err == nil && repos == nil {
+				repos = []githubclient.JsonObject{}
+			}
+
+			return repos, true, err, resp.StatusCode
+		})
+
+	report.recordDataset(DATASET_TEAMS, statusCode, len(visibleTeams), err)
+	if err != nil {
+		return datasetUpdate{}, statusCode, fmt.Errorf("Failed to fetch team repos: %w", err)
+	}
+
+	return newTeamsUpdate(visibleTeams, teamRepos), http.StatusOK, nil
+}
+
+// Get an update of the teams dataset, fingerprinting the teams along with their repos so the ETag changes with either
+func newTeamsUpdate(teams []githubclient.JsonObject, teamRepos map[string][]githubclient.JsonObject) datasetUpdate {
+	payload := map[string]interface{}{"teams": teams, "team_repos": teamRepos}
+
+	return newDatasetUpdate(DATASET_TEAMS, payload, teamsData{
+		netflixTeams:     teams,
+		netflixTeamRepos: teamRepos,
+	})
+}
+
+// Get the Netflix Organization Teams from Cache, empty unless teams are hydrated
+func (c *cache) GetNetflixTeams() []githubclient.JsonObject {
+	return loadDataset[teamsData](c.store, DATASET_TEAMS).netflixTeams
+}
+
+// Get the repos of a single Netflix Organization Team by slug from Cache, false if the team isn't cached
+func (c *cache) GetNetflixTeamRepos(team string) ([]githubclient.JsonObject, bool) {
+	repos, ok := loadDataset[teamsData](c.store, DATASET_TEAMS).netflixTeamRepos[strings.ToLower(team)]
+	return repos, ok
+}
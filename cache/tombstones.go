@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	githubclient "github.com/adamjeanlaurent/github-api-read-cache-service/github-client"
+)
+
+// Repo that disappeared from the org between syncs, e.g. deleted, transferred, or made private
+type RemovedRepo struct {
+	Name      string    `json:"name"`
+	RemovedAt time.Time `json:"removed_at"` // when the sync that noticed it was missing ran
+}
+
+// Get the repos removed from the org as of repos, given the repos and removed repos of the previous sync. Repos listed again
+// are no longer removed, and removals older than retention are forgotten. Most recently removed first
+func trackRemovedRepos(previous reposData, repos []githubclient.JsonObject, now time.Time, retention time.Duration) []RemovedRepo {
+	current := indexReposByName(repos)
+	var removed []RemovedRepo
+
+	for _, repo := range previous.netflixOrganizationRepos {
+		name, _ := repo["name"].(string)
+		if _, ok := current[strings.ToLower(name)]; !ok && name != "" {
+			removed = append(removed, RemovedRepo{Name: name, RemovedAt: now})
+		}
+	}
+
+	for _, tombstone := range previous.removedRepos {
+		if _, ok := current[strings.ToLower(tombstone.Name)]; !ok && now.Sub(tombstone.RemovedAt) < retention {
+			removed = append(removed, tombstone)
+		}
+	}
+
+	// newest first, ties by name so the view is stable
+	slices.SortFunc(removed, func(a RemovedRepo, b RemovedRepo) int {
+		if cmp := b.RemovedAt.Compare(a.RemovedAt); cmp != 0 {
+			return cmp
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return removed
+}
+
+// Computes the view of removed repos, as [repo, removed at] tuples
+func computeRemovedReposView(removed []RemovedRepo) []Tuple {
+	view := make([]Tuple, 0, len(removed))
+
+	for _, tombstone := range removed {
+		view = append(view, Tuple{fmt.Sprintf("Netflix/%s", tombstone.Name), tombstone.RemovedAt.Format(time.RFC3339)})
+	}
+
+	return view
+}
+
+// Get the repos removed from the org, most recently removed first, as [repo, removed at] tuples
+func (snapshot Snapshot) RemovedNetflixRepos() []Tuple {
+	return snapshotDataset[reposData](snapshot, DATASET_REPOS).viewRemovedRepos
+}
@@ -0,0 +1,50 @@
+package cache
+
+import (
+	"net/http"
+	"time"
+
+	githubclient "github.com/adamjeanlaurent/github-api-read-cache-service/github-client"
+	"go.uber.org/zap"
+)
+
+// Re-fetches dataset on its own schedule like syncDataset, unless skipping unchanged syncs and the org has no new events since
+// the dataset's last sync. The events are polled conditionally, which doesn't cost quota when there are none, and a dataset is
+// still synced once it's older than skipUnchangedMaxAge, since not every change is a public event
+func (c *cache) syncUnlessUnchanged(dataset string, fetch datasetFetcher) {
+	if !c.skipUnchangedSyncs {
+		c.syncDataset(dataset, fetch)
+		return
+	}
+
+	c.statsLock.Lock()
+	previousETag := c.eventsETags[dataset]
+	c.statsLock.Unlock()
+
+	report := newSyncReport()
+	ctx, account := githubclient.CountRequests(c.ctx)
+
+	// polled before syncing, so events raised while the dataset is fetched are picked up by the next sync
+	resp, err := c.githubClient.GetNetflixOrgEventsETag(ctx, previousETag)
+	if err != nil {
+		c.logger.Warn("Failed to poll netflix organization events, syncing anyway", zap.String("dataset", dataset), zap.Error(err), zap.Int("Http status code", resp.StatusCode))
+	}
+
+	stored, hydrated := c.store.Get(dataset)
+	if err == nil && previousETag != "" && resp.StatusCode == http.StatusNotModified && hydrated && !stored.Approximate && time.Since(stored.HydratedAt) < c.skipUnchangedMaxAge {
+		report.Datasets[dataset] = DatasetSyncReport{Status: http.StatusNotModified, Items: c.GetLastSyncReport().Datasets[dataset].Items}
+		report.finish(http.StatusNotModified, nil)
+		report.Upstream = account.Usage()
+		c.observeUpstreamUsage(dataset, report)
+		c.applySync(report, true)
+
+		c.logger.Info("Skipped re-hydrating cache dataset, no new netflix organization events", zap.String("dataset", dataset))
+		return
+	}
+
+	if c.syncDataset(dataset, fetch) == nil && err == nil {
+		c.statsLock.Lock()
+		c.eventsETags[dataset] = resp.Body
+		c.statsLock.Unlock()
+	}
+}
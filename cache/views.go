@@ -0,0 +1,192 @@
+package cache
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	githubclient "github.com/adamjeanlaurent/github-api-read-cache-service/github-client"
+)
+
+// Names of the extra repo views, only computed when enabled with --extra-views
+const (
+	VIEW_SIZE     string = "size"
+	VIEW_WATCHERS string = "watchers"
+	VIEW_CREATED  string = "created" // newest created first
+)
+
+// Types of the values views rank repos by
+const (
+	VALUE_TYPE_COUNT     string = "count"
+	VALUE_TYPE_TIMESTAMP string = "timestamp"
+)
+
+// Ranking of the repos by a single value, computed every time repos are hydrated
+type ViewDefinition struct {
+	Name      string
+	Field     string // repo field the value is extracted from, reported when a repo is missing it
+	ValueType string
+	extract   func(repo githubclient.JsonObject) (interface{}, bool)
+	sort      func(tuples []Tuple) // ranks entries from the top of the view to the bottom
+}
+
+// Get a view ranking repos by a numeric field, highest first
+func countView(name string, field string) ViewDefinition {
+	return ViewDefinition{
+		Name:      name,
+		Field:     field,
+		ValueType: VALUE_TYPE_COUNT,
+		extract: func(repo githubclient.JsonObject) (interface{}, bool) {
+			count, ok := repo[field].(float64)
+			return count, ok
+		},
+		sort: sortBottomViewByCount,
+	}
+}
+
+// Get a view ranking repos by an RFC 3339 timestamp field, most recent first
+func timestampView(name string, field string) ViewDefinition {
+	return ViewDefinition{
+		Name:      name,
+		Field:     field,
+		ValueType: VALUE_TYPE_TIMESTAMP,
+		extract: func(repo githubclient.JsonObject) (interface{}, bool) {
+			timestamp, ok := repo[field].(string)
+			return timestamp, ok
+		},
+		sort: sortBottomViewByTimestamp,
+	}
+}
+
+// Views that are always computed, in the order they're listed
+var repoViews = []ViewDefinition{
+	countView(VIEW_BOTTOM_FORKS, "forks_count"),
+	timestampView(VIEW_BOTTOM_LAST_UPDATED, "updated_at"),
+	countView(VIEW_BOTTOM_OPEN_ISSUES, "open_issues_count"),
+	countView(VIEW_BOTTOM_STARS, "stargazers_count"),
+}
+
+// Views that can be enabled with --extra-views, by name
+var extraRepoViews = map[string]ViewDefinition{
+	VIEW_SIZE:     countView(VIEW_SIZE, "size"),
+	VIEW_WATCHERS: countView(VIEW_WATCHERS, "watchers_count"),
+	VIEW_CREATED:  timestampView(VIEW_CREATED, "created_at"),
+}
+
+// Get the always computed views followed by the enabled extra views, unknown names are skipped
+func EnabledViews(extraViews []string) []ViewDefinition {
+	views := slices.Clone(repoViews)
+
+	for _, name := range extraViews {
+		if view, ok := extraRepoViews[name]; ok {
+			views = append(views, view)
+		}
+	}
+
+	return views
+}
+
+// Computes every view of repos, sorted from top to bottom, by view name
+func computeBottomViews(netflixOrgRepos []githubclient.JsonObject, views []ViewDefinition) (viewsData, error) {
+	computed := make(map[string][]Tuple, len(views))
+
+	for _, view := range views {
+		tuples := make([]Tuple, 0, len(netflixOrgRepos))
+
+		for _, repo := range netflixOrgRepos {
+			repoName, ok := repo["name"].(string)
+			if !ok {
+				return viewsData{}, fmt.Errorf("Missing repository name")
+			}
+
+			value, ok := view.extract(repo)
+			if !ok {
+				return viewsData{}, fmt.Errorf("Missing %s for repository %s", view.Field, repoName)
+			}
+
+			tuples = append(tuples, Tuple{"Netflix/" + repoName, value})
+		}
+
+		view.sort(tuples)
+		computed[view.Name] = tuples
+	}
+
+	return viewsData{views: computed}, nil
+}
+
+// Get the fields a repo is missing, or has with the wrong type, that views need
+func MissingRepoFields(repo githubclient.JsonObject, views []ViewDefinition) []string {
+	var missing []string
+
+	if _, ok := repo["name"].(string); !ok {
+		missing = append(missing, "name")
+	}
+
+	for _, view := range views {
+		if _, ok := view.extract(repo); !ok && !slices.Contains(missing, view.Field) {
+			missing = append(missing, view.Field)
+		}
+	}
+
+	return missing
+}
+
+// Splits repos into those every view can rank and those missing fields views need
+func partitionRankableRepos(netflixOrgRepos []githubclient.JsonObject, views []ViewDefinition) ([]githubclient.JsonObject, []SkippedRecord) {
+	rankable := make([]githubclient.JsonObject, 0, len(netflixOrgRepos))
+	var skipped []SkippedRecord
+
+	for _, repo := range netflixOrgRepos {
+		missing := MissingRepoFields(repo, views)
+		if len(missing) == 0 {
+			rankable = append(rankable, repo)
+			continue
+		}
+
+		// repos without a name are identified by their id
+		record, ok := repo["name"].(string)
+		if !ok {
+			record = fmt.Sprintf("id %v", repo["id"])
+		}
+
+		skipped = append(skipped, SkippedRecord{Record: record, MissingFields: missing})
+	}
+
+	return rankable, skipped
+}
+
+// Get the timestamps of repos that timestamp views can't parse as RFC 3339, those repos are ranked at the bottom of the view
+func unparsableRepoTimestamps(netflixOrgRepos []githubclient.JsonObject, views []ViewDefinition) []UnparsableRecord {
+	var unparsable []UnparsableRecord
+
+	for _, view := range views {
+		if view.ValueType != VALUE_TYPE_TIMESTAMP {
+			continue
+		}
+
+		for _, repo := range netflixOrgRepos {
+			// repos missing the field are reported as skipped
+			timestamp, ok := repo[view.Field].(string)
+			if !ok {
+				continue
+			}
+
+			if _, err := time.Parse(time.RFC3339, timestamp); err != nil {
+				name, _ := repo["name"].(string)
+				unparsable = append(unparsable, UnparsableRecord{Record: name, Field: view.Field, Value: timestamp})
+			}
+		}
+	}
+
+	return unparsable
+}
+
+// Get the definitions of the views computed by the cache, in the order they're listed
+func (c *cache) GetViewDefinitions() []ViewDefinition {
+	return c.views
+}
+
+// Get a view sorted from top to bottom, false if the view isn't computed by the cache
+func (c *cache) GetView(view string) ([]Tuple, bool) {
+	return c.Snapshot().View(view)
+}
@@ -0,0 +1,25 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/internal/testutil"
+)
+
+func BenchmarkComputeBottomViews(b *testing.B) {
+	for _, repoCount := range []int{100, 1000, 10000} {
+		b.Run(fmt.Sprintf("repos-%d", repoCount), func(b *testing.B) {
+			repos := testutil.GenerateRepos(repoCount)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := computeBottomViews(repos, repoViews); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	netUrl "net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DEFAULT_MAX_RETRIES   int           = 3
+	DEFAULT_RETRY_BACKOFF time.Duration = 200 * time.Millisecond // doubled after every retry
+	MAX_RETRY_AFTER       time.Duration = 30 * time.Second       // longer Retry-After are returned as errors instead of waited out
+	MAX_ERROR_BODY_BYTES  int64         = 4 * 1024
+)
+
+// A response from the service with a non 2xx status code, or 202 while the cache is syncing
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (err *StatusError) Error() string {
+	return fmt.Sprintf("Service responded with status code %d: %s", err.StatusCode, err.Body)
+}
+
+// A single entry of a view, the repo's full name and the value it's ranked by
+type ViewEntry struct {
+	Repo  string
+	Value interface{}
+}
+
+// Views are served as [repo, value] pairs
+func (entry *ViewEntry) UnmarshalJSON(data []byte) error {
+	var pair [2]interface{}
+	if err := json.Unmarshal(data, &pair); err != nil {
+		return err
+	}
+
+	repo, ok := pair[0].(string)
+	if !ok {
+		return fmt.Errorf("View entry %s doesn't start with a repo name", data)
+	}
+
+	entry.Repo = repo
+	entry.Value = pair[1]
+
+	return nil
+}
+
+// Typed client for the service's cached endpoints. Requests that fail with a network error, 429, or 5xx, or are answered
+// 202 while the cache is syncing, are retried with backoff, honoring Retry-After, until the context is done
+type Client interface {
+	GetOrg(ctx context.Context) (JsonObject, error)
+	GetRepos(ctx context.Context) ([]JsonObject, error)
+	GetBottomN(ctx context.Context, view string, n int) ([]ViewEntry, error)
+	CacheStatus(ctx context.Context) (CacheStatus, error)
+}
+
+type client struct {
+	baseUrl      string
+	httpClient   *http.Client
+	maxRetries   int
+	retryBackoff time.Duration
+	headers      http.Header // sent with every request, e.g. credentials
+}
+
+// Optional setting of a Client
+type Option func(c *client)
+
+// Authenticates every request with token as a bearer token, either a JWT or an API key of a service run with --auth-mode
+func WithBearerToken(token string) Option {
+	return func(c *client) {
+		c.headers.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// Authenticates every request with key in the X-API-Key header, for a service run with --auth-mode=static
+func WithApiKey(key string) Option {
+	return func(c *client) {
+		c.headers.Set("X-API-Key", key)
+	}
+}
+
+// Get new Client for the service at baseUrl (e.g. http://localhost:7101), httpClient may be nil to use http.DefaultClient
+func NewClient(baseUrl string, httpClient *http.Client, options ...Option) Client {
+	return NewClientWithRetries(baseUrl, httpClient, DEFAULT_MAX_RETRIES, DEFAULT_RETRY_BACKOFF, options...)
+}
+
+// Get new Client retrying failed requests up to maxRetries times, waiting retryBackoff before the first retry
+func NewClientWithRetries(baseUrl string, httpClient *http.Client, maxRetries int, retryBackoff time.Duration, options ...Option) Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	c := &client{
+		baseUrl:      strings.TrimSuffix(baseUrl, "/"),
+		httpClient:   httpClient,
+		maxRetries:   maxRetries,
+		retryBackoff: retryBackoff,
+		headers:      http.Header{},
+	}
+
+	for _, option := range options {
+		option(c)
+	}
+
+	return c
+}
+
+// Get the cached Netflix organization
+func (c *client) GetOrg(ctx context.Context) (JsonObject, error) {
+	var org JsonObject
+	return org, c.getJson(ctx, "/orgs/Netflix", &org)
+}
+
+// Get every cached Netflix organization repo
+func (c *client) GetRepos(ctx context.Context) ([]JsonObject, error) {
+	var repos []JsonObject
+	return repos, c.getJson(ctx, "/orgs/Netflix/repos", &repos)
+}
+
+// Get the bottom n repos of view, e.g. stars or forks
+func (c *client) GetBottomN(ctx context.Context, view string, n int) ([]ViewEntry, error) {
+	var entries []ViewEntry
+	return entries, c.getJson(ctx, fmt.Sprintf("/view/bottom/%d/%s", n, netUrl.PathEscape(view)), &entries)
+}
+
+// Get the readiness, last sync report, size, token health, and maintenance mode of the cache
+func (c *client) CacheStatus(ctx context.Context) (CacheStatus, error) {
+	var status CacheStatus
+	return status, c.getJson(ctx, "/cachestatus", &status)
+}
+
+// GETs path and decodes the JSON response into v, retrying failed requests
+func (c *client) getJson(ctx context.Context, path string, v interface{}) error {
+	backoff := c.retryBackoff
+
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := c.tryGetJson(ctx, path, v)
+		if err == nil {
+			return nil
+		}
+
+		if retryAfter < 0 || attempt >= c.maxRetries || ctx.Err() != nil {
+			return err
+		}
+
+		wait := backoff
+		if retryAfter > 0 {
+			wait = retryAfter
+		}
+		backoff *= 2
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		}
+	}
+}
+
+// Makes a single attempt at GETting path. On failure, returns how long the service asked to wait before retrying (0 to
+// back off as usual), or a negative duration when the request shouldn't be retried
+func (c *client) tryGetJson(ctx context.Context, path string, v interface{}) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseUrl+path, nil)
+	if err != nil {
+		return -1, fmt.Errorf("Failed to create request: %w", err)
+	}
+	for name, values := range c.headers {
+		req.Header[name] = values
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to request %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	// a 202 means the cache is syncing and the data isn't there yet, so it's retried like a 503 rather than decoded
+	if resp.StatusCode < 200 || resp.StatusCode > 299 || resp.StatusCode == http.StatusAccepted {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, MAX_ERROR_BODY_BYTES))
+		statusErr := &StatusError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+
+		if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusAccepted && resp.StatusCode < 500 {
+			return -1, statusErr
+		}
+
+		retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
+		if retryAfter > MAX_RETRY_AFTER {
+			return -1, statusErr
+		}
+
+		return retryAfter, statusErr
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return -1, fmt.Errorf("Failed to decode response of %s: %w", path, err)
+	}
+
+	return 0, nil
+}
+
+// Get the wait of a Retry-After header given in seconds, 0 if it's missing or isn't a number of seconds
+func parseRetryAfter(header string) time.Duration {
+	seconds, err := strconv.Atoi(header)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,77 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Serves the responses in order, the last one to every request after them
+func sequenceServer(t *testing.T, responses ...func(w http.ResponseWriter)) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := int(requests.Add(1)) - 1
+		responses[min(i, len(responses)-1)](w)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &requests
+}
+
+func syncing(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", "0")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	w.Write([]byte(`{"status":"syncing"}`))
+}
+
+func org(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"login":"Netflix"}`))
+}
+
+func TestAcceptedIsRetried(t *testing.T) {
+	server, requests := sequenceServer(t, syncing, syncing, org)
+
+	org, err := NewClientWithRetries(server.URL, nil, 3, time.Millisecond).GetOrg(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if org["login"] != "Netflix" {
+		t.Errorf("Expected the org once syncing finished, got %v", org)
+	}
+	if got := requests.Load(); got != 3 {
+		t.Errorf("Expected 3 requests, got %d", got)
+	}
+}
+
+func TestAcceptedIsNotDecoded(t *testing.T) {
+	server, _ := sequenceServer(t, syncing)
+
+	_, err := NewClientWithRetries(server.URL, nil, 1, time.Millisecond).GetOrg(context.Background())
+
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected a 202 StatusError once retries ran out, got %v", err)
+	}
+}
+
+func TestLongRetryAfterIsNotWaited(t *testing.T) {
+	server, requests := sequenceServer(t, func(w http.ResponseWriter) {
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	if _, err := NewClient(server.URL, nil).GetOrg(context.Background()); err == nil {
+		t.Fatal("Expected an error")
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("Expected a single request, got %d", got)
+	}
+}
@@ -0,0 +1,125 @@
+package client
+
+import "time"
+
+// Responses of the service, declared here so the client doesn't pull in the server's packages. Their JSON matches what
+// the service serves, fields the client doesn't know about are ignored
+
+// GitHub JSON object, as cached and served by the service
+type JsonObject map[string]interface{}
+
+// Response of /cachestatus
+type CacheStatus struct {
+	Generation  uint64                `json:"generation"`
+	Readiness   Readiness             `json:"readiness"`
+	LastSync    SyncReport            `json:"last_sync"`
+	Stats       CacheStats            `json:"stats"`
+	Token       TokenHealth           `json:"token"`
+	Maintenance MaintenanceStatus     `json:"maintenance"`
+	Routes      map[string]RouteStats `json:"routes"`
+}
+
+// Readiness of every cached dataset
+type Readiness struct {
+	Ready    bool                        `json:"ready"`
+	Syncing  bool                        `json:"syncing"` // a full hydration is in progress
+	Datasets map[string]DatasetReadiness `json:"datasets"`
+}
+
+// Readiness of a single cached dataset, and the last error fetching it
+type DatasetReadiness struct {
+	Ready              bool       `json:"ready"`
+	HydratedAt         *time.Time `json:"hydrated_at"` // nil until the dataset is first hydrated
+	Version            uint64     `json:"version"`
+	LastError          string     `json:"last_error,omitempty"`
+	LastUpstreamStatus int        `json:"last_upstream_status"`
+	HttpStatus         int        `json:"http_status"` // status the service responds with while the dataset is unavailable
+}
+
+// Outcome of the last cache sync
+type SyncReport struct {
+	StartTime   time.Time                    `json:"start_time"`
+	EndTime     time.Time                    `json:"end_time"`
+	DurationMs  int64                        `json:"duration_ms"`
+	Status      int                          `json:"status"`
+	Error       string                       `json:"error,omitempty"`
+	Approximate bool                         `json:"approximate,omitempty"`
+	Datasets    map[string]DatasetSyncReport `json:"datasets"`
+	Upstream    RequestUsage                 `json:"upstream"`
+}
+
+// Outcome of a single dataset in a sync
+type DatasetSyncReport struct {
+	Status     int                `json:"status"`
+	Items      int                `json:"items"`
+	Error      string             `json:"error,omitempty"`
+	Skipped    []SkippedRecord    `json:"skipped,omitempty"`
+	Unparsable []UnparsableRecord `json:"unparsable,omitempty"`
+}
+
+// Record left out of a dataset because it's missing fields the dataset needs
+type SkippedRecord struct {
+	Record        string   `json:"record"`
+	MissingFields []string `json:"missing_fields"`
+}
+
+// Record with a field the dataset couldn't parse
+type UnparsableRecord struct {
+	Record string `json:"record"`
+	Field  string `json:"field"`
+	Value  string `json:"value"`
+}
+
+// GitHub requests a sync made
+type RequestUsage struct {
+	Requests    int            `json:"requests"`
+	NotModified int            `json:"not_modified,omitempty"`
+	Cost        map[string]int `json:"cost,omitempty"` // by rate limit resource
+}
+
+// Size accounting of the cached datasets
+type CacheStats struct {
+	TotalBytes    int                     `json:"total_bytes"`
+	OptionalBytes map[string]int          `json:"optional_bytes"`
+	MaxBytes      int                     `json:"max_bytes,omitempty"`
+	OverLimit     bool                    `json:"over_limit"`
+	Trimmed       []string                `json:"trimmed,omitempty"`
+	Datasets      map[string]DatasetStats `json:"datasets"`
+}
+
+// Current size of a dataset, and how it has grown over recent hydrations
+type DatasetStats struct {
+	Bytes   int                 `json:"bytes"`
+	History []DatasetSizeSample `json:"history"`
+}
+
+// Size of a dataset as of a hydration
+type DatasetSizeSample struct {
+	HydratedAt time.Time `json:"hydrated_at"`
+	Bytes      int       `json:"bytes"`
+}
+
+// Whether GitHub accepts the service's token
+type TokenHealth struct {
+	Status            string     `json:"status"` // none, unknown, valid, or rejected
+	AnonymousFallback bool       `json:"anonymous_fallback"`
+	RejectedSince     *time.Time `json:"rejected_since,omitempty"`
+	LastCheckedAt     *time.Time `json:"last_checked_at,omitempty"`
+}
+
+// Whether the service is in maintenance mode
+type MaintenanceStatus struct {
+	Enabled   bool       `json:"enabled"`
+	Reason    string     `json:"reason,omitempty"`
+	EnabledAt *time.Time `json:"enabled_at,omitempty"`
+}
+
+// Cache usage of a single cached route
+type RouteStats struct {
+	Hits             uint64            `json:"hits"`
+	Misses           uint64            `json:"misses"`
+	HitRatio         float64           `json:"hit_ratio"`
+	ForcedHydrations uint64            `json:"forced_hydrations"` // misses that hydrated the cache before responding
+	BytesServed      uint64            `json:"bytes_served"`
+	Errors           map[string]uint64 `json:"errors,omitempty"` // requests that couldn't be served from the cache, by reason
+}
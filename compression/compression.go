@@ -0,0 +1,57 @@
+package compression
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	ENCODING_GZIP string = "gzip"
+	ENCODING_ZSTD string = "zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// Parse a zstd compression level, one of fastest, default, better, best
+func ParseZstdLevel(name string) (zstd.EncoderLevel, bool) {
+	ok, level := zstd.EncoderLevelFromString(name)
+	return level, ok
+}
+
+// Get a writer that zstd compresses everything written to w at level, must be closed to flush the compressed stream
+func NewZstdWriter(w io.Writer, level zstd.EncoderLevel) (io.WriteCloser, error) {
+	return zstd.NewWriter(w, zstd.WithEncoderLevel(level))
+}
+
+// Get an encoder for compressing whole in-memory payloads with EncodeAll, safe for concurrent use
+func NewZstdEncoder(level zstd.EncoderLevel) (*zstd.Encoder, error) {
+	return zstd.NewWriter(nil, zstd.WithEncoderLevel(level))
+}
+
+// Get a reader that transparently decompresses r, gzip and zstd streams are detected by their magic bytes,
+// anything else is read as is
+func NewReader(r io.Reader) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(r)
+
+	if magic, err := buffered.Peek(len(zstdMagic)); err == nil && bytes.Equal(magic, zstdMagic) {
+		zstdReader, err := zstd.NewReader(buffered)
+		if err != nil {
+			return nil, err
+		}
+
+		return zstdReader.IOReadCloser(), nil
+	}
+
+	if magic, err := buffered.Peek(len(gzipMagic)); err == nil && bytes.Equal(magic, gzipMagic) {
+		return gzip.NewReader(buffered)
+	}
+
+	return io.NopCloser(buffered), nil
+}
@@ -0,0 +1,126 @@
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Builds a JSON payload shaped like a cache export of an org with n repos
+func benchmarkPayload(n int) []byte {
+	repos := make([]map[string]interface{}, 0, n)
+
+	for i := 0; i < n; i++ {
+		repos = append(repos, map[string]interface{}{
+			"id":                i,
+			"name":              fmt.Sprintf("repo-%d", i),
+			"full_name":         fmt.Sprintf("Netflix/repo-%d", i),
+			"html_url":          fmt.Sprintf("https://github.com/Netflix/repo-%d", i),
+			"description":       "A library for building resilient services at scale",
+			"fork":              i%7 == 0,
+			"language":          []string{"Java", "Go", "Python", "JavaScript"}[i%4],
+			"forks_count":       i * 3 % 997,
+			"stargazers_count":  i * 17 % 9973,
+			"open_issues_count": i % 53,
+			"updated_at":        fmt.Sprintf("2024-%02d-%02dT12:00:00Z", i%12+1, i%28+1),
+		})
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{"netflix_organization_repos": repos})
+	if err != nil {
+		panic(err)
+	}
+
+	return payload
+}
+
+// Compresses payload b.N times with the writer returned by newWriter, reporting the compressed size relative to the payload
+func benchmarkCompression(b *testing.B, payload []byte, newWriter func(w io.Writer) (io.WriteCloser, error)) {
+	var compressed bytes.Buffer
+
+	b.SetBytes(int64(len(payload)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		compressed.Reset()
+
+		writer, err := newWriter(&compressed)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		if _, err := writer.Write(payload); err != nil {
+			b.Fatal(err)
+		}
+
+		if err := writer.Close(); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ReportMetric(float64(compressed.Len()), "compressed-bytes")
+	b.ReportMetric(float64(compressed.Len())/float64(len(payload)), "ratio")
+}
+
+func BenchmarkCompression(b *testing.B) {
+	payload := benchmarkPayload(5000)
+
+	gzipLevels := []struct {
+		name  string
+		level int
+	}{{"fastest", gzip.BestSpeed}, {"default", gzip.DefaultCompression}, {"best", gzip.BestCompression}}
+
+	for _, gzipLevel := range gzipLevels {
+		level := gzipLevel.level
+		b.Run("gzip-"+gzipLevel.name, func(b *testing.B) {
+			benchmarkCompression(b, payload, func(w io.Writer) (io.WriteCloser, error) {
+				return gzip.NewWriterLevel(w, level)
+			})
+		})
+	}
+
+	for _, level := range []zstd.EncoderLevel{zstd.SpeedFastest, zstd.SpeedDefault, zstd.SpeedBetterCompression, zstd.SpeedBestCompression} {
+		b.Run("zstd-"+level.String(), func(b *testing.B) {
+			benchmarkCompression(b, payload, func(w io.Writer) (io.WriteCloser, error) {
+				return NewZstdWriter(w, level)
+			})
+		})
+	}
+}
+
+func BenchmarkDecompression(b *testing.B) {
+	payload := benchmarkPayload(5000)
+
+	var gzipped, zstded bytes.Buffer
+
+	gzipWriter := gzip.NewWriter(&gzipped)
+	gzipWriter.Write(payload)
+	gzipWriter.Close()
+
+	zstdWriter, _ := NewZstdWriter(&zstded, zstd.SpeedDefault)
+	zstdWriter.Write(payload)
+	zstdWriter.Close()
+
+	for name, compressed := range map[string][]byte{"gzip": gzipped.Bytes(), "zstd": zstded.Bytes()} {
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(payload)))
+
+			for i := 0; i < b.N; i++ {
+				reader, err := NewReader(bytes.NewReader(compressed))
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				if _, err := io.Copy(io.Discard, reader); err != nil {
+					b.Fatal(err)
+				}
+				reader.Close()
+			}
+		})
+	}
+}
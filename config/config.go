@@ -1,24 +1,292 @@
 package config
 
 import (
+	"crypto/x509"
 	"errors"
 	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/adamjeanlaurent/github-api-read-cache-service/compression"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/customroutes"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/replication"
+	"github.com/klauspost/compress/zstd"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
+// default cache ttl is 10 minutes
+const DEFAULT_CACHE_TTL time.Duration = 10 * time.Minute
+
+// methods the proxy can forward to GitHub, CONNECT and TRACE never are
+var PROXY_METHODS = []string{http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
 type Configuration interface {
 	GetGitHubApiKey() string
 	GetPort() int
+	GetTLSPort() int
+	GetTLSCertFile() string
+	GetTLSKeyFile() string
 	GetCacheTTL() time.Duration
+	GetOrgTTL() time.Duration
+	GetMembersTTL() time.Duration
+	GetReposTTL() time.Duration
+	GetShutdownTimeout() time.Duration
+	GetProbeCacheTTL() time.Duration
+	GetDisableProxy() bool
+	GetWaitForCache() bool
+	GetWaitForCacheTimeout() time.Duration
+	GetBootstrapArchiveFile() string
+	GetSnapshotFile() string
+	GetSnapshotReplicaUrl() string
+	GetSnapshotBucket() replication.Bucket
+	GetPreferFreshestSnapshot() bool
+	GetZstdLevel() zstd.EncoderLevel
+	GetPrecomputedViewSizes() []int
+	GetCustomRoutes() []*customroutes.Route
+	GetMaxCacheBytes() int
+	GetMemoryLimitAction() string
+	GetDigestWebhookUrl() string
+	GetDigestInterval() time.Duration
+	GetDigestMinStarDelta() int
+	GetForcedHydrationQueueSize() int
+	GetForcedHydrationTimeout() time.Duration
+	GetHydrateContributors() bool
+	GetContributorsTTL() time.Duration
+	GetContributorsConcurrency() int
+	GetReleaseRepos() []string
+	GetReleasesTTL() time.Duration
+	GetQuotaReserve() int
+	GetQuotaMaxWait() time.Duration
+	GetIncrementalRepoSync() bool
+	GetReposFullSyncInterval() time.Duration
+	GetDebugPort() int
+	GetLogLevel() zapcore.Level
+	GetLogFormat() string
+	GetLogFile() string
+	GetLogFileMaxBytes() int
+	GetLogFileMaxBackups() int
+	GetAccessLogSampleRate() float64
+	GetProxyAuth() string
+	GetProxyNegativeCacheTTL() time.Duration
+	GetGitHubApiVersion() string
+	GetGitHubAccept() string
+	GetValidate() bool
+	GetExtraViews() []string
+	GetStrictHydration() bool
+	GetSeedFile() string
+	GetSeedUrl() string
+	GetStatsdAddress() string
+	GetStatsdPrefix() string
+	GetStatsdFlavor() string
+	GetStatsdInterval() time.Duration
+	GetReadTimeout() time.Duration
+	GetWriteTimeout() time.Duration
+	GetIdleTimeout() time.Duration
+	GetMaxHeaderBytes() int
+	GetReposHandlerTimeout() time.Duration
+	GetProxyHandlerTimeout() time.Duration
+	GetHydrateCommitActivity() bool
+	GetCommitActivityTTL() time.Duration
+	GetAdminAllowedNetworks() []netip.Prefix
+	GetAdminUser() string
+	GetAdminPassword() string
+	GetAnonymousFallback() bool
+	GetGitHubTokenFile() string
+	GetGitHubProxy() *url.URL
+	GetGitHubRootCAs() *x509.CertPool
+	GetGitHubInsecureSkipVerify() bool
+	GetMaxViewN() int
+	GetDefaultViewN() int
+	GetRefreshOnMutationDelay() time.Duration
+	GetEnableChaos() bool
+	GetChaosLatency() time.Duration
+	GetChaosErrorRate() float64
+	GetChaosRateLimitRate() float64
+	GetHydrateTeams() bool
+	GetTeamsTTL() time.Duration
+	GetHydrateIssueCounts() bool
+	GetIssueCountsTTL() time.Duration
+	GetIssueCountsConcurrency() int
+	GetUpstreamDeadline() time.Duration
+	GetDisableHttp2() bool
+	GetHttp2MaxConcurrentStreams() int
+	GetDisableKeepAlives() bool
+	GetTcpKeepAlivePeriod() time.Duration
+	GetAuthMode() string
+	GetAuthApiKeys() []string
+	GetAuthJwksUrl() string
+	GetAuthJwtIssuer() string
+	GetAuthJwtAudience() string
+	GetAuthOidcIssuer() string
+	GetAuthLogClaims() []string
+	GetAuditLog() string
+	GetHydrateReadmes() bool
+	GetReadmesTTL() time.Duration
+	GetReadmesConcurrency() int
+	GetHydrateLanguages() bool
+	GetLanguagesTTL() time.Duration
+	GetLanguagesConcurrency() int
+	GetGitHubPageSize() int
+	GetGitHubPagination() string
+	GetRemovedReposRetention() time.Duration
+	GetExtraEndpoints() []string
+	GetExtraEndpointsTTL() time.Duration
+	GetAlertSlackWebhookUrl() string
+	GetAlertPagerDutyKey() string
+	GetAlertSyncFailures() int
+	GetAlertCooldown() time.Duration
+	GetDisableCacheControl() bool
+	GetAdminPort() int
+	GetProxyMaxBodyBytes() int64
+	GetProxyMethods() []string
+	GetProxyStripHeaders() []string
+	GetGitHubUserAgent() string
+	GetGitHubHeaders() http.Header
+	GetSkipUnchangedSyncs() bool
+	GetSkipUnchangedMaxAge() time.Duration
+	GetConsistencyCheck() bool
+	GetConsistencyCheckInterval() time.Duration
+	GetConsistencyCheckSample() int
+	GetOnce() bool
 }
 
 type configuration struct {
-	gitHubApiKey string
-	port         int
-	cacheTTL     time.Duration
+	gitHubApiKey             string
+	port                     int
+	tlsPort                  int
+	tlsCertFile              string
+	tlsKeyFile               string
+	cacheTTL                 time.Duration
+	orgTTL                   time.Duration
+	membersTTL               time.Duration
+	reposTTL                 time.Duration
+	shutdownTimeout          time.Duration
+	probeCacheTTL            time.Duration
+	disableProxy             bool
+	waitForCache             bool
+	waitForCacheTimeout      time.Duration
+	bootstrapArchiveFile     string
+	snapshotFile             string
+	snapshotReplicaUrl       string
+	snapshotBucket           replication.Bucket
+	preferFreshestSnapshot   bool
+	zstdLevel                zstd.EncoderLevel
+	precomputedViewSizes     []int
+	customRoutes             []*customroutes.Route
+	maxCacheBytes            int
+	memoryLimitAction        string
+	digestWebhookUrl         string
+	digestInterval           time.Duration
+	digestMinStarDelta       int
+	forcedHydrationQueueSize int
+	forcedHydrationTimeout   time.Duration
+	hydrateContributors      bool
+	contributorsTTL          time.Duration
+	contributorsConcurrency  int
+	releaseRepos             []string
+	releasesTTL              time.Duration
+	quotaReserve             int
+	quotaMaxWait             time.Duration
+	incrementalRepoSync      bool
+	reposFullSyncInterval    time.Duration
+	debugPort                int
+	logLevel                 zapcore.Level
+	logFormat                string
+	logFile                  string
+	logFileMaxBytes          int
+	logFileMaxBackups        int
+	accessLogSampleRate      float64
+	proxyAuth                string
+	proxyNegativeCacheTTL    time.Duration
+	gitHubApiVersion         string
+	gitHubAccept             string
+	validate                 bool
+	extraViews               []string
+	strictHydration          bool
+	seedFile                 string
+	seedUrl                  string
+	statsdAddress            string
+	statsdPrefix             string
+	statsdFlavor             string
+	statsdInterval           time.Duration
+	readTimeout              time.Duration
+	writeTimeout             time.Duration
+	idleTimeout              time.Duration
+	maxHeaderBytes           int
+	reposHandlerTimeout      time.Duration
+	proxyHandlerTimeout      time.Duration
+	hydrateCommitActivity    bool
+	commitActivityTTL        time.Duration
+	adminAllowedNetworks     []netip.Prefix
+	adminUser                string
+	adminPassword            string
+	anonymousFallback        bool
+	gitHubTokenFile          string
+	gitHubProxy              *url.URL
+	gitHubRootCAs            *x509.CertPool
+	gitHubInsecureSkipVerify bool
+	maxViewN                 int
+	defaultViewN             int
+	refreshOnMutationDelay   time.Duration
+	enableChaos              bool
+	chaosLatency             time.Duration
+	chaosErrorRate           float64
+	chaosRateLimitRate       float64
+	hydrateTeams             bool
+	teamsTTL                 time.Duration
+	hydrateIssueCounts       bool
+	issueCountsTTL           time.Duration
+	issueCountsConcurrency   int
+	upstreamDeadline         time.Duration
+	disableHttp2             bool
+	http2MaxStreams          int
+	disableKeepAlives        bool
+	tcpKeepAlivePeriod       time.Duration
+	authMode                 string
+	authApiKeys              []string
+	authJwksUrl              string
+	authJwtIssuer            string
+	authJwtAudience          string
+	authOidcIssuer           string
+	authLogClaims            []string
+	auditLog                 string
+	hydrateReadmes           bool
+	readmesTTL               time.Duration
+	readmesConcurrency       int
+	hydrateLanguages         bool
+	languagesTTL             time.Duration
+	languagesConcurrency     int
+	githubPageSize           int
+	githubPagination         string
+	removedReposRetention    time.Duration
+	extraEndpoints           []string
+	extraEndpointsTTL        time.Duration
+	alertSlackWebhookUrl     string
+	alertPagerDutyKey        string
+	alertSyncFailures        int
+	alertCooldown            time.Duration
+	disableCacheControl      bool
+	adminPort                int
+	proxyMaxBodyBytes        int64
+	proxyMethods             []string
+	proxyStripHeaders        []string
+	gitHubUserAgent          string
+	gitHubHeaders            http.Header
+	skipUnchangedSyncs       bool
+	skipUnchangedMaxAge      time.Duration
+	consistencyCheck         bool
+	consistencyCheckInterval time.Duration
+	consistencyCheckSample   int
+	once                     bool
 }
 
 // Retrieve Github API Key from config.
@@ -31,34 +299,1632 @@ func (config *configuration) GetPort() int {
 	return config.port
 }
 
+// Retrieve the port of the HTTPS listener from config, 0 if HTTPS is disabled.
+func (config *configuration) GetTLSPort() int {
+	return config.tlsPort
+}
+
+// Retrieve the TLS certificate file of the HTTPS listener from config.
+func (config *configuration) GetTLSCertFile() string {
+	return config.tlsCertFile
+}
+
+// Retrieve the TLS key file of the HTTPS listener from config.
+func (config *configuration) GetTLSKeyFile() string {
+	return config.tlsKeyFile
+}
+
 // Retrieve CacheTTL from config.
 func (config *configuration) GetCacheTTL() time.Duration {
 	return config.cacheTTL
 }
 
-// Parse and validate configuration
+// Retrieve the refresh interval of the Netflix organization dataset from config.
+func (config *configuration) GetOrgTTL() time.Duration {
+	return config.orgTTL
+}
+
+// Retrieve the refresh interval of the Netflix organization members dataset from config.
+func (config *configuration) GetMembersTTL() time.Duration {
+	return config.membersTTL
+}
+
+// Retrieve the refresh interval of the Netflix organization repos dataset from config.
+func (config *configuration) GetReposTTL() time.Duration {
+	return config.reposTTL
+}
+
+// Retrieve how long in-flight requests are given to finish when the server shuts down from config.
+func (config *configuration) GetShutdownTimeout() time.Duration {
+	return config.shutdownTimeout
+}
+
+// Retrieve how long active dependency probe results are reused for from config.
+func (config *configuration) GetProbeCacheTTL() time.Duration {
+	return config.probeCacheTTL
+}
+
+// Retrieve whether the GitHub API proxy is disabled from config.
+func (config *configuration) GetDisableProxy() bool {
+	return config.disableProxy
+}
+
+// Retrieve whether the server should wait for the initial cache hydration before accepting traffic from config.
+func (config *configuration) GetWaitForCache() bool {
+	return config.waitForCache
+}
+
+// Retrieve the max time to wait for the initial cache hydration from config.
+func (config *configuration) GetWaitForCacheTimeout() time.Duration {
+	return config.waitForCacheTimeout
+}
+
+// Retrieve the GHArchive / BigQuery export file used to seed the cache when the quota is exhausted at startup from config.
+func (config *configuration) GetBootstrapArchiveFile() string {
+	return config.bootstrapArchiveFile
+}
+
+// Retrieve the path of the persisted cache snapshot from config, empty if snapshots are disabled.
+func (config *configuration) GetSnapshotFile() string {
+	return config.snapshotFile
+}
+
+// Retrieve the URL snapshots are replicated to from config, empty if snapshots aren't replicated.
+func (config *configuration) GetSnapshotReplicaUrl() string {
+	return config.snapshotReplicaUrl
+}
+
+// Retrieve the bucket snapshots are replicated to from config, its name is empty if snapshots aren't replicated to a bucket.
+func (config *configuration) GetSnapshotBucket() replication.Bucket {
+	return config.snapshotBucket
+}
+
+// Retrieve whether the freshest of the local and replicated snapshots is loaded at startup from config.
+func (config *configuration) GetPreferFreshestSnapshot() bool {
+	return config.preferFreshestSnapshot
+}
+
+// Retrieve the zstd compression level used for snapshots, exports, and responses from config.
+func (config *configuration) GetZstdLevel() zstd.EncoderLevel {
+	return config.zstdLevel
+}
+
+// Retrieve the bottom N view sizes whose responses are serialized ahead of time from config.
+func (config *configuration) GetPrecomputedViewSizes() []int {
+	return config.precomputedViewSizes
+}
+
+// Retrieve the operator-defined custom routes from config.
+func (config *configuration) GetCustomRoutes() []*customroutes.Route {
+	return config.customRoutes
+}
+
+// Retrieve the approximate memory limit of the cache in bytes from config, 0 if unlimited.
+func (config *configuration) GetMaxCacheBytes() int {
+	return config.maxCacheBytes
+}
+
+// Retrieve what happens when the cache exceeds its memory limit from config, warn or trim.
+func (config *configuration) GetMemoryLimitAction() string {
+	return config.memoryLimitAction
+}
+
+// Retrieve the webhook digests of changes to the cached data are posted to from config, empty if digests are disabled.
+func (config *configuration) GetDigestWebhookUrl() string {
+	return config.digestWebhookUrl
+}
+
+// Retrieve how often digests are posted from config.
+func (config *configuration) GetDigestInterval() time.Duration {
+	return config.digestInterval
+}
+
+// Retrieve the min change in stars for a repo to be listed in a digest from config.
+func (config *configuration) GetDigestMinStarDelta() int {
+	return config.digestMinStarDelta
+}
+
+// Retrieve how many requests can wait on a forced hydration after a cache miss from config.
+func (config *configuration) GetForcedHydrationQueueSize() int {
+	return config.forcedHydrationQueueSize
+}
+
+// Retrieve how long a request waits on a forced hydration before it's answered 503 from config.
+func (config *configuration) GetForcedHydrationTimeout() time.Duration {
+	return config.forcedHydrationTimeout
+}
+
+// Retrieve whether the contributors of every repo are cached from config.
+func (config *configuration) GetHydrateContributors() bool {
+	return config.hydrateContributors
+}
+
+// Retrieve the refresh interval of the contributors dataset from config.
+func (config *configuration) GetContributorsTTL() time.Duration {
+	return config.contributorsTTL
+}
+
+// Retrieve how many repos' contributors are fetched at once from config.
+func (config *configuration) GetContributorsConcurrency() int {
+	return config.contributorsConcurrency
+}
+
+// Retrieve the repos whose latest release is cached from config, "*" for every repo, empty if releases aren't cached.
+func (config *configuration) GetReleaseRepos() []string {
+	return config.releaseRepos
+}
+
+// Retrieve the refresh interval of the releases dataset from config.
+func (config *configuration) GetReleasesTTL() time.Duration {
+	return config.releasesTTL
+}
+
+// Retrieve the remaining GitHub API quota outbound requests start being paced at from config, 0 if pacing is disabled.
+func (config *configuration) GetQuotaReserve() int {
+	return config.quotaReserve
+}
+
+// Retrieve how long a proxied request is queued waiting for GitHub API quota from config.
+func (config *configuration) GetQuotaMaxWait() time.Duration {
+	return config.quotaMaxWait
+}
+
+// Retrieve whether repo syncs only fetch repos updated since the last sync from config.
+func (config *configuration) GetIncrementalRepoSync() bool {
+	return config.incrementalRepoSync
+}
+
+// Retrieve how often every repo is re-fetched when syncing incrementally from config.
+func (config *configuration) GetReposFullSyncInterval() time.Duration {
+	return config.reposFullSyncInterval
+}
+
+// Retrieve the port of the pprof and expvar debug listener from config, 0 if it's disabled.
+func (config *configuration) GetDebugPort() int {
+	return config.debugPort
+}
+
+// Retrieve the level logs start at from config.
+func (config *configuration) GetLogLevel() zapcore.Level {
+	return config.logLevel
+}
+
+// Retrieve the format logs are written in from config, json or console.
+func (config *configuration) GetLogFormat() string {
+	return config.logFormat
+}
+
+// Retrieve the file logs are written to from config, empty if they're written to stderr.
+func (config *configuration) GetLogFile() string {
+	return config.logFile
+}
+
+// Retrieve the size log files are rotated at from config.
+func (config *configuration) GetLogFileMaxBytes() int {
+	return config.logFileMaxBytes
+}
+
+// Retrieve the number of rotated log files kept from config.
+func (config *configuration) GetLogFileMaxBackups() int {
+	return config.logFileMaxBackups
+}
+
+// Retrieve the fraction of requests written to the access log from config, 0 if access logging is disabled.
+func (config *configuration) GetAccessLogSampleRate() float64 {
+	return config.accessLogSampleRate
+}
+
+// Retrieve how the proxy authenticates requests to GitHub from config, service, caller, or caller-only.
+func (config *configuration) GetProxyAuth() string {
+	return config.proxyAuth
+}
+
+// Retrieve how long proxied 404 responses are cached from config, 0 if they aren't.
+func (config *configuration) GetProxyNegativeCacheTTL() time.Duration {
+	return config.proxyNegativeCacheTTL
+}
+
+// Retrieve the GitHub REST API version requests are pinned to from config, empty if they aren't.
+func (config *configuration) GetGitHubApiVersion() string {
+	return config.gitHubApiVersion
+}
+
+// Retrieve the media type requested from GitHub from config.
+func (config *configuration) GetGitHubAccept() string {
+	return config.gitHubAccept
+}
+
+// Retrieve whether the service only validates a single hydration instead of serving from config.
+func (config *configuration) GetValidate() bool {
+	return config.validate
+}
+
+// Retrieve the extra repo views computed and served from config.
+func (config *configuration) GetExtraViews() []string {
+	return config.extraViews
+}
+
+// Retrieve whether a repo missing fields fails the whole hydration from config.
+func (config *configuration) GetStrictHydration() bool {
+	return config.strictHydration
+}
+
+// Retrieve the path of the cache export the cache is seeded from at startup from config, empty if it isn't seeded.
+func (config *configuration) GetSeedFile() string {
+	return config.seedFile
+}
+
+// Retrieve the URL of the cache export the cache is seeded from at startup from config, empty if it isn't seeded.
+func (config *configuration) GetSeedUrl() string {
+	return config.seedUrl
+}
+
+// Retrieve the UDP address metrics are pushed to from config, empty if they aren't pushed.
+func (config *configuration) GetStatsdAddress() string {
+	return config.statsdAddress
+}
+
+// Retrieve the prefix of pushed metric names from config.
+func (config *configuration) GetStatsdPrefix() string {
+	return config.statsdPrefix
+}
+
+// Retrieve the StatsD line protocol metrics are pushed with from config.
+func (config *configuration) GetStatsdFlavor() string {
+	return config.statsdFlavor
+}
+
+// Retrieve how often metrics are pushed from config.
+func (config *configuration) GetStatsdInterval() time.Duration {
+	return config.statsdInterval
+}
+
+// Retrieve the max time to read a request, including its body, from config, 0 for no limit.
+func (config *configuration) GetReadTimeout() time.Duration {
+	return config.readTimeout
+}
+
+// Retrieve the max time to write a response, from the end of reading the request headers, from config, 0 for no limit.
+func (config *configuration) GetWriteTimeout() time.Duration {
+	return config.writeTimeout
+}
+
+// Retrieve how long idle keep-alive connections are kept open from config, 0 to fall back to the read timeout.
+func (config *configuration) GetIdleTimeout() time.Duration {
+	return config.idleTimeout
+}
+
+// Retrieve the max size of request headers from config.
+func (config *configuration) GetMaxHeaderBytes() int {
+	return config.maxHeaderBytes
+}
+
+// Retrieve the max time /orgs/Netflix/repos is given to respond from config, 0 for no limit.
+func (config *configuration) GetReposHandlerTimeout() time.Duration {
+	return config.reposHandlerTimeout
+}
+
+// Retrieve the max time proxied requests are given to respond from config, 0 for no limit.
+func (config *configuration) GetProxyHandlerTimeout() time.Duration {
+	return config.proxyHandlerTimeout
+}
+
+// Retrieve whether the weekly commit activity of every repo is cached from config.
+func (config *configuration) GetHydrateCommitActivity() bool {
+	return config.hydrateCommitActivity
+}
+
+// Retrieve the refresh interval of the commit activity dataset from config.
+func (config *configuration) GetCommitActivityTTL() time.Duration {
+	return config.commitActivityTTL
+}
+
+// Retrieve the networks admin and debug routes can be reached from from config, empty if they can be reached from anywhere.
+func (config *configuration) GetAdminAllowedNetworks() []netip.Prefix {
+	return config.adminAllowedNetworks
+}
+
+// Retrieve the basic auth username of admin and debug routes from config, empty if basic auth isn't required.
+func (config *configuration) GetAdminUser() string {
+	return config.adminUser
+}
+
+// Retrieve the basic auth password of admin and debug routes from config.
+func (config *configuration) GetAdminPassword() string {
+	return config.adminPassword
+}
+
+// Retrieve whether GitHub requests fall back to anonymous while the token is rejected from config.
+func (config *configuration) GetAnonymousFallback() bool {
+	return config.anonymousFallback
+}
+
+// Retrieve the path of the file the GitHub API token is read from from config, empty if it's read from GITHUB_API_TOKEN.
+func (config *configuration) GetGitHubTokenFile() string {
+	return config.gitHubTokenFile
+}
+
+// Retrieve the proxy GitHub requests are sent through from config, nil to use HTTP_PROXY / HTTPS_PROXY / NO_PROXY.
+func (config *configuration) GetGitHubProxy() *url.URL {
+	return config.gitHubProxy
+}
+
+// Retrieve the root CAs GitHub's certificate is verified against from config, nil for the system roots.
+func (config *configuration) GetGitHubRootCAs() *x509.CertPool {
+	return config.gitHubRootCAs
+}
+
+// Retrieve whether GitHub's certificate isn't verified from config.
+func (config *configuration) GetGitHubInsecureSkipVerify() bool {
+	return config.gitHubInsecureSkipVerify
+}
+
+// Retrieve the largest N view endpoints accept from config, 0 if N isn't limited.
+func (config *configuration) GetMaxViewN() int {
+	return config.maxViewN
+}
+
+// Retrieve the N view endpoints respond with when the request doesn't set N from config.
+func (config *configuration) GetDefaultViewN() int {
+	return config.defaultViewN
+}
+
+// Retrieve how long after a proxied write to re-hydrate the dataset it changed from config, 0 if proxied writes don't trigger a re-hydration.
+func (config *configuration) GetRefreshOnMutationDelay() time.Duration {
+	return config.refreshOnMutationDelay
+}
+
+// Retrieve whether faults may be injected into GitHub requests from config.
+func (config *configuration) GetEnableChaos() bool {
+	return config.enableChaos
+}
+
+// Retrieve the latency injected into every GitHub request from config, 0 if none is.
+func (config *configuration) GetChaosLatency() time.Duration {
+	return config.chaosLatency
+}
+
+// Retrieve the fraction of GitHub requests answered with an injected 500 from config.
+func (config *configuration) GetChaosErrorRate() float64 {
+	return config.chaosErrorRate
+}
+
+// Retrieve the fraction of GitHub requests answered with an injected rate limit from config.
+func (config *configuration) GetChaosRateLimitRate() float64 {
+	return config.chaosRateLimitRate
+}
+
+// Retrieve whether the org's teams and the repos of each team are cached from config.
+func (config *configuration) GetHydrateTeams() bool {
+	return config.hydrateTeams
+}
+
+// Retrieve the refresh interval of the teams dataset from config.
+func (config *configuration) GetTeamsTTL() time.Duration {
+	return config.teamsTTL
+}
+
+// Retrieve whether the open issue and pull request counts of every repo are cached from config.
+func (config *configuration) GetHydrateIssueCounts() bool {
+	return config.hydrateIssueCounts
+}
+
+// Retrieve the refresh interval of the issue counts dataset from config.
+func (config *configuration) GetIssueCountsTTL() time.Duration {
+	return config.issueCountsTTL
+}
+
+// Retrieve the max number of repos whose issue counts are fetched at once from config.
+func (config *configuration) GetIssueCountsConcurrency() int {
+	return config.issueCountsConcurrency
+}
+
+// Retrieve the max time a request's calls to GitHub may take from config, 0 for no limit.
+func (config *configuration) GetUpstreamDeadline() time.Duration {
+	return config.upstreamDeadline
+}
+
+// Retrieve whether HTTP/2 is disabled on the HTTPS listener from config.
+func (config *configuration) GetDisableHttp2() bool {
+	return config.disableHttp2
+}
+
+// Retrieve the max concurrent streams per HTTP/2 connection from config.
+func (config *configuration) GetHttp2MaxConcurrentStreams() int {
+	return config.http2MaxStreams
+}
+
+// Retrieve whether HTTP/1.1 keep-alives are disabled from config.
+func (config *configuration) GetDisableKeepAlives() bool {
+	return config.disableKeepAlives
+}
+
+// Retrieve the interval of TCP keep-alive probes on accepted connections from config, negative to disable them.
+func (config *configuration) GetTcpKeepAlivePeriod() time.Duration {
+	return config.tcpKeepAlivePeriod
+}
+
+// Retrieve how inbound requests are authorized from config, none, static-keys, jwt, or oidc.
+func (config *configuration) GetAuthMode() string {
+	return config.authMode
+}
+
+// Retrieve the API keys accepted with the static-keys auth mode from config.
+func (config *configuration) GetAuthApiKeys() []string {
+	return config.authApiKeys
+}
+
+// Retrieve the URL of the JWKS that JWTs are validated against from config.
+func (config *configuration) GetAuthJwksUrl() string {
+	return config.authJwksUrl
+}
+
+// Retrieve the issuer JWTs must have from config, empty if any issuer is accepted.
+func (config *configuration) GetAuthJwtIssuer() string {
+	return config.authJwtIssuer
+}
+
+// Retrieve the audience JWTs must be intended for from config, empty if any audience is accepted.
+func (config *configuration) GetAuthJwtAudience() string {
+	return config.authJwtAudience
+}
+
+// Retrieve the OIDC issuer bearer tokens are validated against from config.
+func (config *configuration) GetAuthOidcIssuer() string {
+	return config.authOidcIssuer
+}
+
+// Retrieve the token claims logged in the access log from config.
+func (config *configuration) GetAuthLogClaims() []string {
+	return config.authLogClaims
+}
+
+// Retrieve where proxied write requests are audit logged from config, stdout or a file path, empty if they aren't.
+func (config *configuration) GetAuditLog() string {
+	return config.auditLog
+}
+
+// Retrieve whether the README of every repo is cached from config.
+func (config *configuration) GetHydrateReadmes() bool {
+	return config.hydrateReadmes
+}
+
+// Retrieve the refresh interval of the readmes dataset from config.
+func (config *configuration) GetReadmesTTL() time.Duration {
+	return config.readmesTTL
+}
+
+// Retrieve the max number of repos whose README is fetched at once from config.
+func (config *configuration) GetReadmesConcurrency() int {
+	return config.readmesConcurrency
+}
+
+// Retrieve whether the language statistics of every repo are cached from config.
+func (config *configuration) GetHydrateLanguages() bool {
+	return config.hydrateLanguages
+}
+
+// Retrieve the refresh interval of the languages dataset from config.
+func (config *configuration) GetLanguagesTTL() time.Duration {
+	return config.languagesTTL
+}
+
+// Retrieve the max number of repos whose language statistics are fetched at once from config.
+func (config *configuration) GetLanguagesConcurrency() int {
+	return config.languagesConcurrency
+}
+
+// Retrieve the number of items requested per page of paginated GitHub API requests from config.
+func (config *configuration) GetGitHubPageSize() int {
+	return config.githubPageSize
+}
+
+// Retrieve how paginated GitHub API requests find their next page from config, page or link.
+func (config *configuration) GetGitHubPagination() string {
+	return config.githubPagination
+}
+
+// Retrieve how long repos removed from the org are listed on /view/removed from config.
+func (config *configuration) GetRemovedReposRetention() time.Duration {
+	return config.removedReposRetention
+}
+
+// Retrieve the extra GitHub paths cached verbatim from config.
+func (config *configuration) GetExtraEndpoints() []string {
+	return config.extraEndpoints
+}
+
+// Retrieve the refresh interval of the extra endpoints dataset from config.
+func (config *configuration) GetExtraEndpointsTTL() time.Duration {
+	return config.extraEndpointsTTL
+}
+
+// Retrieve the Slack webhook alerts are posted to from config, empty if not configured.
+func (config *configuration) GetAlertSlackWebhookUrl() string {
+	return config.alertSlackWebhookUrl
+}
+
+// Retrieve the PagerDuty routing key alerts are sent with from config, empty if not configured.
+func (config *configuration) GetAlertPagerDutyKey() string {
+	return config.alertPagerDutyKey
+}
+
+// Retrieve the number of consecutive failed syncs of a dataset that raise an alert from config.
+func (config *configuration) GetAlertSyncFailures() int {
+	return config.alertSyncFailures
+}
+
+// Retrieve how long an alert is muted after being raised from config.
+func (config *configuration) GetAlertCooldown() time.Duration {
+	return config.alertCooldown
+}
+
+// Retrieve whether cached responses are sent without a Cache-Control header from config.
+func (config *configuration) GetDisableCacheControl() bool {
+	return config.disableCacheControl
+}
+
+// Retrieve the port of the admin listener from config, 0 if admin routes are served on the API ports.
+func (config *configuration) GetAdminPort() int {
+	return config.adminPort
+}
+
+// Retrieve the max size of proxied request bodies from config.
+func (config *configuration) GetProxyMaxBodyBytes() int64 {
+	return config.proxyMaxBodyBytes
+}
+
+// Retrieve the methods forwarded to GitHub from config.
+func (config *configuration) GetProxyMethods() []string {
+	return config.proxyMethods
+}
+
+// Retrieve the inbound headers removed from proxied requests, on top of the ones always removed, from config.
+func (config *configuration) GetProxyStripHeaders() []string {
+	return config.proxyStripHeaders
+}
+
+// Retrieve the User-Agent sent to GitHub from config.
+func (config *configuration) GetGitHubUserAgent() string {
+	return config.gitHubUserAgent
+}
+
+// Retrieve the headers set on every request to GitHub from config, empty if none are configured.
+func (config *configuration) GetGitHubHeaders() http.Header {
+	return config.gitHubHeaders
+}
+
+// Retrieve whether members and repos syncs are skipped while the org has no new events from config.
+func (config *configuration) GetSkipUnchangedSyncs() bool {
+	return config.skipUnchangedSyncs
+}
+
+// Retrieve how long members and repos can go unsynced while the org has no new events from config.
+func (config *configuration) GetSkipUnchangedMaxAge() time.Duration {
+	return config.skipUnchangedMaxAge
+}
+
+// Retrieve whether cached repos are periodically compared against GitHub from config.
+func (config *configuration) GetConsistencyCheck() bool {
+	return config.consistencyCheck
+}
+
+// Retrieve how often cached repos are compared against GitHub from config.
+func (config *configuration) GetConsistencyCheckInterval() time.Duration {
+	return config.consistencyCheckInterval
+}
+
+// Retrieve how many cached repos each consistency check compares against GitHub from config.
+func (config *configuration) GetConsistencyCheckSample() int {
+	return config.consistencyCheckSample
+}
+
+// Retrieve whether the service hydrates once, persists a snapshot, and exits instead of serving from config.
+func (config *configuration) GetOnce() bool {
+	return config.once
+}
+
+// Parse and validate configuration from the command line
 func NewConfiguration(logger *zap.Logger) (Configuration, error) {
-	port := flag.Int("port", 0, "Port for server to listen on")
-	flag.Parse()
+	return ParseConfiguration(flag.CommandLine, os.Args[1:], true, logger)
+}
+
+// Parse and validate configuration from args, registering the configuration flags on flags.
+// Lets subcommands register their own flags alongside the configuration, port is only required when serving
+func ParseConfiguration(flags *flag.FlagSet, args []string, requirePort bool, logger *zap.Logger) (Configuration, error) {
+	port := flags.Int("port", 0, "Port for server to listen on")
+	tlsPort := flags.Int("tls-port", 0, "Port for an additional HTTPS listener, requires --tls-cert-file and --tls-key-file")
+	tlsCertFile := flags.String("tls-cert-file", "", "PEM certificate (chain) for the HTTPS listener")
+	tlsKeyFile := flags.String("tls-key-file", "", "PEM private key for the HTTPS listener")
+	disableProxy := flags.Bool("disable-proxy", false, "Disable proxying of non-cached paths to the GitHub API")
+	waitForCache := flags.Bool("wait-for-cache", false, "Don't accept traffic until the initial cache hydration succeeds or --wait-for-cache-timeout elapses")
+	waitTimeout := flags.Duration("wait-for-cache-timeout", 2*time.Minute, "Max time to wait for the initial cache hydration when --wait-for-cache is set")
+	bootstrapArchiveFile := flags.String("bootstrap-archive-file", "", "GHArchive / BigQuery export file (newline delimited JSON, optionally gzipped) used to seed approximate repo data when the GitHub API quota is exhausted at startup")
+	orgTTL := flags.Duration("org-ttl", DEFAULT_CACHE_TTL, "Refresh interval of the cached Netflix organization")
+	membersTTL := flags.Duration("members-ttl", DEFAULT_CACHE_TTL, "Refresh interval of the cached Netflix organization members")
+	reposTTL := flags.Duration("repos-ttl", DEFAULT_CACHE_TTL, "Refresh interval of the cached Netflix organization repos and views")
+	shutdownTimeout := flags.Duration("shutdown-timeout", 5*time.Second, "How long in-flight requests are given to finish when the server shuts down")
+	probeCacheTTL := flags.Duration("probe-cache-ttl", 10*time.Second, "How long results of the active dependency probes on /healthz/detail are reused for")
+	snapshotFile := flags.String("snapshot-file", "", "Path of a zstd compressed cache snapshot, written after every successful sync and loaded at startup")
+	snapshotReplicaUrl := flags.String("snapshot-replica-url", "", "URL snapshots are uploaded to (PUT) and recovered from (GET) when there's no local snapshot, so it must accept both, e.g. an internal object store in a secondary region. Use snapshot-bucket for S3 or GCS")
+	snapshotBucketUrl := flags.String("snapshot-bucket", "", "S3 (s3://bucket) or GCS (gs://bucket) bucket snapshots are uploaded to and recovered from when there's no local snapshot, like --snapshot-replica-url. Signed with the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, and optional AWS_SESSION_TOKEN environment variables, an HMAC key for GCS")
+	snapshotBucketPrefix := flags.String("snapshot-bucket-prefix", "", "Prefix of the snapshot object in --snapshot-bucket, e.g. github-cache/us-east-1")
+	snapshotBucketRegion := flags.String("snapshot-bucket-region", "us-east-1", "Region of the S3 --snapshot-bucket, ignored for GCS")
+	snapshotBucketEndpoint := flags.String("snapshot-bucket-endpoint", "", "Endpoint --snapshot-bucket is reached at instead of the provider's, e.g. an S3 compatible store like MinIO. Buckets are addressed by path")
+	preferFreshestSnapshot := flags.Bool("prefer-freshest-snapshot", false, "At startup, load whichever of the local and replicated snapshots was hydrated most recently")
+	zstdLevelName := flags.String("zstd-level", "default", "zstd compression level for snapshots, exports, and responses (fastest, default, better, best)")
+	precomputedViewSizesList := flags.String("precomputed-view-sizes", "5,10,25", "Comma separated bottom N view sizes serialized at hydration time instead of per request, empty disables precomputation")
+	customRoutesFile := flags.String("custom-routes-file", "", "JSON file defining custom endpoints evaluated against cached datasets")
+	maxCacheBytes := flags.Int("max-cache-bytes", 0, "Approximate memory limit of the cache in bytes, 0 for no limit")
+	memoryLimitAction := flags.String("memory-limit-action", "warn", "What happens when the cache exceeds --max-cache-bytes, warn or trim (drop optional data like precomputed views)")
+	digestWebhookUrl := flags.String("digest-webhook-url", "", "Webhook a digest of changes to the cached data (new repos, star movers, members joined / left) is posted to, empty disables digests")
+	digestInterval := flags.Duration("digest-interval", 24*time.Hour, "How often digests are posted to --digest-webhook-url")
+	digestMinStarDelta := flags.Int("digest-min-star-delta", 10, "Min change in stars for a repo to be listed as a star mover in digests")
+	forcedHydrationQueueSize := flags.Int("forced-hydration-queue-size", 64, "Max requests waiting on a forced hydration after a cache miss, requests past it are answered 503 with Retry-After")
+	forcedHydrationTimeout := flags.Duration("forced-hydration-timeout", 10*time.Second, "Max time a request waits on a forced hydration after a cache miss before it's answered 503 with Retry-After")
+	hydrateContributors := flags.Bool("hydrate-contributors", false, "Cache the contributors of every repo, costs a request per repo every --contributors-ttl")
+	contributorsTTL := flags.Duration("contributors-ttl", time.Hour, "Refresh interval of the cached repo contributors")
+	contributorsConcurrency := flags.Int("contributors-concurrency", 8, "Max repos whose contributors are fetched at once")
+	releaseReposList := flags.String("release-repos", "", "Comma separated repos whose latest release is cached, \"*\" for every repo (a request per repo every --releases-ttl), empty disables release caching")
+	releasesTTL := flags.Duration("releases-ttl", DEFAULT_CACHE_TTL, "Refresh interval of the cached repo releases")
+	quotaReserve := flags.Int("quota-reserve", 100, "Remaining GitHub API quota at which outbound requests start being paced to spread what's left over the rest of the rate limit window, so the quota never runs out. 0 disables pacing")
+	quotaMaxWait := flags.Duration("quota-max-wait", 30*time.Second, "Max time a proxied request is queued waiting for quota while requests are paced, before it's answered 429 with Retry-After")
+	incrementalRepoSync := flags.Bool("incremental-repo-sync", false, "Only fetch repos updated since the last sync and merge them into the cached repos, with a full sync every --repos-full-sync-interval. Repo pages are requested conditionally, unchanged pages don't cost quota")
+	reposFullSyncInterval := flags.Duration("repos-full-sync-interval", 6*time.Hour, "How often every repo is re-fetched when --incremental-repo-sync is set, picking up deleted, transferred, or privated repos")
+	debugPort := flags.Int("debug-port", 0, "Port for a separate listener serving net/http/pprof profiles and expvar runtime stats, 0 disables it. Keep it off public networks")
+	logLevelName := flags.String("log-level", "info", "Level logs start at (debug, info, warn, error), can be changed at runtime through /admin/loglevel")
+	logFormat := flags.String("log-format", "json", "Format logs are written in, json or console (human readable)")
+	logFile := flags.String("log-file", "", "File logs are written to instead of stderr, rotated once it reaches --log-file-max-bytes")
+	logFileMaxBytes := flags.Int("log-file-max-bytes", 100*1024*1024, "Size in bytes --log-file is rotated at")
+	logFileMaxBackups := flags.Int("log-file-max-backups", 5, "Number of rotated log files kept, older ones are deleted")
+	accessLogSampleRate := flags.Float64("access-log-sample-rate", 0, "Fraction of requests written to the access log (0 to 1), server errors are always logged while it's enabled. 0 disables access logging")
+	proxyAuth := flags.String("proxy-auth", "service", "How proxied requests are authenticated to GitHub: service (always the service token), caller (the caller's Authorization header when sent, otherwise the service token), or caller-only (requests without an Authorization header are rejected)")
+	proxyNegativeCacheTTL := flags.Duration("proxy-negative-cache-ttl", 30*time.Second, "How long a 404 from GitHub for a proxied GET is cached and replayed without a request upstream, so clients polling a missing path don't use up quota. 0 disables negative caching")
+	gitHubApiVersion := flags.String("github-api-version", "2022-11-28", "GitHub REST API version sent as X-GitHub-Api-Version on every request, and on proxied requests that don't set one. Empty leaves it to GitHub's default")
+	gitHubAccept := flags.String("github-accept", "application/vnd.github+json", "Accept header sent on every request, and on proxied requests that don't ask for a specific GitHub media type")
+	validate := flags.Bool("validate", false, "Run a single hydration, print a summary of it (dataset counts, repos missing fields, duration, quota consumed) and exit, non-zero if it failed. Nothing is served or persisted, useful in CI to check the token and org configuration")
+	extraViewsList := flags.String("extra-views", "", "Comma separated extra repo views to compute and serve at /view/{direction}/{n}/{view}: size, watchers, created (newest created first)")
+	strictHydration := flags.Bool("strict-hydration", false, "Fail the whole hydration when a repo is missing a field views need, or when any of the org, members, and repos fails to be fetched, instead of leaving the repo out of the views and listing it as skipped in the sync report, and publishing the datasets that were fetched")
+	seedFile := flags.String("seed-file", "", "Cache export (as from /admin/cache/export, zstd, gzip, or plain JSON) loaded at startup when there's no snapshot, so the cache serves data before the first GitHub hydration finishes")
+	seedUrl := flags.String("seed-url", "", "Like --seed-file, but the export is downloaded from a URL, e.g. a presigned S3 or GCS object URL")
+	statsdAddress := flags.String("statsd-address", "", "UDP host:port of a StatsD / DogStatsD server metrics are pushed to, for environments without Prometheus scraping, empty disables pushing")
+	statsdPrefix := flags.String("statsd-prefix", "github_cache", "Prefix of metric names pushed to --statsd-address, empty for none")
+	statsdFlavor := flags.String("statsd-flavor", "statsd", "Line protocol metrics are pushed with, statsd (labels folded into metric names) or dogstatsd (labels sent as tags)")
+	statsdInterval := flags.Duration("statsd-interval", 10*time.Second, "How often metrics are pushed to --statsd-address")
+	readTimeout := flags.Duration("read-timeout", 15*time.Second, "Max time to read a request, including its body, 0 for no limit")
+	writeTimeout := flags.Duration("write-timeout", time.Minute, "Max time to write a response, from the end of reading the request headers, 0 for no limit. Streams (/events, /ws) aren't limited")
+	idleTimeout := flags.Duration("idle-timeout", 2*time.Minute, "How long idle keep-alive connections are kept open, 0 to fall back to --read-timeout")
+	maxHeaderBytes := flags.Int("max-header-bytes", http.DefaultMaxHeaderBytes, "Max size of request headers in bytes, including the request line")
+	reposHandlerTimeout := flags.Duration("repos-handler-timeout", 15*time.Second, "Max time /orgs/Netflix/repos is given to respond before it's answered 503, 0 for no limit")
+	proxyHandlerTimeout := flags.Duration("proxy-handler-timeout", 45*time.Second, "Max time proxied requests are given to respond, including waiting for quota, before they're answered 503 (or 504 once sent to GitHub), 0 for no limit")
+	hydrateCommitActivity := flags.Bool("hydrate-commit-activity", false, "Cache the weekly commit activity of every repo over the last year, costs at least a request per repo every --commit-activity-ttl")
+	commitActivityTTL := flags.Duration("commit-activity-ttl", 6*time.Hour, "Refresh interval of the cached repo commit activity")
+	adminAllowedCidrs := flags.String("admin-allowed-cidrs", "", "Comma separated CIDRs (or single addresses) admin and debug routes can be reached from, e.g. 10.0.0.0/8,127.0.0.1, empty allows every address with --admin-user, and only loopback addresses without it")
+	adminUser := flags.String("admin-user", "", "Basic auth username required on admin and debug routes, along with the ADMIN_PASSWORD environment variable, empty disables basic auth")
+	anonymousFallback := flags.Bool("anonymous-fallback", false, "Make GitHub requests anonymously while GITHUB_API_TOKEN is rejected (401), so public data keeps syncing under the lower anonymous rate limit. The token is retried every 5 minutes")
+	gitHubTokenFile := flags.String("github-token-file", "", "File the GitHub API token is read from instead of GITHUB_API_TOKEN, e.g. a mounted Kubernetes secret. The file is re-read when it changes, so rotated tokens are picked up without a restart")
+	gitHubProxyUrl := flags.String("github-proxy", "", "Proxy URL GitHub requests are sent through (http, https, or socks5), e.g. http://proxy.corp:3128. Defaults to the HTTPS_PROXY / HTTP_PROXY / NO_PROXY environment variables")
+	gitHubCAFile := flags.String("github-ca-file", "", "PEM bundle of extra root CAs GitHub's certificate is verified against, along with the system roots, e.g. for a TLS intercepting proxy or GitHub Enterprise with an internal CA")
+	gitHubInsecureSkipVerify := flags.Bool("github-insecure-skip-verify", false, "Don't verify GitHub's certificate. Discouraged, anyone on the network path can read and tamper with requests including the token, prefer --github-ca-file")
+	maxViewN := flags.Int("max-view-n", 1000, "Largest N view endpoints accept, larger N are rejected with 400 instead of producing huge responses. 0 doesn't limit N")
+	defaultViewN := flags.Int("default-view-n", 10, "N view endpoints respond with when the request doesn't set N, e.g. /view/bottom/forks without ?n=")
+	refreshOnMutationDelay := flags.Duration("refresh-on-mutation-delay", 2*time.Second, "How long after a successful write (POST, PUT, PATCH, DELETE) proxied to a path under the Netflix org to re-hydrate the dataset it changed, writes in the meantime are coalesced into the same re-hydration. 0 disables refreshing on writes")
+	enableChaos := flags.Bool("enable-chaos", false, "Test only: allow the --chaos-* flags to inject faults into requests to GitHub. Never set it in production, the service logs an error at startup while it's set")
+	chaosLatency := flags.Duration("chaos-latency", 0, "Test only: latency added to every request to GitHub, to exercise timeouts and stale serving in staging. Needs --enable-chaos, 0 disables it")
+	chaosErrorRate := flags.Float64("chaos-error-rate", 0, "Test only: fraction of requests to GitHub (0 to 1) answered with an injected 500 instead of being sent. Needs --enable-chaos, 0 disables it")
+	chaosRateLimitRate := flags.Float64("chaos-rate-limit-rate", 0, "Test only: fraction of requests to GitHub (0 to 1) answered with an injected 403 with an exhausted rate limit, resetting a minute later, instead of being sent. Needs --enable-chaos, 0 disables it")
+	hydrateTeams := flags.Bool("hydrate-teams", false, "Cache the org's visible teams and the repos of each team, costs at least a request per team every --teams-ttl. Needs a token that can list the org's teams")
+	teamsTTL := flags.Duration("teams-ttl", time.Hour, "Refresh interval of the cached teams and team repos")
+	hydrateIssueCounts := flags.Bool("hydrate-issue-counts", false, "Cache the open issue and pull request counts of every repo from the search API, costs two search requests per repo every --issue-counts-ttl")
+	issueCountsTTL := flags.Duration("issue-counts-ttl", 6*time.Hour, "Refresh interval of the cached repo issue and pull request counts")
+	issueCountsConcurrency := flags.Int("issue-counts-concurrency", 2, "Max number of repos whose issue and pull request counts are searched for at once, GitHub's secondary rate limits discourage concurrent searches")
+	upstreamDeadline := flags.Duration("upstream-deadline", 30*time.Second, "Max time calls to GitHub made on behalf of a request may take, a proxied request (including waiting for quota) or a forced hydration on a cache miss, before they're abandoned. 0 for no limit")
+	disableHttp2 := flags.Bool("disable-http2", false, "Serve only HTTP/1.1 on the HTTPS listener, instead of HTTP/2 to clients that negotiate it")
+	http2MaxConcurrentStreams := flags.Int("http2-max-concurrent-streams", 250, "Max requests a single HTTP/2 client connection may have in flight at once")
+	disableKeepAlives := flags.Bool("disable-keep-alives", false, "Close HTTP/1.1 connections after every response instead of keeping them open for reuse")
+	tcpKeepAlivePeriod := flags.Duration("tcp-keep-alive-period", 15*time.Second, "Interval of TCP keep-alive probes on accepted connections, so dead peers are noticed, negative to disable them")
+	authMode := flags.String("auth-mode", "none", "How inbound requests are authorized: none, static-keys (an API key from the AUTH_API_KEYS environment variable), jwt (a JWT signed by a key from --auth-jwks-url), or oidc (a token issued by --auth-oidc-issuer)")
+	authJwksUrl := flags.String("auth-jwks-url", "", "URL of the JSON Web Key Set JWTs must be signed by a key of, required with --auth-mode=jwt")
+	authJwtIssuer := flags.String("auth-jwt-issuer", "", "Issuer (iss) JWTs must have, empty accepts any issuer")
+	authJwtAudience := flags.String("auth-jwt-audience", "", "Audience (aud) JWTs must be intended for, empty accepts any audience")
+	authOidcIssuer := flags.String("auth-oidc-issuer", "", "OIDC issuer URL, whose discovery document points at its JWKS, bearer tokens must be issued by, required with --auth-mode=oidc")
+	authLogClaimsList := flags.String("auth-log-claims", "sub,email", "Comma separated claims of validated bearer tokens logged in the access log, with --auth-mode=jwt or oidc")
+	auditLog := flags.String("audit-log", "", "Where every proxied write request (anything but GET, HEAD, OPTIONS) is recorded, with its caller and response status: stdout, or a file rotated like --log-file. Empty disables the audit log")
+	hydrateReadmes := flags.Bool("hydrate-readmes", false, "Cache the README of every repo, costs a request per repo every --readmes-ttl")
+	readmesTTL := flags.Duration("readmes-ttl", 24*time.Hour, "Refresh interval of the cached repo READMEs, which rarely change")
+	readmesConcurrency := flags.Int("readmes-concurrency", 4, "Max number of repos whose README is fetched at once")
+	hydrateLanguages := flags.Bool("hydrate-languages", false, "Cache the bytes of code per language of every repo, and their totals across the org, costs a request per repo every --languages-ttl")
+	languagesTTL := flags.Duration("languages-ttl", 6*time.Hour, "Refresh interval of the cached repo language statistics")
+	languagesConcurrency := flags.Int("languages-concurrency", 8, "Max number of repos whose language statistics are fetched at once")
+	githubPageSize := flags.Int("github-page-size", 100, "Number of items requested per page of paginated GitHub API requests, at most 100. Some GitHub Enterprise instances cap pages lower")
+	githubPagination := flags.String("github-pagination", "page", "How paginated GitHub API requests find their next page: page (increments ?page= until an empty page) or link (follows the Link header's next page, saving the request for the empty page)")
+	removedReposRetention := flags.Duration("removed-repos-retention", 30*24*time.Hour, "How long repos that disappeared from the org between syncs are listed on /view/removed")
+	extraEndpointsList := flags.String("extra-endpoints", "", "Comma separated GitHub GET paths (e.g. /orgs/Netflix/events) cached verbatim every --extra-endpoints-ttl and served at the same paths, empty disables them")
+	extraEndpointsTTL := flags.Duration("extra-endpoints-ttl", 15*time.Minute, "Refresh interval of the cached extra endpoints")
+	alertSlackWebhookUrl := flags.String("alert-slack-webhook-url", "", "Slack incoming webhook alerts (persistent sync failures, rate limit backoff, proxy errors) are posted to, empty disables Slack alerts")
+	alertSyncFailures := flags.Int("alert-sync-failures", 3, "Consecutive failed syncs of a dataset before an alert is raised")
+	alertCooldown := flags.Duration("alert-cooldown", 30*time.Minute, "How long the same alert is muted after being raised, so a persistent failure doesn't flood the alert channels")
+	disableCacheControl := flags.Bool("disable-cache-control", false, "Don't send Cache-Control on cached responses. By default they're cacheable until the next sync of their dataset, so a CDN in front of the service can absorb reads between hydrations")
+	adminPort := flags.Int("admin-port", 0, "Port for a separate listener serving the admin, metrics, cache status, and debug (pprof, expvar) routes, which are then no longer served on port and tls-port. 0 serves them on the API ports")
+	proxyMaxBodyBytes := flags.Int64("proxy-max-body-bytes", 10<<20, "Max size in bytes of a request body forwarded to GitHub, larger requests are answered 413")
+	proxyMethodsList := flags.String("proxy-methods", "GET,HEAD,OPTIONS,POST,PUT,PATCH,DELETE", "Comma separated HTTP methods forwarded to GitHub, requests with other methods are answered 405. e.g. GET,HEAD,OPTIONS for a read only proxy")
+	proxyStripHeadersList := flags.String("proxy-strip-headers", "", "Comma separated request headers removed before forwarding to GitHub, on top of cookies, proxy credentials, the service's API key, and forwarding headers, which are always removed")
+	gitHubUserAgent := flags.String("github-user-agent", "github-api-read-cache-service", "User-Agent sent on every request to GitHub, proxied requests included. GitHub asks for one that identifies the app, e.g. with a contact")
+	gitHubHeaders := http.Header{}
+	flags.Func("github-header", "Header set on every request to GitHub, proxied requests included, as 'Name: value', overriding the service's and the caller's. Can be repeated, e.g. for GitHub Enterprise headers or to override Accept", func(value string) error {
+		return parseHeader(gitHubHeaders, value)
+	})
+	skipUnchangedSyncs := flags.Bool("skip-unchanged-syncs", false, "Before re-fetching members or repos, conditionally poll the org's public events, and skip the sync if there are none since the last one. The poll doesn't cost quota when nothing changed, see --skip-unchanged-max-age")
+	skipUnchangedMaxAge := flags.Duration("skip-unchanged-max-age", 6*time.Hour, "How long members and repos can go without a sync when --skip-unchanged-syncs is set, picking up changes that don't show up as public events, e.g. stars or private members")
+	consistencyCheck := flags.Bool("consistency-check", false, "Every --consistency-check-interval, fetch a sample of the cached repos live from GitHub and compare their key fields, reporting drift in the logs and metrics. Useful to catch bugs in --incremental-repo-sync or --skip-unchanged-syncs")
+	consistencyCheckInterval := flags.Duration("consistency-check-interval", time.Hour, "How often --consistency-check compares a sample of the cached repos against GitHub")
+	consistencyCheckSample := flags.Int("consistency-check-sample", 5, "How many cached repos --consistency-check fetches from GitHub per check, each costs a request")
+	once := flags.Bool("once", false, "Run a single hydration, write it to --snapshot-file (uploading it to --snapshot-replica-url) if set, and exit, non-zero if either failed. Nothing is served, useful as a scheduled job feeding a static file server")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
 
 	// github api key is optional
 	githubApiKey := os.Getenv("GITHUB_API_TOKEN")
+
+	if *gitHubTokenFile != "" {
+		if githubApiKey != "" {
+			flags.Usage()
+			return nil, errors.New("only one of GITHUB_API_TOKEN and github-token-file can be set")
+		}
+
+		token, err := ReadTokenFile(*gitHubTokenFile)
+		if err != nil {
+			flags.Usage()
+			return nil, fmt.Errorf("github-token-file is invalid: %w", err)
+		}
+		githubApiKey = token
+	}
+
 	if len(githubApiKey) == 0 {
 		logger.Warn("No GITHUB_API_TOKEN envirnment variable found, may be subject to rate limits")
 	}
 
-	if *port == 0 {
-		flag.Usage()
+	// validation and once mode don't serve anything
+	requirePort = requirePort && !*validate && !*once
+
+	if requirePort && *port == 0 {
+		flags.Usage()
 		return nil, errors.New("--port is required")
 	}
 
-	if *port <= 0 || *port > 66535 {
-		flag.Usage()
+	if requirePort && (*port <= 0 || *port > 66535) {
+		flags.Usage()
 		return nil, errors.New("port must be in valid range (1 to 66535) inclusive")
 	}
 
-	// default cache ttl is 10 minutes
-	cacheTtl := 10 * time.Minute
+	if *tlsPort != 0 && (*tlsPort < 0 || *tlsPort > 66535 || *tlsPort == *port) {
+		flags.Usage()
+		return nil, errors.New("tls-port must be in valid range (1 to 66535) inclusive, and differ from port")
+	}
+
+	if *tlsPort != 0 && (*tlsCertFile == "" || *tlsKeyFile == "") {
+		flags.Usage()
+		return nil, errors.New("tls-port requires tls-cert-file and tls-key-file")
+	}
+
+	if *waitTimeout <= 0 {
+		flags.Usage()
+		return nil, errors.New("wait-for-cache-timeout must be positive")
+	}
+
+	if *orgTTL <= 0 || *membersTTL <= 0 || *reposTTL <= 0 {
+		flags.Usage()
+		return nil, errors.New("org-ttl, members-ttl, and repos-ttl must be positive")
+	}
+
+	if *shutdownTimeout <= 0 {
+		flags.Usage()
+		return nil, errors.New("shutdown-timeout must be positive")
+	}
+
+	if *probeCacheTTL < 0 {
+		flags.Usage()
+		return nil, errors.New("probe-cache-ttl must not be negative")
+	}
+
+	if *snapshotReplicaUrl != "" && *snapshotFile == "" {
+		flags.Usage()
+		return nil, errors.New("snapshot-replica-url requires snapshot-file")
+	}
+
+	if *snapshotReplicaUrl != "" {
+		if replicaUrl, err := url.Parse(*snapshotReplicaUrl); err != nil || (replicaUrl.Scheme != "http" && replicaUrl.Scheme != "https") {
+			flags.Usage()
+			return nil, errors.New("snapshot-replica-url must be an http(s) URL")
+		}
+	}
+
+	var snapshotBucket replication.Bucket
+	if *snapshotBucketUrl != "" {
+		bucketUrl, err := url.Parse(*snapshotBucketUrl)
+		if err != nil || (bucketUrl.Scheme != replication.BUCKET_PROVIDER_S3 && bucketUrl.Scheme != replication.BUCKET_PROVIDER_GCS) || bucketUrl.Host == "" || strings.Trim(bucketUrl.Path, "/") != "" {
+			flags.Usage()
+			return nil, errors.New("snapshot-bucket must be an s3://bucket or gs://bucket URL, set the prefix with --snapshot-bucket-prefix")
+		}
+
+		if *snapshotFile == "" {
+			flags.Usage()
+			return nil, errors.New("snapshot-bucket requires snapshot-file")
+		}
+
+		if *snapshotReplicaUrl != "" {
+			flags.Usage()
+			return nil, errors.New("snapshot-bucket and snapshot-replica-url are mutually exclusive")
+		}
+
+		snapshotBucket = replication.Bucket{
+			Provider:        bucketUrl.Scheme,
+			Name:            bucketUrl.Host,
+			Prefix:          strings.Trim(*snapshotBucketPrefix, "/"),
+			Region:          *snapshotBucketRegion,
+			Endpoint:        *snapshotBucketEndpoint,
+			AccessKeyId:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}
+
+		if snapshotBucket.AccessKeyId == "" || snapshotBucket.SecretAccessKey == "" {
+			flags.Usage()
+			return nil, errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables are required with --snapshot-bucket")
+		}
+
+		if snapshotBucket.Provider == replication.BUCKET_PROVIDER_S3 && snapshotBucket.Region == "" {
+			flags.Usage()
+			return nil, errors.New("snapshot-bucket-region is required for S3 buckets")
+		}
+
+		if _, err := replication.NewBucketReplicator(snapshotBucket); err != nil {
+			flags.Usage()
+			return nil, fmt.Errorf("snapshot-bucket-endpoint must be an http(s) URL: %w", err)
+		}
+	}
+
+	zstdLevel, ok := compression.ParseZstdLevel(*zstdLevelName)
+	if !ok {
+		flags.Usage()
+		return nil, errors.New("zstd-level must be one of fastest, default, better, best")
+	}
+
+	precomputedViewSizes, err := parseSizeList(*precomputedViewSizesList)
+	if err != nil {
+		flags.Usage()
+		return nil, fmt.Errorf("precomputed-view-sizes: %w", err)
+	}
+
+	if *maxCacheBytes < 0 {
+		flags.Usage()
+		return nil, errors.New("max-cache-bytes must not be negative")
+	}
+
+	if *memoryLimitAction != "warn" && *memoryLimitAction != "trim" {
+		flags.Usage()
+		return nil, errors.New("memory-limit-action must be warn or trim")
+	}
+
+	if *digestWebhookUrl != "" {
+		if webhookUrl, err := url.Parse(*digestWebhookUrl); err != nil || (webhookUrl.Scheme != "http" && webhookUrl.Scheme != "https") {
+			flags.Usage()
+			return nil, errors.New("digest-webhook-url must be an http(s) URL")
+		}
+	}
+
+	if *digestInterval <= 0 || *digestMinStarDelta <= 0 {
+		flags.Usage()
+		return nil, errors.New("digest-interval and digest-min-star-delta must be positive")
+	}
+
+	if *forcedHydrationQueueSize <= 0 || *forcedHydrationTimeout <= 0 {
+		flags.Usage()
+		return nil, errors.New("forced-hydration-queue-size and forced-hydration-timeout must be positive")
+	}
+
+	if *contributorsTTL <= 0 || *contributorsConcurrency <= 0 {
+		flags.Usage()
+		return nil, errors.New("contributors-ttl and contributors-concurrency must be positive")
+	}
+
+	if *releasesTTL <= 0 {
+		flags.Usage()
+		return nil, errors.New("releases-ttl must be positive")
+	}
+
+	var releaseRepos []string
+	for _, repo := range strings.Split(*releaseReposList, ",") {
+		repo = strings.TrimPrefix(strings.TrimSpace(repo), "Netflix/")
+		if repo != "" && !slices.Contains(releaseRepos, repo) {
+			releaseRepos = append(releaseRepos, repo)
+		}
+	}
+
+	if *quotaReserve < 0 || *quotaMaxWait <= 0 {
+		flags.Usage()
+		return nil, errors.New("quota-reserve can't be negative and quota-max-wait must be positive")
+	}
+
+	if *reposFullSyncInterval <= 0 {
+		flags.Usage()
+		return nil, errors.New("repos-full-sync-interval must be positive")
+	}
+
+	if *debugPort != 0 && (*debugPort < 0 || *debugPort > 66535 || *debugPort == *port || *debugPort == *tlsPort) {
+		flags.Usage()
+		return nil, errors.New("debug-port must be in valid range (1 to 66535) inclusive, and differ from port and tls-port")
+	}
+
+	logLevel, err := zapcore.ParseLevel(*logLevelName)
+	if err != nil {
+		flags.Usage()
+		return nil, errors.New("log-level must be one of debug, info, warn, error")
+	}
+
+	if *logFormat != "json" && *logFormat != "console" {
+		flags.Usage()
+		return nil, errors.New("log-format must be json or console")
+	}
+
+	if *logFileMaxBytes <= 0 || *logFileMaxBackups < 0 {
+		flags.Usage()
+		return nil, errors.New("log-file-max-bytes must be positive and log-file-max-backups can't be negative")
+	}
+
+	if *accessLogSampleRate < 0 || *accessLogSampleRate > 1 {
+		flags.Usage()
+		return nil, errors.New("access-log-sample-rate must be between 0 and 1")
+	}
+
+	if *proxyAuth != "service" && *proxyAuth != "caller" && *proxyAuth != "caller-only" {
+		flags.Usage()
+		return nil, errors.New("proxy-auth must be one of service, caller, caller-only")
+	}
+
+	if *proxyNegativeCacheTTL < 0 {
+		flags.Usage()
+		return nil, errors.New("proxy-negative-cache-ttl can't be negative")
+	}
+
+	if strings.TrimSpace(*gitHubAccept) == "" {
+		flags.Usage()
+		return nil, errors.New("github-accept can't be empty")
+	}
+
+	var extraViews []string
+	for _, view := range strings.Split(*extraViewsList, ",") {
+		view = strings.TrimSpace(view)
+		if view == "" || slices.Contains(extraViews, view) {
+			continue
+		}
+
+		if view != "size" && view != "watchers" && view != "created" {
+			flags.Usage()
+			return nil, fmt.Errorf("unknown extra view %q, must be one of size, watchers, created", view)
+		}
+
+		extraViews = append(extraViews, view)
+	}
+
+	if *seedFile != "" && *seedUrl != "" {
+		flags.Usage()
+		return nil, errors.New("only one of seed-file and seed-url can be set")
+	}
+
+	if *seedUrl != "" {
+		if parsed, err := url.Parse(*seedUrl); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			flags.Usage()
+			return nil, errors.New("seed-url must be an http or https URL")
+		}
+	}
+
+	if *statsdAddress != "" {
+		if _, _, err := net.SplitHostPort(*statsdAddress); err != nil {
+			flags.Usage()
+			return nil, errors.New("statsd-address must be host:port")
+		}
+	}
+
+	if *statsdFlavor != "statsd" && *statsdFlavor != "dogstatsd" {
+		flags.Usage()
+		return nil, errors.New("statsd-flavor must be statsd or dogstatsd")
+	}
+
+	if *statsdInterval <= 0 {
+		flags.Usage()
+		return nil, errors.New("statsd-interval must be positive")
+	}
+
+	if *readTimeout < 0 || *writeTimeout < 0 || *idleTimeout < 0 {
+		flags.Usage()
+		return nil, errors.New("read-timeout, write-timeout, and idle-timeout can't be negative")
+	}
+
+	if *maxHeaderBytes <= 0 {
+		flags.Usage()
+		return nil, errors.New("max-header-bytes must be positive")
+	}
+
+	if *reposHandlerTimeout < 0 || *proxyHandlerTimeout < 0 {
+		flags.Usage()
+		return nil, errors.New("repos-handler-timeout and proxy-handler-timeout can't be negative")
+	}
+
+	// past the write timeout the connection is closed, so the handler's 503 would never reach the client
+	if *writeTimeout > 0 && (*reposHandlerTimeout >= *writeTimeout || *proxyHandlerTimeout >= *writeTimeout) {
+		flags.Usage()
+		return nil, errors.New("repos-handler-timeout and proxy-handler-timeout must be shorter than write-timeout")
+	}
+
+	if *commitActivityTTL <= 0 {
+		flags.Usage()
+		return nil, errors.New("commit-activity-ttl must be positive")
+	}
+
+	adminAllowedNetworks, err := parsePrefixList(*adminAllowedCidrs)
+	if err != nil {
+		flags.Usage()
+		return nil, fmt.Errorf("admin-allowed-cidrs is invalid: %w", err)
+	}
+
+	// the password is a secret, so it's only read from the environment, like the GitHub token
+	adminPassword := os.Getenv("ADMIN_PASSWORD")
+	if *adminUser != "" && adminPassword == "" {
+		flags.Usage()
+		return nil, errors.New("ADMIN_PASSWORD environment variable is required with --admin-user")
+	}
+
+	// likewise the PagerDuty routing key
+	alertPagerDutyKey := os.Getenv("PAGERDUTY_ROUTING_KEY")
+
+	var gitHubProxy *url.URL
+	if *gitHubProxyUrl != "" {
+		parsed, err := url.Parse(*gitHubProxyUrl)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https" && parsed.Scheme != "socks5") || parsed.Host == "" {
+			flags.Usage()
+			return nil, errors.New("github-proxy must be an http, https, or socks5 URL")
+		}
+		gitHubProxy = parsed
+	}
+
+	gitHubRootCAs, err := loadCertPool(*gitHubCAFile)
+	if err != nil {
+		flags.Usage()
+		return nil, fmt.Errorf("github-ca-file is invalid: %w", err)
+	}
+
+	if *gitHubInsecureSkipVerify {
+		logger.Warn("GitHub's certificate isn't verified (--github-insecure-skip-verify), requests and the token can be intercepted")
+	}
+
+	if *maxViewN < 0 {
+		flags.Usage()
+		return nil, errors.New("max-view-n can't be negative")
+	}
+
+	if *defaultViewN <= 0 || (*maxViewN > 0 && *defaultViewN > *maxViewN) {
+		flags.Usage()
+		return nil, errors.New("default-view-n must be positive, and at most max-view-n")
+	}
+
+	if *refreshOnMutationDelay < 0 {
+		flags.Usage()
+		return nil, errors.New("refresh-on-mutation-delay can't be negative")
+	}
+
+	if *chaosLatency < 0 {
+		flags.Usage()
+		return nil, errors.New("chaos-latency can't be negative")
+	}
+
+	if *chaosErrorRate < 0 || *chaosErrorRate > 1 {
+		flags.Usage()
+		return nil, errors.New("chaos-error-rate must be between 0 and 1")
+	}
+
+	if *chaosRateLimitRate < 0 || *chaosRateLimitRate > 1 {
+		flags.Usage()
+		return nil, errors.New("chaos-rate-limit-rate must be between 0 and 1")
+	}
+
+	if *chaosErrorRate+*chaosRateLimitRate > 1 {
+		flags.Usage()
+		return nil, errors.New("chaos-error-rate and chaos-rate-limit-rate can't add up to more than 1")
+	}
+
+	// faults are only injected when asked for twice, so a stray chaos flag can't degrade production
+	if !*enableChaos && (*chaosLatency > 0 || *chaosErrorRate > 0 || *chaosRateLimitRate > 0) {
+		flags.Usage()
+		return nil, errors.New("chaos-latency, chaos-error-rate, and chaos-rate-limit-rate need --enable-chaos")
+	}
+
+	// a validation run with injected faults says nothing about the token or org configuration
+	if *enableChaos && *validate {
+		flags.Usage()
+		return nil, errors.New("enable-chaos can't be used with validate")
+	}
+
+	if *teamsTTL <= 0 {
+		flags.Usage()
+		return nil, errors.New("teams-ttl must be positive")
+	}
+
+	if *issueCountsTTL <= 0 {
+		flags.Usage()
+		return nil, errors.New("issue-counts-ttl must be positive")
+	}
+
+	if *issueCountsConcurrency <= 0 {
+		flags.Usage()
+		return nil, errors.New("issue-counts-concurrency must be positive")
+	}
+
+	if *upstreamDeadline < 0 {
+		flags.Usage()
+		return nil, errors.New("upstream-deadline can't be negative")
+	}
+
+	if *http2MaxConcurrentStreams <= 0 {
+		flags.Usage()
+		return nil, errors.New("http2-max-concurrent-streams must be positive")
+	}
+
+	if *authMode != "none" && *authMode != "static-keys" && *authMode != "jwt" && *authMode != "oidc" {
+		flags.Usage()
+		return nil, errors.New("auth-mode must be one of none, static-keys, jwt, oidc")
+	}
+
+	// API keys are secrets, so they're only read from the environment, like the admin password
+	var authApiKeys []string
+	for _, key := range strings.Split(os.Getenv("AUTH_API_KEYS"), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			authApiKeys = append(authApiKeys, key)
+		}
+	}
+
+	if *authMode == "static-keys" && len(authApiKeys) == 0 {
+		flags.Usage()
+		return nil, errors.New("AUTH_API_KEYS environment variable is required with --auth-mode=static-keys")
+	}
+
+	if *authMode == "jwt" {
+		parsed, err := url.Parse(*authJwksUrl)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			flags.Usage()
+			return nil, errors.New("auth-jwks-url must be an http or https URL with --auth-mode=jwt")
+		}
+	}
+
+	if *authMode == "oidc" {
+		parsed, err := url.Parse(*authOidcIssuer)
+		if err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+			flags.Usage()
+			return nil, errors.New("auth-oidc-issuer must be an https URL with --auth-mode=oidc")
+		}
+
+		// without an audience, a token the SSO issued for any other app would be accepted
+		if *authJwtAudience == "" {
+			flags.Usage()
+			return nil, errors.New("auth-jwt-audience is required with --auth-mode=oidc")
+		}
+	}
+
+	// the authorizer consumes the caller's Authorization header, so it can't also be their GitHub credential
+	if *authMode != "none" && *proxyAuth != "service" {
+		flags.Usage()
+		return nil, errors.New("proxy-auth must be service with --auth-mode=" + *authMode)
+	}
+
+	var authLogClaims []string
+	for _, claim := range strings.Split(*authLogClaimsList, ",") {
+		if claim = strings.TrimSpace(claim); claim != "" {
+			authLogClaims = append(authLogClaims, claim)
+		}
+	}
+
+	if *readmesTTL <= 0 {
+		flags.Usage()
+		return nil, errors.New("readmes-ttl must be positive")
+	}
+
+	if *readmesConcurrency <= 0 {
+		flags.Usage()
+		return nil, errors.New("readmes-concurrency must be positive")
+	}
+
+	if *languagesTTL <= 0 {
+		flags.Usage()
+		return nil, errors.New("languages-ttl must be positive")
+	}
+
+	if *languagesConcurrency <= 0 {
+		flags.Usage()
+		return nil, errors.New("languages-concurrency must be positive")
+	}
+
+	if *githubPageSize <= 0 || *githubPageSize > 100 {
+		flags.Usage()
+		return nil, errors.New("github-page-size must be between 1 and 100")
+	}
+
+	if *githubPagination != "page" && *githubPagination != "link" {
+		flags.Usage()
+		return nil, errors.New("github-pagination must be one of page, link")
+	}
+
+	if *removedReposRetention <= 0 {
+		flags.Usage()
+		return nil, errors.New("removed-repos-retention must be positive")
+	}
+
+	extraEndpoints, err := parsePathList(*extraEndpointsList)
+	if err != nil {
+		flags.Usage()
+		return nil, fmt.Errorf("extra-endpoints: %w", err)
+	}
+
+	if *extraEndpointsTTL <= 0 {
+		flags.Usage()
+		return nil, errors.New("extra-endpoints-ttl must be positive")
+	}
+
+	if *alertSlackWebhookUrl != "" {
+		if webhookUrl, err := url.Parse(*alertSlackWebhookUrl); err != nil || (webhookUrl.Scheme != "http" && webhookUrl.Scheme != "https") {
+			flags.Usage()
+			return nil, errors.New("alert-slack-webhook-url must be an http(s) URL")
+		}
+	}
+
+	if *alertSyncFailures <= 0 {
+		flags.Usage()
+		return nil, errors.New("alert-sync-failures must be positive")
+	}
+
+	if *alertCooldown < 0 {
+		flags.Usage()
+		return nil, errors.New("alert-cooldown can't be negative")
+	}
+
+	if *adminPort != 0 && (*adminPort < 0 || *adminPort > 66535 || *adminPort == *port || *adminPort == *tlsPort || *adminPort == *debugPort) {
+		flags.Usage()
+		return nil, errors.New("admin-port must be in valid range (1 to 66535) inclusive, and differ from port, tls-port, and debug-port")
+	}
+
+	if *proxyMaxBodyBytes <= 0 {
+		flags.Usage()
+		return nil, errors.New("proxy-max-body-bytes must be positive")
+	}
+
+	proxyMethods, err := parseMethodList(*proxyMethodsList)
+	if err != nil {
+		flags.Usage()
+		return nil, fmt.Errorf("proxy-methods: %w", err)
+	}
+	if len(proxyMethods) == 0 {
+		flags.Usage()
+		return nil, errors.New("proxy-methods must list at least one method")
+	}
+
+	var proxyStripHeaders []string
+	for _, header := range strings.Split(*proxyStripHeadersList, ",") {
+		if header = strings.TrimSpace(header); header != "" {
+			proxyStripHeaders = append(proxyStripHeaders, http.CanonicalHeaderKey(header))
+		}
+	}
+
+	if strings.TrimSpace(*gitHubUserAgent) == "" {
+		flags.Usage()
+		return nil, errors.New("github-user-agent can't be empty")
+	}
+
+	if *skipUnchangedMaxAge <= 0 {
+		flags.Usage()
+		return nil, errors.New("skip-unchanged-max-age must be positive")
+	}
+
+	if *consistencyCheckInterval <= 0 {
+		flags.Usage()
+		return nil, errors.New("consistency-check-interval must be positive")
+	}
+
+	if *consistencyCheckSample < 1 {
+		flags.Usage()
+		return nil, errors.New("consistency-check-sample must be at least 1")
+	}
+
+	if *once && *validate {
+		flags.Usage()
+		return nil, errors.New("once and validate are mutually exclusive")
+	}
+
+	var customRoutes []*customroutes.Route
+	if *customRoutesFile != "" {
+		if customRoutes, err = customroutes.LoadRoutes(*customRoutesFile); err != nil {
+			flags.Usage()
+			return nil, err
+		}
+	}
+
+	cacheTtl := DEFAULT_CACHE_TTL
+
+	return &configuration{
+		cacheTTL:                 cacheTtl,
+		orgTTL:                   *orgTTL,
+		membersTTL:               *membersTTL,
+		reposTTL:                 *reposTTL,
+		shutdownTimeout:          *shutdownTimeout,
+		probeCacheTTL:            *probeCacheTTL,
+		port:                     *port,
+		tlsPort:                  *tlsPort,
+		tlsCertFile:              *tlsCertFile,
+		tlsKeyFile:               *tlsKeyFile,
+		gitHubApiKey:             githubApiKey,
+		disableProxy:             *disableProxy,
+		waitForCache:             *waitForCache,
+		waitForCacheTimeout:      *waitTimeout,
+		bootstrapArchiveFile:     *bootstrapArchiveFile,
+		snapshotFile:             *snapshotFile,
+		snapshotReplicaUrl:       *snapshotReplicaUrl,
+		snapshotBucket:           snapshotBucket,
+		preferFreshestSnapshot:   *preferFreshestSnapshot,
+		zstdLevel:                zstdLevel,
+		precomputedViewSizes:     precomputedViewSizes,
+		customRoutes:             customRoutes,
+		maxCacheBytes:            *maxCacheBytes,
+		memoryLimitAction:        *memoryLimitAction,
+		digestWebhookUrl:         *digestWebhookUrl,
+		digestInterval:           *digestInterval,
+		digestMinStarDelta:       *digestMinStarDelta,
+		forcedHydrationQueueSize: *forcedHydrationQueueSize,
+		forcedHydrationTimeout:   *forcedHydrationTimeout,
+		hydrateContributors:      *hydrateContributors,
+		contributorsTTL:          *contributorsTTL,
+		contributorsConcurrency:  *contributorsConcurrency,
+		releaseRepos:             releaseRepos,
+		releasesTTL:              *releasesTTL,
+		quotaReserve:             *quotaReserve,
+		quotaMaxWait:             *quotaMaxWait,
+		incrementalRepoSync:      *incrementalRepoSync,
+		reposFullSyncInterval:    *reposFullSyncInterval,
+		debugPort:                *debugPort,
+		logLevel:                 logLevel,
+		logFormat:                *logFormat,
+		logFile:                  *logFile,
+		logFileMaxBytes:          *logFileMaxBytes,
+		logFileMaxBackups:        *logFileMaxBackups,
+		accessLogSampleRate:      *accessLogSampleRate,
+		proxyAuth:                *proxyAuth,
+		proxyNegativeCacheTTL:    *proxyNegativeCacheTTL,
+		gitHubApiVersion:         *gitHubApiVersion,
+		gitHubAccept:             *gitHubAccept,
+		validate:                 *validate,
+		extraViews:               extraViews,
+		strictHydration:          *strictHydration,
+		seedFile:                 *seedFile,
+		seedUrl:                  *seedUrl,
+		statsdAddress:            *statsdAddress,
+		statsdPrefix:             *statsdPrefix,
+		statsdFlavor:             *statsdFlavor,
+		statsdInterval:           *statsdInterval,
+		readTimeout:              *readTimeout,
+		writeTimeout:             *writeTimeout,
+		idleTimeout:              *idleTimeout,
+		maxHeaderBytes:           *maxHeaderBytes,
+		reposHandlerTimeout:      *reposHandlerTimeout,
+		proxyHandlerTimeout:      *proxyHandlerTimeout,
+		hydrateCommitActivity:    *hydrateCommitActivity,
+		commitActivityTTL:        *commitActivityTTL,
+		adminAllowedNetworks:     adminAllowedNetworks,
+		adminUser:                *adminUser,
+		adminPassword:            adminPassword,
+		anonymousFallback:        *anonymousFallback,
+		gitHubTokenFile:          *gitHubTokenFile,
+		gitHubProxy:              gitHubProxy,
+		gitHubRootCAs:            gitHubRootCAs,
+		gitHubInsecureSkipVerify: *gitHubInsecureSkipVerify,
+		maxViewN:                 *maxViewN,
+		defaultViewN:             *defaultViewN,
+		refreshOnMutationDelay:   *refreshOnMutationDelay,
+		enableChaos:              *enableChaos,
+		chaosLatency:             *chaosLatency,
+		chaosErrorRate:           *chaosErrorRate,
+		chaosRateLimitRate:       *chaosRateLimitRate,
+		hydrateTeams:             *hydrateTeams,
+		teamsTTL:                 *teamsTTL,
+		hydrateIssueCounts:       *hydrateIssueCounts,
+		issueCountsTTL:           *issueCountsTTL,
+		issueCountsConcurrency:   *issueCountsConcurrency,
+		upstreamDeadline:         *upstreamDeadline,
+		disableHttp2:             *disableHttp2,
+		http2MaxStreams:          *http2MaxConcurrentStreams,
+		disableKeepAlives:        *disableKeepAlives,
+		tcpKeepAlivePeriod:       *tcpKeepAlivePeriod,
+		authMode:                 *authMode,
+		authApiKeys:              authApiKeys,
+		authJwksUrl:              *authJwksUrl,
+		authJwtIssuer:            *authJwtIssuer,
+		authJwtAudience:          *authJwtAudience,
+		authOidcIssuer:           *authOidcIssuer,
+		authLogClaims:            authLogClaims,
+		auditLog:                 *auditLog,
+		hydrateReadmes:           *hydrateReadmes,
+		readmesTTL:               *readmesTTL,
+		readmesConcurrency:       *readmesConcurrency,
+		hydrateLanguages:         *hydrateLanguages,
+		languagesTTL:             *languagesTTL,
+		languagesConcurrency:     *languagesConcurrency,
+		githubPageSize:           *githubPageSize,
+		githubPagination:         *githubPagination,
+		removedReposRetention:    *removedReposRetention,
+		extraEndpoints:           extraEndpoints,
+		extraEndpointsTTL:        *extraEndpointsTTL,
+		alertSlackWebhookUrl:     *alertSlackWebhookUrl,
+		alertPagerDutyKey:        alertPagerDutyKey,
+		alertSyncFailures:        *alertSyncFailures,
+		alertCooldown:            *alertCooldown,
+		disableCacheControl:      *disableCacheControl,
+		adminPort:                *adminPort,
+		proxyMaxBodyBytes:        *proxyMaxBodyBytes,
+		proxyMethods:             proxyMethods,
+		proxyStripHeaders:        proxyStripHeaders,
+		gitHubUserAgent:          *gitHubUserAgent,
+		gitHubHeaders:            gitHubHeaders,
+		skipUnchangedSyncs:       *skipUnchangedSyncs,
+		skipUnchangedMaxAge:      *skipUnchangedMaxAge,
+		consistencyCheck:         *consistencyCheck,
+		consistencyCheckInterval: *consistencyCheckInterval,
+		consistencyCheckSample:   *consistencyCheckSample,
+		once:                     *once,
+	}, nil
+}
+
+// Parse a comma separated list of positive integers, duplicates are dropped
+func parseSizeList(list string) ([]int, error) {
+	var sizes []int
+
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		size, err := strconv.Atoi(field)
+		if err != nil || size <= 0 {
+			return nil, fmt.Errorf("%q is not a positive integer", field)
+		}
+
+		if !slices.Contains(sizes, size) {
+			sizes = append(sizes, size)
+		}
+	}
+
+	return sizes, nil
+}
+
+// Parse a comma separated list of CIDRs, single addresses are treated as networks of just that address
+func parsePrefixList(list string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		if addr, err := netip.ParseAddr(field); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(field)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a CIDR or address", field)
+		}
+
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return prefixes, nil
+}
+
+// Reads a GitHub API token from a file, surrounding whitespace (e.g. a trailing newline) is ignored
+func ReadTokenFile(path string) (string, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	token := strings.TrimSpace(string(contents))
+	if token == "" {
+		return "", fmt.Errorf("%s is empty", path)
+	}
+
+	return token, nil
+}
+
+// Get the system root CAs along with the CAs of a PEM bundle, nil if path is empty
+func loadCertPool(path string) (*x509.CertPool, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	bundle, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	if !pool.AppendCertsFromPEM(bundle) {
+		return nil, fmt.Errorf("%s has no PEM encoded certificates", path)
+	}
+
+	return pool, nil
+}
+
+// Parse a comma separated list of URL paths, duplicates are dropped. Paths can't carry a query or wildcards, as they're
+// matched exactly
+func parsePathList(list string) ([]string, error) {
+	var paths []string
+
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		if !strings.HasPrefix(field, "/") || strings.ContainsAny(field, "?#{} ") {
+			return nil, fmt.Errorf("%q must start with / and can't contain a query, wildcards or spaces", field)
+		}
+
+		if !slices.Contains(paths, field) {
+			paths = append(paths, field)
+		}
+	}
+
+	return paths, nil
+}
+
+// Parse a comma separated list of HTTP methods the proxy can forward, upper-cased, duplicates are dropped
+func parseMethodList(list string) ([]string, error) {
+	var methods []string
+
+	for _, field := range strings.Split(list, ",") {
+		field = strings.ToUpper(strings.TrimSpace(field))
+		if field == "" {
+			continue
+		}
+
+		if !slices.Contains(PROXY_METHODS, field) {
+			return nil, fmt.Errorf("%q is not one of %s", field, strings.Join(PROXY_METHODS, ", "))
+		}
+
+		if !slices.Contains(methods, field) {
+			methods = append(methods, field)
+		}
+	}
+
+	return methods, nil
+}
+
+// Parse a 'Name: value' header into header. Authorization and Host can't be set, they're managed by the service
+func parseHeader(header http.Header, value string) error {
+	name, headerValue, found := strings.Cut(value, ":")
+	name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+
+	if !found || name == "" || strings.ContainsAny(name, " \t") {
+		return fmt.Errorf("%q must be 'Name: value'", value)
+	}
+
+	if name == "Authorization" || name == "Host" {
+		return fmt.Errorf("%s can't be set, it's managed by the service", name)
+	}
 
-	return &configuration{cacheTTL: cacheTtl, port: *port, gitHubApiKey: githubApiKey}, nil
+	header.Add(name, strings.TrimSpace(headerValue))
+	return nil
 }
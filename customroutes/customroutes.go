@@ -0,0 +1,174 @@
+package customroutes
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// custom routes are served under this prefix so they can never shadow a built-in route
+const PATH_PREFIX string = "/custom/"
+
+// Datasets custom routes can be evaluated against
+const (
+	DATASET_ORGANIZATION string = "organization"
+	DATASET_MEMBERS      string = "members"
+	DATASET_REPOS        string = "repos"
+)
+
+// Operator-defined endpoint, evaluated against a cached dataset at request time. e.g.
+//
+//	{"path": "/custom/android-repos", "dataset": "repos", "select": ".name contains \"android\"", "fields": [".name", ".stargazers_count"], "sort_by": ".stargazers_count", "order": "desc", "limit": 10}
+type Route struct {
+	Path    string   `json:"path"`
+	Dataset string   `json:"dataset"`
+	Select  string   `json:"select,omitempty"`  // jq-style predicate, conditions like .field == "value" joined with and
+	Fields  []string `json:"fields,omitempty"`  // jq-style paths to keep, everything is kept when empty
+	SortBy  string   `json:"sort_by,omitempty"` // jq-style path to sort by
+	Order   string   `json:"order,omitempty"`   // asc (default) or desc
+	Limit   int      `json:"limit,omitempty"`   // 0 for no limit
+
+	predicate []condition
+	fields    [][]string
+	sortBy    []string
+}
+
+// Reads and validates custom routes from a JSON file containing an array of routes
+func LoadRoutes(path string) ([]*Route, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read custom routes file: %w", err)
+	}
+
+	var routes []*Route
+	if err := json.Unmarshal(contents, &routes); err != nil {
+		return nil, fmt.Errorf("Failed to parse custom routes file: %w", err)
+	}
+
+	paths := map[string]bool{}
+
+	for _, route := range routes {
+		if err := route.compile(); err != nil {
+			return nil, fmt.Errorf("Invalid custom route %q: %w", route.Path, err)
+		}
+
+		if paths[route.Path] {
+			return nil, fmt.Errorf("Duplicate custom route %q", route.Path)
+		}
+		paths[route.Path] = true
+	}
+
+	return routes, nil
+}
+
+// Validates the route and parses its expressions
+func (route *Route) compile() error {
+	if !strings.HasPrefix(route.Path, PATH_PREFIX) || len(route.Path) == len(PATH_PREFIX) || strings.ContainsAny(route.Path, "{} ") {
+		return fmt.Errorf("path must start with %s, and can't contain wildcards or spaces", PATH_PREFIX)
+	}
+
+	switch route.Dataset {
+	case DATASET_MEMBERS, DATASET_REPOS:
+	case DATASET_ORGANIZATION:
+		if route.Select != "" || route.SortBy != "" || route.Limit != 0 {
+			return fmt.Errorf("the organization dataset is a single object, only fields are supported")
+		}
+	default:
+		return fmt.Errorf("dataset must be one of %s, %s, %s", DATASET_ORGANIZATION, DATASET_MEMBERS, DATASET_REPOS)
+	}
+
+	if route.Order != "" && route.Order != "asc" && route.Order != "desc" {
+		return fmt.Errorf("order must be asc or desc")
+	}
+
+	if route.Limit < 0 {
+		return fmt.Errorf("limit must not be negative")
+	}
+
+	var err error
+
+	if route.predicate, err = parsePredicate(route.Select); err != nil {
+		return fmt.Errorf("select: %w", err)
+	}
+
+	for _, field := range route.Fields {
+		path, err := parsePath(field)
+		if err != nil {
+			return fmt.Errorf("fields: %w", err)
+		}
+
+		route.fields = append(route.fields, path)
+	}
+
+	if route.SortBy != "" {
+		if route.sortBy, err = parsePath(route.SortBy); err != nil {
+			return fmt.Errorf("sort_by: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Evaluates the route against the organization dataset, only fields apply to a single object
+func (route *Route) EvaluateObject(object map[string]interface{}) interface{} {
+	return route.project(object)
+}
+
+// Evaluates route against a members or repos dataset, the dataset itself is never modified
+func EvaluateList[T ~map[string]interface{}](route *Route, dataset []T) []interface{} {
+	var matched []map[string]interface{}
+
+	for _, item := range dataset {
+		if route.matches(item) {
+			matched = append(matched, item)
+		}
+	}
+
+	if route.sortBy != nil {
+		sort.SliceStable(matched, func(a int, b int) bool {
+			cmp := compareValues(lookup(matched[a], route.sortBy), lookup(matched[b], route.sortBy))
+			if route.Order == "desc" {
+				return cmp > 0
+			}
+			return cmp < 0
+		})
+	}
+
+	if route.Limit > 0 && len(matched) > route.Limit {
+		matched = matched[:route.Limit]
+	}
+
+	results := make([]interface{}, 0, len(matched))
+	for _, item := range matched {
+		results = append(results, route.project(item))
+	}
+
+	return results
+}
+
+// Determines if every condition of the route's predicate holds for item
+func (route *Route) matches(item map[string]interface{}) bool {
+	for _, cond := range route.predicate {
+		if !cond.holds(item) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Keeps only the route's fields of item, keyed by the last segment of each field's path
+func (route *Route) project(item map[string]interface{}) interface{} {
+	if len(route.fields) == 0 {
+		return item
+	}
+
+	projected := make(map[string]interface{}, len(route.fields))
+	for _, path := range route.fields {
+		projected[path[len(path)-1]] = lookup(item, path)
+	}
+
+	return projected
+}
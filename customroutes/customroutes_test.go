@@ -0,0 +1,137 @@
+package customroutes
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		route Route
+		err   string
+	}{
+		{name: "outside the prefix", route: Route{Path: "/repos", Dataset: DATASET_REPOS}, err: "path must start with"},
+		{name: "bare prefix", route: Route{Path: PATH_PREFIX, Dataset: DATASET_REPOS}, err: "path must start with"},
+		{name: "wildcard", route: Route{Path: "/custom/{name}", Dataset: DATASET_REPOS}, err: "path must start with"},
+		{name: "space", route: Route{Path: "/custom/java repos", Dataset: DATASET_REPOS}, err: "path must start with"},
+		{name: "unknown dataset", route: Route{Path: "/custom/teams", Dataset: "teams"}, err: "dataset must be one of"},
+		{name: "select on the organization", route: Route{Path: "/custom/org", Dataset: DATASET_ORGANIZATION, Select: `.login == "Netflix"`}, err: "only fields are supported"},
+		{name: "limit on the organization", route: Route{Path: "/custom/org", Dataset: DATASET_ORGANIZATION, Limit: 1}, err: "only fields are supported"},
+		{name: "unknown order", route: Route{Path: "/custom/repos", Dataset: DATASET_REPOS, Order: "descending"}, err: "order must be asc or desc"},
+		{name: "negative limit", route: Route{Path: "/custom/repos", Dataset: DATASET_REPOS, Limit: -1}, err: "limit must not be negative"},
+		{name: "invalid select", route: Route{Path: "/custom/repos", Dataset: DATASET_REPOS, Select: `.language == Java`}, err: "select:"},
+		{name: "invalid field", route: Route{Path: "/custom/repos", Dataset: DATASET_REPOS, Fields: []string{".name", "stargazers_count"}}, err: "fields:"},
+		{name: "invalid sort_by", route: Route{Path: "/custom/repos", Dataset: DATASET_REPOS, SortBy: ".license."}, err: "sort_by:"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.route.compile()
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("compile() = %v, want an error containing %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestLoadRoutes(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		err      string
+	}{
+		{name: "valid", contents: `[{"path": "/custom/java", "dataset": "repos", "select": ".language == \"Java\""}, {"path": "/custom/org", "dataset": "organization", "fields": [".login"]}]`},
+		{name: "not JSON", contents: `{"path": "/custom/java"`, err: "Failed to parse custom routes file"},
+		{name: "not an array", contents: `{"path": "/custom/java", "dataset": "repos"}`, err: "Failed to parse custom routes file"},
+		{name: "invalid route", contents: `[{"path": "/custom/java", "dataset": "repos", "select": ".language =="}]`, err: `Invalid custom route "/custom/java"`},
+		{name: "duplicate path", contents: `[{"path": "/custom/java", "dataset": "repos"}, {"path": "/custom/java", "dataset": "members"}]`, err: "Duplicate custom route"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "routes.json")
+			if err := os.WriteFile(path, []byte(test.contents), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			routes, err := LoadRoutes(path)
+			if test.err == "" {
+				if err != nil || len(routes) != 2 {
+					t.Errorf("LoadRoutes = %d routes, %v", len(routes), err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("LoadRoutes = %v, want an error containing %q", err, test.err)
+			}
+		})
+	}
+
+	if _, err := LoadRoutes(filepath.Join(t.TempDir(), "missing.json")); err == nil || !strings.Contains(err.Error(), "Failed to read custom routes file") {
+		t.Errorf("LoadRoutes of a missing file = %v", err)
+	}
+}
+
+func TestEvaluateList(t *testing.T) {
+	repos := []map[string]interface{}{
+		{"name": "zuul", "language": "Java", "stargazers_count": float64(13000), "license": map[string]interface{}{"spdx_id": "Apache-2.0"}},
+		{"name": "hystrix", "language": "Java", "stargazers_count": float64(24000)},
+		{"name": "metaflow", "language": "Python", "stargazers_count": float64(8000), "license": map[string]interface{}{"spdx_id": "Apache-2.0"}},
+		{"name": "eureka", "language": "Java", "stargazers_count": "12000"},
+		{"name": "archive", "language": nil},
+	}
+
+	tests := []struct {
+		name  string
+		route Route
+		want  []interface{}
+	}{
+		{
+			name:  "select, sort, and limit",
+			route: Route{Select: `.language == "Java" and .stargazers_count > 0`, Fields: []string{".name"}, SortBy: ".stargazers_count", Order: "desc", Limit: 1},
+			want:  []interface{}{map[string]interface{}{"name": "hystrix"}},
+		},
+		{
+			// missing values sort first, then numbers, then strings
+			name:  "sort by values of mixed kinds",
+			route: Route{Fields: []string{".name"}, SortBy: ".stargazers_count"},
+			want: []interface{}{
+				map[string]interface{}{"name": "archive"},
+				map[string]interface{}{"name": "metaflow"},
+				map[string]interface{}{"name": "zuul"},
+				map[string]interface{}{"name": "hystrix"},
+				map[string]interface{}{"name": "eureka"},
+			},
+		},
+		{
+			name:  "missing fields project as null, keyed by their last segment",
+			route: Route{Select: `.license.spdx_id == null`, Fields: []string{".name", ".license.spdx_id"}},
+			want: []interface{}{
+				map[string]interface{}{"name": "hystrix", "spdx_id": nil},
+				map[string]interface{}{"name": "eureka", "spdx_id": nil},
+				map[string]interface{}{"name": "archive", "spdx_id": nil},
+			},
+		},
+		{
+			name:  "nothing matches",
+			route: Route{Select: `.language == "Go"`},
+			want:  []interface{}{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			route := test.route
+			route.Path, route.Dataset = "/custom/test", DATASET_REPOS
+			if err := route.compile(); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := EvaluateList(&route, repos); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("EvaluateList = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package customroutes
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Comparison of the value at a path against a literal, e.g. .language == "Java"
+type condition struct {
+	path     []string
+	operator string
+	literal  interface{}
+}
+
+var operators = []string{"==", "!=", "<=", ">=", "<", ">", "contains"}
+
+// Parses a jq-style path like .license.spdx_id into its segments
+func parsePath(expression string) ([]string, error) {
+	if !strings.HasPrefix(expression, ".") || len(expression) == 1 {
+		return nil, fmt.Errorf("%q must be a path like .name or .license.spdx_id", expression)
+	}
+
+	segments := strings.Split(expression[1:], ".")
+	for _, segment := range segments {
+		if segment == "" {
+			return nil, fmt.Errorf("%q has an empty path segment", expression)
+		}
+	}
+
+	return segments, nil
+}
+
+// Parses a predicate of conditions joined with and, e.g. .language == "Java" and .stargazers_count > 100.
+// Literals are JSON values, an empty predicate matches everything
+func parsePredicate(expression string) ([]condition, error) {
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	var conditions []condition
+
+	for len(tokens) > 0 {
+		if len(tokens) < 3 {
+			return nil, fmt.Errorf("expected a condition like .field == \"value\"")
+		}
+
+		path, err := parsePath(tokens[0])
+		if err != nil {
+			return nil, err
+		}
+
+		if !isOperator(tokens[1]) {
+			return nil, fmt.Errorf("%q isn't one of the operators %s", tokens[1], strings.Join(operators, ", "))
+		}
+
+		var literal interface{}
+		if err := json.Unmarshal([]byte(tokens[2]), &literal); err != nil {
+			return nil, fmt.Errorf("%s isn't a JSON literal", tokens[2])
+		}
+
+		// arrays and objects don't order, so they'd compare equal to any other array or object
+		switch literal.(type) {
+		case []interface{}, map[string]interface{}:
+			return nil, fmt.Errorf("%s must be null, a bool, a number, or a string", tokens[2])
+		}
+
+		conditions = append(conditions, condition{path: path, operator: tokens[1], literal: literal})
+		tokens = tokens[3:]
+
+		if len(tokens) > 0 {
+			if tokens[0] != "and" {
+				return nil, fmt.Errorf("expected and between conditions, got %q", tokens[0])
+			}
+			if len(tokens) == 1 {
+				return nil, fmt.Errorf("expected a condition after and")
+			}
+			tokens = tokens[1:]
+		}
+	}
+
+	return conditions, nil
+}
+
+// Splits an expression on whitespace and around operators, quoted strings are kept whole with their quotes
+func tokenize(expression string) ([]string, error) {
+	var tokens []string
+
+	for i := 0; i < len(expression); {
+		switch c := expression[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(expression) && expression[end] != '"' {
+				if expression[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(expression) {
+				return nil, fmt.Errorf("unterminated string in %q", expression)
+			}
+			tokens = append(tokens, expression[i:end+1])
+			i = end + 1
+		case strings.ContainsRune("=!<>", rune(c)):
+			end := i + 1
+			if end < len(expression) && expression[end] == '=' {
+				end++
+			}
+			tokens = append(tokens, expression[i:end])
+			i = end
+		default:
+			end := i
+			for end < len(expression) && !strings.ContainsRune(" \t\"=!<>", rune(expression[end])) {
+				end++
+			}
+			tokens = append(tokens, expression[i:end])
+			i = end
+		}
+	}
+
+	return tokens, nil
+}
+
+func isOperator(token string) bool {
+	for _, operator := range operators {
+		if token == operator {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Determines if the condition holds for item, missing values only equal null
+func (cond condition) holds(item map[string]interface{}) bool {
+	value := lookup(item, cond.path)
+
+	switch cond.operator {
+	case "==":
+		return sameKind(value, cond.literal) && compareValues(value, cond.literal) == 0
+	case "!=":
+		return !sameKind(value, cond.literal) || compareValues(value, cond.literal) != 0
+	case "<":
+		return sameKind(value, cond.literal) && compareValues(value, cond.literal) < 0
+	case "<=":
+		return sameKind(value, cond.literal) && compareValues(value, cond.literal) <= 0
+	case ">":
+		return sameKind(value, cond.literal) && compareValues(value, cond.literal) > 0
+	case ">=":
+		return sameKind(value, cond.literal) && compareValues(value, cond.literal) >= 0
+	case "contains":
+		return contains(value, cond.literal)
+	}
+
+	return false
+}
+
+// Determines if a string contains a substring, or an array contains an element
+func contains(value interface{}, literal interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		substring, ok := literal.(string)
+		return ok && strings.Contains(v, substring)
+	case []interface{}:
+		for _, element := range v {
+			if sameKind(element, literal) && compareValues(element, literal) == 0 {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Get the value at path in item, nil if any segment is missing
+func lookup(item map[string]interface{}, path []string) interface{} {
+	var value interface{} = item
+
+	for _, segment := range path {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+
+		value = object[segment]
+	}
+
+	return value
+}
+
+// Ranks JSON value kinds, so values of different kinds still sort consistently: null, bools, numbers, strings, then everything else
+func kindRank(value interface{}) int {
+	switch value.(type) {
+	case nil:
+		return 0
+	case bool:
+		return 1
+	case float64:
+		return 2
+	case string:
+		return 3
+	}
+
+	return 4
+}
+
+func sameKind(a interface{}, b interface{}) bool {
+	return kindRank(a) == kindRank(b)
+}
+
+// Compares two decoded JSON values, returning -1, 0, or 1. Arrays and objects compare equal to each other
+func compareValues(a interface{}, b interface{}) int {
+	if rankA, rankB := kindRank(a), kindRank(b); rankA != rankB {
+		if rankA < rankB {
+			return -1
+		}
+		return 1
+	}
+
+	switch a := a.(type) {
+	case bool:
+		if a == b.(bool) {
+			return 0
+		} else if !a {
+			return -1
+		}
+		return 1
+	case float64:
+		if a < b.(float64) {
+			return -1
+		} else if a > b.(float64) {
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(a, b.(string))
+	}
+
+	return 0
+}
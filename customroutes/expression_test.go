@@ -0,0 +1,131 @@
+package customroutes
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePredicateErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		err        string
+	}{
+		{name: "missing literal", expression: `.language ==`, err: "expected a condition"},
+		{name: "missing operator", expression: `.language`, err: "expected a condition"},
+		{name: "path without a dot", expression: `language == "Java"`, err: "must be a path"},
+		{name: "bare dot", expression: `. == "Java"`, err: "must be a path"},
+		{name: "empty path segment", expression: `.license..spdx_id == "MIT"`, err: "empty path segment"},
+		{name: "single equals", expression: `.language = "Java"`, err: "isn't one of the operators"},
+		{name: "unknown operator", expression: `.language ~= "Java"`, err: "isn't one of the operators"},
+		{name: "unquoted string", expression: `.language == Java`, err: "isn't a JSON literal"},
+		{name: "unterminated string", expression: `.language == "Java`, err: "unterminated string"},
+		{name: "array literal", expression: `.topics == [1]`, err: "must be null, a bool, a number, or a string"},
+		{name: "object literal", expression: `.license != {}`, err: "must be null, a bool, a number, or a string"},
+		{name: "or isn't supported", expression: `.fork == true or .archived == true`, err: "expected and between conditions"},
+		{name: "uppercase and", expression: `.fork == true AND .archived == true`, err: "expected and between conditions"},
+		{name: "trailing and", expression: `.fork == true and`, err: "expected a condition after and"},
+		{name: "leading and", expression: `and .fork == true`, err: "must be a path"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parsePredicate(test.expression)
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("parsePredicate(%q) = %v, want an error containing %q", test.expression, err, test.err)
+			}
+		})
+	}
+}
+
+// Longer operators win over their prefixes, with or without whitespace around them
+func TestParsePredicateOperators(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       []condition
+	}{
+		{expression: ``, want: nil},
+		{expression: `.stars>=10`, want: []condition{{path: []string{"stars"}, operator: ">=", literal: float64(10)}}},
+		{expression: `.stars <= 10`, want: []condition{{path: []string{"stars"}, operator: "<=", literal: float64(10)}}},
+		{expression: `.stars>10`, want: []condition{{path: []string{"stars"}, operator: ">", literal: float64(10)}}},
+		{expression: `.name!="zuul"`, want: []condition{{path: []string{"name"}, operator: "!=", literal: "zuul"}}},
+		{expression: `.name contains"zuul"`, want: []condition{{path: []string{"name"}, operator: "contains", literal: "zuul"}}},
+		{expression: `.description == "a \"quoted\" and == b"`, want: []condition{{path: []string{"description"}, operator: "==", literal: `a "quoted" and == b`}}},
+		{expression: `.license.spdx_id == null and .fork == false and .stars < 1.5`, want: []condition{
+			{path: []string{"license", "spdx_id"}, operator: "==", literal: nil},
+			{path: []string{"fork"}, operator: "==", literal: false},
+			{path: []string{"stars"}, operator: "<", literal: 1.5},
+		}},
+	}
+
+	for _, test := range tests {
+		got, err := parsePredicate(test.expression)
+		if err != nil {
+			t.Errorf("parsePredicate(%q) failed: %v", test.expression, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("parsePredicate(%q) = %+v, want %+v", test.expression, got, test.want)
+		}
+	}
+}
+
+func TestConditionHolds(t *testing.T) {
+	repo := map[string]interface{}{
+		"name":             "zuul",
+		"stargazers_count": float64(100),
+		"fork":             false,
+		"license":          nil,
+		"owner":            map[string]interface{}{"login": "Netflix"},
+		"topics":           []interface{}{"gateway", "java"},
+	}
+
+	tests := []struct {
+		expression string
+		holds      bool
+	}{
+		{expression: `.stargazers_count == 100`, holds: true},
+		{expression: `.stargazers_count > 99 and .stargazers_count < 101`, holds: true},
+		{expression: `.stargazers_count > 99 and .fork == true`, holds: false},
+		{expression: `.owner.login == "Netflix"`, holds: true},
+		{expression: `.topics contains "java"`, holds: true},
+		{expression: `.topics contains "go"`, holds: false},
+		{expression: `.name contains "uu"`, holds: true},
+
+		// values of another kind than the literal never order against it, and are never equal to it
+		{expression: `.stargazers_count == "100"`, holds: false},
+		{expression: `.stargazers_count != "100"`, holds: true},
+		{expression: `.stargazers_count > "1"`, holds: false},
+		{expression: `.stargazers_count <= "1"`, holds: false},
+		{expression: `.name > 1`, holds: false},
+		{expression: `.fork == 0`, holds: false},
+		{expression: `.stargazers_count contains 1`, holds: false},
+		{expression: `.topics contains 1`, holds: false},
+
+		// missing fields, and fields under a missing or null object, are null
+		{expression: `.missing == null`, holds: true},
+		{expression: `.missing != null`, holds: false},
+		{expression: `.missing == ""`, holds: false},
+		{expression: `.missing != "x"`, holds: true},
+		{expression: `.missing < 1`, holds: false},
+		{expression: `.missing contains "x"`, holds: false},
+		{expression: `.license == null`, holds: true},
+		{expression: `.license.spdx_id == null`, holds: true},
+		{expression: `.license.spdx_id == "MIT"`, holds: false},
+		{expression: `.name.first == null`, holds: true},
+	}
+
+	for _, test := range tests {
+		conditions, err := parsePredicate(test.expression)
+		if err != nil {
+			t.Fatalf("parsePredicate(%q) failed: %v", test.expression, err)
+		}
+
+		route := &Route{predicate: conditions}
+		if holds := route.matches(repo); holds != test.holds {
+			t.Errorf("%s = %v, want %v", test.expression, holds, test.holds)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package digest
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/cache"
+	githubclient "github.com/adamjeanlaurent/github-api-read-cache-service/github-client"
+)
+
+// max star movers listed in a digest's summary
+const MAX_STAR_MOVERS int = 10
+
+// A repo whose star count changed by at least the configured threshold between two digests
+type StarMove struct {
+	Repo   string `json:"repo"`
+	Before int    `json:"before"`
+	After  int    `json:"after"`
+	Delta  int    `json:"delta"`
+}
+
+// Changes to the cached Netflix organization between two points in time
+type Digest struct {
+	From          time.Time  `json:"from"`
+	To            time.Time  `json:"to"`
+	NewRepos      []string   `json:"new_repos"`
+	RemovedRepos  []string   `json:"removed_repos"`
+	StarMovers    []StarMove `json:"star_movers"`
+	MembersJoined []string   `json:"members_joined"`
+	MembersLeft   []string   `json:"members_left"`
+}
+
+// Compiles the changes between two exports of the cache, star movers are repos whose star count changed by at least minStarDelta
+func Compile(previous cache.CacheExport, current cache.CacheExport, minStarDelta int) Digest {
+	digest := Digest{
+		From: previous.Metadata.HydratedAt,
+		To:   current.Metadata.HydratedAt,
+	}
+
+	previousStars := starsByRepo(previous.NetflixOrganizationRepos)
+	currentStars := starsByRepo(current.NetflixOrganizationRepos)
+
+	for repo, stars := range currentStars {
+		before, ok := previousStars[repo]
+		if !ok {
+			digest.NewRepos = append(digest.NewRepos, repo)
+			continue
+		}
+
+		if delta := stars - before; abs(delta) >= minStarDelta {
+			digest.StarMovers = append(digest.StarMovers, StarMove{Repo: repo, Before: before, After: stars, Delta: delta})
+		}
+	}
+
+	for repo := range previousStars {
+		if _, ok := currentStars[repo]; !ok {
+			digest.RemovedRepos = append(digest.RemovedRepos, repo)
+		}
+	}
+
+	previousMembers := logins(previous.NetflixOrganizationMembers)
+	currentMembers := logins(current.NetflixOrganizationMembers)
+
+	for login := range currentMembers {
+		if !previousMembers[login] {
+			digest.MembersJoined = append(digest.MembersJoined, login)
+		}
+	}
+
+	for login := range previousMembers {
+		if !currentMembers[login] {
+			digest.MembersLeft = append(digest.MembersLeft, login)
+		}
+	}
+
+	slices.Sort(digest.NewRepos)
+	slices.Sort(digest.RemovedRepos)
+	slices.Sort(digest.MembersJoined)
+	slices.Sort(digest.MembersLeft)
+
+	// biggest movers first, ties by name so digests are stable
+	slices.SortFunc(digest.StarMovers, func(a StarMove, b StarMove) int {
+		if abs(a.Delta) != abs(b.Delta) {
+			return abs(b.Delta) - abs(a.Delta)
+		}
+		return strings.Compare(a.Repo, b.Repo)
+	})
+
+	return digest
+}
+
+// Whether anything changed between the two exports
+func (digest Digest) Empty() bool {
+	return len(digest.NewRepos) == 0 && len(digest.RemovedRepos) == 0 && len(digest.StarMovers) == 0 &&
+		len(digest.MembersJoined) == 0 && len(digest.MembersLeft) == 0
+}
+
+// Get a human readable summary of the digest, e.g. for chat webhooks
+func (digest Digest) Summary() string {
+	var summary strings.Builder
+
+	fmt.Fprintf(&summary, "Netflix GitHub digest, %s to %s\n", digest.From.Format(time.RFC3339), digest.To.Format(time.RFC3339))
+
+	if digest.Empty() {
+		summary.WriteString("No changes.\n")
+		return summary.String()
+	}
+
+	writeList(&summary, "New repos", digest.NewRepos)
+	writeList(&summary, "Removed repos", digest.RemovedRepos)
+
+	if len(digest.StarMovers) > 0 {
+		fmt.Fprintf(&summary, "Star movers (%d):\n", len(digest.StarMovers))
+		for _, move := range digest.StarMovers[:min(len(digest.StarMovers), MAX_STAR_MOVERS)] {
+			fmt.Fprintf(&summary, "  %s %+d (%d -> %d)\n", move.Repo, move.Delta, move.Before, move.After)
+		}
+		if len(digest.StarMovers) > MAX_STAR_MOVERS {
+			fmt.Fprintf(&summary, "  and %d more\n", len(digest.StarMovers)-MAX_STAR_MOVERS)
+		}
+	}
+
+	writeList(&summary, "Members joined", digest.MembersJoined)
+	writeList(&summary, "Members left", digest.MembersLeft)
+
+	return summary.String()
+}
+
+func writeList(summary *strings.Builder, title string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+
+	fmt.Fprintf(summary, "%s (%d): %s\n", title, len(items), strings.Join(items, ", "))
+}
+
+// Get the star count of each repo by name
+func starsByRepo(repos []githubclient.JsonObject) map[string]int {
+	stars := make(map[string]int, len(repos))
+
+	for _, repo := range repos {
+		name, ok := repo["name"].(string)
+		if !ok {
+			continue
+		}
+
+		// stars are float64 when decoded from JSON, int when seeded from an archive
+		switch count := repo["stargazers_count"].(type) {
+		case float64:
+			stars[name] = int(count)
+		case int:
+			stars[name] = count
+		default:
+			stars[name] = 0
+		}
+	}
+
+	return stars
+}
+
+// Get the set of member logins
+func logins(members []githubclient.JsonObject) map[string]bool {
+	set := make(map[string]bool, len(members))
+
+	for _, member := range members {
+		if login, ok := member["login"].(string); ok {
+			set[login] = true
+		}
+	}
+
+	return set
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,161 @@
+package digest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/cache"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/config"
+	"go.uber.org/zap"
+)
+
+// Posts a digest of changes to the cached data to a webhook on a fixed interval
+type Digester interface {
+	Start(ctx context.Context, fail func(error)) error
+	Stop(ctx context.Context) error
+}
+
+type digester struct {
+	ctx          context.Context // cancelled by Stop, aborting an in-flight webhook call
+	cancel       context.CancelFunc
+	done         chan struct{} // closed once the digest thread has exited
+	dataCache    cache.Cache
+	logger       *zap.Logger
+	webhookUrl   string
+	interval     time.Duration
+	minStarDelta int
+	httpClient   *http.Client
+	previous     *cache.CacheExport // cache contents as of the last posted digest, nil until the cache is hydrated
+}
+
+// Body posted to the webhook, text is understood by most chat webhooks (e.g. Slack)
+type webhookPayload struct {
+	Text   string `json:"text"`
+	Digest Digest `json:"digest"`
+}
+
+// Get new Digester
+func NewDigester(cfg config.Configuration, dataCache cache.Cache, logger *zap.Logger) Digester {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &digester{
+		ctx:          ctx,
+		cancel:       cancel,
+		done:         make(chan struct{}),
+		dataCache:    dataCache,
+		logger:       logger,
+		webhookUrl:   cfg.GetDigestWebhookUrl(),
+		interval:     cfg.GetDigestInterval(),
+		minStarDelta: cfg.GetDigestMinStarDelta(),
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// Starts thread that posts a digest every interval, until stopped
+func (d *digester) Start(ctx context.Context, fail func(error)) error {
+	d.logger.Info("Starting digest job", zap.Duration("interval", d.interval))
+
+	// the first digest covers changes since startup
+	d.setBaseline()
+
+	go func() {
+		ticker := time.NewTicker(d.interval)
+		defer ticker.Stop()
+		defer close(d.done)
+
+		for {
+			select {
+			case <-ticker.C:
+				d.postDigest()
+			case <-d.ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Stops posting digests, changes since the last digest aren't posted
+func (d *digester) Stop(ctx context.Context) error {
+	d.cancel()
+
+	select {
+	case <-d.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Remembers the current cache contents as the base of the next digest, if the cache has been hydrated
+func (d *digester) setBaseline() {
+	export := d.dataCache.Export()
+	if export.NetflixOrganization == nil {
+		return
+	}
+
+	d.previous = &export
+}
+
+// Compiles and posts the changes since the last digest. The baseline only moves forward once a digest is delivered, so failed deliveries are covered by the next digest
+func (d *digester) postDigest() {
+	if d.previous == nil {
+		d.setBaseline()
+		return
+	}
+
+	current := d.dataCache.Export()
+	if current.NetflixOrganization == nil {
+		return
+	}
+
+	digest := Compile(*d.previous, current, d.minStarDelta)
+
+	if digest.Empty() {
+		d.logger.Info("No changes since the last digest, skipping", zap.Time("since", digest.From))
+		d.previous = &current
+		return
+	}
+
+	if err := d.send(digest); err != nil {
+		d.logger.Warn("Failed to post digest, changes will be included in the next digest", zap.Error(err))
+		return
+	}
+
+	d.logger.Info("Posted digest", zap.Int("new repos", len(digest.NewRepos)), zap.Int("removed repos", len(digest.RemovedRepos)),
+		zap.Int("star movers", len(digest.StarMovers)), zap.Int("members joined", len(digest.MembersJoined)), zap.Int("members left", len(digest.MembersLeft)))
+	d.previous = &current
+}
+
+// Posts the digest to the webhook
+func (d *digester) send(digest Digest) error {
+	body, err := json.Marshal(webhookPayload{Text: digest.Summary(), Digest: digest})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, d.webhookUrl, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("Failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("Failed to call webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("Webhook failed with status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
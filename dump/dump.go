@@ -0,0 +1,93 @@
+package dump
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/cache"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/config"
+	githubclient "github.com/adamjeanlaurent/github-api-read-cache-service/github-client"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/logging"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/metrics"
+	"go.uber.org/zap"
+)
+
+// Views that can be dumped, mapped to the cache data they print
+var dumpableViews = map[string]func(c cache.Cache) interface{}{
+	"org":                 func(c cache.Cache) interface{} { return c.GetNetflixOrganization() },
+	"members":             func(c cache.Cache) interface{} { return c.GetNetflixOrganizationMembers() },
+	"repos":               func(c cache.Cache) interface{} { return c.GetNetflixOrganizationRepos() },
+	"bottom-forks":        func(c cache.Cache) interface{} { return c.GetBottomNetflixReposByForks() },
+	"bottom-last_updated": func(c cache.Cache) interface{} { return c.GetBottomNetflixReposByUpdateTime() },
+	"bottom-open_issues":  func(c cache.Cache) interface{} { return c.GetBottomNetflixReposByOpenIssues() },
+	"bottom-stars":        func(c cache.Cache) interface{} { return c.GetBottomNetflixReposByStars() },
+}
+
+// Hydrates the cache once and prints a single view to stdout, without running a server. e.g. dump --view bottom-stars --n 10
+func Run(args []string, logger *zap.Logger) error {
+	flags := flag.NewFlagSet("dump", flag.ExitOnError)
+	view := flags.String("view", "bottom-stars", "View to print, one of "+strings.Join(viewNames(), ", "))
+	n := flags.Int("n", 10, "Number of repos to print for bottom views")
+
+	cfg, err := config.ParseConfiguration(flags, args, false, logger)
+	if err != nil {
+		return fmt.Errorf("Invalid Configuration: %w", err)
+	}
+
+	logger, err = logging.NewLogger(cfg, zap.NewAtomicLevel())
+	if err != nil {
+		return err
+	}
+	defer logger.Sync()
+
+	getView, ok := dumpableViews[*view]
+	if !ok {
+		return fmt.Errorf("Unknown view %q, must be one of %s", *view, strings.Join(viewNames(), ", "))
+	}
+
+	if *n <= 0 {
+		return fmt.Errorf("n must be a positive integer")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	registry := metrics.NewRegistry()
+	githubClient := githubclient.NewGithubClient(cfg, logger, registry)
+	dataCache := cache.NewCache(cfg, githubClient, ctx, logger, registry)
+
+	if statusCode, err := dataCache.HydrateCache(ctx); err != nil {
+		return fmt.Errorf("Failed to hydrate cache (status %d): %w", statusCode, err)
+	}
+
+	return printView(os.Stdout, getView(dataCache), *n)
+}
+
+// Prints a view as indented JSON, bottom views are trimmed to their last n entries like the /view/bottom/{n} endpoints
+func printView(w io.Writer, data interface{}, n int) error {
+	if tuples, ok := data.([]cache.Tuple); ok && n < len(tuples) {
+		data = tuples[len(tuples)-n:]
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(data)
+}
+
+func viewNames() []string {
+	names := make([]string, 0, len(dumpableViews))
+	for name := range dumpableViews {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,67 @@
+package githubclient
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// GitHub requests made on behalf of a single operation, e.g. a cache sync
+type RequestUsage struct {
+	Requests    int            `json:"requests"`
+	NotModified int            `json:"not_modified,omitempty"` // conditional requests answered 304, which don't count against the rate limit
+	Cost        map[string]int `json:"cost,omitempty"`         // requests counted against the rate limit, by rate limit resource (core, search, ...)
+}
+
+// Counts the GitHub requests made with a context, see CountRequests
+type RequestAccount struct {
+	lock  sync.Mutex
+	usage RequestUsage
+}
+
+type requestAccountKey struct{}
+
+// Get ctx along with an account of every GitHub request the client makes with it, including each page of paginated requests
+func CountRequests(ctx context.Context) (context.Context, *RequestAccount) {
+	account := &RequestAccount{usage: RequestUsage{Cost: map[string]int{}}}
+	return context.WithValue(ctx, requestAccountKey{}, account), account
+}
+
+// Get the requests counted so far
+func (account *RequestAccount) Usage() RequestUsage {
+	account.lock.Lock()
+	defer account.lock.Unlock()
+
+	usage := account.usage
+	usage.Cost = make(map[string]int, len(account.usage.Cost))
+	for resource, cost := range account.usage.Cost {
+		usage.Cost[resource] = cost
+	}
+
+	return usage
+}
+
+// Counts a request made with ctx against its account, if it has one. resp is nil if the request failed before GitHub responded
+func accountRequest(ctx context.Context, resp *http.Response) {
+	account, ok := ctx.Value(requestAccountKey{}).(*RequestAccount)
+	if !ok {
+		return
+	}
+
+	account.lock.Lock()
+	defer account.lock.Unlock()
+
+	account.usage.Requests++
+
+	switch {
+	case resp == nil:
+	case resp.StatusCode == http.StatusNotModified:
+		account.usage.NotModified++
+	default:
+		resource := resp.Header.Get("x-ratelimit-resource")
+		if resource == "" {
+			resource = "core"
+		}
+		account.usage.Cost[resource]++
+	}
+}
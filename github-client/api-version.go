@@ -0,0 +1,60 @@
+package githubclient
+
+import (
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// docs: https://docs.github.com/en/rest/about-the-rest-api/api-versions?apiVersion=2022-11-28
+const HEADER_API_VERSION string = "X-GitHub-Api-Version"
+
+// Sets the pinned API version and media type on a request made by the service
+func (ghc *githubClient) setApiHeaders(header http.Header) {
+	header.Set("Accept", ghc.accept)
+
+	if ghc.apiVersion != "" {
+		header.Set(HEADER_API_VERSION, ghc.apiVersion)
+	}
+
+	ghc.injectHeaders(header)
+}
+
+// Fills in the pinned API version and media type on a proxied request, keeping the caller's when they chose one. Generic
+// media types (*/*, application/json) are replaced, GitHub media types like application/vnd.github.raw+json are kept
+func (ghc *githubClient) normalizeProxyHeaders(header http.Header) {
+	if header.Get(HEADER_API_VERSION) == "" && ghc.apiVersion != "" {
+		header.Set(HEADER_API_VERSION, ghc.apiVersion)
+	}
+
+	if !requestsGithubMediaType(header.Values("Accept")) {
+		header.Set("Accept", ghc.accept)
+	}
+
+	ghc.injectHeaders(header)
+}
+
+// Sets the configured User-Agent and headers (--github-user-agent, --github-header) on a request to GitHub. Called last,
+// so configured headers override the service's and the caller's
+func (ghc *githubClient) injectHeaders(header http.Header) {
+	header.Set("User-Agent", ghc.userAgent)
+
+	for name, values := range ghc.headers {
+		header[name] = slices.Clone(values)
+	}
+}
+
+// Get whether an Accept header asks for at least one GitHub specific media type
+func requestsGithubMediaType(accept []string) bool {
+	for _, value := range accept {
+		for _, mediaRange := range strings.Split(value, ",") {
+			mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+			if err == nil && strings.HasPrefix(mediaType, "application/vnd.github") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,109 @@
+package githubclient
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Paces outbound requests once the remaining quota drops into the reserve, so the service never actually runs out
+type requestBudget struct {
+	reserve  int           // remaining quota pacing starts at, 0 disables pacing
+	maxWait  time.Duration // longest a proxied request is queued for quota before it's rejected
+	nextSlot time.Time     // earliest time the next paced request may be sent
+	pacing   bool
+}
+
+// Quota needed for a request won't be available in time
+type budgetExhaustedError struct {
+	retryAfter time.Duration
+}
+
+func (err *budgetExhaustedError) Error() string {
+	return fmt.Sprintf("GitHub API quota reserve reached, try again in %s", err.retryAfter.Round(time.Second))
+}
+
+// Waits until the request budget allows another request to GitHub. While the remaining quota is above the reserve requests
+// are sent right away, below it they're spread evenly over the rest of the rate limit window, keeping the last request in
+// hand. Returns an error instead of waiting longer than maxWait (0 waits as long as needed), or if ctx is done first
+func (ghc *githubClient) waitForBudget(ctx context.Context, maxWait time.Duration) error {
+	wait, err := ghc.reserveBudgetSlot(maxWait)
+	if err != nil || wait <= 0 {
+		return err
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Reserves the next request slot, returning how long to wait for it
+func (ghc *githubClient) reserveBudgetSlot(maxWait time.Duration) (time.Duration, error) {
+	if ghc.budget.reserve <= 0 {
+		return 0, nil
+	}
+
+	ghc.rateLimitLock.Lock()
+	defer ghc.rateLimitLock.Unlock()
+
+	// every endpoint the service calls draws from the core quota
+	state, seen := ghc.rateLimits["core"]
+	now := time.Now().UTC()
+
+	resetEpoch, err := strconv.ParseInt(state.reset, 10, 64)
+	resetTime := time.Unix(resetEpoch, 0).UTC()
+
+	if !seen || err != nil || state.remaining > ghc.budget.reserve || !now.Before(resetTime) {
+		if ghc.budget.pacing {
+			ghc.budget.pacing = false
+			ghc.logger.Info("GitHub API quota back above reserve, no longer pacing requests")
+		}
+		return 0, nil
+	}
+
+	if !ghc.budget.pacing {
+		ghc.budget.pacing = true
+		ghc.logger.Warn("GitHub API quota reached reserve, pacing requests until the window resets", zap.Int("remaining", state.remaining), zap.String("reset", resetTime.String()))
+	}
+
+	slot := now
+	if ghc.budget.nextSlot.After(now) {
+		slot = ghc.budget.nextSlot
+	}
+	next := resetTime
+
+	// the last request is kept in hand, once it's reached requests wait for the window to reset
+	if spendable := state.remaining - 1; spendable > 0 {
+		next = slot.Add(resetTime.Sub(now) / time.Duration(spendable))
+	} else {
+		slot = resetTime
+	}
+
+	wait := slot.Sub(now)
+	if maxWait > 0 && wait > maxWait {
+		return 0, &budgetExhaustedError{retryAfter: wait}
+	}
+
+	ghc.budget.nextSlot = next
+	return wait, nil
+}
+
+// Rejects a proxied request that couldn't get quota in time, telling the caller when to retry
+func writeBudgetExhausted(w http.ResponseWriter, err error) {
+	if exhausted, ok := err.(*budgetExhaustedError); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(exhausted.retryAfter.Seconds()))))
+	}
+
+	http.Error(w, err.Error(), http.StatusTooManyRequests)
+}
@@ -0,0 +1,95 @@
+package githubclient
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/config"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/metrics"
+	"go.uber.org/zap"
+)
+
+// How long after an injected rate limit the rate limit resets
+const CHAOS_RATE_LIMIT_RESET time.Duration = time.Minute
+
+// Whether any fault is injected into GitHub requests, only ever with --enable-chaos
+func chaosEnabled(cfg config.Configuration) bool {
+	return cfg.GetEnableChaos() && (cfg.GetChaosLatency() > 0 || cfg.GetChaosErrorRate() > 0 || cfg.GetChaosRateLimitRate() > 0)
+}
+
+// Get a transport injecting the configured faults into requests sent through transport, for exercising backoff and stale
+// serving in staging: every request is delayed by the chaos latency, then a fraction is answered 500 and another fraction
+// 403 with an exhausted rate limit, without reaching GitHub. Without any fault configured, transport is returned as is
+func newChaosTransport(cfg config.Configuration, logger *zap.Logger, registry metrics.Registry, transport http.RoundTripper) http.RoundTripper {
+	if !chaosEnabled(cfg) {
+		return transport
+	}
+
+	latency := cfg.GetChaosLatency()
+	errorRate := cfg.GetChaosErrorRate()
+	rateLimitRate := cfg.GetChaosRateLimitRate()
+	injected := registry.Counter("chaos_faults_injected_total", "Number of faults injected into requests to GitHub, by fault (latency, error, or rate_limit)")
+
+	logger.Error("CHAOS ENABLED: injecting faults into requests to GitHub, this is meant for testing only and must never run in production",
+		zap.Duration("latency", latency), zap.Float64("error rate", errorRate), zap.Float64("rate limit rate", rateLimitRate))
+
+	return metrics.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		if latency > 0 {
+			injected.Add(1, "fault", "latency")
+
+			timer := time.NewTimer(latency)
+			select {
+			case <-timer.C:
+			case <-r.Context().Done():
+				timer.Stop()
+				return nil, r.Context().Err()
+			}
+		}
+
+		roll := rand.Float64()
+		switch {
+		case roll < errorRate:
+			injected.Add(1, "fault", "error")
+			return chaosResponse(r, http.StatusInternalServerError, "Server Error", nil), nil
+
+		case roll < errorRate+rateLimitRate:
+			injected.Add(1, "fault", "rate_limit")
+			return chaosResponse(r, http.StatusForbidden, "API rate limit exceeded.", http.Header{
+				"X-Ratelimit-Limit":     {"5000"},
+				"X-Ratelimit-Remaining": {"0"},
+				"X-Ratelimit-Used":      {"5000"},
+				"X-Ratelimit-Reset":     {strconv.FormatInt(time.Now().Add(CHAOS_RATE_LIMIT_RESET).Unix(), 10)},
+				"X-Ratelimit-Resource":  {"core"},
+			}), nil
+		}
+
+		return transport.RoundTrip(r)
+	})
+}
+
+// Get a response to r with status code and a JSON error message, the way GitHub answers errors
+func chaosResponse(r *http.Request, statusCode int, message string, header http.Header) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set("Content-Type", "application/json; charset=utf-8")
+
+	body := []byte(fmt.Sprintf(`{"message":%q}`, message))
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		StatusCode:    statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       r,
+	}
+}
@@ -0,0 +1,105 @@
+package githubclient
+
+import (
+	"io"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	DEBUG_BODY_MAX_BYTES       int           = 2048        // upstream bodies are truncated to this many bytes before logging
+	DEBUG_BODY_SAMPLE_LIMIT    int           = 5           // max number of upstream bodies logged per sample interval
+	DEBUG_BODY_SAMPLE_INTERVAL time.Duration = time.Minute // window the sample limit applies to
+	REDACTED                   string        = "[REDACTED]"
+)
+
+// headers that are never written to the logs
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Github-Token"}
+
+// matches GitHub personal access, oauth, app, and fine-grained tokens
+var githubTokenPattern = regexp.MustCompile(`(gh[pousr]_[A-Za-z0-9]{20,}|github_pat_[A-Za-z0-9_]{20,})`)
+
+// Limits how many upstream responses are debug logged per interval, so a failing hydration loop can't flood the logs
+type debugSampler struct {
+	lock        sync.Mutex
+	windowStart time.Time
+	count       int
+}
+
+// determines if another upstream response can be logged in the current sample interval
+func (s *debugSampler) allow() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+
+	if now.Sub(s.windowStart) >= DEBUG_BODY_SAMPLE_INTERVAL {
+		s.windowStart = now
+		s.count = 0
+	}
+
+	if s.count >= DEBUG_BODY_SAMPLE_LIMIT {
+		return false
+	}
+
+	s.count++
+	return true
+}
+
+// Logs a truncated and scrubbed copy of a failed upstream response, only when the logger is at debug level and the sampler allows it.
+// Useful for diagnosing malformed JSON or schema drift in GitHub responses without redeploying.
+func (ghc *githubClient) debugLogFailedResponse(reason string, url string, statusCode int, headers http.Header, body []byte) {
+	if !ghc.logger.Core().Enabled(zap.DebugLevel) || !ghc.debugSampler.allow() {
+		return
+	}
+
+	truncated := len(body) > DEBUG_BODY_MAX_BYTES
+	if truncated {
+		body = body[:DEBUG_BODY_MAX_BYTES]
+	}
+
+	ghc.logger.Debug("Failed upstream GitHub response",
+		zap.String("reason", reason),
+		zap.String("url", url),
+		zap.Int("status", statusCode),
+		zap.Any("headers", scrubHeaders(headers)),
+		zap.String("body", githubTokenPattern.ReplaceAllString(string(body), REDACTED)),
+		zap.Bool("truncated", truncated),
+	)
+}
+
+// Returns a copy of headers with credentials redacted
+func scrubHeaders(headers http.Header) http.Header {
+	scrubbed := headers.Clone()
+
+	for _, header := range sensitiveHeaders {
+		if scrubbed.Get(header) != "" {
+			scrubbed.Set(header, REDACTED)
+		}
+	}
+
+	for header, values := range scrubbed {
+		for i, value := range values {
+			scrubbed[header][i] = githubTokenPattern.ReplaceAllString(value, REDACTED)
+		}
+	}
+
+	return scrubbed
+}
+
+// Reads and closes the body of a failed upstream response, then debug logs it
+func (ghc *githubClient) debugLogFailedBody(reason string, url string, resp *http.Response) {
+	defer resp.Body.Close()
+
+	if !ghc.logger.Core().Enabled(zap.DebugLevel) {
+		return
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, int64(DEBUG_BODY_MAX_BYTES)+1))
+
+	ghc.debugLogFailedResponse(reason, url, resp.StatusCode, resp.Header, body)
+}
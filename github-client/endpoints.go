@@ -0,0 +1,56 @@
+package githubclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Response of an arbitrary GitHub GET path, kept verbatim
+type RawResponse struct {
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+}
+
+// Fetches path (e.g. /orgs/Netflix/events) from the GitHub API as is, without decoding its body
+func (ghc *githubClient) GetEndpoint(ctx context.Context, path string) (*Response[RawResponse], error) {
+	if ghc.shouldBackoff() {
+		return failedResponse[RawResponse](http.StatusTooManyRequests, nil), fmt.Errorf("Rate Limited, in backoff, try again later")
+	}
+
+	if err := ghc.waitForBudget(ctx, 0); err != nil {
+		return failedResponse[RawResponse](http.StatusTooManyRequests, nil), err
+	}
+
+	url := GITHUB_API_URL + path
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return failedResponse[RawResponse](http.StatusInternalServerError, nil), fmt.Errorf("Failed to create request: %v", err)
+	}
+
+	ghc.setApiHeaders(req.Header)
+
+	resp, err := ghc.doWithToken(req)
+	if err != nil {
+		return failedResponse[RawResponse](http.StatusBadGateway, nil), err
+	}
+
+	ghc.updateBackoffState(resp.Header)
+	ghc.trackRateLimit(resp.Header)
+
+	if resp.StatusCode != http.StatusOK {
+		ghc.debugLogFailedBody("unexpected status code", url, resp)
+		return failedResponse[RawResponse](resp.StatusCode, resp.Header), fmt.Errorf("Request failed")
+	}
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return failedResponse[RawResponse](http.StatusInternalServerError, resp.Header), fmt.Errorf("Failed to read response body: %v", err)
+	}
+
+	return NewResponse(resp.StatusCode, resp.Header, RawResponse{ContentType: resp.Header.Get("Content-Type"), Body: body}), nil
+}
@@ -0,0 +1,48 @@
+package githubclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Fetches the ETag of the Netflix org's public events, conditionally on etag when it's set, as the response's body. Responds
+// 304 with etag when there were no new events since, which doesn't count against the rate limit
+func (ghc *githubClient) GetNetflixOrgEventsETag(ctx context.Context, etag string) (*Response[string], error) {
+	if ghc.shouldBackoff() {
+		return failedResponse[string](http.StatusTooManyRequests, nil), fmt.Errorf("Rate Limited, in backoff, try again later")
+	}
+
+	if err := ghc.waitForBudget(ctx, 0); err != nil {
+		return failedResponse[string](http.StatusTooManyRequests, nil), err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ENDPOINT_ORG_NETFLIX_EVENTS, nil)
+	if err != nil {
+		return failedResponse[string](http.StatusInternalServerError, nil), fmt.Errorf("Failed to create request: %v", err)
+	}
+
+	ghc.setApiHeaders(req.Header)
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := ghc.doWithToken(req)
+	if err != nil {
+		return failedResponse[string](http.StatusBadGateway, nil), err
+	}
+	defer resp.Body.Close()
+
+	ghc.updateBackoffState(resp.Header)
+	ghc.trackRateLimit(resp.Header)
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return NewResponse(resp.StatusCode, resp.Header, etag), nil
+	case http.StatusOK:
+		return NewResponse(resp.StatusCode, resp.Header, resp.Header.Get("ETag")), nil
+	default:
+		ghc.debugLogFailedBody("unexpected status code", ENDPOINT_ORG_NETFLIX_EVENTS, resp)
+		return failedResponse[string](resp.StatusCode, resp.Header), fmt.Errorf("Request failed")
+	}
+}
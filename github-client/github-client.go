@@ -3,25 +3,54 @@ package githubclient
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
+	"slices"
 	"strconv"
 	"sync"
 	"time"
 
 	netUrl "net/url"
 
+	"github.com/adamjeanlaurent/github-api-read-cache-service/alerts"
 	"github.com/adamjeanlaurent/github-api-read-cache-service/config"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/metrics"
 	"go.uber.org/zap"
 )
 
 const (
-	GITHUB_API_URL               string = "https://api.github.com"
-	ENDPOINT_ORG_NETFLIX         string = GITHUB_API_URL + "/orgs/Netflix"
-	ENDPOINT_ORG_NETFLIX_MEMBERS string = GITHUB_API_URL + "/orgs/Netflix/public_members"    // only get public repository members
-	ENDPOINT_ORG_NETFLIX_REPOS   string = GITHUB_API_URL + "/orgs/Netflix/repos?type=public" // only get public repositories
-	PAGE_SIZE                    int    = 100
+	GITHUB_API_URL                       string = "https://api.github.com"
+	ENDPOINT_ORG_NETFLIX                 string = GITHUB_API_URL + "/orgs/Netflix"
+	ENDPOINT_ORG_NETFLIX_MEMBERS         string = GITHUB_API_URL + "/orgs/Netflix/public_members"             // only get public repository members
+	ENDPOINT_ORG_NETFLIX_REPOS           string = GITHUB_API_URL + "/orgs/Netflix/repos?type=public"          // only get public repositories
+	ENDPOINT_ORG_NETFLIX_REPOS_BY_UPDATE string = ENDPOINT_ORG_NETFLIX_REPOS + "&sort=updated&direction=desc" // most recently updated first
+	ENDPOINT_RATE_LIMIT                  string = GITHUB_API_URL + "/rate_limit"                              // doesn't count against the rate limit
+	ENDPOINT_REPO                        string = GITHUB_API_URL + "/repos/Netflix/%s"                        // formatted with the repo name
+	ENDPOINT_REPO_CONTRIBUTORS           string = GITHUB_API_URL + "/repos/Netflix/%s/contributors"           // formatted with the repo name
+	ENDPOINT_REPO_LATEST_RELEASE         string = GITHUB_API_URL + "/repos/Netflix/%s/releases/latest"        // formatted with the repo name
+	ENDPOINT_REPO_COMMIT_ACTIVITY        string = GITHUB_API_URL + "/repos/Netflix/%s/stats/commit_activity"  // formatted with the repo name
+	ENDPOINT_ORG_NETFLIX_TEAMS           string = GITHUB_API_URL + "/orgs/Netflix/teams"                      // only the teams visible to the token
+	ENDPOINT_TEAM_REPOS                  string = GITHUB_API_URL + "/orgs/Netflix/teams/%s/repos"             // formatted with the team slug
+	ENDPOINT_REPO_README                 string = GITHUB_API_URL + "/repos/Netflix/%s/readme"                 // formatted with the repo name
+	ENDPOINT_REPO_LANGUAGES              string = GITHUB_API_URL + "/repos/Netflix/%s/languages"              // formatted with the repo name
+	ENDPOINT_ORG_NETFLIX_EVENTS          string = GITHUB_API_URL + "/orgs/Netflix/events?per_page=1"          // only the latest event, its ETag changes with every new event
+)
+
+// GitHub computes repo statistics in the background, answering 202 Accepted until they're ready.
+// docs: https://docs.github.com/en/rest/metrics/statistics?apiVersion=2022-11-28#a-word-about-caching
+const (
+	STATS_MAX_ATTEMPTS int           = 4
+	STATS_RETRY_WAIT   time.Duration = 2 * time.Second // doubled after every attempt
+)
+
+// How the proxy authenticates requests to GitHub
+const (
+	PROXY_AUTH_SERVICE     string = "service"     // always the service token, overwriting the caller's Authorization
+	PROXY_AUTH_CALLER      string = "caller"      // the caller's Authorization when sent, otherwise the service token
+	PROXY_AUTH_CALLER_ONLY string = "caller-only" // only the caller's Authorization, requests without one are rejected
 )
 
 type JsonObject map[string]interface{}
@@ -29,105 +58,286 @@ type JsonObject map[string]interface{}
 // Client responsible for communicating with Github's REST API. docs: https://docs.github.com/en/rest/quickstart?apiVersion=2022-11-28
 type GithubClient interface {
 	ForwardRequest(w http.ResponseWriter, r *http.Request)
-	GetNetflixOrg(ctx context.Context) (JsonObject, error, int)
-	GetNetflixOrgMembers(ctx context.Context) ([]JsonObject, error, int)
-	GetNetflixRepos(ctx context.Context) ([]JsonObject, error, int)
+	GetNetflixOrg(ctx context.Context) (*Response[JsonObject], error)
+	GetNetflixOrgMembers(ctx context.Context) (*Response[[]JsonObject], error)
+	GetNetflixRepos(ctx context.Context) (*Response[[]JsonObject], error)
+	GetNetflixReposUpdatedSince(ctx context.Context, since time.Time) (*Response[[]JsonObject], error)
+	GetNetflixRepo(ctx context.Context, repo string) (*Response[JsonObject], error)
+	GetRateLimit(ctx context.Context) (*Response[JsonObject], error)
+	GetNetflixRepoContributors(ctx context.Context, repo string) (*Response[[]JsonObject], error)
+	GetNetflixRepoLatestRelease(ctx context.Context, repo string) (*Response[JsonObject], error)
+	GetNetflixRepoCommitActivity(ctx context.Context, repo string) (*Response[[]JsonObject], error)
+	GetNetflixTeams(ctx context.Context) (*Response[[]JsonObject], error)
+	GetNetflixTeamRepos(ctx context.Context, team string) (*Response[[]JsonObject], error)
+	GetNetflixRepoIssueCounts(ctx context.Context, repo string) (*Response[IssueCounts], error)
+	GetNetflixRepoReadme(ctx context.Context, repo string) (*Response[Readme], error)
+	GetNetflixRepoLanguages(ctx context.Context, repo string) (*Response[JsonObject], error)
+	GetEndpoint(ctx context.Context, path string) (*Response[RawResponse], error)
+	GetNetflixOrgEventsETag(ctx context.Context, etag string) (*Response[string], error)
+	GetTokenHealth() TokenHealth
+	GetBackoffResetTime() time.Time
+	SetToken(token string)
+	SetAlertHooks(hooks alerts.Hooks)
 }
 
 type githubClient struct {
-	httpClient       *http.Client
-	apiKey           string
-	inBackoff        bool
-	backoffLock      sync.RWMutex
-	backoffResetTime time.Time
-	logger           *zap.Logger
+	httpClient           *http.Client
+	apiKey               string // guarded by tokenLock, replaced when the token is rotated
+	tokenLock            sync.RWMutex
+	inBackoff            bool
+	backoffLock          sync.RWMutex
+	backoffResetTime     time.Time
+	logger               *zap.Logger
+	debugSampler         debugSampler
+	rateLimitLock        sync.Mutex
+	rateLimits           map[string]rateLimitState // by resource
+	proxyAuth            string
+	apiVersion           string // pinned REST API version, empty for GitHub's default
+	accept               string
+	conditionalRepoPages bool // repo pages are requested conditionally, trading memory for quota
+	pageLock             sync.Mutex
+	pages                map[string]cachedPage // last response of each conditionally requested page, by url
+	budget               requestBudget         // guarded by rateLimitLock
+	searchesInFlight     int                   // searches sent without a response yet, guarded by rateLimitLock
+	negativeCache        negativeCache
+	tokenHealth          *tokenHealth
+	pageSize             int    // items requested per page of paginated requests
+	pagination           string // how paginated requests find their next page, see PAGINATION_PAGE and PAGINATION_LINK
+	alertHooks           alerts.Hooks
+	proxyMethods         []string // methods forwarded to GitHub
+	proxyMaxBodyBytes    int64
+	proxyStripHeaders    []string // inbound headers removed on top of PROXY_STRIPPED_HEADERS
+	userAgent            string
+	headers              http.Header // set on every request, overriding any other value
 }
 
 // Get newly created GitHubClient
-func NewGithubClient(cfg config.Configuration, logger *zap.Logger) GithubClient {
+func NewGithubClient(cfg config.Configuration, logger *zap.Logger, registry metrics.Registry) GithubClient {
 	httpClient := &http.Client{
-		Timeout: 10 * time.Second,
+		Timeout:   10 * time.Second,
+		Transport: metrics.InstrumentTransport(registry, newChaosTransport(cfg, logger, registry, newTransport(cfg))),
 	}
 
 	return &githubClient{
-		httpClient:       httpClient,
-		apiKey:           cfg.GetGitHubApiKey(),
-		inBackoff:        false,
-		backoffResetTime: time.Now(),
-		logger:           logger,
+		httpClient:           httpClient,
+		apiKey:               cfg.GetGitHubApiKey(),
+		inBackoff:            false,
+		backoffResetTime:     time.Now(),
+		logger:               logger,
+		rateLimits:           map[string]rateLimitState{},
+		pages:                map[string]cachedPage{},
+		pageSize:             cfg.GetGitHubPageSize(),
+		pagination:           cfg.GetGitHubPagination(),
+		proxyAuth:            cfg.GetProxyAuth(),
+		apiVersion:           cfg.GetGitHubApiVersion(),
+		accept:               cfg.GetGitHubAccept(),
+		conditionalRepoPages: cfg.GetIncrementalRepoSync(),
+		budget:               requestBudget{reserve: cfg.GetQuotaReserve(), maxWait: cfg.GetQuotaMaxWait()},
+		negativeCache:        negativeCache{ttl: cfg.GetProxyNegativeCacheTTL(), entries: map[string]negativeEntry{}},
+		tokenHealth:          newTokenHealth(cfg.GetGitHubApiKey(), cfg.GetAnonymousFallback(), logger),
+		alertHooks:           alerts.NopHooks{},
+		proxyMethods:         cfg.GetProxyMethods(),
+		proxyMaxBodyBytes:    cfg.GetProxyMaxBodyBytes(),
+		proxyStripHeaders:    cfg.GetProxyStripHeaders(),
+		userAgent:            cfg.GetGitHubUserAgent(),
+		headers:              cfg.GetGitHubHeaders(),
 	}
 }
 
 // Fetches Netflix Org data
-func (ghc *githubClient) GetNetflixOrg(ctx context.Context) (JsonObject, error, int) {
+func (ghc *githubClient) GetNetflixOrg(ctx context.Context) (*Response[JsonObject], error) {
 	return ghc.sendGithubApiRequest(http.MethodGet, ENDPOINT_ORG_NETFLIX, ctx)
 }
 
 // Fetches Netflix Org Member data
-func (ghc *githubClient) GetNetflixOrgMembers(ctx context.Context) ([]JsonObject, error, int) {
+func (ghc *githubClient) GetNetflixOrgMembers(ctx context.Context) (*Response[[]JsonObject], error) {
 	return ghc.sendPaginatedGithubApiRequests(http.MethodGet, ENDPOINT_ORG_NETFLIX_MEMBERS, ctx)
 }
 
 // Fetches Netflix Org repo data
-func (ghc *githubClient) GetNetflixRepos(ctx context.Context) ([]JsonObject, error, int) {
-	return ghc.sendPaginatedGithubApiRequests(http.MethodGet, ENDPOINT_ORG_NETFLIX_REPOS, ctx)
+func (ghc *githubClient) GetNetflixRepos(ctx context.Context) (*Response[[]JsonObject], error) {
+	return ghc.sendPaginatedGithubApiRequestsWithOptions(http.MethodGet, ENDPOINT_ORG_NETFLIX_REPOS, ctx, paginationOptions{conditional: ghc.conditionalRepoPages})
 }
 
-// Helper function to make paginated reponses and flatten the responses in a single list
-func (ghc *githubClient) sendPaginatedGithubApiRequests(method string, url string, ctx context.Context) ([]JsonObject, error, int) {
-	if ghc.shouldBackoff() {
-		return nil, fmt.Errorf("Rate Limited, in backoff, try again later"), http.StatusTooManyRequests
+// Fetches the Netflix Org repos updated since a point in time. The repos endpoint has no since filter, so repos are listed
+// most recently updated first, stopping at the first page that reaches back past since
+func (ghc *githubClient) GetNetflixReposUpdatedSince(ctx context.Context, since time.Time) (*Response[[]JsonObject], error) {
+	resp, err := ghc.sendPaginatedGithubApiRequestsWithOptions(http.MethodGet, ENDPOINT_ORG_NETFLIX_REPOS_BY_UPDATE, ctx, paginationOptions{
+		conditional: ghc.conditionalRepoPages,
+		done: func(page []JsonObject) bool {
+			return updatedBefore(page[len(page)-1], since)
+		},
+	})
+	if err != nil {
+		return resp, err
 	}
 
-	nextPage := 1
-	var flatResponse []JsonObject
+	resp.Body = slices.DeleteFunc(resp.Body, func(repo JsonObject) bool { return updatedBefore(repo, since) })
+	return resp, nil
+}
 
-	for {
-		endpontUrl, err := netUrl.Parse(url)
+// Fetches a single Netflix repo by name, responds 404 if it doesn't exist or isn't public
+func (ghc *githubClient) GetNetflixRepo(ctx context.Context, repo string) (*Response[JsonObject], error) {
+	return ghc.sendGithubApiRequest(http.MethodGet, fmt.Sprintf(ENDPOINT_REPO, netUrl.PathEscape(repo)), ctx)
+}
 
-		if err != nil {
-			return nil, fmt.Errorf("Failed to encode request url: %v", err), http.StatusInternalServerError
-		}
+// Fetches the current rate limit status, useful to check GitHub is reachable without consuming quota
+func (ghc *githubClient) GetRateLimit(ctx context.Context) (*Response[JsonObject], error) {
+	return ghc.sendGithubApiRequest(http.MethodGet, ENDPOINT_RATE_LIMIT, ctx)
+}
 
-		queryParams := endpontUrl.Query()
+// Fetches the contributors of a Netflix repo, empty repos have none
+func (ghc *githubClient) GetNetflixRepoContributors(ctx context.Context, repo string) (*Response[[]JsonObject], error) {
+	return ghc.sendPaginatedGithubApiRequests(http.MethodGet, fmt.Sprintf(ENDPOINT_REPO_CONTRIBUTORS, netUrl.PathEscape(repo)), ctx)
+}
 
-		queryParams.Add("per_page", fmt.Sprintf("%d", PAGE_SIZE))
-		queryParams.Add("page", fmt.Sprintf("%d", nextPage))
+// Fetches the latest published release of a Netflix repo, responds 404 if the repo has no releases
+func (ghc *githubClient) GetNetflixRepoLatestRelease(ctx context.Context, repo string) (*Response[JsonObject], error) {
+	return ghc.sendGithubApiRequest(http.MethodGet, fmt.Sprintf(ENDPOINT_REPO_LATEST_RELEASE, netUrl.PathEscape(repo)), ctx)
+}
 
-		endpontUrl.RawQuery = queryParams.Encode()
+// Fetches the bytes of code per language of a Netflix repo, empty repos have none
+func (ghc *githubClient) GetNetflixRepoLanguages(ctx context.Context, repo string) (*Response[JsonObject], error) {
+	return ghc.sendGithubApiRequest(http.MethodGet, fmt.Sprintf(ENDPOINT_REPO_LANGUAGES, netUrl.PathEscape(repo)), ctx)
+}
 
-		requestUrl := endpontUrl.String()
+// Fetches the weekly commit activity of a Netflix repo over the last year, oldest week first. Retried while GitHub is still
+// computing it, if it isn't ready after STATS_MAX_ATTEMPTS the status is 202 Accepted
+func (ghc *githubClient) GetNetflixRepoCommitActivity(ctx context.Context, repo string) (*Response[[]JsonObject], error) {
+	url := fmt.Sprintf(ENDPOINT_REPO_COMMIT_ACTIVITY, netUrl.PathEscape(repo))
+	wait := STATS_RETRY_WAIT
 
-		req, err := http.NewRequestWithContext(ctx, method, requestUrl, nil)
-		if err != nil {
-			return nil, fmt.Errorf("Failed to create request: %v", err), http.StatusInternalServerError
+	// not paginated, every page is the same year of weeks
+	options := paginationOptions{done: func(page []JsonObject) bool { return true }}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := ghc.sendPaginatedGithubApiRequestsWithOptions(http.MethodGet, url, ctx, options)
+		if resp.StatusCode != http.StatusAccepted || attempt == STATS_MAX_ATTEMPTS {
+			return resp, err
 		}
 
-		if len(ghc.apiKey) > 0 {
-			req.Header.Set("Authorization", "Bearer "+ghc.apiKey)
+		select {
+		case <-time.After(wait):
+			wait *= 2
+		case <-ctx.Done():
+			return failedResponse[[]JsonObject](http.StatusServiceUnavailable, resp.Header), ctx.Err()
 		}
+	}
+}
 
-		resp, err := ghc.httpClient.Do(req)
-		if err != nil {
-			return nil, err, resp.StatusCode
+// Get the health of the GitHub API token, whether GitHub accepts it and whether requests fall back to anonymous
+func (ghc *githubClient) GetTokenHealth() TokenHealth {
+	return ghc.tokenHealth.health()
+}
+
+// Replaces the GitHub API token, e.g. once it's rotated. Requests already sent keep the previous token
+func (ghc *githubClient) SetToken(token string) {
+	ghc.tokenLock.Lock()
+	ghc.apiKey = token
+	ghc.tokenLock.Unlock()
+
+	ghc.tokenHealth.reset(token)
+}
+
+// Get the current GitHub API token
+func (ghc *githubClient) token() string {
+	ghc.tokenLock.RLock()
+	defer ghc.tokenLock.RUnlock()
+
+	return ghc.apiKey
+}
+
+// Fetches the Netflix Org teams visible to the token, GitHub answers 403 or 404 when it can't list them
+func (ghc *githubClient) GetNetflixTeams(ctx context.Context) (*Response[[]JsonObject], error) {
+	return ghc.sendPaginatedGithubApiRequests(http.MethodGet, ENDPOINT_ORG_NETFLIX_TEAMS, ctx)
+}
+
+// Fetches the repos a Netflix Org team has access to, by the team's slug
+func (ghc *githubClient) GetNetflixTeamRepos(ctx context.Context, team string) (*Response[[]JsonObject], error) {
+	return ghc.sendPaginatedGithubApiRequests(http.MethodGet, fmt.Sprintf(ENDPOINT_TEAM_REPOS, netUrl.PathEscape(team)), ctx)
+}
+
+// Helper function to make paginated reponses and flatten the responses in a single list
+func (ghc *githubClient) sendPaginatedGithubApiRequests(method string, url string, ctx context.Context) (*Response[[]JsonObject], error) {
+	return ghc.sendPaginatedGithubApiRequestsWithOptions(method, url, ctx, paginationOptions{})
+}
+
+// Helper function to make paginated requests with options, flattening the responses in a single list
+func (ghc *githubClient) sendPaginatedGithubApiRequestsWithOptions(method string, url string, ctx context.Context, options paginationOptions) (*Response[[]JsonObject], error) {
+	if ghc.shouldBackoff() {
+		return failedResponse[[]JsonObject](http.StatusTooManyRequests, nil), fmt.Errorf("Rate Limited, in backoff, try again later")
+	}
+
+	nextPage := 1
+	var flatResponse []JsonObject
+	var lastHeader http.Header // of the last page, reporting the rate limit left after every page
+
+	requestUrl, err := pageUrl(url, ghc.pageSize, nextPage)
+	if err != nil {
+		return failedResponse[[]JsonObject](http.StatusInternalServerError, nil), fmt.Errorf("Failed to encode request url: %v", err)
+	}
+
+	for requestUrl != "" {
+		if err := ghc.waitForBudget(ctx, 0); err != nil {
+			return failedResponse[[]JsonObject](http.StatusTooManyRequests, lastHeader), err
 		}
 
-		if resp.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("Request failed"), resp.StatusCode
+		req, err := http.NewRequestWithContext(ctx, method, requestUrl, nil)
+		if err != nil {
+			return failedResponse[[]JsonObject](http.StatusInternalServerError, lastHeader), fmt.Errorf("Failed to create request: %v", err)
 		}
 
-		ghc.updateBackoffState(resp.Header)
+		ghc.setApiHeaders(req.Header)
 
-		defer resp.Body.Close()
+		cached, hasCached := ghc.getCachedPage(requestUrl)
+		if options.conditional && hasCached {
+			req.Header.Set("If-None-Match", cached.etag)
+		}
 
-		body, err := io.ReadAll(resp.Body)
+		resp, err := ghc.doWithToken(req)
 		if err != nil {
-			return nil, fmt.Errorf("Failed to read response body: %v", err), http.StatusInternalServerError
+			return failedResponse[[]JsonObject](http.StatusBadGateway, lastHeader), err
 		}
+		lastHeader = resp.Header
+
+		// a page rejected for exhausting the rate limit still reports it, so backoff starts mid-pagination too
+		ghc.updateBackoffState(resp.Header)
+		ghc.trackRateLimit(resp.Header)
 
 		var result []JsonObject
-		if err := json.Unmarshal(body, &result); err != nil {
-			return nil, fmt.Errorf("error unmarshalling JSON: %v", err), http.StatusInternalServerError
+		next := nextPageLink(resp.Header)
+
+		switch {
+		// e.g. contributors of an empty repo
+		case resp.StatusCode == http.StatusNoContent:
+			resp.Body.Close()
+
+		// page hasn't changed since it was last fetched, doesn't count against the rate limit
+		case resp.StatusCode == http.StatusNotModified && options.conditional && hasCached:
+			resp.Body.Close()
+
+			result, next = cached.result, cached.next
+
+		case resp.StatusCode != http.StatusOK:
+			ghc.debugLogFailedBody("unexpected status code", requestUrl, resp)
+			return failedResponse[[]JsonObject](resp.StatusCode, resp.Header), fmt.Errorf("Request failed")
+
+		default:
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				return failedResponse[[]JsonObject](http.StatusInternalServerError, resp.Header), fmt.Errorf("Failed to read response body: %v", err)
+			}
+
+			if err := json.Unmarshal(body, &result); err != nil {
+				ghc.debugLogFailedResponse(err.Error(), requestUrl, resp.StatusCode, resp.Header, body)
+				return failedResponse[[]JsonObject](http.StatusInternalServerError, resp.Header), fmt.Errorf("error unmarshalling JSON: %v", err)
+			}
+
+			if options.conditional && resp.Header.Get("ETag") != "" {
+				ghc.cachePage(requestUrl, cachedPage{etag: resp.Header.Get("ETag"), result: result, next: next})
+			}
 		}
 
 		if len(result) == 0 {
@@ -136,63 +346,114 @@ func (ghc *githubClient) sendPaginatedGithubApiRequests(method string, url strin
 
 		flatResponse = append(flatResponse, result...)
 
+		if options.done != nil && options.done(result) {
+			break
+		}
+
 		nextPage++
+
+		if ghc.pagination == PAGINATION_LINK {
+			if requestUrl, err = sameHostLink(url, next); err != nil {
+				return failedResponse[[]JsonObject](http.StatusBadGateway, lastHeader), err
+			}
+		} else if requestUrl, err = pageUrl(url, ghc.pageSize, nextPage); err != nil {
+			return failedResponse[[]JsonObject](http.StatusInternalServerError, lastHeader), fmt.Errorf("Failed to encode request url: %v", err)
+		}
 	}
 
-	return flatResponse, nil, http.StatusOK
+	return NewResponse(http.StatusOK, lastHeader, flatResponse), nil
 }
 
 // Helper function to make a non-paginated request
-func (ghc *githubClient) sendGithubApiRequest(method string, url string, ctx context.Context) (JsonObject, error, int) {
+func (ghc *githubClient) sendGithubApiRequest(method string, url string, ctx context.Context) (*Response[JsonObject], error) {
 	if ghc.shouldBackoff() {
-		return nil, fmt.Errorf("Rate Limited, in backoff, try again later"), http.StatusTooManyRequests
+		return failedResponse[JsonObject](http.StatusTooManyRequests, nil), fmt.Errorf("Rate Limited, in backoff, try again later")
+	}
+
+	if err := ghc.waitForBudget(ctx, 0); err != nil {
+		return failedResponse[JsonObject](http.StatusTooManyRequests, nil), err
 	}
 
 	req, err := http.NewRequestWithContext(ctx, method, url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to create request: %v", err), http.StatusInternalServerError
+		return failedResponse[JsonObject](http.StatusInternalServerError, nil), fmt.Errorf("Failed to create request: %v", err)
 	}
 
-	if len(ghc.apiKey) > 0 {
-		req.Header.Set("Authorization", "Bearer "+ghc.apiKey)
-	}
+	ghc.setApiHeaders(req.Header)
 
-	resp, err := ghc.httpClient.Do(req)
+	resp, err := ghc.doWithToken(req)
 	if err != nil {
-		return nil, err, resp.StatusCode
+		return failedResponse[JsonObject](http.StatusBadGateway, nil), err
 	}
 
 	ghc.updateBackoffState(resp.Header)
+	ghc.trackRateLimit(resp.Header)
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Request failed"), resp.StatusCode
+		ghc.debugLogFailedBody("unexpected status code", url, resp)
+		return failedResponse[JsonObject](resp.StatusCode, resp.Header), fmt.Errorf("Request failed")
 	}
 
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to read response body: %v", err), http.StatusInternalServerError
+		return failedResponse[JsonObject](http.StatusInternalServerError, resp.Header), fmt.Errorf("Failed to read response body: %v", err)
 	}
 
 	var result JsonObject
 	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("error unmarshalling JSON: %v", err), http.StatusInternalServerError
+		ghc.debugLogFailedResponse(err.Error(), url, resp.StatusCode, resp.Header, body)
+		return failedResponse[JsonObject](http.StatusInternalServerError, resp.Header), fmt.Errorf("error unmarshalling JSON: %v", err)
 	}
 
-	return result, nil, resp.StatusCode
+	return NewResponse(resp.StatusCode, resp.Header, result), nil
 }
 
 // Proxies an incoming http request to the GitHub API
 func (ghc *githubClient) ForwardRequest(w http.ResponseWriter, r *http.Request) {
-	if ghc.shouldBackoff() {
-		http.Error(w, "Rate Limited, in backoff, try again later", http.StatusTooManyRequests)
+	if !ghc.limitProxyRequest(w, r) {
+		return
+	}
+
+	// requests made with the caller's own token draw from the caller's quota, not the service's
+	callerAuth := ghc.proxyAuth != PROXY_AUTH_SERVICE && r.Header.Get("Authorization") != ""
+
+	if ghc.proxyAuth == PROXY_AUTH_CALLER_ONLY && !callerAuth {
+		http.Error(w, "Authorization header required", http.StatusUnauthorized)
 		return
 	}
 
 	targetURL := GITHUB_API_URL + r.URL.Path
 
-	proxyReq, err := http.NewRequest(r.Method, targetURL, r.Body)
+	// a caller's token may see paths the service's can't, only 404s seen with the service token are cached
+	negativeCacheable := !callerAuth && r.Method == http.MethodGet && ghc.negativeCache.ttl > 0
+	if negativeCacheable {
+		if entry, ok := ghc.negativeCache.get(targetURL); ok {
+			entry.write(w)
+			return
+		}
+	}
+
+	if !callerAuth && ghc.shouldBackoff() {
+		w.Header().Set("Retry-After", RetryAfter(ghc.GetBackoffResetTime()))
+		http.Error(w, "Rate Limited, in backoff, try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	// proxied requests are only queued for so long, callers are better off retrying than holding a connection open
+	if !callerAuth {
+		if err := ghc.waitForBudget(r.Context(), ghc.budget.maxWait); err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				http.Error(w, "Error: Request timed out waiting for GitHub quota", http.StatusServiceUnavailable)
+				return
+			}
+			writeBudgetExhausted(w, err)
+			return
+		}
+	}
+
+	proxyReq, err := http.NewRequestWithContext(r.Context(), r.Method, targetURL, r.Body)
 	if err != nil {
 		ghc.logger.Error("Failed to create proxy request", zap.Error(err))
 		http.Error(w, "Failed to create request", http.StatusInternalServerError)
@@ -206,22 +467,42 @@ func (ghc *githubClient) ForwardRequest(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	gitHubApiKey := ghc.apiKey
+	ghc.stripProxyHeaders(proxyReq.Header)
+	ghc.normalizeProxyHeaders(proxyReq.Header)
 
-	if len(gitHubApiKey) > 0 {
-		proxyReq.Header.Set("Authorization", "Bearer "+gitHubApiKey)
+	// Send the request to the target service, with the service token unless the caller's is used
+	var resp *http.Response
+	if callerAuth {
+		resp, err = ghc.httpClient.Do(proxyReq)
+	} else {
+		resp, err = ghc.doWithToken(proxyReq)
 	}
-
-	// Send the request to the target service
-	resp, err := ghc.httpClient.Do(proxyReq)
 	if err != nil {
+		if isBodyTooLarge(err) {
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
 		ghc.logger.Error("Failed to forward proxy request", zap.Error(err))
+
+		if errors.Is(err, context.DeadlineExceeded) {
+			ghc.alertHooks.OnProxyError(r.URL.Path, http.StatusGatewayTimeout, err)
+			http.Error(w, "GitHub didn't respond in time", http.StatusGatewayTimeout)
+			return
+		}
+
+		// requests abandoned by their client didn't fail because of GitHub
+		if r.Context().Err() == nil {
+			ghc.alertHooks.OnProxyError(r.URL.Path, http.StatusBadGateway, err)
+		}
 		http.Error(w, "Failed to forward request", http.StatusBadGateway)
 		return
 	}
 	defer resp.Body.Close()
 
-	ghc.updateBackoffState(resp.Header)
+	if resp.StatusCode >= http.StatusInternalServerError {
+		ghc.alertHooks.OnProxyError(r.URL.Path, resp.StatusCode, nil)
+	}
 
 	// Copy response headers to the original response
 	for header, values := range resp.Header {
@@ -230,6 +511,26 @@ func (ghc *githubClient) ForwardRequest(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
+	// the rate limit headers of a caller's token say nothing about the service's quota, GitHub's own headers already report theirs
+	if !callerAuth {
+		ghc.updateBackoffState(resp.Header)
+		remaining, cost := ghc.trackRateLimit(resp.Header)
+		setQuotaHeaders(w, remaining, cost)
+	}
+
+	if negativeCacheable && resp.StatusCode == http.StatusNotFound {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, NEGATIVE_CACHE_MAX_BODY_SIZE+1))
+		if err == nil && int64(len(body)) <= NEGATIVE_CACHE_MAX_BODY_SIZE {
+			ghc.negativeCache.put(targetURL, resp.Header, body)
+		}
+
+		// the part of the body already read is written first, then the rest is copied below
+		w.WriteHeader(resp.StatusCode)
+		w.Write(body)
+		io.Copy(w, resp.Body)
+		return
+	}
+
 	// Write the response status code and body
 	w.WriteHeader(resp.StatusCode)
 	_, err = io.Copy(w, resp.Body)
@@ -271,9 +572,36 @@ func (ghc *githubClient) shouldBackoff() bool {
 	}
 }
 
+// Get when requests to GitHub are let through again, zero unless they're in backoff
+func (ghc *githubClient) GetBackoffResetTime() time.Time {
+	if !ghc.shouldBackoff() {
+		return time.Time{}
+	}
+
+	ghc.backoffLock.RLock()
+	defer ghc.backoffLock.RUnlock()
+
+	return ghc.backoffResetTime
+}
+
+// Sets the hooks notified of failures, must be called before any request is made
+func (ghc *githubClient) SetAlertHooks(hooks alerts.Hooks) {
+	ghc.alertHooks = hooks
+}
+
+// Get the Retry-After header value, in seconds, telling clients to retry at until. At least a second, so clients never retry right away
+func RetryAfter(until time.Time) string {
+	return strconv.Itoa(max(int(math.Ceil(time.Until(until).Seconds())), 1))
+}
+
 // determines it request was rate limited by github, and if so enters backoff for the specified time period
 // https://docs.github.com/en/rest/using-the-rest-api/rate-limits-for-the-rest-api?apiVersion=2022-11-28
 func (ghc *githubClient) updateBackoffState(responseHeaders http.Header) {
+	// other resources, e.g. search, have their own quota, running out of it doesn't stop requests drawing from the core quota
+	if resource := responseHeaders.Get("x-ratelimit-resource"); resource != "" && resource != "core" {
+		return
+	}
+
 	// Extract headers
 	rateLimitRemaining := responseHeaders.Get("x-ratelimit-remaining")
 	rateLimitReset := responseHeaders.Get("x-ratelimit-reset")
@@ -300,9 +628,15 @@ func (ghc *githubClient) updateBackoffState(responseHeaders http.Header) {
 
 		ghc.backoffLock.Lock()
 
+		// every request that sees the exhausted quota lands here, only the first one enters backoff
+		entered := !ghc.inBackoff || time.Now().After(ghc.backoffResetTime)
 		ghc.inBackoff = true
 		ghc.backoffResetTime = resetTimeUTC
 
 		ghc.backoffLock.Unlock()
+
+		if entered {
+			ghc.alertHooks.OnBackoffEntered(resetTimeUTC)
+		}
 	}
 }
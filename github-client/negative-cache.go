@@ -0,0 +1,84 @@
+package githubclient
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	NEGATIVE_CACHE_MAX_ENTRIES   int   = 10000     // past it, expired entries are dropped, then new 404s aren't cached until some expire
+	NEGATIVE_CACHE_MAX_BODY_SIZE int64 = 64 * 1024 // 404s with a larger body are passed through uncached
+)
+
+// 404 responses of proxied requests, replayed until they expire so clients hammering a missing path don't use up quota
+type negativeCache struct {
+	ttl     time.Duration // 0 disables negative caching
+	lock    sync.Mutex
+	entries map[string]negativeEntry // by url
+}
+
+// Cached 404 response
+type negativeEntry struct {
+	header   http.Header
+	body     []byte
+	cachedAt time.Time
+}
+
+// Get the cached 404 for a url, false if there's none or it expired
+func (nc *negativeCache) get(url string) (negativeEntry, bool) {
+	nc.lock.Lock()
+	defer nc.lock.Unlock()
+
+	entry, ok := nc.entries[url]
+	if ok && time.Since(entry.cachedAt) >= nc.ttl {
+		delete(nc.entries, url)
+		return negativeEntry{}, false
+	}
+
+	return entry, ok
+}
+
+// Caches a 404 for a url. Rate limit headers are dropped, they'd be stale by the time the response is replayed
+func (nc *negativeCache) put(url string, header http.Header, body []byte) {
+	header = header.Clone()
+	for name := range header {
+		if strings.HasPrefix(strings.ToLower(name), "x-ratelimit-") {
+			header.Del(name)
+		}
+	}
+	header.Del("Date")
+
+	nc.lock.Lock()
+	defer nc.lock.Unlock()
+
+	if len(nc.entries) >= NEGATIVE_CACHE_MAX_ENTRIES {
+		for cachedUrl, entry := range nc.entries {
+			if time.Since(entry.cachedAt) >= nc.ttl {
+				delete(nc.entries, cachedUrl)
+			}
+		}
+
+		if len(nc.entries) >= NEGATIVE_CACHE_MAX_ENTRIES {
+			return
+		}
+	}
+
+	nc.entries[url] = negativeEntry{header: header, body: body, cachedAt: time.Now()}
+}
+
+// Replays a cached 404
+func (entry negativeEntry) write(w http.ResponseWriter) {
+	for header, values := range entry.header {
+		for _, value := range values {
+			w.Header().Add(header, value)
+		}
+	}
+
+	w.Header().Set("X-Cache", "HIT")
+	w.Header().Set("Age", strconv.Itoa(int(time.Since(entry.cachedAt).Seconds())))
+	w.WriteHeader(http.StatusNotFound)
+	w.Write(entry.body)
+}
@@ -0,0 +1,114 @@
+package githubclient
+
+import (
+	"fmt"
+	"net/http"
+	netUrl "net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// How paginated requests find their next page
+const (
+	PAGINATION_PAGE string = "page" // increments the page query parameter until a page comes back empty
+	PAGINATION_LINK string = "link" // follows the Link header's next page until there's none
+)
+
+// Options of a paginated request
+type paginationOptions struct {
+	conditional bool                         // send each page's last ETag, reusing the page when GitHub answers 304 Not Modified
+	done        func(page []JsonObject) bool // stops paginating after a page it returns true for
+}
+
+// Last response of a conditionally requested page
+type cachedPage struct {
+	etag   string
+	result []JsonObject
+	next   string // the page's Link to the next page, GitHub doesn't always send it with 304 Not Modified
+}
+
+// Get the url of a page of a paginated endpoint, any page parameters already in the url are replaced
+func pageUrl(url string, pageSize int, page int) (string, error) {
+	endpointUrl, err := netUrl.Parse(url)
+	if err != nil {
+		return "", err
+	}
+
+	queryParams := endpointUrl.Query()
+	queryParams.Set("per_page", strconv.Itoa(pageSize))
+	queryParams.Set("page", strconv.Itoa(page))
+	endpointUrl.RawQuery = queryParams.Encode()
+
+	return endpointUrl.String(), nil
+}
+
+// Get the url of the next page from a response's Link header, empty on the last page.
+// docs: https://docs.github.com/en/rest/using-the-rest-api/using-pagination-in-the-rest-api?apiVersion=2022-11-28#using-link-headers
+func nextPageLink(header http.Header) string {
+	for _, link := range strings.Split(header.Get("Link"), ",") {
+		target, params, found := strings.Cut(strings.TrimSpace(link), ";")
+		if !found || !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+			continue
+		}
+
+		for _, param := range strings.Split(params, ";") {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.TrimSuffix(strings.TrimPrefix(target, "<"), ">")
+			}
+		}
+	}
+
+	return ""
+}
+
+// Get link if it's on the same host as url, so the token is never sent elsewhere by following a Link header. Empty links stay empty
+func sameHostLink(url string, link string) (string, error) {
+	if link == "" {
+		return "", nil
+	}
+
+	endpointUrl, err := netUrl.Parse(url)
+	if err != nil {
+		return "", err
+	}
+
+	linkUrl, err := netUrl.Parse(link)
+	if err != nil {
+		return "", fmt.Errorf("Failed to parse next page link: %v", err)
+	}
+
+	if linkUrl.Scheme != endpointUrl.Scheme || linkUrl.Host != endpointUrl.Host {
+		return "", fmt.Errorf("Next page link %s isn't on %s", linkUrl.Redacted(), endpointUrl.Host)
+	}
+
+	return link, nil
+}
+
+// Get the last response of a page, false if it was never conditionally requested
+func (ghc *githubClient) getCachedPage(url string) (cachedPage, bool) {
+	ghc.pageLock.Lock()
+	defer ghc.pageLock.Unlock()
+
+	page, ok := ghc.pages[url]
+	return page, ok
+}
+
+// Keeps the response of a page, sent as If-None-Match the next time it's requested
+func (ghc *githubClient) cachePage(url string, page cachedPage) {
+	ghc.pageLock.Lock()
+	defer ghc.pageLock.Unlock()
+
+	ghc.pages[url] = page
+}
+
+// Get whether a repo was updated before since, repos with a missing or malformed updated_at are treated as updated
+func updatedBefore(repo JsonObject, since time.Time) bool {
+	updatedAt, ok := repo["updated_at"].(string)
+	if !ok {
+		return false
+	}
+
+	updatedTime, err := time.Parse(time.RFC3339, updatedAt)
+	return err == nil && updatedTime.Before(since)
+}
@@ -0,0 +1,51 @@
+package githubclient
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Inbound headers never forwarded to GitHub: they're meant for the service (or a proxy in front of it), and would leak
+// the caller's cookies, credentials, or network addresses to GitHub
+var PROXY_STRIPPED_HEADERS = []string{"Cookie", "Proxy-Authorization", "X-Api-Key", "Forwarded", "X-Forwarded-For", "X-Forwarded-Host", "X-Forwarded-Proto", "X-Real-Ip"}
+
+// Rejects requests the proxy doesn't forward, a method that isn't allowed (405) or a body over the limit (413), and
+// limits the body of the rest. Returns false if the request was rejected
+func (ghc *githubClient) limitProxyRequest(w http.ResponseWriter, r *http.Request) bool {
+	if !slices.Contains(ghc.proxyMethods, r.Method) {
+		w.Header().Set("Allow", strings.Join(ghc.proxyMethods, ", "))
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+
+	// bodies of unknown length (chunked) are cut off once they pass the limit, see isBodyTooLarge
+	if r.ContentLength > ghc.proxyMaxBodyBytes {
+		http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+		return false
+	}
+
+	if r.Body != nil {
+		r.Body = http.MaxBytesReader(w, r.Body, ghc.proxyMaxBodyBytes)
+	}
+
+	return true
+}
+
+// Determines if a proxied request failed because its body went over the limit
+func isBodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+// Removes the inbound headers that must not reach GitHub
+func (ghc *githubClient) stripProxyHeaders(header http.Header) {
+	for _, name := range PROXY_STRIPPED_HEADERS {
+		header.Del(name)
+	}
+
+	for _, name := range ghc.proxyStripHeaders {
+		header.Del(name)
+	}
+}
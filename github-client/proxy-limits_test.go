@@ -0,0 +1,155 @@
+package githubclient
+
+import (
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/config"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/metrics"
+	"go.uber.org/zap"
+)
+
+// Get a client configured with args that sends every GitHub request to upstream instead
+func newProxyTestClient(t *testing.T, upstream *httptest.Server, args ...string) *githubClient {
+	t.Helper()
+
+	t.Setenv("GITHUB_API_TOKEN", "test-token")
+	cfg, err := config.ParseConfiguration(flag.NewFlagSet("test", flag.ContinueOnError), append([]string{"--port=7101"}, args...), true, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	upstreamUrl, err := url.Parse(upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ghc := NewGithubClient(cfg, zap.NewNop(), metrics.NewRegistry()).(*githubClient)
+	ghc.httpClient.Transport = metrics.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		r.URL.Scheme, r.URL.Host = upstreamUrl.Scheme, upstreamUrl.Host
+		return upstream.Client().Transport.RoundTrip(r)
+	})
+
+	return ghc
+}
+
+func TestForwardRequestStripsHeaders(t *testing.T) {
+	var received http.Header
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+	}))
+	defer upstream.Close()
+
+	ghc := newProxyTestClient(t, upstream, "--proxy-strip-headers=X-Internal-Trace")
+
+	req := httptest.NewRequest(http.MethodGet, "/repos/Netflix/zuul/issues", nil)
+	stripped := append(slices.Clone(PROXY_STRIPPED_HEADERS), "X-Internal-Trace")
+	for _, name := range stripped {
+		req.Header.Set(name, "value")
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("If-None-Match", `"etag"`)
+
+	recorder := httptest.NewRecorder()
+	ghc.ForwardRequest(recorder, req)
+
+	if recorder.Code != http.StatusOK || received == nil {
+		t.Fatalf("ForwardRequest = %d, upstream reached: %v", recorder.Code, received != nil)
+	}
+
+	for _, name := range stripped {
+		if value := received.Get(name); value != "" {
+			t.Errorf("%s reached GitHub as %q", name, value)
+		}
+	}
+
+	for _, name := range []string{"Accept", "If-None-Match"} {
+		if received.Get(name) != req.Header.Get(name) {
+			t.Errorf("%s reached GitHub as %q, want %q", name, received.Get(name), req.Header.Get(name))
+		}
+	}
+	if received.Get("Authorization") != "Bearer test-token" {
+		t.Errorf("Authorization reached GitHub as %q, want the service token", received.Get("Authorization"))
+	}
+}
+
+func TestForwardRequestLimits(t *testing.T) {
+	// an upstream still reading a body cut off over the limit may outlive the request
+	var lock sync.Mutex
+	var receivedBody string
+	reached := false
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		lock.Lock()
+		defer lock.Unlock()
+		reached, receivedBody = true, string(body)
+	}))
+	defer upstream.Close()
+
+	ghc := newProxyTestClient(t, upstream, "--proxy-methods=GET,HEAD,POST", "--proxy-max-body-bytes=16")
+
+	tests := []struct {
+		name    string
+		method  string
+		body    string
+		chunked bool // sent without a Content-Length
+		status  int
+	}{
+		{name: "allowed method", method: http.MethodGet, status: http.StatusOK},
+		{name: "disallowed method", method: http.MethodDelete, status: http.StatusMethodNotAllowed},
+		{name: "method outside the defaults", method: http.MethodPatch, body: "{}", status: http.StatusMethodNotAllowed},
+		{name: "body within the limit", method: http.MethodPost, body: `{"title": "bug"}`, status: http.StatusOK},
+		{name: "body over the limit", method: http.MethodPost, body: `{"title": "a bug"}`, status: http.StatusRequestEntityTooLarge},
+		{name: "chunked body within the limit", method: http.MethodPost, body: `{"title": "bug"}`, chunked: true, status: http.StatusOK},
+		{name: "chunked body over the limit", method: http.MethodPost, body: strings.Repeat("x", 1<<16), chunked: true, status: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lock.Lock()
+			reached, receivedBody = false, ""
+			lock.Unlock()
+
+			req := httptest.NewRequest(test.method, "/repos/Netflix/zuul/issues", strings.NewReader(test.body))
+			if test.chunked {
+				req.ContentLength = -1
+			}
+
+			recorder := httptest.NewRecorder()
+			ghc.ForwardRequest(recorder, req)
+
+			if recorder.Code != test.status {
+				t.Fatalf("ForwardRequest = %d, want %d: %s", recorder.Code, test.status, recorder.Body.String())
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+
+			switch test.status {
+			case http.StatusOK:
+				if receivedBody != test.body {
+					t.Errorf("GitHub received body %q, want %q", receivedBody, test.body)
+				}
+			case http.StatusMethodNotAllowed:
+				if allow := recorder.Header().Get("Allow"); allow != "GET, HEAD, POST" {
+					t.Errorf("Allow = %q", allow)
+				}
+				if reached {
+					t.Error("Disallowed method reached GitHub")
+				}
+			case http.StatusRequestEntityTooLarge:
+				if !test.chunked && reached {
+					t.Error("Body over the limit reached GitHub")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package githubclient
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// Last seen rate limit of a GitHub API resource (core, search, graphql, ...), each resource has its own quota
+type rateLimitState struct {
+	remaining int
+	reset     string // epoch seconds the window resets at, identifies the window
+}
+
+// Records the rate limit reported by a GitHub API response, returning the quota remaining after the request, and how much
+// quota the request cost, i.e. the drop in remaining since the previous response for the same resource. The cost is -1 when
+// it can't be determined, e.g. the response had no rate limit headers or it's the first response seen for the resource in its
+// rate limit window
+func (ghc *githubClient) trackRateLimit(responseHeaders http.Header) (remaining int, cost int) {
+	remaining, err := strconv.Atoi(responseHeaders.Get("x-ratelimit-remaining"))
+	if err != nil {
+		return -1, -1
+	}
+
+	resource := responseHeaders.Get("x-ratelimit-resource")
+	if resource == "" {
+		resource = "core"
+	}
+
+	current := rateLimitState{remaining: remaining, reset: responseHeaders.Get("x-ratelimit-reset")}
+
+	ghc.rateLimitLock.Lock()
+	previous, seen := ghc.rateLimits[resource]
+	ghc.rateLimits[resource] = current
+	ghc.rateLimitLock.Unlock()
+
+	// without a prior sample in the same window there's nothing to compare against, x-ratelimit-used counts everything
+	// used in the window, by this service and every other client of the token, not what this request cost
+	if !seen || previous.reset != current.reset {
+		return remaining, -1
+	}
+
+	// concurrent requests can be reported out of order, never report a negative cost
+	return remaining, max(previous.remaining-current.remaining, 0)
+}
+
+// Attaches the quota remaining and the cost of a proxied request, so proxy consumers can self-regulate
+func setQuotaHeaders(w http.ResponseWriter, remaining int, cost int) {
+	if remaining >= 0 {
+		w.Header().Set("X-GitHub-Quota-Remaining", strconv.Itoa(remaining))
+	}
+
+	if cost >= 0 {
+		w.Header().Set("X-Request-Quota-Cost", strconv.Itoa(cost))
+	}
+}
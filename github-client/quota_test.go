@@ -0,0 +1,46 @@
+package githubclient
+
+import (
+	"net/http"
+	"testing"
+)
+
+func rateLimitHeaders(resource string, remaining string, used string, reset string) http.Header {
+	headers := http.Header{}
+	headers.Set("x-ratelimit-remaining", remaining)
+	headers.Set("x-ratelimit-used", used)
+	headers.Set("x-ratelimit-reset", reset)
+	if resource != "" {
+		headers.Set("x-ratelimit-resource", resource)
+	}
+
+	return headers
+}
+
+func TestTrackRateLimit(t *testing.T) {
+	ghc := &githubClient{rateLimits: map[string]rateLimitState{}}
+
+	// responses in order, each with the remaining and cost trackRateLimit should report for it
+	steps := []struct {
+		name          string
+		headers       http.Header
+		wantRemaining int
+		wantCost      int
+	}{
+		{name: "no rate limit headers", headers: http.Header{}, wantRemaining: -1, wantCost: -1},
+		{name: "first sample, quota used by other clients isn't attributed", headers: rateLimitHeaders("", "4000", "1000", "100"), wantRemaining: 4000, wantCost: -1},
+		{name: "drop since the previous sample", headers: rateLimitHeaders("core", "3999", "1001", "100"), wantRemaining: 3999, wantCost: 1},
+		{name: "other clients' requests in between", headers: rateLimitHeaders("core", "3990", "1010", "100"), wantRemaining: 3990, wantCost: 9},
+		{name: "reported out of order", headers: rateLimitHeaders("core", "3995", "1005", "100"), wantRemaining: 3995, wantCost: 0},
+		{name: "other resources are tracked apart", headers: rateLimitHeaders("search", "29", "1", "100"), wantRemaining: 29, wantCost: -1},
+		{name: "first sample of a new window", headers: rateLimitHeaders("core", "4999", "1", "200"), wantRemaining: 4999, wantCost: -1},
+		{name: "drop within the new window", headers: rateLimitHeaders("core", "4997", "3", "200"), wantRemaining: 4997, wantCost: 2},
+	}
+
+	for _, step := range steps {
+		remaining, cost := ghc.trackRateLimit(step.headers)
+		if remaining != step.wantRemaining || cost != step.wantCost {
+			t.Errorf("%s: trackRateLimit = (%d, %d), want (%d, %d)", step.name, remaining, cost, step.wantRemaining, step.wantCost)
+		}
+	}
+}
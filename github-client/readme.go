@@ -0,0 +1,40 @@
+package githubclient
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	netUrl "net/url"
+	"strings"
+)
+
+// README of a repo, as GitHub describes it along with its decoded content
+type Readme struct {
+	Metadata JsonObject `json:"metadata"` // GitHub's response as is, content still base64 encoded
+	Content  string     `json:"content"`
+}
+
+// Fetches the README of a Netflix repo and decodes its content, responds 404 if the repo has no README
+func (ghc *githubClient) GetNetflixRepoReadme(ctx context.Context, repo string) (*Response[Readme], error) {
+	url := fmt.Sprintf(ENDPOINT_REPO_README, netUrl.PathEscape(repo))
+
+	resp, err := ghc.sendGithubApiRequest(http.MethodGet, url, ctx)
+	if err != nil {
+		return withBody(resp, Readme{}), err
+	}
+
+	metadata := resp.Body
+	encoded, _ := metadata["content"].(string)
+	if encoding, _ := metadata["encoding"].(string); encoding != "base64" {
+		return failedResponse[Readme](http.StatusBadGateway, resp.Header), fmt.Errorf("Unexpected README encoding %q", encoding)
+	}
+
+	// GitHub wraps the encoded content every 60 characters
+	content, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(encoded, "\n", ""))
+	if err != nil {
+		return failedResponse[Readme](http.StatusBadGateway, resp.Header), fmt.Errorf("Failed to decode README: %v", err)
+	}
+
+	return withBody(resp, Readme{Metadata: metadata, Content: string(content)}), nil
+}
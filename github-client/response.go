@@ -0,0 +1,63 @@
+package githubclient
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Outcome of a request to the GitHub API, along with its decoded body. Requests that fail still return their response, its
+// status code tells why, e.g. 429 while in backoff or 502 when GitHub couldn't be reached
+type Response[T any] struct {
+	StatusCode int
+	Header     http.Header // of the last response GitHub sent, nil if it wasn't reached
+	RateLimit  RateLimit   // reported by Header
+	Body       T           // zero unless the request succeeded
+}
+
+// Rate limit of a GitHub API resource as reported by a response, zero if it didn't report one
+type RateLimit struct {
+	Resource  string // core, search, graphql, ...
+	Limit     int
+	Remaining int
+	Used      int
+	Reset     time.Time // when the window resets
+}
+
+// Get a new response with body, parsing the rate limit header reports, e.g. for fake clients
+func NewResponse[T any](statusCode int, header http.Header, body T) *Response[T] {
+	return &Response[T]{StatusCode: statusCode, Header: header, RateLimit: parseRateLimit(header), Body: body}
+}
+
+// Get a response for a request that failed with statusCode, without a body
+func failedResponse[T any](statusCode int, header http.Header) *Response[T] {
+	var body T
+	return NewResponse(statusCode, header, body)
+}
+
+// Get a copy of resp with another body, e.g. once its decoded body is converted
+func withBody[T any, U any](resp *Response[T], body U) *Response[U] {
+	return &Response[U]{StatusCode: resp.StatusCode, Header: resp.Header, RateLimit: resp.RateLimit, Body: body}
+}
+
+// Get the rate limit reported by header, the resource is core unless the header says otherwise
+func parseRateLimit(header http.Header) RateLimit {
+	if header.Get("x-ratelimit-remaining") == "" {
+		return RateLimit{}
+	}
+
+	rateLimit := RateLimit{Resource: header.Get("x-ratelimit-resource")}
+	if rateLimit.Resource == "" {
+		rateLimit.Resource = "core"
+	}
+
+	rateLimit.Limit, _ = strconv.Atoi(header.Get("x-ratelimit-limit"))
+	rateLimit.Remaining, _ = strconv.Atoi(header.Get("x-ratelimit-remaining"))
+	rateLimit.Used, _ = strconv.Atoi(header.Get("x-ratelimit-used"))
+
+	if reset, err := strconv.ParseInt(header.Get("x-ratelimit-reset"), 10, 64); err == nil {
+		rateLimit.Reset = time.Unix(reset, 0).UTC()
+	}
+
+	return rateLimit
+}
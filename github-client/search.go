@@ -0,0 +1,142 @@
+package githubclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	netUrl "net/url"
+	"strconv"
+	"time"
+)
+
+// Counts only, a single result is the smallest page the search API serves.
+// docs: https://docs.github.com/en/rest/search/search?apiVersion=2022-11-28#search-issues-and-pull-requests
+const ENDPOINT_SEARCH_ISSUES string = GITHUB_API_URL + "/search/issues?per_page=1&q=%s" // formatted with the escaped query
+
+const (
+	SEARCH_MAX_ATTEMPTS        int           = 3
+	SEARCH_QUOTA_POLL_INTERVAL time.Duration = 100 * time.Millisecond // how often searches waiting on others in flight check the quota again
+)
+
+// Open issues and pull requests of a repo. GitHub counts pull requests as issues in a repo's open_issues_count
+type IssueCounts struct {
+	OpenIssues       int `json:"open_issues"`
+	OpenPullRequests int `json:"open_pull_requests"`
+}
+
+// Fetches the open issue and pull request counts of a Netflix repo with two searches. Searches draw from their own,
+// much smaller, quota, which is paced separately from the core quota
+func (ghc *githubClient) GetNetflixRepoIssueCounts(ctx context.Context, repo string) (*Response[IssueCounts], error) {
+	issues, err := ghc.searchIssuesCount(ctx, fmt.Sprintf("repo:Netflix/%s is:issue is:open", repo))
+	if err != nil {
+		return withBody(issues, IssueCounts{}), err
+	}
+
+	pullRequests, err := ghc.searchIssuesCount(ctx, fmt.Sprintf("repo:Netflix/%s is:pr is:open", repo))
+	if err != nil {
+		return withBody(pullRequests, IssueCounts{}), err
+	}
+
+	return withBody(pullRequests, IssueCounts{OpenIssues: issues.Body, OpenPullRequests: pullRequests.Body}), nil
+}
+
+// Get the total count of issues and pull requests matching query. Searches refused because the search quota ran out, e.g.
+// spent by proxied searches, wait for it to reset and are retried
+func (ghc *githubClient) searchIssuesCount(ctx context.Context, query string) (*Response[int], error) {
+	url := fmt.Sprintf(ENDPOINT_SEARCH_ISSUES, netUrl.QueryEscape(query))
+
+	for attempt := 1; ; attempt++ {
+		if err := ghc.waitForSearchQuota(ctx); err != nil {
+			return failedResponse[int](http.StatusServiceUnavailable, nil), err
+		}
+
+		resp, err := ghc.sendGithubApiRequest(http.MethodGet, url, ctx)
+		ghc.releaseSearchQuota()
+
+		if err != nil {
+			if resp.StatusCode == http.StatusForbidden && attempt < SEARCH_MAX_ATTEMPTS && ghc.searchQuotaExhausted() {
+				continue
+			}
+			return withBody(resp, 0), err
+		}
+
+		// counts of searches that timed out on GitHub's side are lower bounds
+		if incomplete, _ := resp.Body["incomplete_results"].(bool); incomplete {
+			return failedResponse[int](http.StatusGatewayTimeout, resp.Header), fmt.Errorf("Search for %q returned incomplete results", query)
+		}
+
+		total, ok := resp.Body["total_count"].(float64)
+		if !ok {
+			return failedResponse[int](http.StatusBadGateway, resp.Header), fmt.Errorf("Search for %q has no total_count", query)
+		}
+
+		return withBody(resp, int(total)), nil
+	}
+}
+
+// Waits until the search quota has a request left that isn't already taken by a search in flight, and takes it. Until the
+// search quota is known, searches are sent one at a time
+func (ghc *githubClient) waitForSearchQuota(ctx context.Context) error {
+	for {
+		wait := ghc.reserveSearchQuota()
+		if wait <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// Takes a search request from the quota, returning how long to wait before trying again if none is left
+func (ghc *githubClient) reserveSearchQuota() time.Duration {
+	ghc.rateLimitLock.Lock()
+	defer ghc.rateLimitLock.Unlock()
+
+	available := 1
+	untilReset := SEARCH_QUOTA_POLL_INTERVAL
+
+	if state, seen := ghc.rateLimits["search"]; seen {
+		if resetEpoch, err := strconv.ParseInt(state.reset, 10, 64); err == nil {
+			// a second past the reset, so the new window has surely started
+			untilReset = time.Until(time.Unix(resetEpoch, 0).Add(time.Second))
+			available = state.remaining
+			if untilReset <= 0 {
+				available = 1
+			}
+		}
+	}
+
+	if available-ghc.searchesInFlight <= 0 {
+		// searches in flight report the quota when they're answered
+		if ghc.searchesInFlight > 0 || untilReset <= 0 {
+			return SEARCH_QUOTA_POLL_INTERVAL
+		}
+		return untilReset
+	}
+
+	ghc.searchesInFlight++
+	return 0
+}
+
+// Returns a search request taken from the quota once it's answered, its response reported the quota left
+func (ghc *githubClient) releaseSearchQuota() {
+	ghc.rateLimitLock.Lock()
+	defer ghc.rateLimitLock.Unlock()
+
+	ghc.searchesInFlight--
+}
+
+// Whether the last seen search quota ran out
+func (ghc *githubClient) searchQuotaExhausted() bool {
+	ghc.rateLimitLock.Lock()
+	defer ghc.rateLimitLock.Unlock()
+
+	state, seen := ghc.rateLimits["search"]
+	return seen && state.remaining == 0
+}
@@ -0,0 +1,89 @@
+package githubclient
+
+import (
+	"context"
+	"time"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/config"
+	"go.uber.org/zap"
+)
+
+// How often the token file is re-read. Kubernetes takes up to a minute to update mounted secrets anyway
+const TOKEN_FILE_POLL_INTERVAL time.Duration = 10 * time.Second
+
+// Re-reads the GitHub API token from a file as it changes on disk, e.g. a rotated Kubernetes secret or a Vault agent template,
+// and hands new tokens to the client
+type TokenFileWatcher interface {
+	Start(ctx context.Context, fail func(error)) error
+	Stop(ctx context.Context) error
+}
+
+type tokenFileWatcher struct {
+	path   string
+	client GithubClient
+	token  string // last token read from the file
+	logger *zap.Logger
+	stop   chan struct{}
+	done   chan struct{} // closed once the polling thread has exited
+}
+
+// Get new TokenFileWatcher, token is the token the client was created with
+func NewTokenFileWatcher(path string, token string, client GithubClient, logger *zap.Logger) TokenFileWatcher {
+	return &tokenFileWatcher{
+		path:   path,
+		client: client,
+		token:  token,
+		logger: logger,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// Starts thread that re-reads the token file every TOKEN_FILE_POLL_INTERVAL until stopped
+func (w *tokenFileWatcher) Start(ctx context.Context, fail func(error)) error {
+	go func() {
+		ticker := time.NewTicker(TOKEN_FILE_POLL_INTERVAL)
+		defer ticker.Stop()
+		defer close(w.done)
+
+		for {
+			select {
+			case <-ticker.C:
+				w.reload()
+			case <-w.stop:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Stops re-reading the token file
+func (w *tokenFileWatcher) Stop(ctx context.Context) error {
+	close(w.stop)
+
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Hands the token to the client if it changed. A file that can't be read, or is empty (e.g. mid rewrite), keeps the current token
+func (w *tokenFileWatcher) reload() {
+	token, err := config.ReadTokenFile(w.path)
+	if err != nil {
+		w.logger.Warn("Failed to read GitHub token file, keeping the current token", zap.String("path", w.path), zap.Error(err))
+		return
+	}
+
+	if token == w.token {
+		return
+	}
+
+	w.token = token
+	w.client.SetToken(token)
+	w.logger.Info("GitHub API token rotated, read a new token from the token file", zap.String("path", w.path))
+}
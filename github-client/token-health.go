@@ -0,0 +1,156 @@
+package githubclient
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// While the token is rejected and requests fall back to anonymous, the token is tried again this often so a rotated or
+// reinstated token is picked back up
+const TOKEN_RECHECK_INTERVAL time.Duration = 5 * time.Minute
+
+// Health of the GitHub API token
+const (
+	TOKEN_STATUS_NONE     string = "none"     // no token configured, requests are anonymous
+	TOKEN_STATUS_UNKNOWN  string = "unknown"  // no request was made with the token yet
+	TOKEN_STATUS_VALID    string = "valid"    // GitHub accepted the token on the last request made with it
+	TOKEN_STATUS_REJECTED string = "rejected" // GitHub answered 401, the token is expired or revoked
+)
+
+// Health of the GitHub API token, as reported on /cachestatus
+type TokenHealth struct {
+	Status            string     `json:"status"`
+	AnonymousFallback bool       `json:"anonymous_fallback"` // requests are currently made without the token
+	RejectedSince     *time.Time `json:"rejected_since,omitempty"`
+	LastCheckedAt     *time.Time `json:"last_checked_at,omitempty"` // last response to a request made with the token
+}
+
+// Tracks whether GitHub accepts the token, and whether requests should currently be made without it
+type tokenHealth struct {
+	lock          sync.Mutex
+	fallback      bool // requests fall back to anonymous while the token is rejected
+	status        string
+	rejectedSince time.Time
+	lastChecked   time.Time
+	logger        *zap.Logger
+}
+
+// Get new tokenHealth for the token, which may be empty
+func newTokenHealth(apiKey string, fallback bool, logger *zap.Logger) *tokenHealth {
+	status := TOKEN_STATUS_UNKNOWN
+	if apiKey == "" {
+		status = TOKEN_STATUS_NONE
+	}
+
+	return &tokenHealth{fallback: fallback, status: status, logger: logger}
+}
+
+// Starts tracking a new token, its health is unknown until a request is made with it
+func (th *tokenHealth) reset(apiKey string) {
+	th.lock.Lock()
+	defer th.lock.Unlock()
+
+	th.status = TOKEN_STATUS_UNKNOWN
+	if apiKey == "" {
+		th.status = TOKEN_STATUS_NONE
+	}
+	th.rejectedSince = time.Time{}
+	th.lastChecked = time.Time{}
+}
+
+// Whether a request should be made with the token. A rejected token is only tried again once TOKEN_RECHECK_INTERVAL has
+// passed since it was last rejected, when requests fall back to anonymous
+func (th *tokenHealth) useToken() bool {
+	th.lock.Lock()
+	defer th.lock.Unlock()
+
+	switch th.status {
+	case TOKEN_STATUS_NONE:
+		return false
+	case TOKEN_STATUS_REJECTED:
+		return !th.fallback || time.Since(th.lastChecked) >= TOKEN_RECHECK_INTERVAL
+	default:
+		return true
+	}
+}
+
+// Records GitHub's response to a request made with the token, returns whether the token was rejected
+func (th *tokenHealth) record(statusCode int) bool {
+	th.lock.Lock()
+	defer th.lock.Unlock()
+
+	rejected := statusCode == http.StatusUnauthorized
+	th.lastChecked = time.Now()
+
+	switch {
+	case rejected && th.status != TOKEN_STATUS_REJECTED:
+		th.status = TOKEN_STATUS_REJECTED
+		th.rejectedSince = th.lastChecked
+		th.logger.Error("GITHUB_API_TOKEN was rejected by GitHub (401), it's expired or revoked. Replace it to restore authenticated requests",
+			zap.Bool("anonymous fallback", th.fallback))
+
+	// any other response, even an error, means the token was authenticated
+	case !rejected && th.status != TOKEN_STATUS_VALID:
+		if th.status == TOKEN_STATUS_REJECTED {
+			th.logger.Info("GITHUB_API_TOKEN is accepted by GitHub again", zap.Duration("rejected for", th.lastChecked.Sub(th.rejectedSince)))
+		}
+		th.status = TOKEN_STATUS_VALID
+	}
+
+	return rejected
+}
+
+// Get the health of the token
+func (th *tokenHealth) health() TokenHealth {
+	th.lock.Lock()
+	defer th.lock.Unlock()
+
+	health := TokenHealth{
+		Status:            th.status,
+		AnonymousFallback: th.fallback && th.status == TOKEN_STATUS_REJECTED,
+	}
+
+	if th.status == TOKEN_STATUS_REJECTED {
+		rejectedSince := th.rejectedSince
+		health.RejectedSince = &rejectedSince
+	}
+
+	if !th.lastChecked.IsZero() {
+		lastChecked := th.lastChecked
+		health.LastCheckedAt = &lastChecked
+	}
+
+	return health
+}
+
+// Sends a request authenticated with the token, unless the token is rejected and requests fall back to anonymous. A request
+// the token is rejected for is retried anonymously when falling back, if it has no body to replay
+func (ghc *githubClient) doWithToken(req *http.Request) (*http.Response, error) {
+	withToken := ghc.tokenHealth.useToken()
+	if withToken {
+		req.Header.Set("Authorization", "Bearer "+ghc.token())
+	}
+
+	resp, err := ghc.httpClient.Do(req)
+	accountRequest(req.Context(), resp)
+	if err != nil || !withToken {
+		return resp, err
+	}
+
+	if !ghc.tokenHealth.record(resp.StatusCode) || !ghc.tokenHealth.fallback || (req.Body != nil && req.Body != http.NoBody) {
+		return resp, nil
+	}
+
+	resp.Body.Close()
+
+	anonymousReq := req.Clone(req.Context())
+	anonymousReq.Header.Del("Authorization")
+
+	resp, err = ghc.httpClient.Do(anonymousReq)
+	accountRequest(req.Context(), resp)
+
+	return resp, err
+}
@@ -0,0 +1,36 @@
+package githubclient
+
+import (
+	"crypto/tls"
+	"net/http"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/config"
+)
+
+// Get the transport GitHub requests are sent with, through the configured proxy (or the environment's) and trusting the
+// configured root CAs. Without either, the default transport is used as is
+func newTransport(cfg config.Configuration) http.RoundTripper {
+	if cfg.GetGitHubProxy() == nil && cfg.GetGitHubRootCAs() == nil && !cfg.GetGitHubInsecureSkipVerify() {
+		return http.DefaultTransport
+	}
+
+	var transport *http.Transport
+	if defaultTransport, ok := http.DefaultTransport.(*http.Transport); ok {
+		transport = defaultTransport.Clone()
+	} else {
+		transport = &http.Transport{Proxy: http.ProxyFromEnvironment, ForceAttemptHTTP2: true}
+	}
+
+	if cfg.GetGitHubProxy() != nil {
+		transport.Proxy = http.ProxyURL(cfg.GetGitHubProxy())
+	}
+
+	if cfg.GetGitHubRootCAs() != nil || cfg.GetGitHubInsecureSkipVerify() {
+		transport.TLSClientConfig = &tls.Config{
+			RootCAs:            cfg.GetGitHubRootCAs(),
+			InsecureSkipVerify: cfg.GetGitHubInsecureSkipVerify(),
+		}
+	}
+
+	return transport
+}
@@ -2,6 +2,17 @@ module github.com/adamjeanlaurent/github-api-read-cache-service
 
 go 1.22.0
 
-require go.uber.org/zap v1.27.0
+require (
+	github.com/gorilla/websocket v1.5.3
+	github.com/klauspost/compress v1.17.11
+	github.com/vmihailenco/msgpack/v5 v5.4.1
+	go.uber.org/zap v1.27.0
+	golang.org/x/net v0.30.0
+	golang.org/x/sync v0.9.0
+)
 
-require go.uber.org/multierr v1.11.0 // indirect
+require (
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	go.uber.org/multierr v1.11.0 // indirect
+	golang.org/x/text v0.19.0 // indirect
+)
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/cache"
+)
+
+// Responds with cached Bottom N Netflix Repos By commits over the last year
+func (handler *httpHandlers) GetCachedBottomNNetflixReposByCommitActivity() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		snapshot := handler.dataCache.Snapshot()
+
+		if snapshot.DatasetHydrationTime(cache.DATASET_COMMIT_ACTIVITY).IsZero() {
+			handler.optionalDatasetUnavailable(w, r, cache.DATASET_COMMIT_ACTIVITY)
+			return
+		}
+
+		handler.getNReposHelper(w, r, snapshot, VIEW_DIRECTION_BOTTOM, cache.VIEW_BOTTOM_COMMIT_ACTIVITY, snapshot.BottomNetflixReposByCommitActivity())
+	})
+}
+
+// Responds with the cached weekly commit activity of a single Netflix repo, shaped like GitHub's. Like GitHub, responds
+// 202 Accepted for repos whose activity GitHub is still computing
+func (handler *httpHandlers) GetCachedNetflixRepoCommitActivity() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		repo := r.PathValue("repo")
+		snapshot := handler.dataCache.Snapshot()
+
+		if snapshot.DatasetHydrationTime(cache.DATASET_COMMIT_ACTIVITY).IsZero() {
+			handler.optionalDatasetUnavailable(w, r, cache.DATASET_COMMIT_ACTIVITY)
+			return
+		}
+
+		activity, found := snapshot.NetflixRepoCommitActivity(repo)
+		if !found {
+			if _, isRepo := snapshot.NetflixOrganizationRepo(repo); isRepo {
+				w.Header().Set("X-Cache", "MISS")
+				w.WriteHeader(http.StatusAccepted)
+				return
+			}
+
+			http.Error(w, "Repo not found", http.StatusNotFound)
+			return
+		}
+
+		etag := viewETag(snapshot.ETag(cache.DATASET_COMMIT_ACTIVITY), "commit-activity-"+strings.ToLower(repo), 1, "json")
+
+		handler.writeCachedFromSnapshot(w, r, snapshot, cache.DATASET_COMMIT_ACTIVITY, etag, jsonSerializer{}, activity)
+	})
+}
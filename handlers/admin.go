@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/cache"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/compression"
+	"go.uber.org/zap"
+)
+
+// Streams the entire cache contents as a downloadable JSON document, compressed when ?format=gzip or ?format=zstd
+func (handler *httpHandlers) ExportCache() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		format := r.URL.Query().Get("format")
+
+		if format != "" && format != "json" && format != compression.ENCODING_GZIP && format != compression.ENCODING_ZSTD {
+			http.Error(w, "format must be one of json, gzip, zstd", http.StatusBadRequest)
+			return
+		}
+
+		export := handler.dataCache.Export()
+
+		var out io.Writer = w
+
+		switch format {
+		case compression.ENCODING_GZIP:
+			w.Header().Set("Content-Type", "application/gzip")
+			w.Header().Set("Content-Disposition", `attachment; filename="cache-export.json.gz"`)
+
+			gzipWriter := gzip.NewWriter(w)
+			defer gzipWriter.Close()
+
+			out = gzipWriter
+		case compression.ENCODING_ZSTD:
+			zstdWriter, err := compression.NewZstdWriter(w, handler.cfg.GetZstdLevel())
+			if err != nil {
+				handler.logger.Error("Failed to create zstd encoder", zap.Error(err))
+				http.Error(w, "Failed to compress export", http.StatusInternalServerError)
+				return
+			}
+			defer zstdWriter.Close()
+
+			w.Header().Set("Content-Type", "application/zstd")
+			w.Header().Set("Content-Disposition", `attachment; filename="cache-export.json.zst"`)
+
+			out = zstdWriter
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Disposition", `attachment; filename="cache-export.json"`)
+		}
+
+		if err := json.NewEncoder(out).Encode(export); err != nil {
+			handler.logger.Error("Failed to stream cache export", zap.Error(err))
+		}
+	})
+}
+
+// Replaces the cache contents with a previously exported JSON document, gzip and zstd compressed documents are detected automatically
+func (handler *httpHandlers) ImportCache() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in, err := compression.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, "Invalid compressed body", http.StatusBadRequest)
+			return
+		}
+		defer in.Close()
+
+		var export cache.CacheExport
+		if err := json.NewDecoder(in).Decode(&export); err != nil {
+			http.Error(w, "Invalid cache export: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := handler.dataCache.Import(export); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		handler.logger.Info("Imported cache export",
+			zap.Int("members", len(export.NetflixOrganizationMembers)),
+			zap.Int("repos", len(export.NetflixOrganizationRepos)),
+			zap.Time("exported at", export.Metadata.ExportedAt))
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// Re-fetches only the ?dataset= dataset from GitHub, e.g. the repos after a webhook says one changed, rather than the whole
+// org. Responds with the dataset's status once it's done
+func (handler *httpHandlers) RefreshCacheDataset() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dataset := r.URL.Query().Get("dataset")
+
+		if handler.maintenance.isEnabled() {
+			http.Error(w, "Service is in maintenance mode, only cached data is served", http.StatusServiceUnavailable)
+			return
+		}
+
+		if handler.setBackoffRetryAfter(w) {
+			http.Error(w, errRateLimited.Error(), http.StatusServiceUnavailable)
+			return
+		}
+
+		ctx := r.Context()
+		if deadline := handler.cfg.GetUpstreamDeadline(); deadline > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, deadline)
+			defer cancel()
+		}
+
+		upstreamStatus, err := handler.dataCache.HydrateDataset(ctx, dataset)
+
+		if errors.Is(err, cache.ErrUnknownDataset) {
+			http.Error(w, "dataset must be one of "+strings.Join(handler.dataCache.HydratableDatasets(), ", "), http.StatusBadRequest)
+			return
+		}
+
+		status := handler.dataCache.Status().Datasets[dataset]
+
+		if err != nil {
+			handler.logger.Error("Failed to refresh cache dataset", zap.String("dataset", dataset), zap.Int("upstream status", upstreamStatus), zap.Error(err))
+			http.Error(w, err.Error(), status.HttpStatus)
+			return
+		}
+
+		handler.logger.Info("Refreshed cache dataset", zap.String("dataset", dataset))
+		handler.writeJson(w, http.StatusOK, status)
+	})
+}
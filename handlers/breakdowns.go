@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/cache"
+)
+
+// Responds with a cached breakdown of the Netflix repos, e.g. repos per license, as [label, count] tuples with the largest count first
+func (handler *httpHandlers) GetCachedNetflixRepoBreakdown(breakdown string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		snapshot := handler.dataCache.Snapshot()
+
+		if snapshot.DatasetHydrationTime(cache.DATASET_VIEWS).IsZero() {
+			status, err := handler.forceCacheUpdateOnCacheMiss(w, r, cache.DATASET_VIEWS)
+
+			if err != nil {
+				handler.writeCacheMiss(w, status)
+				return
+			}
+
+			snapshot = handler.dataCache.Snapshot()
+		}
+
+		counts, ok := snapshot.RepoBreakdown(breakdown)
+		if !ok {
+			http.Error(w, "Unknown breakdown", http.StatusNotFound)
+			return
+		}
+
+		// breakdowns are derived from repos, like views
+		etag := viewETag(snapshot.ETag(cache.DATASET_REPOS), breakdown, len(counts), jsonSerializer{}.name())
+
+		handler.writeCachedFromSnapshot(w, r, snapshot, cache.DATASET_VIEWS, etag, jsonSerializer{}, counts)
+	})
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/cache"
+)
+
+// Responds with cached Bottom N Netflix Repos By Contributors
+func (handler *httpHandlers) GetCachedBottomNNetflixReposByContributors() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		snapshot := handler.dataCache.Snapshot()
+
+		if snapshot.DatasetHydrationTime(cache.DATASET_CONTRIBUTORS).IsZero() {
+			handler.optionalDatasetUnavailable(w, r, cache.DATASET_CONTRIBUTORS)
+			return
+		}
+
+		handler.getNReposHelper(w, r, snapshot, VIEW_DIRECTION_BOTTOM, cache.VIEW_BOTTOM_CONTRIBUTORS, snapshot.BottomNetflixReposByContributors())
+	})
+}
+
+// Responds with the cached contributors of a single Netflix repo
+func (handler *httpHandlers) GetCachedNetflixRepoContributors() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		repo := r.PathValue("repo")
+
+		if handler.dataCache.GetDatasetHydrationTime(cache.DATASET_CONTRIBUTORS).IsZero() {
+			handler.optionalDatasetUnavailable(w, r, cache.DATASET_CONTRIBUTORS)
+			return
+		}
+
+		contributors, found := handler.dataCache.GetNetflixRepoContributors(repo)
+		if !found {
+			http.Error(w, "Repo not found", http.StatusNotFound)
+			return
+		}
+
+		etag := viewETag(handler.dataCache.GetETag(cache.DATASET_CONTRIBUTORS), "contributors-"+strings.ToLower(repo), 1, "json")
+
+		handler.writeCachedJson(w, r, cache.DATASET_CONTRIBUTORS, etag, contributors)
+	})
+}
+
+// Optional datasets (contributors, releases) are hydrated in the background, a forced hydration would take a request per repo,
+// so misses aren't forced
+func (handler *httpHandlers) optionalDatasetUnavailable(w http.ResponseWriter, r *http.Request, dataset string) {
+	w.Header().Set("X-Cache", "MISS")
+	handler.recordError(r, ERROR_REASON_NOT_CACHED)
+
+	status := handler.dataCache.Status().Datasets[dataset].HttpStatus
+	if handler.setBackoffRetryAfter(w) || status == http.StatusOK {
+		// not attempted yet, or won't be until the quota resets
+		status = http.StatusServiceUnavailable
+	}
+
+	http.Error(w, "Error: "+dataset+" not cached yet", status)
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/customroutes"
+)
+
+// Responds with an operator-defined custom route, evaluated against its cached dataset
+func (handler *httpHandlers) GetCustomRoute(route *customroutes.Route) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !handler.dataCache.IsHydrated(route.Dataset) {
+			status, err := handler.forceCacheUpdateOnCacheMiss(w, r, route.Dataset)
+
+			if err != nil {
+				handler.writeCacheMiss(w, status)
+				return
+			}
+		}
+
+		var result interface{}
+
+		switch route.Dataset {
+		case customroutes.DATASET_ORGANIZATION:
+			result = route.EvaluateObject(handler.dataCache.GetNetflixOrganization())
+		case customroutes.DATASET_MEMBERS:
+			result = customroutes.EvaluateList(route, handler.dataCache.GetNetflixOrganizationMembers())
+		case customroutes.DATASET_REPOS:
+			result = customroutes.EvaluateList(route, handler.dataCache.GetNetflixOrganizationRepos())
+		}
+
+		// custom routes are derived from their dataset, so the ETag is the dataset's ETag qualified by the route
+		etag := viewETag(handler.dataCache.GetETag(route.Dataset), route.Path, route.Limit, "json")
+
+		handler.writeCachedJson(w, r, route.Dataset, etag, result)
+	})
+}
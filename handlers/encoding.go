@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/cache"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/compression"
+	"go.uber.org/zap"
+)
+
+const (
+	// buffers that grew past this size are dropped instead of being returned to the pool, so one huge response doesn't pin memory forever
+	MAX_POOLED_BUFFER_BYTES int = 8 << 20
+	// smaller responses aren't worth the CPU to compress
+	MIN_COMPRESSED_RESPONSE_BYTES int = 1024
+)
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// Encodes v as JSON, see writeSerialized
+func (handler *httpHandlers) writeJson(w http.ResponseWriter, status int, v interface{}) {
+	handler.writeSerialized(w, status, jsonSerializer{}, "", true, v)
+}
+
+// Encodes v into a pooled buffer, then writes it with Content-Type and Content-Length headers, zstd compressed when
+// contentEncoding is zstd. Encoding happens before anything is written, so a failed encode can still respond with a clean 500.
+// Without writeBody (HEAD requests), v is still encoded so Content-Length matches the body a GET would get, but isn't written
+func (handler *httpHandlers) writeSerialized(w http.ResponseWriter, status int, serializer serializer, contentEncoding string, writeBody bool, v interface{}) {
+	buf := getPooledBuffer()
+	defer putPooledBuffer(buf)
+
+	if err := serializer.encode(buf, v); err != nil {
+		handler.logger.Error("Failed to serialize", zap.Error(err), zap.String("format", serializer.name()))
+		http.Error(w, "Failed to encode "+serializer.name(), http.StatusInternalServerError)
+		return
+	}
+
+	body := buf
+
+	if contentEncoding == compression.ENCODING_ZSTD && handler.zstdEncoder != nil && buf.Len() >= MIN_COMPRESSED_RESPONSE_BYTES {
+		compressed := getPooledBuffer()
+		defer putPooledBuffer(compressed)
+
+		compressed.Write(handler.zstdEncoder.EncodeAll(buf.Bytes(), compressed.AvailableBuffer()))
+
+		w.Header().Set("Content-Encoding", compression.ENCODING_ZSTD)
+		body = compressed
+	}
+
+	w.Header().Set("Content-Type", serializer.contentType())
+	w.Header().Set("Content-Length", strconv.Itoa(body.Len()))
+	w.WriteHeader(status)
+
+	if !writeBody {
+		return
+	}
+
+	if _, err := body.WriteTo(w); err != nil {
+		handler.logger.Warn("Failed to write response", zap.Error(err))
+	}
+}
+
+// Get an empty buffer from the pool
+func getPooledBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+
+	return buf
+}
+
+// Return a buffer to the pool, unless it grew too large to keep around
+func putPooledBuffer(buf *bytes.Buffer) {
+	if buf.Cap() <= MAX_POOLED_BUFFER_BYTES {
+		bufferPool.Put(buf)
+	}
+}
+
+// Writes cached data as a 200 JSON response, see writeCached
+func (handler *httpHandlers) writeCachedJson(w http.ResponseWriter, r *http.Request, dataset string, etag string, v interface{}) {
+	handler.writeCached(w, r, dataset, etag, jsonSerializer{}, v)
+}
+
+// Writes cached data from dataset as a 200 response, see writeCachedFromSnapshot
+func (handler *httpHandlers) writeCached(w http.ResponseWriter, r *http.Request, dataset string, etag string, serializer serializer, v interface{}) {
+	handler.writeCachedFromSnapshot(w, r, handler.dataCache.Snapshot(), dataset, etag, serializer, v)
+}
+
+// Writes cached data from dataset as a 200 response, along with headers describing the state of the cache as of the
+// snapshot the data was read from. When etag is set and the client already has it (If-None-Match), responds 304 without a body.
+// HEAD requests get the same headers, without the body
+func (handler *httpHandlers) writeCachedFromSnapshot(w http.ResponseWriter, r *http.Request, snapshot cache.Snapshot, dataset string, etag string, serializer serializer, v interface{}) {
+	// the body differs by Accept (JSON or MessagePack) and Accept-Encoding, shared caches must key on both
+	w.Header().Add("Vary", "Accept")
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	// JSON is served as MessagePack to clients that ask for it, under its own ETag since the body differs
+	if _, ok := serializer.(jsonSerializer); ok && acceptsMsgpack(r) {
+		serializer = msgpackSerializer{}
+		etag = formatETag(etag, serializer.name())
+	}
+
+	handler.setCacheFreshnessHeaders(w, snapshot, dataset)
+
+	if snapshot.IsApproximate() {
+		w.Header().Set("X-Cache-Approximate", "true")
+	}
+
+	// the compressed body is a different representation, so it's under its own ETag too, otherwise a cache holding one
+	// encoding could answer a 304 for the other
+	var contentEncoding string
+	if acceptsEncoding(r.Header.Get("Accept-Encoding"), compression.ENCODING_ZSTD) {
+		contentEncoding = compression.ENCODING_ZSTD
+		etag = formatETag(etag, contentEncoding)
+	}
+
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	handler.writeSerialized(w, http.StatusOK, serializer, contentEncoding, r.Method != http.MethodHead, v)
+}
+
+// Sets X-Cache (HIT, unless the request forced a hydration on a miss), X-Cache-Age (seconds since dataset was hydrated),
+// X-Cache-Last-Sync (when the last sync attempt finished, successful or not), so clients can tell how fresh the data is,
+// and X-Cache-Generation, so clients paging through data can tell it rolled over between requests
+func (handler *httpHandlers) setCacheFreshnessHeaders(w http.ResponseWriter, snapshot cache.Snapshot, dataset string) {
+	if w.Header().Get("X-Cache") == "" {
+		w.Header().Set("X-Cache", "HIT")
+	}
+
+	if hydratedAt := snapshot.DatasetHydrationTime(dataset); !hydratedAt.IsZero() {
+		w.Header().Set("X-Cache-Age", strconv.Itoa(int(time.Since(hydratedAt).Seconds())))
+	}
+
+	if lastSync := snapshot.LastSyncReport().EndTime; !lastSync.IsZero() {
+		w.Header().Set("X-Cache-Last-Sync", lastSync.UTC().Format(time.RFC3339))
+	}
+
+	w.Header().Set("X-Cache-Generation", strconv.FormatUint(snapshot.Version(), 10))
+
+	handler.setCacheControl(w, dataset)
+}
+
+// Sets Cache-Control so shared caches (e.g. a CDN) keep the response until the sync loop next re-hydrates dataset.
+// Responses to authorized requests are only cacheable by the client, a shared cache would serve them to anyone
+func (handler *httpHandlers) setCacheControl(w http.ResponseWriter, dataset string) {
+	if handler.cfg.GetDisableCacheControl() {
+		return
+	}
+
+	nextSync := handler.dataCache.GetNextSyncTime(dataset)
+	if nextSync.IsZero() {
+		w.Header().Set("Cache-Control", "no-cache")
+		return
+	}
+
+	scope := "public"
+	if handler.cfg.GetAuthMode() != "none" {
+		scope = "private"
+	}
+
+	maxAge := max(int(time.Until(nextSync).Seconds()), 0)
+	w.Header().Set("Cache-Control", fmt.Sprintf("%s, max-age=%d", scope, maxAge))
+}
+
+// Determines if an Accept-Encoding header allows encoding, i.e. lists it without q=0
+func acceptsEncoding(acceptEncoding string, encoding string) bool {
+	return acceptsToken(acceptEncoding, encoding)
+}
+
+// Determines if an Accept or Accept-Encoding style header lists token without q=0, wildcards aren't matched
+func acceptsToken(header string, token string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(candidate), ";")
+
+		if !strings.EqualFold(strings.TrimSpace(name), token) {
+			continue
+		}
+
+		q, found := strings.CutPrefix(strings.TrimSpace(params), "q=")
+		if !found {
+			return true
+		}
+
+		weight, err := strconv.ParseFloat(q, 64)
+		return err == nil && weight > 0
+	}
+
+	return false
+}
+
+// Determines if an If-None-Match header matches etag, using weak comparison as specified for If-None-Match
+func etagMatches(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Qualifies an ETag with format or content encoding, since each produces a different response body
+func formatETag(etag string, format string) string {
+	if etag == "" {
+		return ""
+	}
+
+	return fmt.Sprintf(`%s-%s"`, strings.TrimSuffix(etag, `"`), format)
+}
+
+// Qualifies a dataset ETag with the view, n, and format, since each produces a different response body
+func viewETag(datasetETag string, view string, n int, format string) string {
+	if datasetETag == "" {
+		return ""
+	}
+
+	return fmt.Sprintf(`%s-%s-%d-%s"`, strings.TrimSuffix(datasetETag, `"`), view, n, format)
+}
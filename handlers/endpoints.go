@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/cache"
+)
+
+// Responds with the cached response of a configured extra endpoint, exactly as GitHub sent it. Requests with a query
+// string ask for something other than what was cached, so they're proxied to the GitHub API, like every other path
+// that isn't cached
+func (handler *httpHandlers) GetCachedExtraEndpoint() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		snapshot := handler.dataCache.Snapshot()
+
+		response, cached := snapshot.ExtraEndpoint(r.URL.Path)
+		if !cached || r.URL.RawQuery != "" {
+			if handler.cfg.GetDisableProxy() {
+				http.Error(w, "Endpoint isn't cached", http.StatusNotFound)
+				return
+			}
+
+			handler.forwardRequest(w, r)
+			return
+		}
+
+		etag := viewETag(snapshot.ETag(cache.DATASET_EXTRA_ENDPOINTS), r.URL.Path, 1, "verbatim")
+
+		handler.writeCachedFromSnapshot(w, r, snapshot, cache.DATASET_EXTRA_ENDPOINTS, etag, verbatimSerializer{mediaType: response.ContentType}, response.Body)
+	})
+}
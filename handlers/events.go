@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/cache"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/digest"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/metrics"
+	"go.uber.org/zap"
+)
+
+const (
+	EVENTS_KEEPALIVE_INTERVAL time.Duration = 30 * time.Second // keeps idle streams from being closed by proxies
+	EVENTS_CLIENT_BUFFER_SIZE int           = 8                // events buffered per client, events past it are dropped for that client
+)
+
+// Sent to /events clients whenever datasets are updated, with what changed since the previous update
+type hydrationEvent struct {
+	cache.DatasetsUpdated
+	Summary string        `json:"summary"`
+	Diff    digest.Digest `json:"diff"`
+}
+
+// Fans dataset updates out to every connected /events client
+type eventBroker struct {
+	lock         sync.Mutex
+	clients      map[chan []byte]struct{}
+	clientsGauge metrics.Gauge
+	logger       *zap.Logger
+}
+
+// Get new eventBroker, streaming updates of dataCache until ctx is done
+func newEventBroker(ctx context.Context, dataCache cache.Cache, logger *zap.Logger, registry metrics.Registry, minStarDelta int) *eventBroker {
+	broker := &eventBroker{
+		clients:      map[chan []byte]struct{}{},
+		clientsGauge: registry.Gauge("events_clients", "Number of clients connected to the /events stream"),
+		logger:       logger,
+	}
+
+	updates, unsubscribe := dataCache.Subscribe()
+
+	go func() {
+		defer unsubscribe()
+
+		previous := dataCache.Export()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case update := <-updates:
+				current := dataCache.Export()
+				diff := digest.Compile(previous, current, minStarDelta)
+				previous = current
+
+				broker.broadcast(hydrationEvent{DatasetsUpdated: update, Summary: diff.Summary(), Diff: diff})
+			}
+		}
+	}()
+
+	return broker
+}
+
+// Encodes event as an SSE message and sends it to every client, never blocks on a slow client
+func (broker *eventBroker) broadcast(event hydrationEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		broker.logger.Error("Failed to encode hydration event", zap.Error(err))
+		return
+	}
+
+	message := []byte(fmt.Sprintf("id: %d\nevent: hydrated\ndata: %s\n\n", event.Version, data))
+
+	broker.lock.Lock()
+	defer broker.lock.Unlock()
+
+	for client := range broker.clients {
+		select {
+		case client <- message:
+		default:
+			broker.logger.Warn("Events client isn't keeping up, dropping event", zap.Uint64("version", event.Version))
+		}
+	}
+}
+
+// Registers a new client, call remove once it disconnects
+func (broker *eventBroker) add() (messages chan []byte, remove func()) {
+	messages = make(chan []byte, EVENTS_CLIENT_BUFFER_SIZE)
+
+	broker.lock.Lock()
+	broker.clients[messages] = struct{}{}
+	broker.clientsGauge.Set(float64(len(broker.clients)))
+	broker.lock.Unlock()
+
+	return messages, func() {
+		broker.lock.Lock()
+		delete(broker.clients, messages)
+		broker.clientsGauge.Set(float64(len(broker.clients)))
+		broker.lock.Unlock()
+	}
+}
+
+// Streams a Server-Sent Event whenever cached datasets are updated, with a diff of the changes, so dashboards can refresh
+// immediately instead of polling cached endpoints
+func (handler *httpHandlers) GetEvents() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		controller := http.NewResponseController(w)
+
+		// the stream stays open indefinitely, past the server's write timeout
+		controller.SetWriteDeadline(time.Time{})
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no") // stops nginx from buffering the stream
+
+		// HEAD only checks the stream is available, it isn't opened
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		messages, remove := handler.events.add()
+		defer remove()
+
+		w.WriteHeader(http.StatusOK)
+
+		write := func(message []byte) bool {
+			if _, err := w.Write(message); err != nil {
+				return false
+			}
+			return controller.Flush() == nil
+		}
+
+		if !write([]byte(": connected\n\n")) {
+			handler.logger.Warn("Failed to start events stream, the connection doesn't support flushing")
+			return
+		}
+
+		keepalive := time.NewTicker(EVENTS_KEEPALIVE_INTERVAL)
+		defer keepalive.Stop()
+
+		for {
+			select {
+			// streams are closed on shutdown, otherwise they'd hold up the graceful shutdown until it times out
+			case <-handler.ctx.Done():
+				return
+			case <-r.Context().Done():
+				return
+			case message := <-messages:
+				if !write(message) {
+					return
+				}
+			case <-keepalive.C:
+				if !write([]byte(": keepalive\n\n")) {
+					return
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/cache"
+)
+
+// Parses the optional ?exclude= query param, a comma separated list of kinds of repos to leave out (archived, forks), sorted
+// and deduplicated so equivalent requests share ETags
+func parseExclusions(r *http.Request) ([]string, error) {
+	var exclusions []string
+
+	for _, exclusion := range strings.Split(r.URL.Query().Get("exclude"), ",") {
+		exclusion = strings.TrimSpace(exclusion)
+		if exclusion == "" {
+			continue
+		}
+
+		if !cache.IsExclusion(exclusion) {
+			return nil, fmt.Errorf("exclude must be a comma separated list of %s, %s", cache.EXCLUDE_ARCHIVED, cache.EXCLUDE_FORKS)
+		}
+
+		exclusions = append(exclusions, exclusion)
+	}
+
+	slices.Sort(exclusions)
+
+	return slices.Compact(exclusions), nil
+}
+
+// Qualifies a name with the exclusions, so responses with repos excluded get their own ETag
+func qualifyExclusions(name string, exclusions []string) string {
+	if len(exclusions) == 0 {
+		return name
+	}
+
+	return name + "~" + strings.Join(exclusions, ",")
+}
+
+// Qualifies a dataset ETag with the exclusions, see qualifyExclusions
+func exclusionsETag(datasetETag string, exclusions []string) string {
+	if datasetETag == "" || len(exclusions) == 0 {
+		return datasetETag
+	}
+
+	return qualifyExclusions(strings.TrimSuffix(datasetETag, `"`), exclusions) + `"`
+}
+
+// Parses the optional ?type= query param, the type of repos to list (sources, forks, archived, mirrors), all by default.
+// The path is a drop-in for GitHub's, so other types, e.g. GitHub's own public and member, aren't rejected but list every
+// repo of this public-only cache
+func parseRepoType(r *http.Request) string {
+	repoType := strings.TrimSpace(r.URL.Query().Get("type"))
+	if !cache.IsRepoType(repoType) {
+		return cache.REPO_TYPE_ALL
+	}
+
+	return repoType
+}
+
+// Qualifies a dataset ETag with the repo type, so each type gets its own ETag
+func repoTypeETag(datasetETag string, repoType string) string {
+	if datasetETag == "" || repoType == cache.REPO_TYPE_ALL {
+		return datasetETag
+	}
+
+	return strings.TrimSuffix(datasetETag, `"`) + "@" + repoType + `"`
+}
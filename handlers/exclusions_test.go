@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/cache"
+)
+
+func TestParseRepoType(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: "", want: cache.REPO_TYPE_ALL},
+		{query: "?type=sources", want: cache.REPO_TYPE_SOURCES},
+		{query: "?type=forks", want: cache.REPO_TYPE_FORKS},
+		{query: "?type=archived", want: cache.REPO_TYPE_ARCHIVED},
+		{query: "?type=mirrors", want: cache.REPO_TYPE_MIRRORS},
+		{query: "?type=%20forks%20", want: cache.REPO_TYPE_FORKS},
+
+		// GitHub's own types, and anything else, list every repo
+		{query: "?type=public", want: cache.REPO_TYPE_ALL},
+		{query: "?type=member", want: cache.REPO_TYPE_ALL},
+		{query: "?type=private", want: cache.REPO_TYPE_ALL},
+		{query: "?type=bogus", want: cache.REPO_TYPE_ALL},
+	}
+
+	for _, test := range tests {
+		if repoType := parseRepoType(httptest.NewRequest("GET", "/orgs/Netflix/repos"+test.query, nil)); repoType != test.want {
+			t.Errorf("parseRepoType(%s) = %s, want %s", test.query, repoType, test.want)
+		}
+	}
+}
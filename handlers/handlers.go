@@ -1,44 +1,118 @@
 package handlers
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"net/http"
-	"strconv"
+	"strings"
+	"time"
 
+	"github.com/adamjeanlaurent/github-api-read-cache-service/auth"
 	"github.com/adamjeanlaurent/github-api-read-cache-service/cache"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/compression"
 	"github.com/adamjeanlaurent/github-api-read-cache-service/config"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/customroutes"
 	githubclient "github.com/adamjeanlaurent/github-api-read-cache-service/github-client"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/metrics"
+	"github.com/klauspost/compress/zstd"
 	"go.uber.org/zap"
 )
 
 type HttpHandlers interface {
 	GetHealth() http.Handler
+	GetLive() http.Handler
+	GetReady() http.Handler
+	GetHealthDetail() http.Handler
 	GetCachedNetflixOrg() http.Handler
 	GetCachedNetflixOrgMembers() http.Handler
+	GetCachedNetflixOrgMember() http.Handler
+	SearchCachedNetflixOrgMembers() http.Handler
 	GetCachedNetflixOrgRepos() http.Handler
+	LookupCachedNetflixOrgRepos() http.Handler
+	SearchCachedNetflixOrgRepos() http.Handler
 	GetCachedBottomNNetflixReposByForks() http.Handler
 	GetCachedBottomNNetflixReposByLastUpdatedTime() http.Handler
 	GetCachedBottomNNetflixReposByOpenIssues() http.Handler
 	GetCachedBottomNNetflixReposByStars() http.Handler
+	GetCachedNNetflixReposByView() http.Handler
+	GetCachedBottomNNetflixReposByContributors() http.Handler
+	GetCachedNetflixRepoContributors() http.Handler
+	GetCachedBottomNNetflixReposByCommitActivity() http.Handler
+	GetCachedNetflixRepoCommitActivity() http.Handler
+	GetCachedBottomNNetflixReposByIssues() http.Handler
+	GetCachedBottomNNetflixReposByPullRequests() http.Handler
+	GetCachedRecentNNetflixReleases() http.Handler
+	GetCachedNetflixRepoLatestRelease() http.Handler
+	GetCachedNetflixTeams() http.Handler
+	GetCachedNetflixTeamRepos() http.Handler
+	GetCachedNetflixRepoReadme() http.Handler
+	GetCachedNetflixOrgLanguages() http.Handler
+	GetCachedNetflixRepoLanguages() http.Handler
+	GetCachedExtraEndpoint() http.Handler
+	GetViewCatalog() http.Handler
+	GetCachedSummary() http.Handler
+	GetCachedNetflixRepoBreakdown(breakdown string) http.Handler
+	GetCachedRemovedNetflixRepos() http.Handler
+	GetCustomRoute(route *customroutes.Route) http.Handler
 	ProxyRequestToGithubAPI() http.Handler
+	ManageLogLevel() http.Handler
+	ManageMaintenanceMode() http.Handler
+	GetCacheStatus() http.Handler
+	GetEvents() http.Handler
+	GetWebSocket() http.Handler
+	GetMetrics() http.Handler
+	ExportCache() http.Handler
+	ImportCache() http.Handler
+	RefreshCacheDataset() http.Handler
 }
 
 // Implements the HTTP handlers for service REST API
 type httpHandlers struct {
-	cfg          config.Configuration
-	dataCache    cache.Cache
-	logger       *zap.Logger
-	githubClient githubclient.GithubClient
+	ctx            context.Context
+	cfg            config.Configuration
+	dataCache      cache.Cache
+	logger         *zap.Logger
+	logLevel       zap.AtomicLevel
+	githubClient   githubclient.GithubClient
+	registry       metrics.Registry
+	zstdEncoder    *zstd.Encoder // compresses responses for clients that accept zstd, nil disables response compression
+	probes         map[string]*memoizedProbe
+	hydrationQueue *hydrationQueue
+	events         *eventBroker
+	ws             *wsHub
+	maintenance    *maintenanceMode
+	forcedCount    metrics.Counter
+	errorCount     metrics.Counter
+	audit          *zap.Logger // records proxied writes
 }
 
 // Retrieve Newly Created HttpHandlers
-func NewHttpHandlers(cfg config.Configuration, dataCache cache.Cache, logger *zap.Logger, githubClient githubclient.GithubClient) HttpHandlers {
-	return &httpHandlers{
+func NewHttpHandlers(ctx context.Context, cfg config.Configuration, dataCache cache.Cache, logger *zap.Logger, logLevel zap.AtomicLevel, githubClient githubclient.GithubClient, registry metrics.Registry, audit *zap.Logger) HttpHandlers {
+	zstdEncoder, err := compression.NewZstdEncoder(cfg.GetZstdLevel())
+	if err != nil {
+		logger.Error("Failed to create zstd encoder, responses won't be compressed", zap.Error(err))
+	}
+
+	handler := &httpHandlers{
+		ctx:          ctx,
 		cfg:          cfg,
 		dataCache:    dataCache,
 		logger:       logger,
+		logLevel:     logLevel,
 		githubClient: githubClient,
+		registry:     registry,
+		zstdEncoder:  zstdEncoder,
+		audit:        audit,
+		forcedCount:  registry.Counter(metrics.METRIC_FORCED_HYDRATIONS, "Number of cache misses that forced a hydration of the cache, by route"),
+		errorCount:   registry.Counter(metrics.METRIC_HANDLER_ERRORS, "Number of requests that couldn't be served from the cache, by route and reason"),
 	}
+	handler.maintenance = newMaintenanceMode(registry)
+	handler.probes = handler.newProbes()
+	handler.hydrationQueue = newHydrationQueue(ctx, dataCache, logger, registry, cfg.GetForcedHydrationQueueSize(), cfg.GetForcedHydrationTimeout(), cfg.GetUpstreamDeadline())
+	handler.events = newEventBroker(ctx, dataCache, logger, registry, cfg.GetDigestMinStarDelta())
+	handler.ws = newWsHub(ctx, dataCache, handler.catalogViews, logger, registry)
+
+	return handler
 }
 
 // Responds with Health Status of server
@@ -48,28 +122,86 @@ func (handler *httpHandlers) GetHealth() http.Handler {
 	})
 }
 
+// Liveness probe, responds 200 as long as the server is able to handle requests
+func (handler *httpHandlers) GetLive() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// Readiness probe, responds 200 once every cached dataset is hydrated, and 503 before that or once the server starts shutting down,
+// so orchestrators stop routing traffic to the instance
+func (handler *httpHandlers) GetReady() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := handler.dataCache.Status()
+
+		if handler.ctx.Err() != nil || !status.Ready {
+			handler.writeJson(w, http.StatusServiceUnavailable, status)
+			return
+		}
+
+		handler.writeJson(w, http.StatusOK, status)
+	})
+}
+
+// Status of the cache, as reported on /cachestatus
+type cacheStatus struct {
+	Generation  uint64                             `json:"generation"`
+	Readiness   cache.Status                       `json:"readiness"`
+	LastSync    cache.SyncReport                   `json:"last_sync"`
+	Stats       cache.CacheStats                   `json:"stats"`
+	Token       githubclient.TokenHealth           `json:"token"`
+	Maintenance MaintenanceStatus                  `json:"maintenance"`
+	Routes      map[string]metrics.RouteCacheStats `json:"routes"`
+}
+
+// Responds with the generation of the cache, the readiness of each cached dataset, the report of the last attempted cache sync,
+// the size of the cached datasets, the health of the GitHub API token, whether maintenance mode is enabled, and the cache hits,
+// misses, forced hydrations, and bytes served of each cached route
+func (handler *httpHandlers) GetCacheStatus() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		snapshot := handler.dataCache.Snapshot()
+
+		handler.writeJson(w, http.StatusOK, cacheStatus{
+			Generation:  snapshot.Version(),
+			Readiness:   handler.dataCache.Status(),
+			LastSync:    snapshot.LastSyncReport(),
+			Stats:       handler.dataCache.GetStats(),
+			Token:       handler.githubClient.GetTokenHealth(),
+			Maintenance: handler.maintenance.status(),
+			Routes:      metrics.GetRouteCacheStats(handler.registry),
+		})
+	})
+}
+
+// Responds with service metrics in the Prometheus text format
+func (handler *httpHandlers) GetMetrics() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+		if err := handler.registry.WritePrometheus(w); err != nil {
+			handler.logger.Warn("Failed to write metrics", zap.Error(err))
+		}
+	})
+}
+
 // Responds with cached Netflix Org Data
 func (handler *httpHandlers) GetCachedNetflixOrg() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		netflixOrg := handler.dataCache.GetNetflixOrganization()
 
-		w.Header().Set("Content-Type", "application/json")
-
 		if netflixOrg == nil {
-			status, err := handler.forceCacheUpdateOnCacheMiss()
+			status, err := handler.forceCacheUpdateOnCacheMiss(w, r, cache.DATASET_ORGANIZATION)
 
 			if err != nil {
-				http.Error(w, "Error: Cache empty", status)
+				handler.writeCacheMiss(w, status)
 				return
 			}
 
 			netflixOrg = handler.dataCache.GetNetflixOrganization()
 		}
 
-		if err := json.NewEncoder(w).Encode(netflixOrg); err != nil {
-			handler.logger.Error("Failed to serialize")
-			http.Error(w, "Failed to encode json", http.StatusInternalServerError)
-		}
+		handler.writeCachedJson(w, r, cache.DATASET_ORGANIZATION, handler.dataCache.GetETag(cache.DATASET_ORGANIZATION), netflixOrg)
 	})
 }
 
@@ -78,172 +210,345 @@ func (handler *httpHandlers) GetCachedNetflixOrgMembers() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		netflixOrgMembers := handler.dataCache.GetNetflixOrganizationMembers()
 
-		w.Header().Set("Content-Type", "application/json")
-
-		if len(netflixOrgMembers) == 0 {
-			status, err := handler.forceCacheUpdateOnCacheMiss()
+		if !handler.dataCache.IsHydrated(cache.DATASET_MEMBERS) {
+			status, err := handler.forceCacheUpdateOnCacheMiss(w, r, cache.DATASET_MEMBERS)
 
 			if err != nil {
-				http.Error(w, "Error: Cache empty", status)
+				handler.writeCacheMiss(w, status)
 				return
 			}
 
 			netflixOrgMembers = handler.dataCache.GetNetflixOrganizationMembers()
 		}
 
-		if err := json.NewEncoder(w).Encode(netflixOrgMembers); err != nil {
-			handler.logger.Error("Failed to serialize")
-			http.Error(w, "Failed to encode json", http.StatusInternalServerError)
-		}
+		handler.writeCachedJson(w, r, cache.DATASET_MEMBERS, handler.dataCache.GetETag(cache.DATASET_MEMBERS), netflixOrgMembers)
 	})
 }
 
-// Responds with cached list of  Netflix Org Repos
+// Responds with cached list of  Netflix Org Repos, only those of a type with ?type=, without archived repos and / or forks with ?exclude=
 func (handler *httpHandlers) GetCachedNetflixOrgRepos() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		netflixRepos := handler.dataCache.GetNetflixOrganizationRepos()
+		exclusions, err := parseExclusions(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
-		w.Header().Set("Content-Type", "application/json")
+		repoType := parseRepoType(r)
 
-		if len(netflixRepos) == 0 {
-			status, err := handler.forceCacheUpdateOnCacheMiss()
+		snapshot := handler.dataCache.Snapshot()
+		netflixRepos := snapshot.NetflixOrganizationReposOfType(repoType)
+
+		if !snapshot.IsHydrated(cache.DATASET_REPOS) {
+			status, err := handler.forceCacheUpdateOnCacheMiss(w, r, cache.DATASET_REPOS)
 
 			if err != nil {
-				http.Error(w, "Error: Cache empty", status)
+				handler.writeCacheMiss(w, status)
 				return
 			}
 
-			netflixRepos = handler.dataCache.GetNetflixOrganizationRepos()
+			snapshot = handler.dataCache.Snapshot()
+			netflixRepos = snapshot.NetflixOrganizationReposOfType(repoType)
 		}
 
-		if err := json.NewEncoder(w).Encode(netflixRepos); err != nil {
-			handler.logger.Error("Failed to serialize")
-			http.Error(w, "Failed to encode json", http.StatusInternalServerError)
-		}
+		etag := exclusionsETag(repoTypeETag(snapshot.ETag(cache.DATASET_REPOS), repoType), exclusions)
+		handler.writeCachedFromSnapshot(w, r, snapshot, cache.DATASET_REPOS, etag, jsonSerializer{}, snapshot.ExcludeRepos(netflixRepos, exclusions))
 	})
 }
 
 // Responds with cached Bottom N Netflix Repos By Forks
 func (handler *httpHandlers) GetCachedBottomNNetflixReposByForks() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		netflixRepos := handler.dataCache.GetBottomNetflixReposByForks()
-
-		if len(netflixRepos) == 0 {
-			status, err := handler.forceCacheUpdateOnCacheMiss()
-
-			if err != nil {
-				http.Error(w, "Error: Cache empty", status)
-				return
-			}
-
-			netflixRepos = handler.dataCache.GetBottomNetflixReposByForks()
-		}
-
-		handler.getBottomNReposHelper(w, r, netflixRepos)
+		handler.serveView(w, r, VIEW_DIRECTION_BOTTOM, cache.VIEW_BOTTOM_FORKS)
 	})
 }
 
 // Responds with cached Bottom N Netflix Repos By Last Updated Time
 func (handler *httpHandlers) GetCachedBottomNNetflixReposByLastUpdatedTime() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		netflixRepos := handler.dataCache.GetBottomNetflixReposByUpdateTime()
-
-		if len(netflixRepos) == 0 {
-			status, err := handler.forceCacheUpdateOnCacheMiss()
-
-			if err != nil {
-				http.Error(w, "Error: Cache empty", status)
-				return
-			}
-
-			netflixRepos = handler.dataCache.GetBottomNetflixReposByUpdateTime()
-		}
-
-		handler.getBottomNReposHelper(w, r, netflixRepos)
+		handler.serveView(w, r, VIEW_DIRECTION_BOTTOM, cache.VIEW_BOTTOM_LAST_UPDATED)
 	})
 }
 
 // Responds with cached Bottom N Netflix Repos By Open Issues
 func (handler *httpHandlers) GetCachedBottomNNetflixReposByOpenIssues() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		netflixRepos := handler.dataCache.GetBottomNetflixReposByOpenIssues()
-
-		if len(netflixRepos) == 0 {
-			status, err := handler.forceCacheUpdateOnCacheMiss()
-
-			if err != nil {
-				http.Error(w, "Error: Cache empty", status)
-				return
-			}
-
-			netflixRepos = handler.dataCache.GetBottomNetflixReposByOpenIssues()
-		}
-
-		handler.getBottomNReposHelper(w, r, netflixRepos)
+		handler.serveView(w, r, VIEW_DIRECTION_BOTTOM, cache.VIEW_BOTTOM_OPEN_ISSUES)
 	})
 }
 
 // Responds with cached Bottom N Netflix Repos By Stars
 func (handler *httpHandlers) GetCachedBottomNNetflixReposByStars() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		netflixRepos := handler.dataCache.GetBottomNetflixReposByStars()
+		handler.serveView(w, r, VIEW_DIRECTION_BOTTOM, cache.VIEW_BOTTOM_STARS)
+	})
+}
 
-		if len(netflixRepos) == 0 {
-			status, err := handler.forceCacheUpdateOnCacheMiss()
+// Responds with the first N entries of a view computed by the cache in direction, forcing a hydration if it was never hydrated.
+// The view, its ETag, and its precomputed encodings are all read from a single snapshot
+func (handler *httpHandlers) serveView(w http.ResponseWriter, r *http.Request, direction string, view string) {
+	snapshot := handler.dataCache.Snapshot()
 
-			if err != nil {
-				http.Error(w, "Error: Cache empty", status)
-				return
-			}
+	netflixRepos, ok := snapshot.View(view)
+	if !ok {
+		http.Error(w, "Unknown view", http.StatusNotFound)
+		return
+	}
 
-			netflixRepos = handler.dataCache.GetBottomNetflixReposByStars()
+	if !snapshot.IsHydrated(cache.DATASET_VIEWS) {
+		status, err := handler.forceCacheUpdateOnCacheMiss(w, r, cache.DATASET_VIEWS)
+
+		if err != nil {
+			handler.writeCacheMiss(w, status)
+			return
 		}
 
-		handler.getBottomNReposHelper(w, r, netflixRepos)
-	})
+		snapshot = handler.dataCache.Snapshot()
+		netflixRepos, _ = snapshot.View(view)
+	}
+
+	handler.getNReposHelper(w, r, snapshot, direction, view, netflixRepos)
 }
 
-// Helper to trim cached bottom view to N length
-func (handler *httpHandlers) getBottomNReposHelper(w http.ResponseWriter, r *http.Request, netflixRepos []cache.Tuple) {
-	n, err := strconv.Atoi(r.PathValue("n"))
-	if err != nil {
-		http.Error(w, "n must be an integer", http.StatusBadRequest)
+// Helper to trim cached view to its first N entries in direction, serialized as JSON or CSV
+func (handler *httpHandlers) getNReposHelper(w http.ResponseWriter, r *http.Request, snapshot cache.Snapshot, direction string, view string, netflixRepos []cache.Tuple) {
+	n, ok := handler.parseViewN(w, r)
+	if !ok {
 		return
 	}
 
-	if n <= 0 {
-		http.Error(w, "n must be a positive integer", http.StatusBadRequest)
+	serializer, ok := negotiateViewSerializer(r, view)
+	if !ok {
+		http.Error(w, "format must be one of json, csv, msgpack", http.StatusBadRequest)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
+	page, err := parseViewPage(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	exclusions, err := parseExclusions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	netflixRepos = snapshot.ExcludeFromView(netflixRepos, exclusions)
 
 	if n > len(netflixRepos) {
 		n = len(netflixRepos)
 	}
 
-	if err := json.NewEncoder(w).Encode(netflixRepos[len(netflixRepos)-n:]); err != nil {
-		handler.logger.Error("Failed to serialize")
-		http.Error(w, "Failed to encode json", http.StatusInternalServerError)
+	// views are derived from repos, so the view ETag is the repos ETag qualified by the view, n, page, and format.
+	// The contributors, commit activity, and issue count views are derived from their own datasets
+	etagDataset, dataset := cache.DATASET_REPOS, cache.DATASET_VIEWS
+	if view == cache.VIEW_BOTTOM_CONTRIBUTORS {
+		etagDataset, dataset = cache.DATASET_CONTRIBUTORS, cache.DATASET_CONTRIBUTORS
+	}
+	if view == cache.VIEW_BOTTOM_COMMIT_ACTIVITY {
+		etagDataset, dataset = cache.DATASET_COMMIT_ACTIVITY, cache.DATASET_COMMIT_ACTIVITY
+	}
+	if view == cache.VIEW_BOTTOM_ISSUES || view == cache.VIEW_BOTTOM_PULL_REQUESTS {
+		etagDataset, dataset = cache.DATASET_ISSUE_COUNTS, cache.DATASET_ISSUE_COUNTS
+	}
+	qualifiedView := view
+	if direction != VIEW_DIRECTION_BOTTOM {
+		qualifiedView = direction + "/" + view
+	}
+	qualifiedView = qualifyExclusions(qualifiedView, exclusions)
+
+	// which repos are excluded depends on the repos, even for views derived from other datasets
+	if len(exclusions) > 0 && etagDataset != cache.DATASET_REPOS {
+		qualifiedView += "~" + strings.Trim(snapshot.ETag(cache.DATASET_REPOS), `"`)
 	}
+	etag := viewETag(snapshot.ETag(etagDataset), page.qualify(qualifiedView), n, serializer.name())
+
+	setPaginationHeaders(w, r, n, page)
+
+	// common sizes were already encoded as JSON and MessagePack when the cache was hydrated
+	if !page.paged && len(exclusions) == 0 && direction == VIEW_DIRECTION_BOTTOM {
+		if encoded, ok := snapshot.PrecomputedBottomView(serializer.name(), view, n); ok {
+			handler.writeCachedFromSnapshot(w, r, snapshot, dataset, etag, preencodedSerializer{serializer}, encoded)
+			return
+		}
+	}
+
+	handler.writeCachedFromSnapshot(w, r, snapshot, dataset, etag, serializer, page.slice(viewEntries(netflixRepos, direction, n)))
 }
 
-// Force Hydrates the cache, to be used on a cache miss
-func (handler *httpHandlers) forceCacheUpdateOnCacheMiss() (int, error) {
-	handler.logger.Warn("cache miss, forcing cache re-sync", zap.Int("Last sync status", handler.dataCache.GetLastCacheSyncStatus()))
+// Reasons a request couldn't be served from the cache, as recorded by the handler errors metric
+const (
+	ERROR_REASON_MAINTENANCE      string = "maintenance"      // a miss in maintenance mode doesn't force a hydration
+	ERROR_REASON_RATE_LIMITED     string = "rate_limited"     // a miss while rate limited doesn't force a hydration
+	ERROR_REASON_HYDRATION_BUSY   string = "hydration_busy"   // the forced hydration queue was full, or the hydration didn't finish in time
+	ERROR_REASON_HYDRATING        string = "hydrating"        // a miss while a full hydration is in progress doesn't force another
+	ERROR_REASON_HYDRATION_FAILED string = "hydration_failed" // the forced hydration failed
+	ERROR_REASON_NOT_CACHED       string = "not_cached"       // an optional dataset wasn't hydrated yet
+)
+
+var errRateLimited = errors.New("Forced hydrations are refused until the GitHub quota resets")
+var errHydrationInProgress = errors.New("A full hydration is already in progress")
+
+// Force Hydrates the dataset a miss of dataset is served from, the whole cache for datasets not hydrated on their own, marking the response as a miss. On failure, returns the status
+// to respond with for that dataset, 503 with Retry-After while rate limited, and 202 with Retry-After while a full hydration,
+// e.g. the initial one, is already in progress
+func (handler *httpHandlers) forceCacheUpdateOnCacheMiss(w http.ResponseWriter, r *http.Request, dataset string) (int, error) {
+	w.Header().Set("X-Cache", "MISS")
+
+	if handler.maintenance.isEnabled() {
+		handler.logger.Warn("cache miss in maintenance mode, not forcing cache re-sync", zap.String("dataset", dataset))
+		handler.recordError(r, ERROR_REASON_MAINTENANCE)
+		return http.StatusServiceUnavailable, errMaintenanceMode
+	}
+
+	// a hydration would be refused without reaching GitHub until the quota resets
+	if handler.setBackoffRetryAfter(w) {
+		handler.logger.Warn("cache miss while rate limited, not forcing cache re-sync", zap.String("dataset", dataset))
+		handler.recordError(r, ERROR_REASON_RATE_LIMITED)
+		return http.StatusServiceUnavailable, errRateLimited
+	}
+
+	// the hydration in progress fills the dataset, forcing another would only double the requests to GitHub
+	if handler.dataCache.IsSyncing() {
+		handler.logger.Warn("cache miss while hydrating, not forcing cache re-sync", zap.String("dataset", dataset))
+		handler.hydrationQueue.setRetryAfter(w)
+		handler.recordError(r, ERROR_REASON_HYDRATING)
+		return http.StatusAccepted, errHydrationInProgress
+	}
 
-	status, err := handler.dataCache.HydrateCache()
+	handler.logger.Warn("cache miss, forcing cache re-sync", zap.String("dataset", dataset), zap.Int("Last sync status", handler.dataCache.GetLastSyncReport().Status))
+	handler.forcedCount.Add(1, "route", metrics.RouteFromContext(r.Context()))
+
+	upstreamStatus, err := handler.hydrationQueue.hydrate(r.Context(), dataset)
+
+	// the client went away (or the request timed out) while waiting, the shared hydration keeps running for the others
+	if err != nil && r.Context().Err() != nil {
+		handler.logger.Debug("Request ended while waiting on a forced cache sync", zap.String("dataset", dataset), zap.Error(r.Context().Err()))
+		return http.StatusServiceUnavailable, err
+	}
+
+	if errors.Is(err, errHydrationQueueFull) || errors.Is(err, errHydrationTimedOut) {
+		handler.logger.Warn("Force cache sync couldn't finish in time, asking client to retry", zap.String("dataset", dataset), zap.Error(err))
+		handler.hydrationQueue.setRetryAfter(w)
+		handler.recordError(r, ERROR_REASON_HYDRATION_BUSY)
+
+		return http.StatusServiceUnavailable, err
+	}
 
 	if err != nil {
-		handler.logger.Error("Force cache sync failed", zap.Int("status", status))
+		status := handler.dataCache.Status().Datasets[dataset].HttpStatus
+		handler.logger.Error("Force cache sync failed", zap.String("dataset", dataset), zap.Int("upstream status", upstreamStatus), zap.Int("status", status))
+		handler.recordError(r, ERROR_REASON_HYDRATION_FAILED)
+
+		// the hydration ran out of quota
+		if handler.setBackoffRetryAfter(w) {
+			return http.StatusServiceUnavailable, err
+		}
+
+		return status, err
 	}
 
-	return status, err
+	return http.StatusOK, nil
+}
+
+// Body of a miss answered 202 while a full hydration is in progress
+type syncingResponse struct {
+	Status string `json:"status"`
 }
 
-// Proxies Requests straight to GitHub API.
+// Responds to a miss forceCacheUpdateOnCacheMiss couldn't fill, with the status it returned. A 202 isn't a failure, the
+// data is on its way, so it's answered with a JSON body clients can tell apart from an error, along with its Retry-After
+func (handler *httpHandlers) writeCacheMiss(w http.ResponseWriter, status int) {
+	if status == http.StatusAccepted {
+		handler.writeJson(w, status, syncingResponse{Status: "syncing"})
+		return
+	}
+
+	http.Error(w, "Error: Cache empty", status)
+}
+
+// Records that the request couldn't be served from the cache, by its route and why
+func (handler *httpHandlers) recordError(r *http.Request, reason string) {
+	handler.errorCount.Add(1, "route", metrics.RouteFromContext(r.Context()), "reason", reason)
+}
+
+// Sets Retry-After to when the GitHub quota resets, if requests to GitHub are in backoff. Returns false if they aren't
+func (handler *httpHandlers) setBackoffRetryAfter(w http.ResponseWriter) bool {
+	resetTime := handler.githubClient.GetBackoffResetTime()
+	if resetTime.IsZero() {
+		return false
+	}
+
+	w.Header().Set("Retry-After", githubclient.RetryAfter(resetTime))
+	return true
+}
+
+// Proxies Requests straight to GitHub API, unless maintenance mode is enabled.
 func (handler *httpHandlers) ProxyRequestToGithubAPI() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.forwardRequest(w, r)
+	})
+}
+
+// Forwards the request to GitHub, unless maintenance mode is enabled. Writes are audit logged, every caller shares the
+// GitHub token so GitHub can't tell them apart, and a successful write to a path under the org schedules a re-hydration of
+// the dataset it changed
+func (handler *httpHandlers) forwardRequest(w http.ResponseWriter, r *http.Request) {
+	if handler.maintenance.isEnabled() {
+		http.Error(w, "Service is in maintenance mode, only cached data is served", http.StatusServiceUnavailable)
+		return
+	}
+
+	// the call to GitHub is abandoned along with the request, and never outlives the upstream deadline or the proxy handler
+	// timeout. The timeout is a deadline on the call rather than an http.TimeoutHandler, which would buffer the whole
+	// response, so large and streamed responses are still written as they arrive
+	deadline := handler.cfg.GetUpstreamDeadline()
+	if timeout := handler.cfg.GetProxyHandlerTimeout(); timeout > 0 && (deadline <= 0 || timeout < deadline) {
+		deadline = timeout
+	}
+
+	if deadline > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), deadline)
+		defer cancel()
+		r = r.WithContext(ctx)
+	}
+
+	if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
 		handler.githubClient.ForwardRequest(w, r)
+		return
+	}
+
+	start := time.Now()
+	recorder := metrics.NewResponseRecorder(w)
+	handler.githubClient.ForwardRequest(recorder, r)
+
+	handler.audit.Info("proxied write",
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+		zap.String("caller", auth.Identity(r.Context())),
+		zap.String("remote addr", r.RemoteAddr),
+		zap.String("user agent", r.UserAgent()),
+		zap.Int("status", recorder.Status()),
+		zap.Duration("duration", time.Since(start)),
+		zap.String("github request id", recorder.Header().Get("X-GitHub-Request-Id")),
+	)
+
+	if recorder.Status() >= 200 && recorder.Status() < 300 {
+		if dataset, ok := cache.MutatedDataset(r.URL.Path); ok {
+			handler.dataCache.RequestRefresh(dataset)
+		}
+	}
+}
+
+// Reports (GET) or changes (PUT) the server log level at runtime, e.g. PUT level=debug.
+// Setting the level to debug also enables logging of failed upstream GitHub response bodies.
+func (handler *httpHandlers) ManageLogLevel() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.logLevel.ServeHTTP(w, r)
+
+		if r.Method == http.MethodPut {
+			handler.logger.Info("Log level changed", zap.String("level", handler.logLevel.String()))
+		}
 	})
 }
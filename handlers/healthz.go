@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// active probes are given this long to finish
+const PROBE_TIMEOUT time.Duration = 5 * time.Second
+
+// Result of an active dependency probe
+type probeResult struct {
+	Healthy    bool      `json:"healthy"`
+	Error      string    `json:"error,omitempty"`
+	LatencyMs  int64     `json:"latency_ms"`
+	CheckedAt  time.Time `json:"checked_at"`
+	AgeSeconds float64   `json:"age_seconds"` // how long ago the probe actually ran, probes are memoized
+}
+
+// Active dependency probe whose result is memoized for a TTL, so aggressive orchestrator probing doesn't consume
+// GitHub quota or hammer the persistence backend. Concurrent callers wait on a single in-flight probe
+type memoizedProbe struct {
+	lock   sync.Mutex
+	ttl    time.Duration
+	probe  func(ctx context.Context) error
+	result *probeResult
+}
+
+// Get the memoized result, running the probe if it's missing or older than the TTL
+func (p *memoizedProbe) get() probeResult {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.result == nil || time.Since(p.result.CheckedAt) >= p.ttl {
+		ctx, cancel := context.WithTimeout(context.Background(), PROBE_TIMEOUT)
+		defer cancel()
+
+		start := time.Now()
+		err := p.probe(ctx)
+
+		p.result = &probeResult{
+			Healthy:   err == nil,
+			LatencyMs: time.Since(start).Milliseconds(),
+			CheckedAt: start.UTC(),
+		}
+		if err != nil {
+			p.result.Error = err.Error()
+		}
+	}
+
+	result := *p.result
+	result.AgeSeconds = time.Since(result.CheckedAt).Seconds()
+
+	return result
+}
+
+// Detailed health, as reported on /healthz/detail
+type healthDetail struct {
+	Healthy bool                   `json:"healthy"`
+	Cache   cacheHealth            `json:"cache"`
+	Probes  map[string]probeResult `json:"probes,omitempty"`
+}
+
+// Passive health of the cache, never makes requests to dependencies
+type cacheHealth struct {
+	Ready          bool `json:"ready"`
+	Approximate    bool `json:"approximate"`
+	LastSyncStatus int  `json:"last_sync_status"`
+}
+
+// Builds the active dependency probes, persistence is only probed when snapshots are enabled
+func (handler *httpHandlers) newProbes() map[string]*memoizedProbe {
+	ttl := handler.cfg.GetProbeCacheTTL()
+
+	probes := map[string]*memoizedProbe{
+		"github": {ttl: ttl, probe: func(ctx context.Context) error {
+			_, err := handler.githubClient.GetRateLimit(ctx)
+			return err
+		}},
+	}
+
+	if handler.cfg.GetSnapshotFile() != "" {
+		probes["persistence"] = &memoizedProbe{ttl: ttl, probe: handler.dataCache.ProbePersistence}
+	}
+
+	return probes
+}
+
+// Responds with detailed health of the cache, and of dependencies selected with ?probe= (comma separated names or all).
+// Responds 503 if the cache isn't ready or any selected probe failed
+func (handler *httpHandlers) GetHealthDetail() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var names []string
+
+		for _, name := range strings.Split(r.URL.Query().Get("probe"), ",") {
+			name = strings.TrimSpace(name)
+
+			switch {
+			case name == "":
+			case name == "all":
+				for probeName := range handler.probes {
+					names = append(names, probeName)
+				}
+			case handler.probes[name] != nil:
+				names = append(names, name)
+			default:
+				http.Error(w, "probe must be a comma separated list of "+strings.Join(handler.probeNames(), ", ")+", or all", http.StatusBadRequest)
+				return
+			}
+		}
+
+		status := handler.dataCache.Status()
+
+		detail := healthDetail{
+			Healthy: status.Ready,
+			Cache: cacheHealth{
+				Ready:          status.Ready,
+				Approximate:    handler.dataCache.IsApproximate(),
+				LastSyncStatus: handler.dataCache.GetLastSyncReport().Status,
+			},
+		}
+
+		if len(names) > 0 {
+			detail.Probes = map[string]probeResult{}
+		}
+
+		for _, name := range names {
+			result := handler.probes[name].get()
+
+			detail.Probes[name] = result
+			detail.Healthy = detail.Healthy && result.Healthy
+		}
+
+		statusCode := http.StatusOK
+		if !detail.Healthy {
+			statusCode = http.StatusServiceUnavailable
+		}
+
+		handler.writeJson(w, statusCode, detail)
+	})
+}
+
+// Get the names of the available probes, sorted
+func (handler *httpHandlers) probeNames() []string {
+	names := make([]string, 0, len(handler.probes))
+	for name := range handler.probes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
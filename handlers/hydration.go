@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/cache"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/metrics"
+	"go.uber.org/zap"
+)
+
+var errHydrationQueueFull = errors.New("Forced hydration queue is full")
+var errHydrationTimedOut = errors.New("Forced hydration didn't finish in time")
+
+// A request waiting on a forced hydration
+type hydrationJob struct {
+	dataset string // re-fetched on its own, or the whole org when empty
+	done    chan struct{}
+	status  int
+	err     error
+}
+
+// Bounded queue of forced hydrations on cache misses. A single worker runs hydrations, and every request queued while one
+// runs is answered by the next batch, so a burst of misses costs one hydration per dataset instead of one per request
+type hydrationQueue struct {
+	ctx           context.Context
+	dataCache     cache.Cache
+	logger        *zap.Logger
+	jobs          chan *hydrationJob
+	timeout       time.Duration
+	deadline      time.Duration // longest a hydration may run for, 0 for no limit
+	rejectedCount metrics.Counter
+	queueDepth    metrics.Gauge
+}
+
+// Get new hydrationQueue, and start its worker
+func newHydrationQueue(ctx context.Context, dataCache cache.Cache, logger *zap.Logger, registry metrics.Registry, size int, timeout time.Duration, deadline time.Duration) *hydrationQueue {
+	queue := &hydrationQueue{
+		ctx:           ctx,
+		dataCache:     dataCache,
+		logger:        logger,
+		jobs:          make(chan *hydrationJob, size),
+		timeout:       timeout,
+		deadline:      deadline,
+		rejectedCount: registry.Counter("forced_hydrations_rejected_total", "Requests answered 503 because the forced hydration queue was full or the hydration didn't finish in time, by reason"),
+		queueDepth:    registry.Gauge("forced_hydration_queue_depth", "Requests waiting on a forced hydration"),
+	}
+
+	go queue.work()
+
+	return queue
+}
+
+// Runs queued hydrations until the context is cancelled
+func (queue *hydrationQueue) work() {
+	for {
+		var batch []*hydrationJob
+
+		select {
+		case job := <-queue.jobs:
+			batch = append(batch, job)
+		case <-queue.ctx.Done():
+			queue.failPending(nil)
+			return
+		}
+
+		// requests queued since are answered by this hydration too
+	drain:
+		for {
+			select {
+			case job := <-queue.jobs:
+				batch = append(batch, job)
+			default:
+				break drain
+			}
+		}
+
+		// a job and the cancellation can arrive together, hydrations aren't started once shutting down
+		if queue.ctx.Err() != nil {
+			queue.failPending(batch)
+			return
+		}
+
+		queue.queueDepth.Set(0)
+		queue.hydrateBatch(batch)
+	}
+}
+
+// Answers batch and every queued request with the context's error once the worker stops, so none waits out its timeout
+// for a hydration that will never run
+func (queue *hydrationQueue) failPending(batch []*hydrationJob) {
+	for _, job := range batch {
+		job.status, job.err = http.StatusServiceUnavailable, queue.ctx.Err()
+		close(job.done)
+	}
+
+	for {
+		select {
+		case job := <-queue.jobs:
+			job.status, job.err = http.StatusServiceUnavailable, queue.ctx.Err()
+			close(job.done)
+		default:
+			queue.queueDepth.Set(0)
+			return
+		}
+	}
+}
+
+// Runs the hydrations a batch of requests waits on, answering each request. When any request needs the whole org, a single
+// hydration answers them all, otherwise each dataset missed is re-fetched once on its own
+func (queue *hydrationQueue) hydrateBatch(batch []*hydrationJob) {
+	datasets := map[string]bool{}
+	for _, job := range batch {
+		datasets[job.dataset] = true
+	}
+
+	if datasets[""] {
+		status, err := queue.run("")
+		for _, job := range batch {
+			job.status, job.err = status, err
+			close(job.done)
+		}
+		return
+	}
+
+	for dataset := range datasets {
+		status, err := queue.run(dataset)
+		for _, job := range batch {
+			if job.dataset == dataset {
+				job.status, job.err = status, err
+				close(job.done)
+			}
+		}
+	}
+}
+
+// Runs a hydration of dataset, or the whole org when empty, bounded by the upstream deadline so a slow GitHub can't keep
+// it running. It isn't tied to any single request's context, every request in the batch shares it, and those that stopped
+// waiting were told to retry once it's done
+func (queue *hydrationQueue) run(dataset string) (int, error) {
+	ctx := queue.ctx
+	if queue.deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, queue.deadline)
+		defer cancel()
+	}
+
+	if dataset == "" {
+		return queue.dataCache.HydrateCache(ctx)
+	}
+
+	return queue.dataCache.HydrateDataset(ctx, dataset)
+}
+
+// Queues a forced hydration of the dataset a miss of dataset is served from, and waits up to the timeout for it. Returns
+// errHydrationQueueFull or errHydrationTimedOut when the request should be retried later, the hydration itself keeps running either way
+func (queue *hydrationQueue) hydrate(ctx context.Context, dataset string) (int, error) {
+	job := &hydrationJob{dataset: hydratedWith(dataset), done: make(chan struct{})}
+
+	select {
+	case queue.jobs <- job:
+		queue.queueDepth.Set(float64(len(queue.jobs)))
+	default:
+		queue.rejectedCount.Add(1, "reason", "queue_full")
+		return http.StatusServiceUnavailable, errHydrationQueueFull
+	}
+
+	timer := time.NewTimer(queue.timeout)
+	defer timer.Stop()
+
+	select {
+	case <-job.done:
+		return job.status, job.err
+	case <-timer.C:
+		queue.rejectedCount.Add(1, "reason", "timeout")
+		return http.StatusServiceUnavailable, errHydrationTimedOut
+	case <-ctx.Done():
+		return http.StatusServiceUnavailable, ctx.Err()
+	case <-queue.ctx.Done():
+		// queued after the worker drained the queue on its way out
+		return http.StatusServiceUnavailable, queue.ctx.Err()
+	}
+}
+
+// Tells the client when to retry a request that couldn't wait on a forced hydration, by then the hydration has most likely finished
+func (queue *hydrationQueue) setRetryAfter(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(queue.timeout.Seconds()))))
+}
+
+// Get the dataset re-fetched on a miss of dataset, empty when a miss re-fetches the whole org
+func hydratedWith(dataset string) string {
+	switch dataset {
+	case cache.DATASET_ORGANIZATION, cache.DATASET_MEMBERS, cache.DATASET_REPOS:
+		return dataset
+	case cache.DATASET_VIEWS:
+		return cache.DATASET_REPOS
+	}
+
+	return ""
+}
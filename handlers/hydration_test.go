@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/metrics"
+	"go.uber.org/zap"
+)
+
+// Get a hydrationQueue whose context is already cancelled, without a worker
+func newStoppedHydrationQueue() *hydrationQueue {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	registry := metrics.NewRegistry()
+
+	return &hydrationQueue{
+		ctx:           ctx,
+		logger:        zap.NewNop(),
+		jobs:          make(chan *hydrationJob, 4),
+		timeout:       time.Minute,
+		rejectedCount: registry.Counter("rejected", ""),
+		queueDepth:    registry.Gauge("depth", ""),
+	}
+}
+
+func TestHydrationQueueFailsPendingJobsOnShutdown(t *testing.T) {
+	queue := newStoppedHydrationQueue()
+
+	jobs := []*hydrationJob{{done: make(chan struct{})}, {dataset: "repos", done: make(chan struct{})}}
+	for _, job := range jobs {
+		queue.jobs <- job
+	}
+
+	// the worker drains the queue as it stops
+	queue.work()
+
+	for _, job := range jobs {
+		select {
+		case <-job.done:
+		default:
+			t.Fatalf("job for %q wasn't answered", job.dataset)
+		}
+
+		if job.status != http.StatusServiceUnavailable || !errors.Is(job.err, context.Canceled) {
+			t.Errorf("job for %q = (%d, %v), want (503, context canceled)", job.dataset, job.status, job.err)
+		}
+	}
+}
+
+func TestHydrationQueuedAfterShutdownDoesNotWait(t *testing.T) {
+	queue := newStoppedHydrationQueue()
+
+	start := time.Now()
+	status, err := queue.hydrate(context.Background(), "repos")
+
+	if status != http.StatusServiceUnavailable || !errors.Is(err, context.Canceled) {
+		t.Errorf("hydrate = (%d, %v), want (503, context canceled)", status, err)
+	}
+	if waited := time.Since(start); waited > time.Second {
+		t.Errorf("hydrate waited %s for a stopped queue", waited)
+	}
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/cache"
+)
+
+// Responds with cached Bottom N Netflix Repos By open issues, not counting pull requests unlike the open_issues view
+func (handler *httpHandlers) GetCachedBottomNNetflixReposByIssues() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		snapshot := handler.dataCache.Snapshot()
+
+		if snapshot.DatasetHydrationTime(cache.DATASET_ISSUE_COUNTS).IsZero() {
+			handler.optionalDatasetUnavailable(w, r, cache.DATASET_ISSUE_COUNTS)
+			return
+		}
+
+		handler.getNReposHelper(w, r, snapshot, VIEW_DIRECTION_BOTTOM, cache.VIEW_BOTTOM_ISSUES, snapshot.BottomNetflixReposByIssues())
+	})
+}
+
+// Responds with cached Bottom N Netflix Repos By open pull requests
+func (handler *httpHandlers) GetCachedBottomNNetflixReposByPullRequests() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		snapshot := handler.dataCache.Snapshot()
+
+		if snapshot.DatasetHydrationTime(cache.DATASET_ISSUE_COUNTS).IsZero() {
+			handler.optionalDatasetUnavailable(w, r, cache.DATASET_ISSUE_COUNTS)
+			return
+		}
+
+		handler.getNReposHelper(w, r, snapshot, VIEW_DIRECTION_BOTTOM, cache.VIEW_BOTTOM_PULL_REQUESTS, snapshot.BottomNetflixReposByPullRequests())
+	})
+}
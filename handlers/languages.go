@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/cache"
+)
+
+// Responds with the cached bytes of code per language summed over every Netflix repo, as [language, bytes] tuples with the
+// most bytes first
+func (handler *httpHandlers) GetCachedNetflixOrgLanguages() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		snapshot := handler.dataCache.Snapshot()
+
+		if snapshot.DatasetHydrationTime(cache.DATASET_LANGUAGES).IsZero() {
+			handler.optionalDatasetUnavailable(w, r, cache.DATASET_LANGUAGES)
+			return
+		}
+
+		languages := snapshot.NetflixOrgLanguages()
+		etag := viewETag(snapshot.ETag(cache.DATASET_LANGUAGES), "languages", len(languages), jsonSerializer{}.name())
+
+		handler.writeCachedFromSnapshot(w, r, snapshot, cache.DATASET_LANGUAGES, etag, jsonSerializer{}, languages)
+	})
+}
+
+// Responds with the cached bytes of code per language of a Netflix repo, shaped like GitHub's. Repos whose languages aren't
+// cached are proxied to the GitHub API
+func (handler *httpHandlers) GetCachedNetflixRepoLanguages() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		repo := r.PathValue("repo")
+		snapshot := handler.dataCache.Snapshot()
+
+		languages, tracked := snapshot.NetflixRepoLanguages(repo)
+		if !tracked && !snapshot.DatasetHydrationTime(cache.DATASET_LANGUAGES).IsZero() {
+			if handler.cfg.GetDisableProxy() {
+				http.Error(w, "Repo languages aren't cached", http.StatusNotFound)
+				return
+			}
+
+			handler.forwardRequest(w, r)
+			return
+		}
+
+		if !tracked {
+			handler.optionalDatasetUnavailable(w, r, cache.DATASET_LANGUAGES)
+			return
+		}
+
+		etag := viewETag(snapshot.ETag(cache.DATASET_LANGUAGES), "languages-"+strings.ToLower(repo), 1, "json")
+
+		handler.writeCachedFromSnapshot(w, r, snapshot, cache.DATASET_LANGUAGES, etag, jsonSerializer{}, languages)
+	})
+}
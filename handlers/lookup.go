@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/cache"
+	githubclient "github.com/adamjeanlaurent/github-api-read-cache-service/github-client"
+)
+
+const (
+	MAX_LOOKUP_NAMES      int   = 100
+	MAX_LOOKUP_BODY_BYTES int64 = 1 << 20
+)
+
+// Result of looking up a single repo by name
+type repoLookupResult struct {
+	Name  string                  `json:"name"`
+	Found bool                    `json:"found"`
+	Repo  githubclient.JsonObject `json:"repo"`
+}
+
+// Responds with the cached repos for a JSON array of repo names, with a found/not-found status per name
+func (handler *httpHandlers) LookupCachedNetflixOrgRepos() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var names []string
+
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MAX_LOOKUP_BODY_BYTES)).Decode(&names); err != nil {
+			http.Error(w, "body must be a JSON array of repo names", http.StatusBadRequest)
+			return
+		}
+
+		if len(names) == 0 || len(names) > MAX_LOOKUP_NAMES {
+			http.Error(w, fmt.Sprintf("must lookup between 1 and %d repos", MAX_LOOKUP_NAMES), http.StatusBadRequest)
+			return
+		}
+
+		if !handler.dataCache.IsHydrated(cache.DATASET_REPOS) {
+			status, err := handler.forceCacheUpdateOnCacheMiss(w, r, cache.DATASET_REPOS)
+
+			if err != nil {
+				handler.writeCacheMiss(w, status)
+				return
+			}
+		}
+
+		results := make([]repoLookupResult, 0, len(names))
+
+		for _, name := range names {
+			repo, found := handler.dataCache.GetNetflixOrganizationRepo(name)
+			results = append(results, repoLookupResult{Name: name, Found: found, Repo: repo})
+		}
+
+		handler.writeCachedJson(w, r, cache.DATASET_REPOS, "", results)
+	})
+}
+
+// Responds with a single cached Netflix Org Member by login, case-insensitive
+func (handler *httpHandlers) GetCachedNetflixOrgMember() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		login := r.PathValue("login")
+
+		if !handler.dataCache.IsHydrated(cache.DATASET_MEMBERS) {
+			status, err := handler.forceCacheUpdateOnCacheMiss(w, r, cache.DATASET_MEMBERS)
+
+			if err != nil {
+				handler.writeCacheMiss(w, status)
+				return
+			}
+		}
+
+		member, found := handler.dataCache.GetNetflixOrganizationMember(login)
+		if !found {
+			http.Error(w, "Member not found", http.StatusNotFound)
+			return
+		}
+
+		etag := viewETag(handler.dataCache.GetETag(cache.DATASET_MEMBERS), "member-"+strings.ToLower(login), 1, "json")
+
+		handler.writeCachedJson(w, r, cache.DATASET_MEMBERS, etag, member)
+	})
+}
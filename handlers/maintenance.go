@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/metrics"
+	"go.uber.org/zap"
+)
+
+var errMaintenanceMode = errors.New("Forced hydrations are refused in maintenance mode")
+
+// State of maintenance mode, as reported on /admin/maintenance and /cachestatus
+type MaintenanceStatus struct {
+	Enabled   bool       `json:"enabled"`
+	Reason    string     `json:"reason,omitempty"`
+	EnabledAt *time.Time `json:"enabled_at,omitempty"`
+}
+
+// Body of POST /admin/maintenance
+type maintenanceRequest struct {
+	Enabled *bool  `json:"enabled"`
+	Reason  string `json:"reason"`
+}
+
+// Maintenance mode, toggled at runtime during GitHub incidents to preserve quota. While enabled, cached data is still
+// served, but nothing a client requests reaches GitHub: the proxy answers 503, and cache misses don't force a hydration
+type maintenanceMode struct {
+	lock      sync.RWMutex
+	enabled   bool
+	reason    string
+	enabledAt time.Time
+	gauge     metrics.Gauge
+}
+
+// Get new maintenanceMode, disabled
+func newMaintenanceMode(registry metrics.Registry) *maintenanceMode {
+	gauge := registry.Gauge("maintenance_mode", "1 while maintenance mode is enabled and requests to GitHub are refused, 0 otherwise")
+	gauge.Set(0)
+
+	return &maintenanceMode{gauge: gauge}
+}
+
+// Whether maintenance mode is enabled
+func (mm *maintenanceMode) isEnabled() bool {
+	mm.lock.RLock()
+	defer mm.lock.RUnlock()
+
+	return mm.enabled
+}
+
+// Enables or disables maintenance mode, returns whether it changed
+func (mm *maintenanceMode) set(enabled bool, reason string) bool {
+	mm.lock.Lock()
+	defer mm.lock.Unlock()
+
+	changed := mm.enabled != enabled
+	mm.enabled = enabled
+	mm.reason = ""
+	if enabled {
+		mm.reason = reason
+	}
+
+	if changed {
+		mm.enabledAt = time.Time{}
+		if enabled {
+			mm.enabledAt = time.Now()
+			mm.gauge.Set(1)
+		} else {
+			mm.gauge.Set(0)
+		}
+	}
+
+	return changed
+}
+
+// Get the state of maintenance mode
+func (mm *maintenanceMode) status() MaintenanceStatus {
+	mm.lock.RLock()
+	defer mm.lock.RUnlock()
+
+	status := MaintenanceStatus{Enabled: mm.enabled, Reason: mm.reason}
+	if mm.enabled {
+		enabledAt := mm.enabledAt
+		status.EnabledAt = &enabledAt
+	}
+
+	return status
+}
+
+// Reports (GET) or toggles (POST) maintenance mode, e.g. POST {"enabled": true, "reason": "GitHub incident"}
+func (handler *httpHandlers) ManageMaintenanceMode() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			var request maintenanceRequest
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Enabled == nil {
+				http.Error(w, `Body must be a JSON object with a boolean "enabled"`, http.StatusBadRequest)
+				return
+			}
+
+			if handler.maintenance.set(*request.Enabled, request.Reason) {
+				if *request.Enabled {
+					handler.logger.Warn("Maintenance mode enabled, the proxy and forced hydrations are refused", zap.String("reason", request.Reason))
+				} else {
+					handler.logger.Info("Maintenance mode disabled")
+				}
+			}
+		}
+
+		handler.writeJson(w, http.StatusOK, handler.maintenance.status())
+	})
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/cache"
+)
+
+// Page of a view's N entries requested with ?offset= and ?limit=, in the order the view is served
+type viewPage struct {
+	offset int
+	limit  int  // 0 for the rest of the view
+	paged  bool // false unless offset or limit were requested
+}
+
+// Parses the ?offset= and ?limit= query params, both optional
+func parseViewPage(r *http.Request) (viewPage, error) {
+	var page viewPage
+	query := r.URL.Query()
+
+	if query.Has("offset") {
+		offset, err := strconv.Atoi(query.Get("offset"))
+		if err != nil || offset < 0 {
+			return viewPage{}, fmt.Errorf("offset must be a non-negative integer")
+		}
+		page.offset, page.paged = offset, true
+	}
+
+	if query.Has("limit") {
+		limit, err := strconv.Atoi(query.Get("limit"))
+		if err != nil || limit <= 0 {
+			return viewPage{}, fmt.Errorf("limit must be a positive integer")
+		}
+		page.limit, page.paged = limit, true
+	}
+
+	return page, nil
+}
+
+// Get the entries of tuples on the page
+func (page viewPage) slice(tuples []cache.Tuple) []cache.Tuple {
+	start := min(page.offset, len(tuples))
+	end := len(tuples)
+	if page.limit > 0 {
+		end = min(start+page.limit, end)
+	}
+
+	return tuples[start:end]
+}
+
+// Qualifies a view's name with the page, so each page gets its own ETag
+func (page viewPage) qualify(view string) string {
+	if !page.paged {
+		return view
+	}
+
+	return fmt.Sprintf("%s@%d+%d", view, page.offset, page.limit)
+}
+
+// Sets X-Total-Count to the number of entries in the view, and when paging a Link header with the first, prev, next, and
+// last pages (https://datatracker.ietf.org/doc/html/rfc8288)
+func setPaginationHeaders(w http.ResponseWriter, r *http.Request, total int, page viewPage) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+
+	if !page.paged || page.limit == 0 {
+		return
+	}
+
+	link := func(offset int, rel string) string {
+		query := r.URL.Query()
+		query.Set("offset", strconv.Itoa(offset))
+		query.Set("limit", strconv.Itoa(page.limit))
+
+		return fmt.Sprintf(`<%s?%s>; rel="%s"`, r.URL.Path, query.Encode(), rel)
+	}
+
+	lastOffset := max(total-1, 0) / page.limit * page.limit
+	links := []string{link(0, "first")}
+
+	if page.offset > 0 {
+		links = append(links, link(max(page.offset-page.limit, 0), "prev"))
+	}
+
+	if page.offset+page.limit < total {
+		links = append(links, link(page.offset+page.limit, "next"))
+	}
+
+	links = append(links, link(lastOffset, "last"))
+
+	w.Header().Set("Link", strings.Join(links, ", "))
+}
+
+// Response to a request with an invalid N, along with the limits so clients can correct the request
+type viewNError struct {
+	Error    string `json:"error"`
+	MaxN     int    `json:"max_n,omitempty"` // omitted when N isn't limited
+	DefaultN int    `json:"default_n"`
+}
+
+// Parses N from the path, or from ?n= on routes without it in the path, falling back to the default N. Invalid N, including
+// N past the max, are answered 400 with a viewNError, returning false
+func (handler *httpHandlers) parseViewN(w http.ResponseWriter, r *http.Request) (int, bool) {
+	raw := r.PathValue("n")
+	if raw == "" {
+		raw = r.URL.Query().Get("n")
+	}
+
+	if raw == "" {
+		return handler.cfg.GetDefaultViewN(), true
+	}
+
+	fail := func(message string) (int, bool) {
+		handler.writeJson(w, http.StatusBadRequest, viewNError{Error: message, MaxN: handler.cfg.GetMaxViewN(), DefaultN: handler.cfg.GetDefaultViewN()})
+		return 0, false
+	}
+
+	n, err := strconv.Atoi(raw)
+	if err != nil {
+		return fail("n must be an integer")
+	}
+
+	if n <= 0 {
+		return fail("n must be a positive integer")
+	}
+
+	if maxN := handler.cfg.GetMaxViewN(); maxN > 0 && n > maxN {
+		return fail(fmt.Sprintf("n must be at most %d", maxN))
+	}
+
+	return n, true
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/cache"
+)
+
+// GitHub's media types for a file's decoded content instead of its JSON description
+var RAW_MEDIA_TYPES = []string{"application/vnd.github.raw", "application/vnd.github.v3.raw"}
+
+// Responds with the cached README of a Netflix repo, shaped like GitHub's. Clients asking for the raw media type, or
+// ?format=raw, get the decoded content instead. Repos whose README isn't cached are proxied to the GitHub API
+func (handler *httpHandlers) GetCachedNetflixRepoReadme() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		repo := r.PathValue("repo")
+		snapshot := handler.dataCache.Snapshot()
+
+		readme, tracked := snapshot.NetflixRepoReadme(repo)
+		if !tracked && !snapshot.DatasetHydrationTime(cache.DATASET_READMES).IsZero() {
+			if handler.cfg.GetDisableProxy() {
+				http.Error(w, "Repo README isn't cached", http.StatusNotFound)
+				return
+			}
+
+			handler.forwardRequest(w, r)
+			return
+		}
+
+		if !tracked {
+			handler.optionalDatasetUnavailable(w, r, cache.DATASET_READMES)
+			return
+		}
+
+		// matches GitHub, which responds 404 for repos without a README
+		if readme == nil {
+			http.Error(w, "README not found", http.StatusNotFound)
+			return
+		}
+
+		var serializer serializer = jsonSerializer{}
+		var body interface{} = readme.Metadata
+		if acceptsRawContent(r) {
+			serializer, body = rawSerializer{}, readme.Content
+		}
+
+		etag := viewETag(snapshot.ETag(cache.DATASET_READMES), "readme-"+strings.ToLower(repo), 1, serializer.name())
+
+		handler.writeCachedFromSnapshot(w, r, snapshot, cache.DATASET_READMES, etag, serializer, body)
+	})
+}
+
+// Determines if the request asks for a file's decoded content, with ?format=raw or one of GitHub's raw media types
+func acceptsRawContent(r *http.Request) bool {
+	if r.URL.Query().Get("format") == "raw" {
+		return true
+	}
+
+	accept := r.Header.Get("Accept")
+	for _, mediaType := range RAW_MEDIA_TYPES {
+		if strings.Contains(accept, mediaType) {
+			return true
+		}
+	}
+
+	return false
+}
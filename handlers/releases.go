@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/cache"
+)
+
+// Responds with the cached N most recently released Netflix Repos, newest first
+func (handler *httpHandlers) GetCachedRecentNNetflixReleases() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, ok := handler.parseViewN(w, r)
+		if !ok {
+			return
+		}
+
+		serializer, ok := negotiateViewSerializer(r, "published_at")
+		if !ok {
+			http.Error(w, "format must be one of json, csv, msgpack", http.StatusBadRequest)
+			return
+		}
+
+		page, err := parseViewPage(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		snapshot := handler.dataCache.Snapshot()
+
+		if snapshot.DatasetHydrationTime(cache.DATASET_RELEASES).IsZero() {
+			handler.optionalDatasetUnavailable(w, r, cache.DATASET_RELEASES)
+			return
+		}
+
+		releases := snapshot.RecentNetflixReleases()
+		n = min(n, len(releases))
+
+		etag := viewETag(snapshot.ETag(cache.DATASET_RELEASES), page.qualify(cache.VIEW_RECENT_RELEASES), n, serializer.name())
+
+		setPaginationHeaders(w, r, n, page)
+		handler.writeCachedFromSnapshot(w, r, snapshot, cache.DATASET_RELEASES, etag, serializer, page.slice(releases[:n]))
+	})
+}
+
+// Responds with the cached latest release of a Netflix repo. Repos whose releases aren't cached are proxied to the GitHub API
+func (handler *httpHandlers) GetCachedNetflixRepoLatestRelease() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		repo := r.PathValue("repo")
+
+		release, tracked := handler.dataCache.GetNetflixRepoLatestRelease(repo)
+		if !tracked && !handler.dataCache.GetDatasetHydrationTime(cache.DATASET_RELEASES).IsZero() {
+			if handler.cfg.GetDisableProxy() {
+				http.Error(w, "Repo releases aren't cached", http.StatusNotFound)
+				return
+			}
+
+			handler.forwardRequest(w, r)
+			return
+		}
+
+		if !tracked {
+			handler.optionalDatasetUnavailable(w, r, cache.DATASET_RELEASES)
+			return
+		}
+
+		// matches GitHub, which responds 404 for repos without releases
+		if release == nil {
+			http.Error(w, "Release not found", http.StatusNotFound)
+			return
+		}
+
+		etag := viewETag(handler.dataCache.GetETag(cache.DATASET_RELEASES), "release-"+strings.ToLower(repo), 1, "json")
+
+		handler.writeCachedJson(w, r, cache.DATASET_RELEASES, etag, release)
+	})
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/cache"
+)
+
+// Responds with the repos removed from the Netflix org between syncs, most recently removed first, as [repo, removed at] tuples
+func (handler *httpHandlers) GetCachedRemovedNetflixRepos() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serializer, ok := negotiateViewSerializer(r, "removed_at")
+		if !ok {
+			http.Error(w, "format must be one of json, csv, msgpack", http.StatusBadRequest)
+			return
+		}
+
+		snapshot := handler.dataCache.Snapshot()
+
+		if snapshot.DatasetHydrationTime(cache.DATASET_REPOS).IsZero() {
+			status, err := handler.forceCacheUpdateOnCacheMiss(w, r, cache.DATASET_REPOS)
+
+			if err != nil {
+				handler.writeCacheMiss(w, status)
+				return
+			}
+
+			snapshot = handler.dataCache.Snapshot()
+		}
+
+		removed := snapshot.RemovedNetflixRepos()
+
+		// removals are tracked with the repos, expired ones can drop out without the repos changing
+		etag := viewETag(snapshot.ETag(cache.DATASET_REPOS), "removed", len(removed), serializer.name())
+
+		handler.writeCachedFromSnapshot(w, r, snapshot, cache.DATASET_REPOS, etag, serializer, removed)
+	})
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/cache"
+	githubclient "github.com/adamjeanlaurent/github-api-read-cache-service/github-client"
+)
+
+// Repo fields search results can be sorted by, mapped to the repo JSON field they sort on
+var repoSearchSortFields = map[string]string{
+	"stars":       "stargazers_count",
+	"forks":       "forks_count",
+	"open_issues": "open_issues_count",
+	"updated":     "updated_at",
+	"name":        "name",
+}
+
+// Search results, shaped like GitHub's search API responses
+type searchResults[T any] struct {
+	TotalCount int `json:"total_count"`
+	Items      []T `json:"items"`
+}
+
+// Responds with cached repos matching ?q= (case-insensitive substring of the name or description), ?language=, and ?min_stars=,
+// sorted by ?sort= (stars by default, most first, names ascending). Simple discovery queries are answered without GitHub's search API
+func (handler *httpHandlers) SearchCachedNetflixOrgRepos() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		q := strings.ToLower(query.Get("q"))
+		language := query.Get("language")
+
+		minStars := 0.0
+		if value := query.Get("min_stars"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 0 {
+				http.Error(w, "min_stars must be a non-negative integer", http.StatusBadRequest)
+				return
+			}
+			minStars = float64(parsed)
+		}
+
+		sortBy := query.Get("sort")
+		if sortBy == "" {
+			sortBy = "stars"
+		}
+
+		sortField, ok := repoSearchSortFields[sortBy]
+		if !ok {
+			http.Error(w, "sort must be one of stars, forks, open_issues, updated, name", http.StatusBadRequest)
+			return
+		}
+
+		netflixRepos := handler.dataCache.GetNetflixOrganizationRepos()
+
+		if !handler.dataCache.IsHydrated(cache.DATASET_REPOS) {
+			status, err := handler.forceCacheUpdateOnCacheMiss(w, r, cache.DATASET_REPOS)
+
+			if err != nil {
+				handler.writeCacheMiss(w, status)
+				return
+			}
+
+			netflixRepos = handler.dataCache.GetNetflixOrganizationRepos()
+		}
+
+		matches := []githubclient.JsonObject{}
+
+		for _, repo := range netflixRepos {
+			name, _ := repo["name"].(string)
+			description, _ := repo["description"].(string)
+			repoLanguage, _ := repo["language"].(string)
+			stars, _ := repo["stargazers_count"].(float64)
+
+			if q != "" && !strings.Contains(strings.ToLower(name), q) && !strings.Contains(strings.ToLower(description), q) {
+				continue
+			}
+
+			if language != "" && !strings.EqualFold(repoLanguage, language) {
+				continue
+			}
+
+			if stars < minStars {
+				continue
+			}
+
+			matches = append(matches, repo)
+		}
+
+		slices.SortStableFunc(matches, func(a githubclient.JsonObject, b githubclient.JsonObject) int {
+			return compareRepoField(a, b, sortField)
+		})
+
+		// results are derived from repos, so the ETag is the repos ETag qualified by the normalized query
+		etag := viewETag(handler.dataCache.GetETag(cache.DATASET_REPOS), "search?"+query.Encode(), len(matches), "json")
+
+		handler.writeCachedJson(w, r, cache.DATASET_REPOS, etag, searchResults[githubclient.JsonObject]{TotalCount: len(matches), Items: matches})
+	})
+}
+
+// Orders repos by field, names ascending and everything else descending (most stars, most recently updated first)
+func compareRepoField(a githubclient.JsonObject, b githubclient.JsonObject, field string) int {
+	switch valueA := a[field].(type) {
+	case float64:
+		valueB, _ := b[field].(float64)
+		return cmp.Compare(valueB, valueA)
+	case string:
+		valueB, _ := b[field].(string)
+
+		if field == "name" {
+			return strings.Compare(strings.ToLower(valueA), strings.ToLower(valueB))
+		}
+
+		// RFC 3339 timestamps order lexicographically
+		return strings.Compare(valueB, valueA)
+	}
+
+	return 0
+}
+
+// Responds with cached members whose login contains ?q= (case-insensitive), sorted by login
+func (handler *httpHandlers) SearchCachedNetflixOrgMembers() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := strings.ToLower(r.URL.Query().Get("q"))
+
+		netflixOrgMembers := handler.dataCache.GetNetflixOrganizationMembers()
+
+		if !handler.dataCache.IsHydrated(cache.DATASET_MEMBERS) {
+			status, err := handler.forceCacheUpdateOnCacheMiss(w, r, cache.DATASET_MEMBERS)
+
+			if err != nil {
+				handler.writeCacheMiss(w, status)
+				return
+			}
+
+			netflixOrgMembers = handler.dataCache.GetNetflixOrganizationMembers()
+		}
+
+		matches := []githubclient.JsonObject{}
+
+		for _, member := range netflixOrgMembers {
+			login, _ := member["login"].(string)
+
+			if strings.Contains(strings.ToLower(login), q) {
+				matches = append(matches, member)
+			}
+		}
+
+		slices.SortStableFunc(matches, func(a githubclient.JsonObject, b githubclient.JsonObject) int {
+			loginA, _ := a["login"].(string)
+			loginB, _ := b["login"].(string)
+			return strings.Compare(strings.ToLower(loginA), strings.ToLower(loginB))
+		})
+
+		etag := viewETag(handler.dataCache.GetETag(cache.DATASET_MEMBERS), "search?"+r.URL.Query().Encode(), len(matches), "json")
+
+		handler.writeCachedJson(w, r, cache.DATASET_MEMBERS, etag, searchResults[githubclient.JsonObject]{TotalCount: len(matches), Items: matches})
+	})
+}
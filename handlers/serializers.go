@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/cache"
+)
+
+// Media type MessagePack responses are served with, the one most clients send
+const MEDIA_TYPE_MSGPACK string = "application/x-msgpack"
+
+// Encodes response bodies in a single format
+type serializer interface {
+	name() string
+	contentType() string
+	encode(w io.Writer, v interface{}) error
+}
+
+// Default serializer, supports any value
+type jsonSerializer struct{}
+
+func (jsonSerializer) name() string {
+	return cache.FORMAT_JSON
+}
+
+func (jsonSerializer) contentType() string {
+	return "application/json"
+}
+
+func (jsonSerializer) encode(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// Serializes any value as MessagePack, for clients that want smaller payloads than JSON
+type msgpackSerializer struct{}
+
+func (msgpackSerializer) name() string {
+	return cache.FORMAT_MSGPACK
+}
+
+func (msgpackSerializer) contentType() string {
+	return MEDIA_TYPE_MSGPACK
+}
+
+func (msgpackSerializer) encode(w io.Writer, v interface{}) error {
+	return cache.EncodeMsgpack(w, v)
+}
+
+// Writes a body that was already encoded ahead of time by serializer, e.g. precomputed views
+type preencodedSerializer struct {
+	serializer
+}
+
+func (s preencodedSerializer) encode(w io.Writer, v interface{}) error {
+	encoded, ok := v.([]byte)
+	if !ok {
+		return fmt.Errorf("preencoded %s only supports []byte, got %T", s.name(), v)
+	}
+
+	_, err := w.Write(encoded)
+	return err
+}
+
+// Writes a GitHub response exactly as it was received, along with its content type
+type verbatimSerializer struct {
+	mediaType string
+}
+
+func (verbatimSerializer) name() string {
+	return "verbatim"
+}
+
+func (s verbatimSerializer) contentType() string {
+	if s.mediaType == "" {
+		return "application/json; charset=utf-8"
+	}
+
+	return s.mediaType
+}
+
+func (verbatimSerializer) encode(w io.Writer, v interface{}) error {
+	body, ok := v.([]byte)
+	if !ok {
+		return fmt.Errorf("verbatim only supports []byte, got %T", v)
+	}
+
+	_, err := w.Write(body)
+	return err
+}
+
+// Serializes tuple views as repo,value rows for dashboards and spreadsheets, header is the view's value column name
+type csvSerializer struct {
+	valueColumn string
+}
+
+func (csvSerializer) name() string {
+	return "csv"
+}
+
+func (csvSerializer) contentType() string {
+	return "text/csv; charset=utf-8"
+}
+
+func (s csvSerializer) encode(w io.Writer, v interface{}) error {
+	tuples, ok := v.([]cache.Tuple)
+	if !ok {
+		return fmt.Errorf("csv only supports tuple views, got %T", v)
+	}
+
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write([]string{"repo", s.valueColumn}); err != nil {
+		return err
+	}
+
+	for _, tuple := range tuples {
+		if err := writer.Write([]string{fmt.Sprint(tuple[0]), formatCsvValue(tuple[1])}); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// Writes text as is, e.g. the decoded content of a file
+type rawSerializer struct{}
+
+func (rawSerializer) name() string {
+	return "raw"
+}
+
+func (rawSerializer) contentType() string {
+	return "text/plain; charset=utf-8"
+}
+
+func (rawSerializer) encode(w io.Writer, v interface{}) error {
+	text, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("raw only supports text, got %T", v)
+	}
+
+	_, err := io.WriteString(w, text)
+	return err
+}
+
+// Formats counts without a trailing exponent or decimal, timestamps are written as is
+func formatCsvValue(value interface{}) string {
+	if count, ok := value.(float64); ok {
+		return strconv.FormatFloat(count, 'f', -1, 64)
+	}
+
+	return fmt.Sprint(value)
+}
+
+// Picks the serializer for a tuple view from the ?format= query param, falling back to the Accept header, JSON is the default.
+// Returns false if the requested format isn't supported
+func negotiateViewSerializer(r *http.Request, valueColumn string) (serializer, bool) {
+	switch r.URL.Query().Get("format") {
+	case "json":
+		return jsonSerializer{}, true
+	case "csv":
+		return csvSerializer{valueColumn: valueColumn}, true
+	case "msgpack":
+		return msgpackSerializer{}, true
+	case "":
+	default:
+		return nil, false
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), "text/csv") {
+		return csvSerializer{valueColumn: valueColumn}, true
+	}
+
+	if acceptsMsgpack(r) {
+		return msgpackSerializer{}, true
+	}
+
+	return jsonSerializer{}, true
+}
+
+// Determines if the Accept header asks for MessagePack, under either media type clients send for it
+func acceptsMsgpack(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	return acceptsToken(accept, MEDIA_TYPE_MSGPACK) || acceptsToken(accept, "application/msgpack")
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/cache"
+	githubclient "github.com/adamjeanlaurent/github-api-read-cache-service/github-client"
+)
+
+// entries of each view included in the summary, per direction
+const SUMMARY_VIEW_ENTRIES int = 5
+
+// The org at a glance, everything a dashboard refresh needs in a single response
+type summary struct {
+	Organization githubclient.JsonObject             `json:"organization"`
+	MemberCount  int                                 `json:"member_count"`
+	RepoCount    int                                 `json:"repo_count"`
+	TotalStars   int                                 `json:"total_stars"`
+	TotalForks   int                                 `json:"total_forks"`
+	Views        map[string]map[string][]cache.Tuple `json:"views"` // first entries of every available view, by view then direction
+	HydratedAt   *time.Time                          `json:"hydrated_at"`
+	Approximate  bool                                `json:"approximate"`
+}
+
+// datasets the summary is computed from, the optional views are included when they're hydrated
+var summaryDatasets = []string{cache.DATASET_ORGANIZATION, cache.DATASET_MEMBERS, cache.DATASET_REPOS, cache.DATASET_VIEWS}
+
+// Responds with the org's metadata, member and repo counts, total stars and forks, and the top and bottom 5 of every view,
+// all read from a single snapshot
+func (handler *httpHandlers) GetCachedSummary() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		snapshot := handler.dataCache.Snapshot()
+
+		for _, dataset := range summaryDatasets {
+			if snapshot.IsHydrated(dataset) {
+				continue
+			}
+
+			// each dataset missing is hydrated on its own, in turn
+			status, err := handler.forceCacheUpdateOnCacheMiss(w, r, dataset)
+			if err != nil {
+				handler.writeCacheMiss(w, status)
+				return
+			}
+
+			snapshot = handler.dataCache.Snapshot()
+			break
+		}
+
+		repos := snapshot.NetflixOrganizationRepos()
+
+		result := summary{
+			Organization: snapshot.NetflixOrganization(),
+			MemberCount:  len(snapshot.NetflixOrganizationMembers()),
+			RepoCount:    len(repos),
+			Views:        map[string]map[string][]cache.Tuple{},
+			Approximate:  snapshot.IsApproximate(),
+		}
+
+		for _, repo := range repos {
+			stars, _ := repo["stargazers_count"].(float64)
+			forks, _ := repo["forks_count"].(float64)
+
+			result.TotalStars += int(stars)
+			result.TotalForks += int(forks)
+		}
+
+		for _, view := range handler.catalogViews(snapshot) {
+			entries := map[string][]cache.Tuple{}
+			for _, direction := range view.directions {
+				entries[direction] = viewEntries(view.data, direction, SUMMARY_VIEW_ENTRIES)
+			}
+
+			result.Views[view.metric] = entries
+		}
+
+		if hydratedAt := snapshot.LastHydrationTime(); !hydratedAt.IsZero() {
+			result.HydratedAt = &hydratedAt
+		}
+
+		// any dataset being updated changes the generation, so it identifies the summary's content
+		etag := `"summary-` + strconv.FormatUint(snapshot.Version(), 10) + `"`
+
+		handler.writeCachedFromSnapshot(w, r, snapshot, handler.nextSyncedDataset(summaryDatasets), etag, jsonSerializer{}, result)
+	})
+}
+
+// Get whichever of datasets the sync loop re-hydrates first, so a response combining them isn't cached past any of them
+// changing
+func (handler *httpHandlers) nextSyncedDataset(datasets []string) string {
+	next := datasets[0]
+
+	for _, dataset := range datasets[1:] {
+		nextSync := handler.dataCache.GetNextSyncTime(dataset)
+		if !nextSync.IsZero() && nextSync.Before(handler.dataCache.GetNextSyncTime(next)) {
+			next = dataset
+		}
+	}
+
+	return next
+}
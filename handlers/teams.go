@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/cache"
+)
+
+// Responds with the cached teams of the Netflix org
+func (handler *httpHandlers) GetCachedNetflixTeams() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		snapshot := handler.dataCache.Snapshot()
+
+		if snapshot.DatasetHydrationTime(cache.DATASET_TEAMS).IsZero() {
+			handler.optionalDatasetUnavailable(w, r, cache.DATASET_TEAMS)
+			return
+		}
+
+		handler.writeCachedFromSnapshot(w, r, snapshot, cache.DATASET_TEAMS, snapshot.ETag(cache.DATASET_TEAMS), jsonSerializer{}, snapshot.NetflixTeams())
+	})
+}
+
+// Responds with the cached repos of a single Netflix team, by slug. Teams that aren't cached (e.g. secret teams) aren't proxied,
+// so they're not served with the service token
+func (handler *httpHandlers) GetCachedNetflixTeamRepos() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		team := r.PathValue("team")
+		snapshot := handler.dataCache.Snapshot()
+
+		if snapshot.DatasetHydrationTime(cache.DATASET_TEAMS).IsZero() {
+			handler.optionalDatasetUnavailable(w, r, cache.DATASET_TEAMS)
+			return
+		}
+
+		repos, found := snapshot.NetflixTeamRepos(team)
+		if !found {
+			http.Error(w, "Team not found", http.StatusNotFound)
+			return
+		}
+
+		etag := viewETag(snapshot.ETag(cache.DATASET_TEAMS), "team-repos-"+strings.ToLower(team), 1, "json")
+
+		handler.writeCachedFromSnapshot(w, r, snapshot, cache.DATASET_TEAMS, etag, jsonSerializer{}, repos)
+	})
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/cache"
+)
+
+// Describes a view that can be requested from the service
+type viewDescription struct {
+	Metric     string   `json:"metric"`
+	Directions []string `json:"directions"`
+	ValueType  string   `json:"value_type"`
+	Route      string   `json:"route"`
+	Items      int      `json:"items"`
+}
+
+// Machine-readable catalog of every available view, so clients can discover views instead of hard-coding routes
+type viewCatalog struct {
+	Views       []viewDescription `json:"views"`
+	HydratedAt  *time.Time        `json:"hydrated_at"`
+	AgeSeconds  *float64          `json:"age_seconds"`
+	Approximate bool              `json:"approximate"`
+}
+
+// Directions views are served in, views are sorted from top to bottom
+const (
+	VIEW_DIRECTION_BOTTOM string = "bottom"
+	VIEW_DIRECTION_TOP    string = "top"
+	VIEW_DIRECTION_RECENT string = "recent"
+)
+
+// A view listed in the catalog, along with its cached data
+type catalogView struct {
+	metric     string
+	valueType  string
+	data       []cache.Tuple
+	directions []string // the first is the direction views are pushed to /ws subscribers in
+}
+
+// Get every available view along with its cached data, as of snapshot
+func (handler *httpHandlers) catalogViews(snapshot cache.Snapshot) []catalogView {
+	var views []catalogView
+
+	for _, definition := range handler.dataCache.GetViewDefinitions() {
+		data, _ := snapshot.View(definition.Name)
+		views = append(views, catalogView{definition.Name, definition.ValueType, data, []string{VIEW_DIRECTION_BOTTOM, VIEW_DIRECTION_TOP}})
+	}
+
+	if handler.cfg.GetHydrateContributors() {
+		views = append(views, catalogView{cache.VIEW_BOTTOM_CONTRIBUTORS, cache.VALUE_TYPE_COUNT, snapshot.BottomNetflixReposByContributors(), []string{VIEW_DIRECTION_BOTTOM}})
+	}
+
+	if handler.cfg.GetHydrateCommitActivity() {
+		views = append(views, catalogView{cache.VIEW_BOTTOM_COMMIT_ACTIVITY, cache.VALUE_TYPE_COUNT, snapshot.BottomNetflixReposByCommitActivity(), []string{VIEW_DIRECTION_BOTTOM}})
+	}
+
+	if handler.cfg.GetHydrateIssueCounts() {
+		views = append(views, catalogView{cache.VIEW_BOTTOM_ISSUES, cache.VALUE_TYPE_COUNT, snapshot.BottomNetflixReposByIssues(), []string{VIEW_DIRECTION_BOTTOM}})
+		views = append(views, catalogView{cache.VIEW_BOTTOM_PULL_REQUESTS, cache.VALUE_TYPE_COUNT, snapshot.BottomNetflixReposByPullRequests(), []string{VIEW_DIRECTION_BOTTOM}})
+	}
+
+	if len(handler.cfg.GetReleaseRepos()) > 0 {
+		views = append(views, catalogView{cache.VIEW_RECENT_RELEASES, cache.VALUE_TYPE_TIMESTAMP, snapshot.RecentNetflixReleases(), []string{VIEW_DIRECTION_RECENT}})
+	}
+
+	return views
+}
+
+// Get the first n entries of the view as they're served in its first direction, bottom views are ranked from the end of their data
+func (view catalogView) first(n int) []cache.Tuple {
+	return viewEntries(view.data, view.directions[0], n)
+}
+
+// Get the first n entries of a view sorted from top to bottom as they're served in direction
+func viewEntries(data []cache.Tuple, direction string, n int) []cache.Tuple {
+	n = min(n, len(data))
+
+	if direction == VIEW_DIRECTION_BOTTOM {
+		return data[len(data)-n:]
+	}
+
+	return data[:n]
+}
+
+// Responds with the catalog of available views, their item counts, and their freshness
+func (handler *httpHandlers) GetViewCatalog() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		snapshot := handler.dataCache.Snapshot()
+		views := handler.catalogViews(snapshot)
+
+		catalog := viewCatalog{Approximate: snapshot.IsApproximate()}
+
+		for _, view := range views {
+			route := "/view/" + view.directions[0] + "/{n}/" + view.metric
+			if len(view.directions) > 1 {
+				route = "/view/{direction}/{n}/" + view.metric
+			}
+
+			catalog.Views = append(catalog.Views, viewDescription{
+				Metric:     view.metric,
+				Directions: view.directions,
+				ValueType:  view.valueType,
+				Route:      route,
+				Items:      len(view.data),
+			})
+		}
+
+		// freshness is left null until the cache has been hydrated
+		if hydratedAt := snapshot.LastHydrationTime(); !hydratedAt.IsZero() {
+			age := time.Since(hydratedAt).Seconds()
+
+			catalog.HydratedAt = &hydratedAt
+			catalog.AgeSeconds = &age
+		}
+
+		handler.writeCachedFromSnapshot(w, r, snapshot, cache.DATASET_VIEWS, "", jsonSerializer{}, catalog)
+	})
+}
+
+// Responds with the top or bottom N repos of any view computed by the cache, including extra views enabled with --extra-views
+func (handler *httpHandlers) GetCachedNNetflixReposByView() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		direction, view := r.PathValue("direction"), r.PathValue("view")
+
+		if direction != VIEW_DIRECTION_BOTTOM && direction != VIEW_DIRECTION_TOP {
+			http.Error(w, "direction must be one of bottom, top", http.StatusNotFound)
+			return
+		}
+
+		handler.serveView(w, r, direction, view)
+	})
+}
@@ -0,0 +1,367 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/cache"
+	githubclient "github.com/adamjeanlaurent/github-api-read-cache-service/github-client"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/metrics"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+const (
+	WS_PING_INTERVAL    time.Duration = 30 * time.Second // clients that don't answer a ping before the next one are disconnected
+	WS_WRITE_TIMEOUT    time.Duration = 10 * time.Second
+	WS_SEND_BUFFER_SIZE int           = 32 // messages buffered per client, clients that fall further behind are disconnected
+	WS_MAX_MESSAGE_SIZE int64         = 64 * 1024
+)
+
+// repo fields pushed to subscribers when they change, counts also carry their delta
+var watchedRepoFields = []string{"stargazers_count", "forks_count", "open_issues_count", "watchers_count", "updated_at", "pushed_at"}
+
+// Subscription request sent by a /ws client
+type wsRequest struct {
+	Action string   `json:"action"` // subscribe or unsubscribe
+	Repos  []string `json:"repos"`
+	Views  []wsView `json:"views"`
+}
+
+// View subscribed to, e.g. the bottom 10 repos by stars
+type wsView struct {
+	View string `json:"view"`
+	N    int    `json:"n"`
+}
+
+// Change to a single field of a repo between two syncs
+type fieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+	Delta  *float64    `json:"delta,omitempty"`
+}
+
+// Message pushed to a /ws client
+type wsMessage struct {
+	Type    string                 `json:"type"` // subscribed, repo, view, or error
+	Version uint64                 `json:"version,omitempty"`
+	Repo    string                 `json:"repo,omitempty"`
+	Added   bool                   `json:"added,omitempty"`
+	Removed bool                   `json:"removed,omitempty"`
+	Changes map[string]fieldChange `json:"changes,omitempty"`
+	View    string                 `json:"view,omitempty"`
+	N       int                    `json:"n,omitempty"`
+	Entries []cache.Tuple          `json:"entries,omitempty"`
+	Repos   []string               `json:"repos,omitempty"`
+	Views   []wsView               `json:"views,omitempty"`
+	Error   string                 `json:"error,omitempty"`
+}
+
+// Connected /ws client and what it's subscribed to
+type wsClient struct {
+	lock  sync.Mutex
+	repos map[string]struct{} // by lower-cased name
+	views map[wsView]struct{}
+	send  chan wsMessage
+}
+
+// Pushes changes to cached repos and views to subscribed /ws clients after every sync
+type wsHub struct {
+	lock         sync.Mutex
+	clients      map[*wsClient]struct{}
+	clientsGauge metrics.Gauge
+	upgrader     websocket.Upgrader
+	logger       *zap.Logger
+}
+
+// Get new wsHub, comparing every update of dataCache against the one before it until ctx is done
+func newWsHub(ctx context.Context, dataCache cache.Cache, views func(snapshot cache.Snapshot) []catalogView, logger *zap.Logger, registry metrics.Registry) *wsHub {
+	hub := &wsHub{
+		clients:      map[*wsClient]struct{}{},
+		clientsGauge: registry.Gauge("ws_clients", "Number of clients connected to /ws"),
+		logger:       logger,
+	}
+
+	updates, unsubscribe := dataCache.Subscribe()
+
+	go func() {
+		defer unsubscribe()
+
+		// repos and views are read from a single snapshot, so changes to both are reported for the same update
+		snapshot := dataCache.Snapshot()
+		previousRepos := indexRepos(snapshot.NetflixOrganizationRepos())
+		previousViews := views(snapshot)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case update := <-updates:
+				snapshot := dataCache.Snapshot()
+				currentRepos := indexRepos(snapshot.NetflixOrganizationRepos())
+				currentViews := views(snapshot)
+
+				hub.publish(update.Version, diffRepos(previousRepos, currentRepos), previousViews, currentViews)
+
+				previousRepos, previousViews = currentRepos, currentViews
+			}
+		}
+	}()
+
+	return hub
+}
+
+// Sends every client the changes it's subscribed to
+func (hub *wsHub) publish(version uint64, repoChanges map[string]wsMessage, previousViews []catalogView, currentViews []catalogView) {
+	hub.lock.Lock()
+	defer hub.lock.Unlock()
+
+	for client := range hub.clients {
+		client.lock.Lock()
+
+		var messages []wsMessage
+
+		for repo := range client.repos {
+			if change, ok := repoChanges[repo]; ok {
+				change.Version = version
+				messages = append(messages, change)
+			}
+		}
+
+		for subscribed := range client.views {
+			before, after := findView(previousViews, subscribed), findView(currentViews, subscribed)
+			if !reflect.DeepEqual(before, after) {
+				messages = append(messages, wsMessage{Type: "view", Version: version, View: subscribed.View, N: subscribed.N, Entries: after})
+			}
+		}
+
+		client.lock.Unlock()
+
+		for _, message := range messages {
+			if !client.trySend(message) {
+				hub.logger.Warn("WebSocket client isn't keeping up, disconnecting it")
+				hub.remove(client)
+				break
+			}
+		}
+	}
+}
+
+// Queues a message for the client, false if its buffer is full
+func (client *wsClient) trySend(message wsMessage) bool {
+	select {
+	case client.send <- message:
+		return true
+	default:
+		return false
+	}
+}
+
+// Removes a client, closing its send channel, must be called with the hub lock held
+func (hub *wsHub) remove(client *wsClient) {
+	if _, ok := hub.clients[client]; !ok {
+		return
+	}
+
+	delete(hub.clients, client)
+	close(client.send)
+	hub.clientsGauge.Set(float64(len(hub.clients)))
+}
+
+// Applies a subscription request, returning the acknowledgement to send
+func (client *wsClient) apply(request wsRequest, available []catalogView) wsMessage {
+	for _, view := range request.Views {
+		if !slices.ContainsFunc(available, func(catalog catalogView) bool { return catalog.metric == view.View }) {
+			return wsMessage{Type: "error", Error: fmt.Sprintf("unknown view %q", view.View)}
+		}
+
+		if view.N <= 0 {
+			return wsMessage{Type: "error", Error: "view n must be a positive integer"}
+		}
+	}
+
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	switch request.Action {
+	case "subscribe":
+		for _, repo := range request.Repos {
+			client.repos[normalizeRepoName(repo)] = struct{}{}
+		}
+		for _, view := range request.Views {
+			client.views[view] = struct{}{}
+		}
+	case "unsubscribe":
+		for _, repo := range request.Repos {
+			delete(client.repos, normalizeRepoName(repo))
+		}
+		for _, view := range request.Views {
+			delete(client.views, view)
+		}
+	default:
+		return wsMessage{Type: "error", Error: "action must be subscribe or unsubscribe"}
+	}
+
+	ack := wsMessage{Type: "subscribed", Repos: []string{}, Views: []wsView{}}
+	for repo := range client.repos {
+		ack.Repos = append(ack.Repos, repo)
+	}
+	for view := range client.views {
+		ack.Views = append(ack.Views, view)
+	}
+	slices.Sort(ack.Repos)
+
+	return ack
+}
+
+// Clients subscribe to repos or views by sending {"action": "subscribe", "repos": ["zuul"], "views": [{"view": "stars", "n": 10}]},
+// then receive a JSON message whenever one of them changes after a sync, with star / fork deltas for repos
+func (handler *httpHandlers) GetWebSocket() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := handler.ws.upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// the upgrader already responded with the error
+			handler.logger.Debug("Failed to upgrade WebSocket connection", zap.Error(err))
+			return
+		}
+		defer conn.Close()
+
+		client := &wsClient{repos: map[string]struct{}{}, views: map[wsView]struct{}{}, send: make(chan wsMessage, WS_SEND_BUFFER_SIZE)}
+
+		handler.ws.lock.Lock()
+		handler.ws.clients[client] = struct{}{}
+		handler.ws.clientsGauge.Set(float64(len(handler.ws.clients)))
+		handler.ws.lock.Unlock()
+
+		defer func() {
+			handler.ws.lock.Lock()
+			handler.ws.remove(client)
+			handler.ws.lock.Unlock()
+		}()
+
+		// reads subscription requests until the client disconnects, gorilla allows one concurrent reader and one writer
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+
+			conn.SetReadLimit(WS_MAX_MESSAGE_SIZE)
+			conn.SetReadDeadline(time.Now().Add(2 * WS_PING_INTERVAL))
+			conn.SetPongHandler(func(string) error {
+				return conn.SetReadDeadline(time.Now().Add(2 * WS_PING_INTERVAL))
+			})
+
+			for {
+				var request wsRequest
+				if err := conn.ReadJSON(&request); err != nil {
+					return
+				}
+
+				handler.ws.lock.Lock()
+				if _, connected := handler.ws.clients[client]; connected && !client.trySend(client.apply(request, handler.catalogViews(handler.dataCache.Snapshot()))) {
+					handler.ws.remove(client)
+				}
+				handler.ws.lock.Unlock()
+			}
+		}()
+
+		ping := time.NewTicker(WS_PING_INTERVAL)
+		defer ping.Stop()
+
+		for {
+			select {
+			case <-handler.ctx.Done():
+				conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"), time.Now().Add(WS_WRITE_TIMEOUT))
+				return
+			case <-done:
+				return
+			case message, ok := <-client.send:
+				if !ok {
+					conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "client too slow"), time.Now().Add(WS_WRITE_TIMEOUT))
+					return
+				}
+
+				conn.SetWriteDeadline(time.Now().Add(WS_WRITE_TIMEOUT))
+				if err := conn.WriteJSON(message); err != nil {
+					return
+				}
+			case <-ping.C:
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(WS_WRITE_TIMEOUT)); err != nil {
+					return
+				}
+			}
+		}
+	})
+}
+
+// Get the first n entries of a subscribed view, nil if the view isn't available
+func findView(views []catalogView, subscribed wsView) []cache.Tuple {
+	for _, view := range views {
+		if view.metric == subscribed.View {
+			return view.first(subscribed.N)
+		}
+	}
+
+	return nil
+}
+
+// Builds a lookup of repos by lower-cased name
+func indexRepos(repos []githubclient.JsonObject) map[string]githubclient.JsonObject {
+	index := make(map[string]githubclient.JsonObject, len(repos))
+
+	for _, repo := range repos {
+		if name, ok := repo["name"].(string); ok {
+			index[strings.ToLower(name)] = repo
+		}
+	}
+
+	return index
+}
+
+// Get the changes to every repo that was added, removed, or had a watched field change between two syncs, by lower-cased name
+func diffRepos(previous map[string]githubclient.JsonObject, current map[string]githubclient.JsonObject) map[string]wsMessage {
+	changes := map[string]wsMessage{}
+
+	for name, repo := range current {
+		before, existed := previous[name]
+		message := wsMessage{Type: "repo", Repo: name, Added: !existed, Changes: map[string]fieldChange{}}
+
+		for _, field := range watchedRepoFields {
+			if reflect.DeepEqual(before[field], repo[field]) {
+				continue
+			}
+
+			change := fieldChange{Before: before[field], After: repo[field]}
+			if beforeCount, ok := before[field].(float64); ok {
+				if afterCount, ok := repo[field].(float64); ok {
+					delta := afterCount - beforeCount
+					change.Delta = &delta
+				}
+			}
+			message.Changes[field] = change
+		}
+
+		if len(message.Changes) > 0 || message.Added {
+			changes[name] = message
+		}
+	}
+
+	for name := range previous {
+		if _, ok := current[name]; !ok {
+			changes[name] = wsMessage{Type: "repo", Repo: name, Removed: true}
+		}
+	}
+
+	return changes
+}
+
+// Normalizes a repo name from a subscription, accepting owner prefixed names. GitHub names are case insensitive, so the
+// owner is too, e.g. "netflix/Zuul" is zuul
+func normalizeRepoName(repo string) string {
+	return strings.TrimPrefix(strings.ToLower(strings.TrimSpace(repo)), "netflix/")
+}
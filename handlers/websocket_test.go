@@ -0,0 +1,22 @@
+package handlers
+
+import "testing"
+
+func TestNormalizeRepoName(t *testing.T) {
+	tests := map[string]string{
+		"zuul":          "zuul",
+		"Zuul":          "zuul",
+		" zuul ":        "zuul",
+		"Netflix/zuul":  "zuul",
+		"netflix/Zuul":  "zuul",
+		"NETFLIX/ZUUL":  "zuul",
+		"other/zuul":    "other/zuul",
+		"netflixoss/zu": "netflixoss/zu",
+	}
+
+	for repo, want := range tests {
+		if got := normalizeRepoName(repo); got != want {
+			t.Errorf("normalizeRepoName(%q) = %q, want %q", repo, got, want)
+		}
+	}
+}
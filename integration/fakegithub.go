@@ -0,0 +1,292 @@
+package integration
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	netUrl "net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	githubclient "github.com/adamjeanlaurent/github-api-read-cache-service/github-client"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/internal/testutil"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/metrics"
+)
+
+// Quota of a fresh rate limit window
+const FAKE_RATE_LIMIT int = 5000
+
+// Emulates the parts of the GitHub API the service calls: paginated org members and repos with Link headers, rate limit
+// headers that count down with every request, conditional requests answered 304 (which don't count), 403s once the rate
+// limit is exhausted, and secondary rate limits. Every request is recorded, so tests can assert what reached GitHub
+type FakeGitHub struct {
+	server           *httptest.Server
+	lock             sync.Mutex
+	org              githubclient.JsonObject
+	members          []githubclient.JsonObject
+	repos            []githubclient.JsonObject
+	remaining        int
+	reset            time.Time
+	secondaryLimited bool
+	exhaustAfter     int            // requests left until the rate limit is exhausted, e.g. by other clients sharing the token, 0 if it isn't
+	requests         map[string]int // by method and path, e.g. GET /orgs/Netflix/repos
+	notModified      int
+	lastAuth         string
+}
+
+// Get new FakeGitHub serving an org with the given number of members and repos, generated like the benchmarks'
+func NewFakeGitHub(members int, repos int) *FakeGitHub {
+	fake := &FakeGitHub{
+		org:       githubclient.JsonObject{"login": "Netflix", "id": float64(913567), "public_repos": float64(repos)},
+		members:   testutil.GenerateMembers(members),
+		repos:     testutil.GenerateRepos(repos),
+		remaining: FAKE_RATE_LIMIT,
+		reset:     time.Now().Add(time.Hour),
+		requests:  map[string]int{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /orgs/Netflix", func(w http.ResponseWriter, r *http.Request) {
+		fake.writeJson(w, fake.org)
+	})
+	mux.HandleFunc("GET /orgs/Netflix/public_members", func(w http.ResponseWriter, r *http.Request) {
+		fake.writePage(w, r, fake.members)
+	})
+	mux.HandleFunc("GET /orgs/Netflix/repos", func(w http.ResponseWriter, r *http.Request) {
+		repos := fake.repos
+
+		// incremental syncs list the most recently updated first
+		if r.URL.Query().Get("sort") == "updated" {
+			repos = slices.Clone(repos)
+			slices.SortStableFunc(repos, func(a, b githubclient.JsonObject) int {
+				return strings.Compare(b["updated_at"].(string), a["updated_at"].(string))
+			})
+		}
+
+		fake.writePage(w, r, repos)
+	})
+	mux.HandleFunc("GET /repos/Netflix/{repo}", func(w http.ResponseWriter, r *http.Request) {
+		for _, repo := range fake.repos {
+			if repo["name"] == r.PathValue("repo") {
+				fake.writeJson(w, repo)
+				return
+			}
+		}
+		fake.writeNotFound(w)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fake.writeNotFound(w)
+	})
+
+	fake.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fake.lock.Lock()
+		defer fake.lock.Unlock()
+
+		fake.requests[r.Method+" "+r.URL.Path]++
+		fake.lastAuth = r.Header.Get("Authorization")
+
+		if fake.secondaryLimited {
+			w.Header().Set("Retry-After", "60")
+			fake.writeError(w, http.StatusForbidden, "You have exceeded a secondary rate limit. Please wait a few minutes before you try again.")
+			return
+		}
+
+		// the rate limit endpoint doesn't count against the rate limit
+		if r.URL.Path == "/rate_limit" {
+			fake.setRateLimitHeaders(w)
+			json.NewEncoder(w).Encode(githubclient.JsonObject{"resources": githubclient.JsonObject{"core": githubclient.JsonObject{"limit": FAKE_RATE_LIMIT, "remaining": fake.remaining}}})
+			return
+		}
+
+		if fake.exhaustAfter > 0 {
+			fake.exhaustAfter--
+			if fake.exhaustAfter == 0 {
+				fake.remaining = 0
+			}
+		}
+
+		if fake.remaining == 0 {
+			fake.writeError(w, http.StatusForbidden, "API rate limit exceeded.")
+			return
+		}
+
+		mux.ServeHTTP(w, r)
+	}))
+
+	return fake
+}
+
+// Stops the server
+func (fake *FakeGitHub) Close() {
+	fake.server.Close()
+}
+
+// Get the URL the server listens on
+func (fake *FakeGitHub) URL() string {
+	return fake.server.URL
+}
+
+// Get a transport that sends requests for api.github.com to the fake instead, and every other request through next
+func (fake *FakeGitHub) Transport(next http.RoundTripper) http.RoundTripper {
+	target, _ := netUrl.Parse(fake.server.URL)
+
+	return metrics.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Host != "api.github.com" {
+			return next.RoundTrip(r)
+		}
+
+		r = r.Clone(r.Context())
+		r.URL.Scheme = target.Scheme
+		r.URL.Host = target.Host
+		r.Host = target.Host
+
+		return next.RoundTrip(r)
+	})
+}
+
+// Sets the quota left in the current rate limit window, 0 answers every request 403 until it's raised
+func (fake *FakeGitHub) SetRateLimitRemaining(remaining int) {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+
+	fake.remaining = remaining
+}
+
+// Exhausts the rate limit once requests more requests were made, as if other clients sharing the token used it up. The last
+// of them is the first answered 403
+func (fake *FakeGitHub) ExhaustRateLimitAfter(requests int) {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+
+	fake.exhaustAfter = requests
+}
+
+// Answers every request with a 403 secondary rate limit while limited
+func (fake *FakeGitHub) SetSecondaryLimited(limited bool) {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+
+	fake.secondaryLimited = limited
+}
+
+// Sets a field of a repo, e.g. its stargazers_count
+func (fake *FakeGitHub) SetRepoField(name string, field string, value interface{}) {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+
+	for i, repo := range fake.repos {
+		if repo["name"] == name {
+			updated := githubclient.JsonObject{}
+			for key, value := range repo {
+				updated[key] = value
+			}
+			updated[field] = value
+
+			fake.repos[i] = updated
+		}
+	}
+}
+
+// Get the number of requests made to path with method
+func (fake *FakeGitHub) Requests(method string, path string) int {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+
+	return fake.requests[method+" "+path]
+}
+
+// Get the number of conditional requests answered 304
+func (fake *FakeGitHub) NotModified() int {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+
+	return fake.notModified
+}
+
+// Get the Authorization header of the last request
+func (fake *FakeGitHub) LastAuthorization() string {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+
+	return fake.lastAuth
+}
+
+// Writes a page of items, as selected by the page and per_page query parameters, with a Link header to the next and last pages.
+// Pages carry an ETag, and are answered 304 without counting against the rate limit when the client already has them
+func (fake *FakeGitHub) writePage(w http.ResponseWriter, r *http.Request, items []githubclient.JsonObject) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	perPage, err := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if err != nil || perPage < 1 {
+		perPage = 30
+	}
+
+	start := min((page-1)*perPage, len(items))
+	end := min(start+perPage, len(items))
+
+	body, _ := json.Marshal(items[start:end])
+	etag := fmt.Sprintf(`"%x"`, md5.Sum(body))
+
+	lastPage := max((len(items)+perPage-1)/perPage, 1)
+	if page < lastPage {
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next", <%s>; rel="last"`, pageUrl(r, page+1), pageUrl(r, lastPage)))
+	}
+	w.Header().Set("ETag", etag)
+
+	if r.Header.Get("If-None-Match") == etag {
+		fake.notModified++
+		fake.setRateLimitHeaders(w)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	fake.remaining--
+	fake.setRateLimitHeaders(w)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+// Writes v as JSON, counting against the rate limit
+func (fake *FakeGitHub) writeJson(w http.ResponseWriter, v interface{}) {
+	fake.remaining--
+	fake.setRateLimitHeaders(w)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func (fake *FakeGitHub) writeNotFound(w http.ResponseWriter) {
+	fake.remaining--
+	fake.writeError(w, http.StatusNotFound, "Not Found")
+}
+
+// Writes an error the way GitHub does, a JSON message along with the rate limit headers
+func (fake *FakeGitHub) writeError(w http.ResponseWriter, status int, message string) {
+	fake.setRateLimitHeaders(w)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(githubclient.JsonObject{"message": message})
+}
+
+func (fake *FakeGitHub) setRateLimitHeaders(w http.ResponseWriter) {
+	w.Header().Set("x-ratelimit-limit", strconv.Itoa(FAKE_RATE_LIMIT))
+	w.Header().Set("x-ratelimit-remaining", strconv.Itoa(fake.remaining))
+	w.Header().Set("x-ratelimit-used", strconv.Itoa(FAKE_RATE_LIMIT-fake.remaining))
+	w.Header().Set("x-ratelimit-reset", strconv.FormatInt(fake.reset.Unix(), 10))
+	w.Header().Set("x-ratelimit-resource", "core")
+}
+
+// Get the URL of another page of the request's listing
+func pageUrl(r *http.Request, page int) string {
+	query := r.URL.Query()
+	query.Set("page", strconv.Itoa(page))
+
+	return "https://api.github.com" + r.URL.Path + "?" + query.Encode()
+}
@@ -0,0 +1,475 @@
+package integration
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/client"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/config"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/server"
+	"go.uber.org/zap"
+)
+
+const (
+	TEST_MEMBERS int = 30
+	TEST_REPOS   int = 250 // spans 3 pages of 100
+	TEST_TOKEN       = "test-token"
+)
+
+// Requests to the server don't keep connections around, a connection the server has accepted but not read a request from
+// holds up its shutdown
+var testClient = &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+// Runs the real server against fake until the test ends, args are parsed as command line flags. Returns the server's base
+// URL once the cache is hydrated and it's serving
+func startServer(t *testing.T, fake *FakeGitHub, args ...string) string {
+	t.Helper()
+
+	// GitHub requests are sent through the default transport unless an outbound proxy or CA is configured
+	defaultTransport := http.DefaultTransport
+	http.DefaultTransport = fake.Transport(defaultTransport)
+	t.Setenv("GITHUB_API_TOKEN", TEST_TOKEN)
+
+	port := freePort(t)
+	cfg, err := config.ParseConfiguration(flag.NewFlagSet("integration", flag.ContinueOnError), append([]string{"--port=" + strconv.Itoa(port)}, args...), true, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+
+	go func() {
+		done <- server.Serve(ctx, cfg, zap.NewNop(), zap.NewAtomicLevel())
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("Server stopped with error: %v", err)
+			}
+		case <-time.After(10 * time.Second):
+			t.Error("Server didn't stop in time")
+		}
+
+		http.DefaultTransport = defaultTransport
+	})
+
+	baseUrl := fmt.Sprintf("http://127.0.0.1:%d", port)
+
+	eventually(t, 10*time.Second, "server to be ready", func() bool {
+		select {
+		case err := <-done:
+			t.Fatalf("Server stopped before it was ready: %v", err)
+		default:
+		}
+
+		resp, err := testClient.Get(baseUrl + "/ready")
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+
+		return resp.StatusCode == http.StatusOK
+	})
+
+	return baseUrl
+}
+
+// Get a port nothing is listening on
+func freePort(t *testing.T) int {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+// Fails the test unless condition is met within timeout
+func eventually(t *testing.T, timeout time.Duration, what string, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// GETs url with headers, given as alternating keys and values. Returns the response, with its body decoded into v when v isn't nil
+func get(t *testing.T, url string, v interface{}, headers ...string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	resp, err := testClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("Failed to decode response of %s: %v", url, err)
+		}
+	}
+
+	return resp
+}
+
+// Get the total number of requests that reached fake for the org, members, and repos
+func hydrationRequests(fake *FakeGitHub) int {
+	return fake.Requests(http.MethodGet, "/orgs/Netflix") + fake.Requests(http.MethodGet, "/orgs/Netflix/public_members") + fake.Requests(http.MethodGet, "/orgs/Netflix/repos")
+}
+
+func TestCachedEndpoints(t *testing.T) {
+	fake := NewFakeGitHub(TEST_MEMBERS, TEST_REPOS)
+	defer fake.Close()
+
+	baseUrl := startServer(t, fake)
+	svc := client.NewClient(baseUrl, testClient)
+	ctx := context.Background()
+
+	// every page is fetched, until the first empty one
+	if requests := fake.Requests(http.MethodGet, "/orgs/Netflix/repos"); requests != 4 {
+		t.Errorf("Expected 4 requests for repo pages, got %d", requests)
+	}
+
+	org, err := svc.GetOrg(ctx)
+	if err != nil || org["login"] != "Netflix" {
+		t.Fatalf("Unexpected org %v: %v", org, err)
+	}
+
+	repos, err := svc.GetRepos(ctx)
+	if err != nil || len(repos) != TEST_REPOS {
+		t.Fatalf("Expected %d repos, got %d: %v", TEST_REPOS, len(repos), err)
+	}
+
+	var members []map[string]interface{}
+	if resp := get(t, baseUrl+"/orgs/Netflix/members", &members); resp.StatusCode != http.StatusOK || len(members) != TEST_MEMBERS {
+		t.Fatalf("Expected %d members, got %d with status %d", TEST_MEMBERS, len(members), resp.StatusCode)
+	}
+
+	// the bottom 5 by stars are the 5 fewest starred repos, fewest last
+	bottom, err := svc.GetBottomN(ctx, "stars", 5)
+	if err != nil || len(bottom) != 5 {
+		t.Fatalf("Expected 5 view entries, got %v: %v", bottom, err)
+	}
+
+	var stars []float64
+	for _, repo := range repos {
+		stars = append(stars, repo["stargazers_count"].(float64))
+	}
+	slices.Sort(stars)
+
+	for i, entry := range bottom {
+		if expected := stars[4-i]; entry.Value != expected {
+			t.Errorf("Expected entry %d of the bottom view to have %v stars, got %v", i, expected, entry)
+		}
+	}
+
+	// cached reads don't reach GitHub
+	before := hydrationRequests(fake)
+	for i := 0; i < 10; i++ {
+		resp := get(t, baseUrl+"/orgs/Netflix/repos", nil)
+		if resp.Header.Get("X-Cache") != "HIT" {
+			t.Errorf("Expected X-Cache: HIT, got %q", resp.Header.Get("X-Cache"))
+		}
+	}
+	if after := hydrationRequests(fake); after != before {
+		t.Errorf("Cached reads made %d requests to GitHub", after-before)
+	}
+
+	// clients that already have the data get a 304
+	etag := get(t, baseUrl+"/view/bottom/5/stars", nil).Header.Get("ETag")
+	if resp := get(t, baseUrl+"/view/bottom/5/stars", nil, "If-None-Match", etag); resp.StatusCode != http.StatusNotModified {
+		t.Errorf("Expected 304 for a matching ETag, got %d", resp.StatusCode)
+	}
+
+	// the zstd body is another representation, so neither encoding's ETag revalidates the other
+	zstdEtag := get(t, baseUrl+"/view/bottom/5/stars", nil, "Accept-Encoding", "zstd").Header.Get("ETag")
+	if zstdEtag == etag {
+		t.Errorf("Expected the zstd response under its own ETag, got %s for both", etag)
+	}
+	if resp := get(t, baseUrl+"/view/bottom/5/stars", nil, "Accept-Encoding", "zstd", "If-None-Match", etag); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 for the uncompressed ETag with Accept-Encoding: zstd, got %d", resp.StatusCode)
+	}
+	if resp := get(t, baseUrl+"/view/bottom/5/stars", nil, "Accept-Encoding", "zstd", "If-None-Match", zstdEtag); resp.StatusCode != http.StatusNotModified {
+		t.Errorf("Expected 304 for a matching zstd ETag, got %d", resp.StatusCode)
+	}
+}
+
+// The client's own response types carry everything /cachestatus serves
+func TestClientCacheStatus(t *testing.T) {
+	fake := NewFakeGitHub(TEST_MEMBERS, TEST_REPOS)
+	defer fake.Close()
+
+	baseUrl := startServer(t, fake)
+
+	// so routes have stats to report
+	if _, err := client.NewClient(baseUrl, testClient).GetRepos(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := testClient.Get(baseUrl + "/cachestatus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var served map[string]interface{}
+	var status client.CacheStatus
+	if err := json.Unmarshal(body, &served); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(body, &status); err != nil {
+		t.Fatal(err)
+	}
+
+	encoded, err := json.Marshal(status)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(decoded, served) {
+		t.Errorf("client.CacheStatus dropped fields of /cachestatus\nserved:  %s\ndecoded: %s", body, encoded)
+	}
+}
+
+func TestClientAuthentication(t *testing.T) {
+	fake := NewFakeGitHub(TEST_MEMBERS, TEST_REPOS)
+	defer fake.Close()
+
+	t.Setenv("AUTH_API_KEYS", "test-api-key")
+	baseUrl := startServer(t, fake, "--auth-mode=static-keys")
+	ctx := context.Background()
+
+	_, err := client.NewClient(baseUrl, testClient).GetOrg(ctx)
+
+	var statusErr *client.StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without credentials, got %v", err)
+	}
+
+	for name, option := range map[string]client.Option{"bearer token": client.WithBearerToken("test-api-key"), "API key": client.WithApiKey("test-api-key")} {
+		if org, err := client.NewClient(baseUrl, testClient, option).GetOrg(ctx); err != nil || org["login"] != "Netflix" {
+			t.Errorf("Expected the org with a %s, got %v: %v", name, org, err)
+		}
+	}
+}
+
+func TestProxy(t *testing.T) {
+	fake := NewFakeGitHub(TEST_MEMBERS, TEST_REPOS)
+	defer fake.Close()
+
+	baseUrl := startServer(t, fake)
+
+	var repo map[string]interface{}
+	if resp := get(t, baseUrl+"/repos/Netflix/repo-1", &repo); resp.StatusCode != http.StatusOK || repo["name"] != "repo-1" {
+		t.Fatalf("Expected repo-1 to be proxied, got %v with status %d", repo, resp.StatusCode)
+	}
+
+	if auth := fake.LastAuthorization(); auth != "Bearer "+TEST_TOKEN {
+		t.Errorf("Expected the proxy to authenticate with the service token, got %q", auth)
+	}
+
+	// 404s are cached, the second request doesn't reach GitHub
+	for i := 0; i < 2; i++ {
+		if resp := get(t, baseUrl+"/repos/Netflix/missing", nil); resp.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected 404 for a missing repo, got %d", resp.StatusCode)
+		}
+	}
+	if requests := fake.Requests(http.MethodGet, "/repos/Netflix/missing"); requests != 1 {
+		t.Errorf("Expected the 404 to be cached, got %d requests to GitHub", requests)
+	}
+}
+
+func TestSecondaryRateLimit(t *testing.T) {
+	fake := NewFakeGitHub(TEST_MEMBERS, TEST_REPOS)
+	defer fake.Close()
+
+	baseUrl := startServer(t, fake, "--repos-ttl=50ms")
+
+	fake.SetSecondaryLimited(true)
+
+	// the proxy passes the secondary limit on, along with when to retry
+	resp := get(t, baseUrl+"/repos/Netflix/repo-1", nil)
+	if resp.StatusCode != http.StatusForbidden || resp.Header.Get("Retry-After") != "60" {
+		t.Errorf("Expected 403 with Retry-After: 60, got %d with Retry-After %q", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+
+	// failed syncs keep the previously cached data
+	eventually(t, 5*time.Second, "a repos sync to fail", func() bool {
+		status, err := client.NewClient(baseUrl, testClient).CacheStatus(context.Background())
+		return err == nil && status.LastSync.Datasets["repos"].Status == http.StatusForbidden
+	})
+
+	var repos []map[string]interface{}
+	if resp := get(t, baseUrl+"/orgs/Netflix/repos", &repos); resp.StatusCode != http.StatusOK || len(repos) != TEST_REPOS {
+		t.Errorf("Expected the cached repos to still be served, got %d repos with status %d", len(repos), resp.StatusCode)
+	}
+}
+
+func TestBackoffWhenRateLimitExhausted(t *testing.T) {
+	fake := NewFakeGitHub(TEST_MEMBERS, TEST_REPOS)
+	defer fake.Close()
+
+	baseUrl := startServer(t, fake, "--quota-reserve=0")
+
+	fake.SetRateLimitRemaining(0)
+
+	if resp := get(t, baseUrl+"/repos/Netflix/repo-1", nil); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected GitHub's 403 to be passed on, got %d", resp.StatusCode)
+	}
+
+	// until the rate limit resets, proxied requests are answered without reaching GitHub
+	for i := 0; i < 3; i++ {
+		if resp := get(t, baseUrl+"/repos/Netflix/repo-1", nil); resp.StatusCode != http.StatusTooManyRequests {
+			t.Errorf("Expected 429 while in backoff, got %d", resp.StatusCode)
+		}
+	}
+	if requests := fake.Requests(http.MethodGet, "/repos/Netflix/repo-1"); requests != 1 {
+		t.Errorf("Expected a single request to reach GitHub, got %d", requests)
+	}
+
+	if resp := get(t, baseUrl+"/orgs/Netflix", nil); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected cached data to be served in backoff, got %d", resp.StatusCode)
+	}
+}
+
+func TestBackoffWhenRateLimitExhaustedMidPagination(t *testing.T) {
+	fake := NewFakeGitHub(TEST_MEMBERS, TEST_REPOS)
+	defer fake.Close()
+
+	baseUrl := startServer(t, fake, "--quota-reserve=0", "--repos-ttl=50ms")
+
+	// the first page of the next repos sync succeeds, the second is rejected
+	fake.ExhaustRateLimitAfter(2)
+
+	// later syncs are skipped without reaching GitHub
+	eventually(t, 5*time.Second, "repos syncs to back off", func() bool {
+		status, err := client.NewClient(baseUrl, testClient).CacheStatus(context.Background())
+		return err == nil && status.LastSync.Datasets["repos"].Status == http.StatusTooManyRequests
+	})
+
+	before := fake.Requests(http.MethodGet, "/orgs/Netflix/repos")
+	time.Sleep(200 * time.Millisecond)
+	if after := fake.Requests(http.MethodGet, "/orgs/Netflix/repos"); after != before {
+		t.Errorf("Expected repos syncs to back off, %d requests reached GitHub", after-before)
+	}
+
+	// as are proxied requests
+	if resp := get(t, baseUrl+"/repos/Netflix/repo-1", nil); resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 while in backoff, got %d", resp.StatusCode)
+	}
+	if requests := fake.Requests(http.MethodGet, "/repos/Netflix/repo-1"); requests != 0 {
+		t.Errorf("Expected no proxied request to reach GitHub, got %d", requests)
+	}
+
+	var repos []map[string]interface{}
+	if resp := get(t, baseUrl+"/orgs/Netflix/repos", &repos); resp.StatusCode != http.StatusOK || len(repos) != TEST_REPOS {
+		t.Errorf("Expected the cached repos to still be served, got %d repos with status %d", len(repos), resp.StatusCode)
+	}
+}
+
+func TestIncrementalSyncWithConditionalPages(t *testing.T) {
+	fake := NewFakeGitHub(TEST_MEMBERS, TEST_REPOS)
+	defer fake.Close()
+
+	baseUrl := startServer(t, fake, "--incremental-repo-sync", "--repos-ttl=50ms")
+
+	// unchanged pages are answered 304
+	eventually(t, 5*time.Second, "a conditional request answered 304", func() bool {
+		return fake.NotModified() > 0
+	})
+
+	fake.SetRepoField("repo-5", "stargazers_count", float64(100000))
+	fake.SetRepoField("repo-5", "updated_at", time.Now().UTC().Add(time.Minute).Format(time.RFC3339))
+
+	// the updated repo is merged into the cached repos
+	eventually(t, 5*time.Second, "the updated repo to be cached", func() bool {
+		var top []client.ViewEntry
+		get(t, baseUrl+"/view/top/1/stars", &top)
+
+		return len(top) == 1 && top[0].Repo == "Netflix/repo-5" && top[0].Value == float64(100000)
+	})
+
+	var repos []map[string]interface{}
+	if get(t, baseUrl+"/orgs/Netflix/repos", &repos); len(repos) != TEST_REPOS {
+		t.Errorf("Expected %d repos after merging, got %d", TEST_REPOS, len(repos))
+	}
+}
+
+// Admin credentials and API keys are both accepted when both are configured, each on the routes it protects
+func TestAdminCredentialsWithAuthMode(t *testing.T) {
+	fake := NewFakeGitHub(TEST_MEMBERS, TEST_REPOS)
+	defer fake.Close()
+
+	t.Setenv("AUTH_API_KEYS", "test-api-key")
+	t.Setenv("ADMIN_PASSWORD", "test-admin-password")
+	baseUrl := startServer(t, fake, "--auth-mode=static-keys", "--admin-user=ops")
+
+	basicAuth := "Basic " + base64.StdEncoding.EncodeToString([]byte("ops:test-admin-password"))
+	wrongBasicAuth := "Basic " + base64.StdEncoding.EncodeToString([]byte("ops:wrong"))
+
+	tests := []struct {
+		name    string
+		path    string
+		headers []string
+		status  int
+	}{
+		{name: "admin route with admin credentials", path: "/admin/maintenance", headers: []string{"Authorization", basicAuth}, status: http.StatusOK},
+		{name: "admin route with admin credentials and an API key", path: "/admin/maintenance", headers: []string{"Authorization", basicAuth, "X-API-Key", "test-api-key"}, status: http.StatusOK},
+		{name: "admin route with wrong admin credentials", path: "/admin/maintenance", headers: []string{"Authorization", wrongBasicAuth}, status: http.StatusUnauthorized},
+		{name: "admin route with only an API key", path: "/admin/maintenance", headers: []string{"X-API-Key", "test-api-key"}, status: http.StatusUnauthorized},
+		{name: "API route with an API key", path: "/orgs/Netflix", headers: []string{"X-API-Key", "test-api-key"}, status: http.StatusOK},
+		{name: "API route with only admin credentials", path: "/orgs/Netflix", headers: []string{"Authorization", basicAuth}, status: http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if resp := get(t, baseUrl+test.path, nil, test.headers...); resp.StatusCode != test.status {
+				t.Errorf("GET %s = %d, want %d", test.path, resp.StatusCode, test.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+// Fixtures shared by the service's tests and benchmarks, so they stay out of the packages the service builds from
+package testutil
+
+import (
+	"context"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/alerts"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/config"
+	githubclient "github.com/adamjeanlaurent/github-api-read-cache-service/github-client"
+	"go.uber.org/zap"
+)
+
+// In-memory GithubClient serving generated data, so tests and benchmarks exercise the cache and handlers without the network
+type fakeGithubClient struct {
+	org     githubclient.JsonObject
+	members []githubclient.JsonObject
+	repos   []githubclient.JsonObject
+}
+
+// Get a GithubClient serving an org with the given number of generated members and repos
+func NewFakeGithubClient(members int, repos int) githubclient.GithubClient {
+	return &fakeGithubClient{
+		org:     githubclient.JsonObject{"login": "Netflix", "public_repos": float64(repos)},
+		members: GenerateMembers(members),
+		repos:   GenerateRepos(repos),
+	}
+}
+
+func (client *fakeGithubClient) ForwardRequest(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, "Proxying isn't supported by the fake GitHub client", http.StatusNotImplemented)
+}
+
+func (client *fakeGithubClient) GetNetflixOrg(ctx context.Context) (*githubclient.Response[githubclient.JsonObject], error) {
+	return githubclient.NewResponse(http.StatusOK, nil, client.org), nil
+}
+
+func (client *fakeGithubClient) GetNetflixOrgMembers(ctx context.Context) (*githubclient.Response[[]githubclient.JsonObject], error) {
+	return githubclient.NewResponse(http.StatusOK, nil, client.members), nil
+}
+
+func (client *fakeGithubClient) GetNetflixRepos(ctx context.Context) (*githubclient.Response[[]githubclient.JsonObject], error) {
+	return githubclient.NewResponse(http.StatusOK, nil, client.repos), nil
+}
+
+// Fixtures never change, so no repo has been updated since a previous sync
+func (client *fakeGithubClient) GetNetflixReposUpdatedSince(ctx context.Context, since time.Time) (*githubclient.Response[[]githubclient.JsonObject], error) {
+	return githubclient.NewResponse[[]githubclient.JsonObject](http.StatusOK, nil, nil), nil
+}
+
+func (client *fakeGithubClient) GetNetflixRepo(ctx context.Context, repo string) (*githubclient.Response[githubclient.JsonObject], error) {
+	for _, fixture := range client.repos {
+		if fixture["name"] == repo {
+			return githubclient.NewResponse(http.StatusOK, nil, fixture), nil
+		}
+	}
+
+	return githubclient.NewResponse[githubclient.JsonObject](http.StatusNotFound, nil, nil), fmt.Errorf("Request failed")
+}
+
+func (client *fakeGithubClient) GetRateLimit(ctx context.Context) (*githubclient.Response[githubclient.JsonObject], error) {
+	return githubclient.NewResponse(http.StatusOK, nil, githubclient.JsonObject{"rate": githubclient.JsonObject{"limit": float64(5000), "remaining": float64(5000)}}), nil
+}
+
+func (client *fakeGithubClient) GetNetflixRepoContributors(ctx context.Context, repo string) (*githubclient.Response[[]githubclient.JsonObject], error) {
+	return githubclient.NewResponse(http.StatusOK, nil, client.members[:min(len(client.members), len(repo))]), nil
+}
+
+func (client *fakeGithubClient) GetNetflixRepoLatestRelease(ctx context.Context, repo string) (*githubclient.Response[githubclient.JsonObject], error) {
+	return githubclient.NewResponse(http.StatusOK, nil, githubclient.JsonObject{"tag_name": "v1.0.0", "published_at": "2024-01-01T00:00:00Z"}), nil
+}
+
+func (client *fakeGithubClient) GetNetflixRepoCommitActivity(ctx context.Context, repo string) (*githubclient.Response[[]githubclient.JsonObject], error) {
+	return githubclient.NewResponse(http.StatusOK, nil, []githubclient.JsonObject{{"total": float64(len(repo)), "week": float64(1704067200), "days": []interface{}{float64(0), float64(1), float64(2), float64(3), float64(4), float64(5), float64(6)}}}), nil
+}
+
+func (client *fakeGithubClient) GetNetflixTeams(ctx context.Context) (*githubclient.Response[[]githubclient.JsonObject], error) {
+	return githubclient.NewResponse(http.StatusOK, nil, []githubclient.JsonObject{{"slug": "platform", "name": "Platform", "privacy": "closed"}}), nil
+}
+
+func (client *fakeGithubClient) GetNetflixTeamRepos(ctx context.Context, team string) (*githubclient.Response[[]githubclient.JsonObject], error) {
+	return githubclient.NewResponse(http.StatusOK, nil, client.repos[:min(len(client.repos), 10)]), nil
+}
+
+func (client *fakeGithubClient) GetNetflixRepoIssueCounts(ctx context.Context, repo string) (*githubclient.Response[githubclient.IssueCounts], error) {
+	return githubclient.NewResponse(http.StatusOK, nil, githubclient.IssueCounts{OpenIssues: len(repo), OpenPullRequests: len(repo) / 2}), nil
+}
+
+func (client *fakeGithubClient) GetNetflixRepoReadme(ctx context.Context, repo string) (*githubclient.Response[githubclient.Readme], error) {
+	content := "# " + repo + "\n"
+	return githubclient.NewResponse(http.StatusOK, nil, githubclient.Readme{Metadata: githubclient.JsonObject{"name": "README.md", "path": "README.md", "encoding": "base64", "content": base64.StdEncoding.EncodeToString([]byte(content))}, Content: content}), nil
+}
+
+func (client *fakeGithubClient) GetNetflixRepoLanguages(ctx context.Context, repo string) (*githubclient.Response[githubclient.JsonObject], error) {
+	return githubclient.NewResponse(http.StatusOK, nil, githubclient.JsonObject{"Java": float64(len(repo) * 1000), "Go": float64(len(repo) * 100)}), nil
+}
+
+func (client *fakeGithubClient) GetEndpoint(ctx context.Context, path string) (*githubclient.Response[githubclient.RawResponse], error) {
+	return githubclient.NewResponse(http.StatusOK, nil, githubclient.RawResponse{ContentType: "application/json; charset=utf-8", Body: []byte(`{"path":"` + path + `"}`)}), nil
+}
+
+// the fixtures never change, so there are never new events
+func (client *fakeGithubClient) GetNetflixOrgEventsETag(ctx context.Context, etag string) (*githubclient.Response[string], error) {
+	if etag != "" {
+		return githubclient.NewResponse(http.StatusNotModified, nil, etag), nil
+	}
+	return githubclient.NewResponse(http.StatusOK, nil, `"events"`), nil
+}
+
+func (client *fakeGithubClient) SetAlertHooks(hooks alerts.Hooks) {}
+
+func (client *fakeGithubClient) GetTokenHealth() githubclient.TokenHealth {
+	return githubclient.TokenHealth{Status: githubclient.TOKEN_STATUS_NONE}
+}
+
+// Fixtures are never rate limited
+func (client *fakeGithubClient) GetBackoffResetTime() time.Time {
+	return time.Time{}
+}
+
+func (client *fakeGithubClient) SetToken(token string) {}
+
+// Generates n members shaped like GitHub's public members response
+func GenerateMembers(n int) []githubclient.JsonObject {
+	members := make([]githubclient.JsonObject, 0, n)
+
+	for i := 0; i < n; i++ {
+		login := fmt.Sprintf("member-%d", i)
+
+		members = append(members, githubclient.JsonObject{
+			"login":      login,
+			"id":         float64(i),
+			"html_url":   "https://github.com/" + login,
+			"avatar_url": fmt.Sprintf("https://avatars.githubusercontent.com/u/%d?v=4", i),
+			"type":       "User",
+		})
+	}
+
+	return members
+}
+
+// Generates n repos shaped like GitHub's org repos response, with counts spread out so views sort non-trivially
+func GenerateRepos(n int) []githubclient.JsonObject {
+	repos := make([]githubclient.JsonObject, 0, n)
+
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("repo-%d", i)
+
+		repos = append(repos, githubclient.JsonObject{
+			"id":                float64(i),
+			"name":              name,
+			"full_name":         "Netflix/" + name,
+			"html_url":          "https://github.com/Netflix/" + name,
+			"description":       "A library for building resilient services at scale",
+			"fork":              i%7 == 0,
+			"language":          []string{"Java", "Go", "Python", "JavaScript"}[i%4],
+			"forks_count":       float64(i * 31 % 997),
+			"stargazers_count":  float64(i * 17 % 9973),
+			"open_issues_count": float64(i % 53),
+			"updated_at":        fmt.Sprintf("2024-%02d-%02dT%02d:00:00Z", i%12+1, i%28+1, i%24),
+		})
+	}
+
+	return repos
+}
+
+// Get a configuration with defaults for everything, args are parsed as command line flags
+func NewConfiguration(args ...string) (config.Configuration, error) {
+	return config.ParseConfiguration(flag.NewFlagSet("testutil", flag.ContinueOnError), args, false, zap.NewNop())
+}
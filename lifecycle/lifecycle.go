@@ -0,0 +1,123 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Long running subsystem of the service, e.g. the cache sync loop or the HTTP listeners
+type Component interface {
+	// Starts the component, returning once it's running. ctx is done if the service is shut down while the component is
+	// starting. Failures once it's running are reported through fail, which shuts down every component
+	Start(ctx context.Context, fail func(error)) error
+	// Stops the component, returning once it has stopped or ctx, which carries the component's stop timeout, is done
+	Stop(ctx context.Context) error
+}
+
+// Starts components in the order they were added, and stops them in reverse order, so e.g. listeners finish draining
+// requests before the cache they read from is stopped
+type Manager interface {
+	Add(name string, component Component, stopTimeout time.Duration)
+	Run(ctx context.Context) error
+}
+
+// Component along with how it's managed
+type managedComponent struct {
+	name        string
+	component   Component
+	stopTimeout time.Duration
+}
+
+type manager struct {
+	components []managedComponent
+	logger     *zap.Logger
+	failOnce   sync.Once
+	failed     chan struct{} // closed by the first failure
+	failure    error         // set before failed is closed
+}
+
+// Get new Manager
+func NewManager(logger *zap.Logger) Manager {
+	return &manager{logger: logger, failed: make(chan struct{})}
+}
+
+// Add a component, must be called before Run
+func (m *manager) Add(name string, component Component, stopTimeout time.Duration) {
+	m.components = append(m.components, managedComponent{name: name, component: component, stopTimeout: stopTimeout})
+}
+
+// Starts every component, then runs until ctx is done or any component fails, at which point every started component is
+// stopped. Returns the failure along with every component that failed to stop in time, joined. Nothing is left running
+// if a component fails to start
+func (m *manager) Run(ctx context.Context) error {
+	started := 0
+
+	for _, managed := range m.components {
+		fail := func(err error) { m.fail(managed.name, err) }
+
+		if err := managed.component.Start(ctx, fail); err != nil {
+			// shutting down mid startup isn't a failure
+			if ctx.Err() == nil {
+				m.fail(managed.name, fmt.Errorf("%s failed to start: %w", managed.name, err))
+			}
+			break
+		}
+
+		m.logger.Info("Started component", zap.String("component", managed.name))
+		started++
+	}
+
+	if started == len(m.components) {
+		select {
+		case <-ctx.Done():
+		case <-m.failed:
+		}
+	}
+
+	stopErr := m.stop(started)
+
+	// the failure is only safe to read once it's been closed over
+	select {
+	case <-m.failed:
+		return errors.Join(m.failure, stopErr)
+	default:
+		return stopErr
+	}
+}
+
+// Records the first failure, which shuts down every component
+func (m *manager) fail(name string, err error) {
+	m.failOnce.Do(func() {
+		m.logger.Error("Component failed, stopping every component", zap.String("component", name), zap.Error(err))
+		m.failure = err
+		close(m.failed)
+	})
+}
+
+// Stops the first n components in reverse order, each within its own stop timeout
+func (m *manager) stop(n int) error {
+	var errs []error
+
+	for i := n - 1; i >= 0; i-- {
+		managed := m.components[i]
+
+		stopCtx, cancel := context.WithTimeout(context.Background(), managed.stopTimeout)
+		err := managed.component.Stop(stopCtx)
+		cancel()
+
+		if err != nil {
+			m.logger.Error("Component didn't stop cleanly", zap.String("component", managed.name), zap.Error(err))
+			errs = append(errs, fmt.Errorf("%s failed to stop: %w", managed.name, err))
+			continue
+		}
+
+		m.logger.Info("Stopped component", zap.String("component", managed.name))
+	}
+
+	return errors.Join(errs...)
+}
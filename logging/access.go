@@ -0,0 +1,69 @@
+package logging
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/metrics"
+	"go.uber.org/zap"
+)
+
+type accessLogFieldsKey struct{}
+
+// Fields the handlers serving a request add to its access log entry
+type accessLogFields struct {
+	lock   sync.Mutex
+	fields []zap.Field
+}
+
+// Adds fields to the access log entry of the request ctx belongs to, e.g. who made it. Does nothing if the request isn't
+// access logged
+func AddAccessLogFields(ctx context.Context, fields ...zap.Field) {
+	added, ok := ctx.Value(accessLogFieldsKey{}).(*accessLogFields)
+	if !ok {
+		return
+	}
+
+	added.lock.Lock()
+	defer added.lock.Unlock()
+
+	added.fields = append(added.fields, fields...)
+}
+
+// Wraps a handler to log an access log entry for a sample of its requests, sampleRate is the fraction of requests logged.
+// Server errors are always logged, they're rare and the ones worth investigating. Handlers can add their own fields to
+// the entry with AddAccessLogFields
+func AccessLog(logger *zap.Logger, sampleRate float64, route string, handler http.Handler) http.Handler {
+	if sampleRate <= 0 {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := metrics.NewResponseRecorder(w)
+		added := &accessLogFields{}
+
+		handler.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), accessLogFieldsKey{}, added)))
+
+		if rec.Status() < http.StatusInternalServerError && rand.Float64() >= sampleRate {
+			return
+		}
+
+		added.lock.Lock()
+		defer added.lock.Unlock()
+
+		logger.Info("access", append([]zap.Field{
+			zap.String("method", r.Method),
+			zap.String("path", r.URL.Path),
+			zap.String("route", route),
+			zap.Int("status", rec.Status()),
+			zap.Int("bytes", rec.Bytes()),
+			zap.Duration("duration", time.Since(start)),
+			zap.String("remote addr", r.RemoteAddr),
+			zap.String("user agent", r.UserAgent()),
+		}, added.fields...)...)
+	})
+}
@@ -0,0 +1,38 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/config"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Audit log written to stdout, rather than a file
+const AUDIT_LOG_STDOUT string = "stdout"
+
+// Get the audit logger configured by cfg, a no-op logger when the audit log is disabled. Entries are JSON with an
+// ISO 8601 timestamp, regardless of the log format, and aren't subject to the log level
+func NewAuditLogger(cfg config.Configuration) (*zap.Logger, error) {
+	if cfg.GetAuditLog() == "" {
+		return zap.NewNop(), nil
+	}
+
+	output := zapcore.Lock(os.Stdout)
+	if cfg.GetAuditLog() != AUDIT_LOG_STDOUT {
+		file, err := newRotatingFile(cfg.GetAuditLog(), cfg.GetLogFileMaxBytes(), cfg.GetLogFileMaxBackups())
+		if err != nil {
+			return nil, fmt.Errorf("Failed to open audit log: %w", err)
+		}
+		output = zapcore.Lock(file)
+	}
+
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.TimeKey = "timestamp"
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(encoderConfig), output, zapcore.DebugLevel)
+
+	return zap.New(core, zap.ErrorOutput(zapcore.Lock(os.Stderr))), nil
+}
@@ -0,0 +1,42 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/config"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	LOG_FORMAT_JSON    string = "json"    // one JSON object per line, for log aggregators
+	LOG_FORMAT_CONSOLE string = "console" // human readable, for local development
+)
+
+// Get a logger configured from cfg, logging at level. level starts at the configured log level, and can be changed at
+// runtime (e.g. by the /admin/loglevel endpoint)
+func NewLogger(cfg config.Configuration, level zap.AtomicLevel) (*zap.Logger, error) {
+	level.SetLevel(cfg.GetLogLevel())
+
+	var encoder zapcore.Encoder
+	switch cfg.GetLogFormat() {
+	case LOG_FORMAT_CONSOLE:
+		encoder = zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig())
+	default:
+		encoder = zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	}
+
+	output := zapcore.Lock(os.Stderr)
+	if cfg.GetLogFile() != "" {
+		file, err := newRotatingFile(cfg.GetLogFile(), cfg.GetLogFileMaxBytes(), cfg.GetLogFileMaxBackups())
+		if err != nil {
+			return nil, fmt.Errorf("Failed to open log file: %w", err)
+		}
+		output = zapcore.Lock(file)
+	}
+
+	core := zapcore.NewCore(encoder, output, level)
+
+	return zap.New(core, zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel), zap.ErrorOutput(zapcore.Lock(os.Stderr))), nil
+}
@@ -0,0 +1,90 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+)
+
+// Log file rotated by size, once a write would take it past maxBytes it's renamed to path.1 (shifting older backups to path.2,
+// path.3, ...) and a new file is started. Only maxBackups rotated files are kept
+type rotatingFile struct {
+	path       string
+	maxBytes   int
+	maxBackups int
+	file       *os.File
+	size       int
+}
+
+// Get a rotating log file at path, appending to it if it already exists
+func newRotatingFile(path string, maxBytes int, maxBackups int) (*rotatingFile, error) {
+	rotating := &rotatingFile{path: path, maxBytes: maxBytes, maxBackups: maxBackups}
+
+	if err := rotating.open(); err != nil {
+		return nil, err
+	}
+
+	return rotating, nil
+}
+
+// Writes p to the file, rotating first if it would grow past maxBytes. Writes are serialized by the logger's core
+func (rotating *rotatingFile) Write(p []byte) (int, error) {
+	if rotating.size > 0 && rotating.size+len(p) > rotating.maxBytes {
+		if err := rotating.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := rotating.file.Write(p)
+	rotating.size += n
+	return n, err
+}
+
+// Flushes the file to disk
+func (rotating *rotatingFile) Sync() error {
+	return rotating.file.Sync()
+}
+
+// Opens the current log file, picking up its size so rotation accounts for what's already in it
+func (rotating *rotatingFile) open() error {
+	file, err := os.OpenFile(rotating.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	rotating.file = file
+	rotating.size = int(info.Size())
+	return nil
+}
+
+// Shifts backups up by one, dropping the oldest, moves the current file to the first backup, then starts a new file
+func (rotating *rotatingFile) rotate() error {
+	if err := rotating.file.Close(); err != nil {
+		return err
+	}
+
+	os.Remove(rotating.backupPath(rotating.maxBackups))
+	for i := rotating.maxBackups - 1; i >= 1; i-- {
+		os.Rename(rotating.backupPath(i), rotating.backupPath(i+1))
+	}
+
+	if rotating.maxBackups > 0 {
+		if err := os.Rename(rotating.path, rotating.backupPath(1)); err != nil {
+			return err
+		}
+	} else if err := os.Remove(rotating.path); err != nil {
+		return err
+	}
+
+	return rotating.open()
+}
+
+// Get the path of the i-th most recent backup
+func (rotating *rotatingFile) backupPath(i int) string {
+	return fmt.Sprintf("%s.%d", rotating.path, i)
+}
@@ -1,22 +1,57 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"os"
 
+	"github.com/adamjeanlaurent/github-api-read-cache-service/bench"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/dump"
 	"github.com/adamjeanlaurent/github-api-read-cache-service/server"
 	"go.uber.org/zap"
 )
 
 func main() {
-	logger, err := zap.NewProduction()
+	// log level can be changed at runtime through the /admin/loglevel endpoint. This logger only reports configuration errors,
+	// the server and subcommands replace it with one built from --log-level, --log-format, and --log-file
+	logLevel := zap.NewAtomicLevelAt(zap.InfoLevel)
+
+	loggerCfg := zap.NewProductionConfig()
+	loggerCfg.Level = logLevel
+
+	logger, err := loggerCfg.Build()
 
 	if err != nil {
 		fmt.Printf("Failed to init logger: %s", err.Error())
 	}
 
-	err = server.StartServer(logger)
+	// dump subcommand hydrates once and prints a view instead of running the server
+	if len(os.Args) > 1 && os.Args[1] == "dump" {
+		if err := dump.Run(os.Args[2:], logger); err != nil {
+			logger.Error("Failed to dump view", zap.Error(err))
+			os.Exit(1)
+		}
+		return
+	}
+
+	// loadtest subcommand generates load against a running server
+	if len(os.Args) > 1 && os.Args[1] == "loadtest" {
+		if err := bench.RunLoadTest(os.Args[2:], os.Stdout); err != nil {
+			logger.Error("Failed to run load test", zap.Error(err))
+			os.Exit(1)
+		}
+		return
+	}
+
+	err = server.StartServer(logger, logLevel)
 
 	if err != nil {
 		logger.Error("Failed to start server", zap.Error(err))
+
+		// only a failed --validate or --once run exits non-zero, as they're run by CI and scheduled jobs
+		var runErr *server.RunError
+		if errors.As(err, &runErr) {
+			os.Exit(1)
+		}
 	}
 }
@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Records the status code and number of body bytes written through a ResponseWriter
+type ResponseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+// Get new ResponseRecorder wrapping w, the status is 200 until a handler writes another
+func NewResponseRecorder(w http.ResponseWriter) *ResponseRecorder {
+	return &ResponseRecorder{ResponseWriter: w, status: http.StatusOK}
+}
+
+// Get the status code written through the recorder
+func (rec *ResponseRecorder) Status() int {
+	return rec.status
+}
+
+// Get the number of body bytes written through the recorder
+func (rec *ResponseRecorder) Bytes() int {
+	return rec.bytes
+}
+
+func (rec *ResponseRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *ResponseRecorder) Write(b []byte) (int, error) {
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+// Supports streaming handlers (e.g. the proxy) by passing flushes through
+func (rec *ResponseRecorder) Flush() {
+	if flusher, ok := rec.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Supports connection upgrades (e.g. WebSockets) by passing hijacks through, recorded as switching protocols
+func (rec *ResponseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := rec.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("%T doesn't support hijacking", rec.ResponseWriter)
+	}
+
+	rec.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+// Allows http.ResponseController to reach the underlying ResponseWriter
+func (rec *ResponseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+type routeContextKey struct{}
+
+// Get the route a request was matched to, as passed to InstrumentHandler, empty if it wasn't instrumented
+func RouteFromContext(ctx context.Context) string {
+	route, _ := ctx.Value(routeContextKey{}).(string)
+	return route
+}
+
+// Wraps a handler to record request counts, response payload sizes, bytes served, and cache hits and misses (from X-Cache)
+// for route. The route is passed down in the request context, so handlers can attribute their own metrics to it
+func InstrumentHandler(reg Registry, route string, handler http.Handler) http.Handler {
+	requests := reg.Counter("http_requests_total", "Number of HTTP requests served, by route and status code")
+	payloadSizes := reg.Histogram("http_response_size_bytes", "Size of HTTP response bodies in bytes, by route", PAYLOAD_SIZE_BUCKETS)
+	bytesServed := reg.Counter(METRIC_RESPONSE_BYTES, "Number of HTTP response body bytes served, by route")
+	cacheResults := reg.Counter(METRIC_CACHE_RESULTS, "Number of responses served from the cache (HIT) or not (MISS), by route")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := NewResponseRecorder(w)
+
+		handler.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), routeContextKey{}, route)))
+
+		requests.Add(1, "route", route, "status", strconv.Itoa(rec.status))
+		payloadSizes.Observe(float64(rec.bytes), "route", route)
+		bytesServed.Add(float64(rec.bytes), "route", route)
+
+		if result := rec.Header().Get("X-Cache"); result != "" {
+			cacheResults.Add(1, "route", route, "result", result)
+		}
+	})
+}
+
+// Wraps a transport to record the number and latency of outbound requests, by host and status code
+func InstrumentTransport(reg Registry, transport http.RoundTripper) http.RoundTripper {
+	requests := reg.Counter("upstream_requests_total", "Number of outbound requests, by host and status code (error when no response was received)")
+	durations := reg.Histogram("upstream_request_duration_seconds", "Time until the response headers of outbound requests were received in seconds, by host", DURATION_BUCKETS)
+
+	return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		start := time.Now()
+
+		resp, err := transport.RoundTrip(r)
+
+		status := "error"
+		if err == nil {
+			status = strconv.Itoa(resp.StatusCode)
+		}
+
+		requests.Add(1, "host", r.URL.Host, "status", status)
+		durations.Observe(time.Since(start).Seconds(), "host", r.URL.Host)
+
+		return resp, err
+	})
+}
+
+// Adapts a function to an http.RoundTripper, like http.HandlerFunc does for handlers
+type RoundTripperFunc func(r *http.Request) (*http.Response, error)
+
+func (f RoundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
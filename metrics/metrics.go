@@ -0,0 +1,284 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Default histogram buckets for payload sizes in bytes, 256B to 64MB
+var PAYLOAD_SIZE_BUCKETS = []float64{256, 1024, 4096, 16384, 65536, 262144, 1048576, 4194304, 16777216, 67108864}
+
+// Default histogram buckets for durations in seconds, 10ms to 5m
+var DURATION_BUCKETS = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300}
+
+// In-process metrics registry, exposed in the Prometheus text format at /metrics
+type Registry interface {
+	Counter(name string, help string) Counter
+	Gauge(name string, help string) Gauge
+	Histogram(name string, help string, buckets []float64) Histogram
+	WritePrometheus(w io.Writer) error
+	Samples() []Sample
+}
+
+// Monotonically increasing value. labels are alternating key, value pairs e.g. Add(1, "route", "/healthcheck")
+type Counter interface {
+	Add(delta float64, labels ...string)
+}
+
+// Value that can go up and down. labels are alternating key, value pairs
+type Gauge interface {
+	Set(value float64, labels ...string)
+}
+
+// Distribution of observed values over fixed buckets. labels are alternating key, value pairs
+type Histogram interface {
+	Observe(value float64, labels ...string)
+}
+
+type metricKind string
+
+const (
+	KIND_COUNTER   metricKind = "counter"
+	KIND_GAUGE     metricKind = "gauge"
+	KIND_HISTOGRAM metricKind = "histogram"
+)
+
+// Single metric and all of its label combinations
+type metric struct {
+	name    string
+	help    string
+	kind    metricKind
+	buckets []float64
+	lock    sync.Mutex
+	series  map[string]*series
+}
+
+// Current value of a metric for one label combination, as read by push based exporters
+type Sample struct {
+	Name   string
+	Kind   metricKind
+	Labels []string // alternating key, value pairs
+	Value  float64  // counter and gauge value
+	Sum    float64  // histogram sum of observations
+	Count  uint64   // histogram number of observations
+}
+
+// Values of a metric for one label combination
+type series struct {
+	labels       string
+	labelPairs   []string
+	value        float64  // counter and gauge value
+	bucketCounts []uint64 // histogram cumulative counts per bucket
+	sum          float64
+	count        uint64
+}
+
+type registry struct {
+	lock    sync.Mutex
+	metrics map[string]*metric
+}
+
+// Get New Registry
+func NewRegistry() Registry {
+	return &registry{metrics: map[string]*metric{}}
+}
+
+// Get or register a metric, registering the same name twice returns the original metric
+func (reg *registry) register(name string, help string, kind metricKind, buckets []float64) *metric {
+	reg.lock.Lock()
+	defer reg.lock.Unlock()
+
+	if existing, ok := reg.metrics[name]; ok {
+		return existing
+	}
+
+	m := &metric{name: name, help: help, kind: kind, buckets: buckets, series: map[string]*series{}}
+	reg.metrics[name] = m
+
+	return m
+}
+
+// Get or register a counter
+func (reg *registry) Counter(name string, help string) Counter {
+	return reg.register(name, help, KIND_COUNTER, nil)
+}
+
+// Get or register a gauge
+func (reg *registry) Gauge(name string, help string) Gauge {
+	return reg.register(name, help, KIND_GAUGE, nil)
+}
+
+// Get or register a histogram
+func (reg *registry) Histogram(name string, help string, buckets []float64) Histogram {
+	return reg.register(name, help, KIND_HISTOGRAM, buckets)
+}
+
+// Get the series for a label combination, creating it if needed. Must be called with the metric lock held
+func (m *metric) seriesFor(labels []string) *series {
+	key := formatLabels(labels)
+
+	s, ok := m.series[key]
+	if !ok {
+		s = &series{labels: key, labelPairs: slices.Clone(labels)}
+		if m.kind == KIND_HISTOGRAM {
+			s.bucketCounts = make([]uint64, len(m.buckets))
+		}
+		m.series[key] = s
+	}
+
+	return s
+}
+
+// Increments a counter
+func (m *metric) Add(delta float64, labels ...string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.seriesFor(labels).value += delta
+}
+
+// Sets a gauge
+func (m *metric) Set(value float64, labels ...string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.seriesFor(labels).value = value
+}
+
+// Records a histogram observation
+func (m *metric) Observe(value float64, labels ...string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	s := m.seriesFor(labels)
+
+	for i, bound := range m.buckets {
+		if value <= bound {
+			s.bucketCounts[i]++
+		}
+	}
+
+	s.sum += value
+	s.count++
+}
+
+// Writes every metric in the Prometheus text exposition format
+func (reg *registry) WritePrometheus(w io.Writer) error {
+	reg.lock.Lock()
+	names := make([]string, 0, len(reg.metrics))
+	for name := range reg.metrics {
+		names = append(names, name)
+	}
+	reg.lock.Unlock()
+
+	sort.Strings(names)
+
+	var out strings.Builder
+
+	for _, name := range names {
+		reg.lock.Lock()
+		m := reg.metrics[name]
+		reg.lock.Unlock()
+
+		m.writePrometheus(&out)
+	}
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// Get the current value of every series of every metric, sorted by name
+func (reg *registry) Samples() []Sample {
+	reg.lock.Lock()
+	metrics := make([]*metric, 0, len(reg.metrics))
+	for _, m := range reg.metrics {
+		metrics = append(metrics, m)
+	}
+	reg.lock.Unlock()
+
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].name < metrics[j].name })
+
+	var samples []Sample
+
+	for _, m := range metrics {
+		m.lock.Lock()
+		for _, s := range m.series {
+			samples = append(samples, Sample{Name: m.name, Kind: m.kind, Labels: s.labelPairs, Value: s.value, Sum: s.sum, Count: s.count})
+		}
+		m.lock.Unlock()
+	}
+
+	return samples
+}
+
+// Writes a single metric in the Prometheus text exposition format
+func (m *metric) writePrometheus(out *strings.Builder) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	fmt.Fprintf(out, "# HELP %s %s\n", m.name, m.help)
+	fmt.Fprintf(out, "# TYPE %s %s\n", m.name, m.kind)
+
+	keys := make([]string, 0, len(m.series))
+	for key := range m.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := m.series[key]
+
+		if m.kind != KIND_HISTOGRAM {
+			fmt.Fprintf(out, "%s%s %s\n", m.name, wrapLabels(s.labels), formatValue(s.value))
+			continue
+		}
+
+		for i, bound := range m.buckets {
+			fmt.Fprintf(out, "%s_bucket%s %d\n", m.name, wrapLabels(joinLabels(s.labels, `le="`+formatValue(bound)+`"`)), s.bucketCounts[i])
+		}
+		fmt.Fprintf(out, "%s_bucket%s %d\n", m.name, wrapLabels(joinLabels(s.labels, `le="+Inf"`)), s.count)
+		fmt.Fprintf(out, "%s_sum%s %s\n", m.name, wrapLabels(s.labels), formatValue(s.sum))
+		fmt.Fprintf(out, "%s_count%s %d\n", m.name, wrapLabels(s.labels), s.count)
+	}
+}
+
+// Formats alternating key, value pairs as Prometheus labels e.g. route="/healthcheck",status="200"
+func formatLabels(labels []string) string {
+	pairs := make([]string, 0, len(labels)/2)
+
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", labels[i], strconv.Quote(labels[i+1])))
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+func joinLabels(labels string, extra string) string {
+	if labels == "" {
+		return extra
+	}
+
+	return labels + "," + extra
+}
+
+func wrapLabels(labels string) string {
+	if labels == "" {
+		return ""
+	}
+
+	return "{" + labels + "}"
+}
+
+func formatValue(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,84 @@
+package metrics
+
+import "math"
+
+// Names of the metrics route cache statistics are read from
+const (
+	METRIC_CACHE_RESULTS     string = "http_cache_results_total"
+	METRIC_FORCED_HYDRATIONS string = "http_forced_hydrations_total"
+	METRIC_RESPONSE_BYTES    string = "http_response_bytes_total"
+	METRIC_HANDLER_ERRORS    string = "http_handler_errors_total"
+)
+
+// Cache usage of a single route since the server started
+type RouteCacheStats struct {
+	Hits             uint64            `json:"hits"`
+	Misses           uint64            `json:"misses"`
+	HitRatio         float64           `json:"hit_ratio"`
+	ForcedHydrations uint64            `json:"forced_hydrations"` // misses that hydrated the cache before responding
+	BytesServed      uint64            `json:"bytes_served"`
+	Errors           map[string]uint64 `json:"errors,omitempty"` // requests that couldn't be served from the cache, by reason
+}
+
+// Get the cache usage of every route that served a cache hit or miss, by route, read from the metrics recorded by
+// InstrumentHandler, the forced hydration counter, and the handler errors counter
+func GetRouteCacheStats(reg Registry) map[string]RouteCacheStats {
+	stats := map[string]RouteCacheStats{}
+	bytesServed := map[string]uint64{}
+
+	for _, sample := range reg.Samples() {
+		route, result := sampleLabel(sample, "route"), sampleLabel(sample, "result")
+		if route == "" {
+			continue
+		}
+
+		switch sample.Name {
+		case METRIC_CACHE_RESULTS:
+			routeStats := stats[route]
+			if result == "HIT" {
+				routeStats.Hits += uint64(sample.Value)
+			} else {
+				routeStats.Misses += uint64(sample.Value)
+			}
+			stats[route] = routeStats
+
+		case METRIC_FORCED_HYDRATIONS:
+			routeStats := stats[route]
+			routeStats.ForcedHydrations += uint64(sample.Value)
+			stats[route] = routeStats
+
+		case METRIC_RESPONSE_BYTES:
+			bytesServed[route] += uint64(sample.Value)
+
+		case METRIC_HANDLER_ERRORS:
+			routeStats := stats[route]
+			if routeStats.Errors == nil {
+				routeStats.Errors = map[string]uint64{}
+			}
+			routeStats.Errors[sampleLabel(sample, "reason")] += uint64(sample.Value)
+			stats[route] = routeStats
+		}
+	}
+
+	// bytes are only reported for routes served from the cache
+	for route, routeStats := range stats {
+		routeStats.BytesServed = bytesServed[route]
+		if total := routeStats.Hits + routeStats.Misses; total > 0 {
+			routeStats.HitRatio = math.Round(float64(routeStats.Hits)/float64(total)*1000) / 1000
+		}
+		stats[route] = routeStats
+	}
+
+	return stats
+}
+
+// Get the value of a sample's label, empty if it doesn't have it
+func sampleLabel(sample Sample, key string) string {
+	for i := 0; i+1 < len(sample.Labels); i += 2 {
+		if sample.Labels[i] == key {
+			return sample.Labels[i+1]
+		}
+	}
+
+	return ""
+}
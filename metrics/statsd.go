@@ -0,0 +1,180 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Line protocols the StatsD pusher can speak
+const (
+	STATSD_FLAVOR_STATSD    string = "statsd"    // labels are folded into the metric name
+	STATSD_FLAVOR_DOGSTATSD string = "dogstatsd" // labels are sent as tags
+)
+
+// Datagrams are kept under the common 1500 byte MTU after IP and UDP headers
+const STATSD_MAX_PACKET_SIZE int = 1432
+
+// Characters replaced with _ in metric names, in segments of a name (where . would nest), and in DogStatsD tags
+var (
+	statsdInvalidNameChars    = regexp.MustCompile(`[^a-zA-Z0-9_.\-]`)
+	statsdInvalidSegmentChars = regexp.MustCompile(`[^a-zA-Z0-9_\-]`)
+	statsdInvalidTagChars     = regexp.MustCompile(`[^a-zA-Z0-9_.\-/]`)
+)
+
+// Pushes the registry's metrics to a StatsD / DogStatsD server, for environments that can't scrape /metrics
+type StatsdPusher interface {
+	Start(ctx context.Context, fail func(error)) error
+	Stop(ctx context.Context) error
+}
+
+type statsdPusher struct {
+	registry Registry
+	conn     net.Conn
+	prefix   string
+	flavor   string
+	interval time.Duration
+	logger   *zap.Logger
+	pushed   map[string]Sample // last pushed sample of each counter and histogram series, to push deltas
+	stop     chan struct{}
+	done     chan struct{} // closed once the last push was sent
+}
+
+// Get new StatsdPusher pushing to the UDP address every interval, metric names are prefixed with prefix when it isn't empty
+func NewStatsdPusher(registry Registry, address string, prefix string, flavor string, interval time.Duration, logger *zap.Logger) (StatsdPusher, error) {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to connect to StatsD at %s: %w", address, err)
+	}
+
+	return &statsdPusher{
+		registry: registry,
+		conn:     conn,
+		prefix:   prefix,
+		flavor:   flavor,
+		interval: interval,
+		logger:   logger,
+		pushed:   map[string]Sample{},
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// Starts thread that pushes metrics every interval until stopped
+func (sp *statsdPusher) Start(ctx context.Context, fail func(error)) error {
+	go func() {
+		ticker := time.NewTicker(sp.interval)
+		defer ticker.Stop()
+		defer close(sp.done)
+		defer sp.conn.Close()
+
+		for {
+			select {
+			case <-ticker.C:
+				sp.push()
+			case <-sp.stop:
+				sp.push()
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Stops pushing, after pushing once more so the last interval isn't lost
+func (sp *statsdPusher) Stop(ctx context.Context) error {
+	close(sp.stop)
+
+	select {
+	case <-sp.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends every metric, batched into as few datagrams as fit. Failed sends are logged, StatsD is best effort
+func (sp *statsdPusher) push() {
+	var packet strings.Builder
+
+	for _, line := range sp.lines() {
+		if packet.Len() > 0 && packet.Len()+1+len(line) > STATSD_MAX_PACKET_SIZE {
+			sp.send(packet.String())
+			packet.Reset()
+		}
+
+		if packet.Len() > 0 {
+			packet.WriteByte('\n')
+		}
+		packet.WriteString(line)
+	}
+
+	if packet.Len() > 0 {
+		sp.send(packet.String())
+	}
+}
+
+func (sp *statsdPusher) send(packet string) {
+	if _, err := sp.conn.Write([]byte(packet)); err != nil {
+		sp.logger.Warn("Failed to push metrics to StatsD", zap.Error(err))
+	}
+}
+
+// Get a StatsD line for every series. Gauges are sent as is, counters as the increase since the last push, and histograms as the
+// increase of their count and sum, since StatsD can't take pre-aggregated buckets
+func (sp *statsdPusher) lines() []string {
+	var lines []string
+
+	for _, sample := range sp.registry.Samples() {
+		key := sample.Name + "{" + strings.Join(sample.Labels, ",") + "}"
+		last := sp.pushed[key]
+
+		switch sample.Kind {
+		case KIND_GAUGE:
+			lines = append(lines, sp.line(sample.Name, sample.Labels, formatValue(sample.Value), "g"))
+		case KIND_COUNTER:
+			if delta := sample.Value - last.Value; delta > 0 {
+				lines = append(lines, sp.line(sample.Name, sample.Labels, formatValue(delta), "c"))
+			}
+		case KIND_HISTOGRAM:
+			if sample.Count > last.Count {
+				lines = append(lines, sp.line(sample.Name+".count", sample.Labels, fmt.Sprintf("%d", sample.Count-last.Count), "c"))
+				lines = append(lines, sp.line(sample.Name+".sum", sample.Labels, formatValue(sample.Sum-last.Sum), "c"))
+			}
+		}
+
+		sp.pushed[key] = sample
+	}
+
+	return lines
+}
+
+// Formats a single StatsD line, e.g. prefix.http_requests_total._healthcheck.200:1|c, or with DogStatsD prefix.http_requests_total:1|c|#route:/healthcheck,status:200
+func (sp *statsdPusher) line(name string, labels []string, value string, statsdType string) string {
+	if sp.prefix != "" {
+		name = sp.prefix + "." + name
+	}
+
+	var tags []string
+
+	for i := 0; i+1 < len(labels); i += 2 {
+		if sp.flavor == STATSD_FLAVOR_DOGSTATSD {
+			tags = append(tags, statsdInvalidTagChars.ReplaceAllString(labels[i], "_")+":"+statsdInvalidTagChars.ReplaceAllString(labels[i+1], "_"))
+		} else {
+			name += "." + statsdInvalidSegmentChars.ReplaceAllString(labels[i+1], "_")
+		}
+	}
+
+	line := statsdInvalidNameChars.ReplaceAllString(name, "_") + ":" + value + "|" + statsdType
+	if len(tags) > 0 {
+		line += "|#" + strings.Join(tags, ",")
+	}
+
+	return line
+}
@@ -0,0 +1,76 @@
+package once
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/cache"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/config"
+	githubclient "github.com/adamjeanlaurent/github-api-read-cache-service/github-client"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/metrics"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/replication"
+	"go.uber.org/zap"
+)
+
+// Configuration of a single hydration, the cache doesn't persist snapshots on its own, they're written and uploaded once it's
+// done so failures are reported rather than logged, and the upload finishes before exiting
+type onceConfiguration struct {
+	config.Configuration
+}
+
+func (cfg onceConfiguration) GetSnapshotFile() string {
+	return ""
+}
+
+func (cfg onceConfiguration) GetSnapshotReplicaUrl() string {
+	return ""
+}
+
+func (cfg onceConfiguration) GetSnapshotBucket() replication.Bucket {
+	return replication.Bucket{}
+}
+
+// Hydrates the cache once, writing it to the snapshot file and uploading it to the snapshot bucket or replica when configured. Returns
+// an error if any step failed, so a scheduled job exits non-zero instead of publishing a stale or partial snapshot
+func Run(cfg config.Configuration, logger *zap.Logger) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	registry := metrics.NewRegistry()
+	githubClient := githubclient.NewGithubClient(cfg, logger, registry)
+	dataCache := cache.NewCache(onceConfiguration{cfg}, githubClient, ctx, logger, registry)
+
+	logger.Info("Hydrating cache once")
+
+	if statusCode, err := dataCache.HydrateCache(ctx); err != nil {
+		return fmt.Errorf("Failed to hydrate cache (status %d): %w", statusCode, err)
+	}
+
+	report := dataCache.GetLastSyncReport()
+	logger.Info("Successfully hydrated cache", zap.Duration("duration", report.EndTime.Sub(report.StartTime)))
+
+	snapshotFile := cfg.GetSnapshotFile()
+	if snapshotFile == "" {
+		return nil
+	}
+
+	if err := dataCache.WriteSnapshot(snapshotFile); err != nil {
+		return err
+	}
+	logger.Info("Wrote cache snapshot", zap.String("file", snapshotFile))
+
+	replicator := cache.NewSnapshotReplicator(cfg)
+	if replicator == nil {
+		return nil
+	}
+
+	if err := replicator.Upload(ctx, snapshotFile); err != nil {
+		return err
+	}
+	logger.Info("Uploaded cache snapshot to the snapshot replica")
+
+	return nil
+}
@@ -0,0 +1,282 @@
+package replication
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Providers of buckets snapshots can be replicated to
+const (
+	BUCKET_PROVIDER_S3  string = "s3"
+	BUCKET_PROVIDER_GCS string = "gs"
+)
+
+// Name of the snapshot object, under the bucket prefix
+const BUCKET_SNAPSHOT_OBJECT string = "cache-snapshot.json.zst"
+
+// SHA-256 of an empty payload, signed for requests without a body
+const emptyPayloadHash string = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// Headers signed along with host and every x-amz-* header when a request sets them, so they can't be altered in transit
+var signedStandardHeaders = []string{"content-md5", "content-type", "date", "range"}
+
+// Bucket snapshots are replicated to, and the credentials to sign requests with. GCS buckets are reached through their
+// S3 compatible XML API, signed with an HMAC key
+type Bucket struct {
+	Provider        string // s3 or gs
+	Name            string
+	Prefix          string // of the snapshot object, empty for the bucket's root
+	Region          string // ignored for GCS
+	Endpoint        string // overrides the provider's endpoint, e.g. an S3 compatible store like MinIO, empty for the provider's
+	AccessKeyId     string
+	SecretAccessKey string
+	SessionToken    string // only for temporary credentials
+}
+
+// Replicates snapshots to an object in an S3 or GCS bucket, signing requests with AWS Signature Version 4
+type bucketReplicator struct {
+	bucket     Bucket
+	objectUrl  *url.URL
+	region     string
+	httpClient *http.Client
+}
+
+// Get new Replicator for the snapshot object in bucket. Returns an error if the endpoint isn't a valid URL
+func NewBucketReplicator(bucket Bucket) (Replicator, error) {
+	objectKey := path.Join(bucket.Prefix, BUCKET_SNAPSHOT_OBJECT)
+	region := bucket.Region
+
+	// the provider's endpoints address buckets by host, custom endpoints by path as most S3 compatible stores expect
+	var rawUrl string
+	switch {
+	case bucket.Endpoint != "":
+		rawUrl = strings.TrimSuffix(bucket.Endpoint, "/") + "/" + bucket.Name + "/" + objectKey
+	case bucket.Provider == BUCKET_PROVIDER_GCS:
+		rawUrl = "https://" + bucket.Name + ".storage.googleapis.com/" + objectKey
+	default:
+		rawUrl = "https://" + bucket.Name + ".s3." + bucket.Region + ".amazonaws.com/" + objectKey
+	}
+
+	if bucket.Provider == BUCKET_PROVIDER_GCS {
+		region = "auto"
+	}
+
+	objectUrl, err := url.Parse(rawUrl)
+	if err != nil || (objectUrl.Scheme != "http" && objectUrl.Scheme != "https") {
+		return nil, fmt.Errorf("Invalid bucket endpoint %q", bucket.Endpoint)
+	}
+
+	return &bucketReplicator{
+		bucket:    bucket,
+		objectUrl: objectUrl,
+		region:    region,
+		httpClient: &http.Client{
+			Timeout: 2 * time.Minute,
+		},
+	}, nil
+}
+
+// Uploads the snapshot file at path, replacing the snapshot object
+func (rep *bucketReplicator) Upload(ctx context.Context, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// the payload is signed, so it's hashed before it's sent
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	req, err := rep.newRequest(ctx, http.MethodPut, file, hex.EncodeToString(hash.Sum(nil)))
+	if err != nil {
+		return fmt.Errorf("Failed to create upload request: %w", err)
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/zstd")
+
+	resp, err := rep.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("Failed to upload snapshot: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("Failed to upload snapshot to %s, status code %d: %s", rep.location(), resp.StatusCode, errorCode(resp.Body))
+	}
+
+	return nil
+}
+
+// Downloads the snapshot object, returns an error wrapping os.ErrNotExist if there isn't one yet
+func (rep *bucketReplicator) Download(ctx context.Context) (io.ReadCloser, error) {
+	req, err := rep.newRequest(ctx, http.MethodGet, nil, emptyPayloadHash)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create download request: %w", err)
+	}
+
+	resp, err := rep.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to download snapshot: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, fmt.Errorf("No remote snapshot at %s: %w", rep.location(), os.ErrNotExist)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, fmt.Errorf("Failed to download snapshot from %s, status code %d: %s", rep.location(), resp.StatusCode, errorCode(resp.Body))
+	}
+
+	return resp.Body, nil
+}
+
+// Checks the bucket is reachable with the credentials, without downloading the snapshot. A missing snapshot still counts as reachable
+func (rep *bucketReplicator) Probe(ctx context.Context) error {
+	req, err := rep.newRequest(ctx, http.MethodHead, nil, emptyPayloadHash)
+	if err != nil {
+		return fmt.Errorf("Failed to create probe request: %w", err)
+	}
+
+	resp, err := rep.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("Failed to reach snapshot bucket: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("Snapshot bucket responded with status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// Get the snapshot object's location, e.g. s3://bucket/prefix/cache-snapshot.json.zst
+func (rep *bucketReplicator) location() string {
+	return rep.bucket.Provider + "://" + rep.bucket.Name + "/" + path.Join(rep.bucket.Prefix, BUCKET_SNAPSHOT_OBJECT)
+}
+
+// Get a new request for the snapshot object, signed with the payload's SHA-256 hex digest
+func (rep *bucketReplicator) newRequest(ctx context.Context, method string, body io.Reader, payloadHash string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rep.objectUrl.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	// the path is sent exactly as it's signed
+	req.URL.RawPath = canonicalUri(req.URL.Path)
+
+	rep.sign(req, payloadHash, time.Now().UTC())
+	return req, nil
+}
+
+// Signs req with AWS Signature Version 4, see https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html
+func (rep *bucketReplicator) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	scope := now.Format("20060102") + "/" + rep.region + "/s3/aws4_request"
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if rep.bucket.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", rep.bucket.SessionToken)
+	}
+
+	// host is sent by the transport rather than as a header, but it's always signed
+	headers := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-amz-") || slices.Contains(signedStandardHeaders, lower) {
+			headers[lower] = strings.TrimSpace(req.Header.Get(name))
+		}
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalUri(req.URL.Path),
+		"",
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex(canonicalRequest)}, "\n")
+
+	key := hmacSha256([]byte("AWS4"+rep.bucket.SecretAccessKey), now.Format("20060102"))
+	key = hmacSha256(key, rep.region)
+	key = hmacSha256(key, "s3")
+	key = hmacSha256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSha256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", rep.bucket.AccessKeyId, scope, signedHeaders, signature))
+}
+
+// Get the URI encoding of an object path as signed by Signature Version 4, every byte but unreserved characters and
+// slashes is percent-encoded
+func canonicalUri(objectPath string) string {
+	var encoded strings.Builder
+
+	for _, b := range []byte(objectPath) {
+		if b == '/' || b == '-' || b == '_' || b == '.' || b == '~' || ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z') || ('0' <= b && b <= '9') {
+			encoded.WriteByte(b)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+
+	return encoded.String()
+}
+
+// Get the code of an S3 style XML error body, e.g. AccessDenied, empty if there isn't one
+func errorCode(body io.Reader) string {
+	data, _ := io.ReadAll(io.LimitReader(body, 4096))
+
+	start := strings.Index(string(data), "<Code>")
+	end := strings.Index(string(data), "</Code>")
+	if start < 0 || end < start {
+		return ""
+	}
+
+	return string(data[start+len("<Code>") : end])
+}
+
+func sha256Hex(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,142 @@
+package replication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// Copies cache snapshots to and from a secondary location (e.g. a bucket in another region), so a warm cache can be recovered after a regional outage
+type Replicator interface {
+	Upload(ctx context.Context, path string) error
+	Download(ctx context.Context) (io.ReadCloser, error)
+	Probe(ctx context.Context) error
+}
+
+// Replicates snapshots to an HTTP endpoint that stores objects with PUT and serves them with GET. Downloading alone also
+// works with a pre-signed GET URL
+type httpReplicator struct {
+	url        string
+	location   string // url without its query, safe to log
+	httpClient *http.Client
+}
+
+// Get new Replicator for the object at url
+func NewHttpReplicator(url string) Replicator {
+	return &httpReplicator{
+		url:      url,
+		location: RedactUrl(url),
+		httpClient: &http.Client{
+			Timeout: 2 * time.Minute,
+		},
+	}
+}
+
+// Uploads the snapshot file at path, replacing the remote snapshot
+func (rep *httpReplicator) Upload(ctx context.Context, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, rep.url, file)
+	if err != nil {
+		return fmt.Errorf("Failed to create upload request: %w", rep.redact(err))
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", "application/zstd")
+
+	resp, err := rep.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("Failed to upload snapshot: %w", rep.redact(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("Failed to upload snapshot to %s, status code %d", rep.location, resp.StatusCode)
+	}
+
+	return nil
+}
+
+// Downloads the remote snapshot, returns an error wrapping os.ErrNotExist if there isn't one yet
+func (rep *httpReplicator) Download(ctx context.Context) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rep.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create download request: %w", rep.redact(err))
+	}
+
+	resp, err := rep.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to download snapshot: %w", rep.redact(err))
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, fmt.Errorf("No remote snapshot at %s: %w", rep.location, os.ErrNotExist)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("Failed to download snapshot from %s, status code %d", rep.location, resp.StatusCode)
+	}
+
+	return resp.Body, nil
+}
+
+// Checks the replica endpoint is reachable, without downloading the snapshot. A missing snapshot still counts as reachable
+func (rep *httpReplicator) Probe(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, rep.url, nil)
+	if err != nil {
+		return fmt.Errorf("Failed to create probe request: %w", rep.redact(err))
+	}
+
+	resp, err := rep.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("Failed to reach snapshot replica: %w", rep.redact(err))
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("Snapshot replica responded with status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// Get err with the URL it names redacted, the HTTP client's errors carry the full URL, signature and all
+func (rep *httpReplicator) redact(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = rep.location
+	}
+
+	return err
+}
+
+// Get rawUrl without its query and user info, which may carry credentials, e.g. the signature of a pre-signed URL
+func RedactUrl(rawUrl string) string {
+	parsed, err := url.Parse(rawUrl)
+	if err != nil {
+		return "<invalid URL>"
+	}
+
+	parsed.User = nil
+	if parsed.RawQuery != "" {
+		parsed.RawQuery = "REDACTED"
+	}
+	parsed.Fragment = ""
+
+	return parsed.String()
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/cache"
+)
+
+// Runs the cache sync loop as a lifecycle component, the sync loop stopping on its own (e.g. a panicking sync) shuts down the server
+type cacheComponent struct {
+	dataCache cache.Cache
+	ctx       context.Context // the cache's context, cancelled by Stop
+	cancel    context.CancelFunc
+	done      chan struct{} // closed once the sync loop has stopped
+}
+
+// Get new cacheComponent, cancel must cancel the context dataCache was created with
+func newCacheComponent(dataCache cache.Cache, ctx context.Context, cancel context.CancelFunc) *cacheComponent {
+	return &cacheComponent{dataCache: dataCache, ctx: ctx, cancel: cancel, done: make(chan struct{})}
+}
+
+// Hydrates the cache and starts its sync loop, returning once the initial hydration finishes
+func (cc *cacheComponent) Start(ctx context.Context, fail func(error)) error {
+	hydrated := make(chan struct{})
+
+	go func() {
+		defer close(hydrated)
+		cc.dataCache.StartSyncLoop()
+	}()
+
+	select {
+	case <-hydrated:
+	case <-ctx.Done():
+		cc.cancel()
+		<-hydrated
+		return ctx.Err()
+	}
+
+	go func() {
+		defer close(cc.done)
+
+		err := <-cc.dataCache.SyncLoopDone()
+		if err == nil && cc.ctx.Err() == nil {
+			err = errors.New("Cache sync loop stopped unexpectedly")
+		}
+
+		if err != nil {
+			fail(err)
+		}
+	}()
+
+	return nil
+}
+
+// Stops the sync loop, a sync in progress is cancelled
+func (cc *cacheComponent) Stop(ctx context.Context) error {
+	cc.cancel()
+
+	select {
+	case <-cc.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
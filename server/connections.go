@@ -0,0 +1,102 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/metrics"
+)
+
+// Connection states open connections are reported in
+var OPEN_CONN_STATES = []http.ConnState{http.StateNew, http.StateActive, http.StateIdle}
+
+// Tracks the open connections and in-flight requests of a single listener, through its server's ConnState hook and handler
+type connTracker struct {
+	listener      string
+	lock          sync.Mutex
+	conns         map[net.Conn]http.ConnState
+	inFlight      int
+	connsGauge    metrics.Gauge
+	inFlightGauge metrics.Gauge
+}
+
+// Open connections and in-flight requests of a listener at a point in time
+type connStats struct {
+	open     int
+	idle     int
+	inFlight int
+}
+
+// Get new connTracker for listener, reporting on the open connections and in-flight requests gauges
+func newConnTracker(listener string, connsGauge metrics.Gauge, inFlightGauge metrics.Gauge) *connTracker {
+	tracker := &connTracker{listener: listener, conns: map[net.Conn]http.ConnState{}, connsGauge: connsGauge, inFlightGauge: inFlightGauge}
+	tracker.report()
+
+	return tracker
+}
+
+// Wires the tracker into server, must be called before it serves
+func (tracker *connTracker) track(server *http.Server) {
+	server.ConnState = tracker.connState
+	server.Handler = tracker.instrument(server.Handler)
+}
+
+// ConnState hook, hijacked connections (e.g. WebSockets) are no longer the server's to track
+func (tracker *connTracker) connState(conn net.Conn, state http.ConnState) {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+
+	if state == http.StateHijacked || state == http.StateClosed {
+		delete(tracker.conns, conn)
+	} else {
+		tracker.conns[conn] = state
+	}
+
+	tracker.report()
+}
+
+// Wraps handler to count the requests in flight, HTTP/2 requests share a connection so they're counted separately
+func (tracker *connTracker) instrument(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tracker.addInFlight(1)
+		defer tracker.addInFlight(-1)
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func (tracker *connTracker) addInFlight(delta int) {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+
+	tracker.inFlight += delta
+	tracker.inFlightGauge.Set(float64(tracker.inFlight), "listener", tracker.listener)
+}
+
+// Get the current open connections and in-flight requests
+func (tracker *connTracker) stats() connStats {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+
+	stats := connStats{open: len(tracker.conns), inFlight: tracker.inFlight}
+	for _, state := range tracker.conns {
+		if state == http.StateIdle {
+			stats.idle++
+		}
+	}
+
+	return stats
+}
+
+// Sets the open connections gauge for every state. Must be called with the lock held
+func (tracker *connTracker) report() {
+	counts := map[http.ConnState]int{}
+	for _, state := range tracker.conns {
+		counts[state]++
+	}
+
+	for _, state := range OPEN_CONN_STATES {
+		tracker.connsGauge.Set(float64(counts[state]), "listener", tracker.listener, "state", state.String())
+	}
+}
@@ -0,0 +1,29 @@
+package server
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/cache"
+)
+
+// Sets up routes of the debug listener, pprof profiles under /debug/pprof/ and expvar runtime stats under /debug/vars.
+// Kept off the API listener, profiles expose internals and can be expensive to collect
+func setupDebugRoutes(dataCache cache.Cache) *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	// alongside the memstats and cmdline expvar publishes by default, so heap growth can be lined up with the cache's size
+	expvar.Publish("cache", expvar.Func(func() any {
+		return dataCache.GetStats()
+	}))
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	return mux
+}
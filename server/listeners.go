@@ -0,0 +1,156 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/metrics"
+	"go.uber.org/zap"
+)
+
+// Single server the process accepts connections on, e.g. the public HTTP API or its HTTPS twin
+type listener struct {
+	name     string
+	server   *http.Server
+	certFile string // serves TLS when set, along with keyFile
+	keyFile  string
+	conns    *connTracker
+}
+
+// Group of listeners that are started, and shut down, together
+type listenerGroup struct {
+	listeners     []*listener
+	logger        *zap.Logger
+	keepAlive     time.Duration // TCP keep-alive period of accepted connections, negative disables keep-alive probes
+	readyGauge    metrics.Gauge
+	connsGauge    metrics.Gauge
+	inFlightGauge metrics.Gauge
+	wg            sync.WaitGroup // running Serve calls
+}
+
+// Get new listenerGroup, listeners report their readiness on the http_listener_ready gauge, and their open connections and
+// in-flight requests on the http_open_connections and http_in_flight_requests gauges
+func newListenerGroup(logger *zap.Logger, registry metrics.Registry, keepAlive time.Duration) *listenerGroup {
+	return &listenerGroup{
+		logger:        logger,
+		keepAlive:     keepAlive,
+		readyGauge:    registry.Gauge("http_listener_ready", "Whether each listener is bound and accepting connections (1) or not (0)"),
+		connsGauge:    registry.Gauge("http_open_connections", "Number of open client connections, by listener and state (new, active, or idle)"),
+		inFlightGauge: registry.Gauge("http_in_flight_requests", "Number of requests being handled, by listener"),
+	}
+}
+
+// Add a listener to the group, tracking its connections, must be called before Start
+func (group *listenerGroup) add(l *listener) {
+	l.conns = newConnTracker(l.name, group.connsGauge, group.inFlightGauge)
+	l.conns.track(l.server)
+
+	group.listeners = append(group.listeners, l)
+	group.readyGauge.Set(0, "listener", l.name)
+}
+
+// Binds every listener, then serves them concurrently. Any listener failing is reported through fail, which shuts all of them
+// down together rather than running half a server. Nothing is served if any listener fails to bind
+func (group *listenerGroup) Start(ctx context.Context, fail func(error)) error {
+	netListeners := make([]net.Listener, 0, len(group.listeners))
+
+	closeBound := func() {
+		for _, bound := range netListeners {
+			bound.Close()
+		}
+	}
+
+	listenConfig := net.ListenConfig{KeepAlive: group.keepAlive}
+
+	for _, l := range group.listeners {
+		netListener, err := listenConfig.Listen(ctx, "tcp", l.server.Addr)
+		if err != nil {
+			closeBound()
+			return fmt.Errorf("%s listener failed to bind %s: %w", l.name, l.server.Addr, err)
+		}
+
+		// certificates are loaded up front, so a listener is only ready once it can complete handshakes
+		if l.certFile != "" {
+			certificate, err := tls.LoadX509KeyPair(l.certFile, l.keyFile)
+			if err != nil {
+				netListener.Close()
+				closeBound()
+				return fmt.Errorf("%s listener failed to load its certificate: %w", l.name, err)
+			}
+
+			// HTTP/2 is only offered when the server is configured to serve it
+			nextProtos := []string{"http/1.1"}
+			if _, ok := l.server.TLSNextProto["h2"]; ok {
+				nextProtos = []string{"h2", "http/1.1"}
+			}
+
+			netListener = tls.NewListener(netListener, &tls.Config{Certificates: []tls.Certificate{certificate}, NextProtos: nextProtos})
+		}
+
+		netListeners = append(netListeners, netListener)
+	}
+
+	for i, l := range group.listeners {
+		group.wg.Add(1)
+
+		go func(l *listener, netListener net.Listener) {
+			defer group.wg.Done()
+
+			group.readyGauge.Set(1, "listener", l.name)
+			group.logger.Info("Listener is ready to handle requests", zap.String("listener", l.name), zap.String("addr", l.server.Addr), zap.Bool("tls", l.certFile != ""))
+
+			err := l.server.Serve(netListener)
+
+			group.readyGauge.Set(0, "listener", l.name)
+
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				fail(fmt.Errorf("%s listener failed: %w", l.name, err))
+			}
+		}(l, netListeners[i])
+	}
+
+	return nil
+}
+
+// Gracefully shuts down every listener concurrently, in-flight requests get until ctx is done to finish. How many connections
+// and requests each listener had to drain, and how many were cut off, are logged
+func (group *listenerGroup) Stop(ctx context.Context) error {
+	group.logger.Info("Shutting down server...")
+	start := time.Now()
+
+	errs := make([]error, len(group.listeners))
+
+	for i, l := range group.listeners {
+		group.wg.Add(1)
+
+		go func(i int, l *listener) {
+			defer group.wg.Done()
+
+			draining := l.conns.stats()
+			group.logger.Info("Draining listener", zap.String("listener", l.name), zap.Int("open connections", draining.open),
+				zap.Int("idle connections", draining.idle), zap.Int("in-flight requests", draining.inFlight))
+
+			err := l.server.Shutdown(ctx)
+
+			remaining := l.conns.stats()
+			group.logger.Info("Drained listener", zap.String("listener", l.name), zap.Duration("took", time.Since(start)),
+				zap.Int("connections cut off", remaining.open), zap.Int("requests cut off", remaining.inFlight))
+
+			if err != nil {
+				group.logger.Error("Listener forced to shutdown", zap.String("listener", l.name), zap.Error(err))
+				l.server.Close()
+				errs[i] = fmt.Errorf("%s listener: %w", l.name, err)
+			}
+		}(i, l)
+	}
+
+	group.wg.Wait()
+
+	return errors.Join(errs...)
+}
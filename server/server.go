@@ -2,81 +2,375 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"syscall"
 	"time"
 
+	"github.com/adamjeanlaurent/github-api-read-cache-service/adminauth"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/alerts"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/auth"
 	"github.com/adamjeanlaurent/github-api-read-cache-service/cache"
 	"github.com/adamjeanlaurent/github-api-read-cache-service/config"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/digest"
 	githubclient "github.com/adamjeanlaurent/github-api-read-cache-service/github-client"
 	"github.com/adamjeanlaurent/github-api-read-cache-service/handlers"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/lifecycle"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/logging"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/metrics"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/once"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/validate"
 	"go.uber.org/zap"
+	"golang.org/x/net/http2"
 )
 
+// Failure of a single hydration run with --validate or --once, the process exits non-zero on it so CI and scheduled jobs notice
+type RunError struct {
+	Err error
+}
+
+func (err *RunError) Error() string {
+	return err.Err.Error()
+}
+
+func (err *RunError) Unwrap() error {
+	return err.Err
+}
+
 // Spawns HTTP Server, and Cache Sync Loop
-func StartServer(logger *zap.Logger) error {
+func StartServer(logger *zap.Logger, logLevel zap.AtomicLevel) error {
 	cfg, err := config.NewConfiguration(logger)
 
 	if err != nil {
 		return fmt.Errorf("Invalid Configuration: %w", err)
 	}
 
-	// Cache Sync Loop and HTTP Server should respect system interupts (e.g CTRL-C)
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
-	defer stop()
+	// the logger main starts with is only used until the configured one is built
+	logger, err = logging.NewLogger(cfg, logLevel)
+	if err != nil {
+		return err
+	}
+	defer logger.Sync()
+
+	// validation hydrates once and exits without serving
+	if cfg.GetValidate() {
+		if err := validate.Run(cfg, logger, os.Stdout); err != nil {
+			return &RunError{Err: err}
+		}
+		return nil
+	}
 
-	githubClient := githubclient.NewGithubClient(cfg, logger)
-	dataCache := cache.NewCache(cfg, githubClient, ctx, logger)
+	// once mode hydrates, persists a snapshot, and exits without serving
+	if cfg.GetOnce() {
+		if err := once.Run(cfg, logger); err != nil {
+			return &RunError{Err: err}
+		}
+		return nil
+	}
+
+	// Cache Sync Loop and HTTP Server should respect system interupts (e.g CTRL-C), and container stops (SIGTERM)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// Start sync loop goroutine for cache
-	dataCache.StartSyncLoop()
+	return Serve(ctx, cfg, logger, logLevel)
+}
 
-	httpHandlers := handlers.NewHttpHandlers(cfg, dataCache, logger, githubClient)
-	mux := setupApiRoutes(httpHandlers)
+// Hydrates the cache and serves the API with cfg until ctx is done or any component fails, e.g. a listener can't bind.
+// Returns once every component has stopped
+func Serve(ctx context.Context, cfg config.Configuration, logger *zap.Logger, logLevel zap.AtomicLevel) error {
+	registry := metrics.NewRegistry()
+	githubClient := githubclient.NewGithubClient(cfg, logger, registry)
 
-	port := fmt.Sprintf(":%d", cfg.GetPort())
-	srv := &http.Server{Addr: port, Handler: mux}
+	// the cache is stopped by the lifecycle manager rather than by the interrupt, so it outlives the listeners draining requests
+	cacheCtx, cancelCache := context.WithCancel(context.Background())
+	defer cancelCache()
+	dataCache := cache.NewCache(cfg, githubClient, cacheCtx, logger, registry)
 
-	// Graceful server shutdown
-	go func() {
-		<-ctx.Done()
-		logger.Info("Shutting down server...")
+	// components are started in order, and stopped in reverse order once interrupted or once any of them fails
+	manager := lifecycle.NewManager(logger)
 
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
+	// rotated tokens are picked up for as long as anything may call GitHub
+	if cfg.GetGitHubTokenFile() != "" {
+		manager.Add("token-file", githubclient.NewTokenFileWatcher(cfg.GetGitHubTokenFile(), cfg.GetGitHubApiKey(), githubClient, logger), cfg.GetShutdownTimeout())
+	}
 
-		if err := srv.Shutdown(shutdownCtx); err != nil {
-			logger.Error("Server forced to shutdown", zap.Error(err))
+	// metrics are pushed until every other component has stopped, so the last push covers shutdown
+	if cfg.GetStatsdAddress() != "" {
+		pusher, err := metrics.NewStatsdPusher(registry, cfg.GetStatsdAddress(), cfg.GetStatsdPrefix(), cfg.GetStatsdFlavor(), cfg.GetStatsdInterval(), logger)
+		if err != nil {
+			return err
 		}
-	}()
+		manager.Add("statsd", pusher, cfg.GetShutdownTimeout())
+	}
+
+	// alerts are delivered until every component that raises them has stopped
+	if alerter := alerts.NewAlerter(cfg, logger, registry); alerter != nil {
+		githubClient.SetAlertHooks(alerter)
+		dataCache.SetAlertHooks(alerter)
+		manager.Add("alerts", alerter, cfg.GetShutdownTimeout())
+	}
 
-	logger.Info("Server is ready to handle requests", zap.String("port", srv.Addr))
+	// Hydrate the cache and start sync loop goroutine for cache, the listeners aren't started until the initial hydration finishes
+	manager.Add("cache", newCacheComponent(dataCache, cacheCtx, cancelCache), cfg.GetShutdownTimeout())
 
-	// start server
-	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		logger.Error("Could not start server ", zap.Error(err))
+	if cfg.GetDigestWebhookUrl() != "" {
+		manager.Add("digest", digest.NewDigester(cfg, dataCache, logger), cfg.GetShutdownTimeout())
 	}
 
-	return nil
+	adminGuard := adminauth.NewGuard(cfg.GetAdminAllowedNetworks(), cfg.GetAdminUser(), cfg.GetAdminPassword(), logger)
+	if adminGuard.LoopbackOnly() {
+		logger.Warn("Admin and debug routes are only served to loopback addresses, open them up with --admin-allowed-cidrs or --admin-user")
+	}
+
+	authorizer, err := auth.NewAuthorizer(cfg, logger)
+	if err != nil {
+		return err
+	}
+
+	auditLogger, err := logging.NewAuditLogger(cfg)
+	if err != nil {
+		return err
+	}
+	defer auditLogger.Sync()
+
+	httpHandlers := handlers.NewHttpHandlers(ctx, cfg, dataCache, logger, logLevel, githubClient, registry, auditLogger)
+	mux, adminMux := setupApiRoutes(httpHandlers, cfg, registry, authorizer, adminGuard, logger)
+
+	listeners := newListenerGroup(logger, registry, cfg.GetTcpKeepAlivePeriod())
+	listeners.add(&listener{name: "http", server: newHttpServer(cfg, cfg.GetPort(), mux)})
+
+	if cfg.GetTLSPort() != 0 {
+		listeners.add(&listener{
+			name:     "https",
+			server:   newHttpServer(cfg, cfg.GetTLSPort(), mux),
+			certFile: cfg.GetTLSCertFile(),
+			keyFile:  cfg.GetTLSKeyFile(),
+		})
+	}
+
+	// built once, expvar can't publish the same variable twice
+	var debugRoutes http.Handler
+	if cfg.GetDebugPort() != 0 || cfg.GetAdminPort() != 0 {
+		debugRoutes = adminGuard.Protect(setupDebugRoutes(dataCache))
+	}
+
+	if cfg.GetDebugPort() != 0 {
+		listeners.add(&listener{name: "debug", server: newHttpServer(cfg, cfg.GetDebugPort(), debugRoutes)})
+	}
+
+	if cfg.GetAdminPort() != 0 {
+		adminMux.Handle("/debug/", debugRoutes)
+		listeners.add(&listener{name: "admin", server: newHttpServer(cfg, cfg.GetAdminPort(), adminMux)})
+	}
+
+	manager.Add("listeners", listeners, cfg.GetShutdownTimeout())
+
+	// serves until interrupted, or until any component fails
+	return manager.Run(ctx)
 }
 
-// Sets up routes for REST API
-func setupApiRoutes(httpHandlers handlers.HttpHandlers) *http.ServeMux {
+// Get a server for handler on port, with the configured timeouts and header limit so slow or idle clients can't hold connections open,
+// and the configured keep-alive and HTTP/2 settings. HTTP/2 is only served over TLS
+func newHttpServer(cfg config.Configuration, port int, handler http.Handler) *http.Server {
+	server := &http.Server{
+		Addr:           fmt.Sprintf(":%d", port),
+		Handler:        handler,
+		ReadTimeout:    cfg.GetReadTimeout(),
+		WriteTimeout:   cfg.GetWriteTimeout(),
+		IdleTimeout:    cfg.GetIdleTimeout(),
+		MaxHeaderBytes: cfg.GetMaxHeaderBytes(),
+	}
+	server.SetKeepAlivesEnabled(!cfg.GetDisableKeepAlives())
+
+	// a non-nil, empty TLSNextProto keeps net/http from enabling HTTP/2 on its own
+	server.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+	if !cfg.GetDisableHttp2() {
+		// only fails when the server's TLS config rules out HTTP/2, which it doesn't have
+		http2.ConfigureServer(server, &http2.Server{
+			MaxConcurrentStreams: uint32(cfg.GetHttp2MaxConcurrentStreams()),
+			IdleTimeout:          cfg.GetIdleTimeout(),
+		})
+	}
+
+	return server
+}
+
+// Limits the time handler is given to respond, answering 503 past it. The response is buffered, so only wrap handlers that don't stream
+func withTimeout(handler http.Handler, timeout time.Duration) http.Handler {
+	if timeout <= 0 {
+		return handler
+	}
+
+	return http.TimeoutHandler(handler, timeout, "Error: Request timed out")
+}
+
+// Determines if a GET of path is already routed by mux, to a route other than the catch all
+func servesPath(mux *http.ServeMux, path string) bool {
+	_, pattern := mux.Handler(&http.Request{Method: http.MethodGet, URL: &url.URL{Path: path}})
+	return pattern != "" && pattern != "/"
+}
+
+// Sets up routes for REST API, and the mux of the operational routes (metrics, cache status, admin). They're the same mux
+// unless --admin-port is set
+func setupApiRoutes(httpHandlers handlers.HttpHandlers, cfg config.Configuration, registry metrics.Registry, authorizer auth.Authorizer, adminGuard adminauth.Guard, logger *zap.Logger) (*http.ServeMux, *http.ServeMux) {
 	mux := http.NewServeMux()
+	adminMux := mux
+	if cfg.GetAdminPort() != 0 {
+		adminMux = http.NewServeMux()
+	}
+
+	// every route records request counts and response payload sizes, and a sample of its requests in the access log
+	handleOn := func(target *http.ServeMux, pattern string, handler http.Handler) {
+		target.Handle(pattern, metrics.InstrumentHandler(registry, pattern, logging.AccessLog(logger, cfg.GetAccessLogSampleRate(), pattern, handler)))
+	}
+
+	handlePublic := func(pattern string, handler http.Handler) {
+		handleOn(mux, pattern, handler)
+	}
+
+	// and only serves requests the authorizer allows
+	handle := func(pattern string, handler http.Handler) {
+		handlePublic(pattern, auth.Protect(authorizer, logger, registry, handler))
+	}
+
+	// operational routes are only served on the admin listener when there's one. The API listeners answer them 404
+	// instead of proxying them to GitHub
+	handleOperationalUnprotected := func(pattern string, handler http.Handler) {
+		handleOn(adminMux, pattern, handler)
+
+		if adminMux != mux {
+			mux.Handle(pattern, http.NotFoundHandler())
+		}
+	}
+
+	handleOperational := func(pattern string, handler http.Handler) {
+		handleOperationalUnprotected(pattern, auth.Protect(authorizer, logger, registry, handler))
+	}
+
+	// orchestrators probe without credentials, on either listener
+	handlePublic("GET /healthcheck", httpHandlers.GetHealth())
+	handlePublic("GET /live", httpHandlers.GetLive())
+	handlePublic("GET /ready", httpHandlers.GetReady())
+	if adminMux != mux {
+		handleOn(adminMux, "GET /healthcheck", httpHandlers.GetHealth())
+		handleOn(adminMux, "GET /live", httpHandlers.GetLive())
+		handleOn(adminMux, "GET /ready", httpHandlers.GetReady())
+	}
+	handleOperational("GET /healthz/detail", httpHandlers.GetHealthDetail())
+	handleOperational("GET /cachestatus", httpHandlers.GetCacheStatus())
+	handleOperational("GET /metrics", httpHandlers.GetMetrics())
+	handle("GET /events", httpHandlers.GetEvents())
+	handle("GET /ws", httpHandlers.GetWebSocket())
+	handle("GET /orgs/Netflix", httpHandlers.GetCachedNetflixOrg())
+	handle("GET /orgs/Netflix/members", httpHandlers.GetCachedNetflixOrgMembers())
+	handle("GET /orgs/Netflix/members/{login}", httpHandlers.GetCachedNetflixOrgMember())
+	handle("GET /orgs/Netflix/repos", withTimeout(httpHandlers.GetCachedNetflixOrgRepos(), cfg.GetReposHandlerTimeout()))
+	handle("POST /orgs/Netflix/repos/lookup", httpHandlers.LookupCachedNetflixOrgRepos())
+	handle("GET /search/repos", httpHandlers.SearchCachedNetflixOrgRepos())
+	handle("GET /search/members", httpHandlers.SearchCachedNetflixOrgMembers())
+	handle("GET /view", httpHandlers.GetViewCatalog())
+	handle("GET /summary", httpHandlers.GetCachedSummary())
+	handle("GET /view/bottom/{n}/forks", httpHandlers.GetCachedBottomNNetflixReposByForks())
+	handle("GET /view/bottom/{n}/last_updated", httpHandlers.GetCachedBottomNNetflixReposByLastUpdatedTime())
+	handle("GET /view/bottom/{n}/open_issues", httpHandlers.GetCachedBottomNNetflixReposByOpenIssues())
+	handle("GET /view/bottom/{n}/stars", httpHandlers.GetCachedBottomNNetflixReposByStars())
+	handle("GET /view/{direction}/{n}/{view}", httpHandlers.GetCachedNNetflixReposByView())
+
+	// every view can also be requested without N in the path, taking ?n= or the default N
+	handle("GET /view/bottom/forks", httpHandlers.GetCachedBottomNNetflixReposByForks())
+	handle("GET /view/bottom/last_updated", httpHandlers.GetCachedBottomNNetflixReposByLastUpdatedTime())
+	handle("GET /view/bottom/open_issues", httpHandlers.GetCachedBottomNNetflixReposByOpenIssues())
+	handle("GET /view/bottom/stars", httpHandlers.GetCachedBottomNNetflixReposByStars())
+	handle("GET /view/{direction}/{view}", httpHandlers.GetCachedNNetflixReposByView())
+
+	handle("GET /view/licenses", httpHandlers.GetCachedNetflixRepoBreakdown(cache.BREAKDOWN_LICENSES))
+	handle("GET /view/archived", httpHandlers.GetCachedNetflixRepoBreakdown(cache.BREAKDOWN_ARCHIVED))
+	handle("GET /view/removed", httpHandlers.GetCachedRemovedNetflixRepos())
+
+	// contributors are only cached when enabled, otherwise their requests are proxied like any other path
+	if cfg.GetHydrateContributors() {
+		handle("GET /view/bottom/{n}/contributors", httpHandlers.GetCachedBottomNNetflixReposByContributors())
+		handle("GET /view/bottom/contributors", httpHandlers.GetCachedBottomNNetflixReposByContributors())
+		handle("GET /repos/Netflix/{repo}/contributors", httpHandlers.GetCachedNetflixRepoContributors())
+	}
+
+	// likewise commit activity
+	if cfg.GetHydrateCommitActivity() {
+		handle("GET /view/bottom/{n}/commit_activity", httpHandlers.GetCachedBottomNNetflixReposByCommitActivity())
+		handle("GET /view/bottom/commit_activity", httpHandlers.GetCachedBottomNNetflixReposByCommitActivity())
+		handle("GET /repos/Netflix/{repo}/stats/commit_activity", httpHandlers.GetCachedNetflixRepoCommitActivity())
+	}
+
+	// likewise issue counts
+	if cfg.GetHydrateIssueCounts() {
+		handle("GET /view/bottom/{n}/issues", httpHandlers.GetCachedBottomNNetflixReposByIssues())
+		handle("GET /view/bottom/issues", httpHandlers.GetCachedBottomNNetflixReposByIssues())
+		handle("GET /view/bottom/{n}/pull_requests", httpHandlers.GetCachedBottomNNetflixReposByPullRequests())
+		handle("GET /view/bottom/pull_requests", httpHandlers.GetCachedBottomNNetflixReposByPullRequests())
+	}
+
+	// likewise releases, only the configured repos are served from cache
+	if len(cfg.GetReleaseRepos()) > 0 {
+		handle("GET /view/recent/{n}/releases", httpHandlers.GetCachedRecentNNetflixReleases())
+		handle("GET /view/recent/releases", httpHandlers.GetCachedRecentNNetflixReleases())
+		handle("GET /repos/Netflix/{repo}/releases/latest", httpHandlers.GetCachedNetflixRepoLatestRelease())
+	}
+
+	// likewise teams
+	if cfg.GetHydrateTeams() {
+		handle("GET /orgs/Netflix/teams", httpHandlers.GetCachedNetflixTeams())
+		handle("GET /orgs/Netflix/teams/{team}/repos", httpHandlers.GetCachedNetflixTeamRepos())
+	}
 
-	mux.Handle("GET /healthcheck", httpHandlers.GetHealth())
-	mux.Handle("GET /orgs/Netflix", httpHandlers.GetCachedNetflixOrg())
-	mux.Handle("GET /orgs/Netflix/members", httpHandlers.GetCachedNetflixOrgMembers())
-	mux.Handle("GET /orgs/Netflix/repos", httpHandlers.GetCachedNetflixOrgRepos())
-	mux.Handle("GET /view/bottom/{n}/forks", httpHandlers.GetCachedBottomNNetflixReposByForks())
-	mux.Handle("GET /view/bottom/{n}/last_updated", httpHandlers.GetCachedBottomNNetflixReposByLastUpdatedTime())
-	mux.Handle("GET /view/bottom/{n}/open_issues", httpHandlers.GetCachedBottomNNetflixReposByOpenIssues())
-	mux.Handle("GET /view/bottom/{n}/stars", httpHandlers.GetCachedBottomNNetflixReposByStars())
+	// likewise READMEs
+	if cfg.GetHydrateReadmes() {
+		handle("GET /repos/Netflix/{repo}/readme", httpHandlers.GetCachedNetflixRepoReadme())
+	}
+
+	// likewise languages
+	if cfg.GetHydrateLanguages() {
+		handle("GET /view/languages", httpHandlers.GetCachedNetflixOrgLanguages())
+		handle("GET /repos/Netflix/{repo}/languages", httpHandlers.GetCachedNetflixRepoLanguages())
+	}
 
-	// catch all, proxies request to github API
-	mux.Handle("/", httpHandlers.ProxyRequestToGithubAPI())
+	// operator-defined routes, always under /custom/ so they can't shadow the routes above
+	for _, route := range cfg.GetCustomRoutes() {
+		handle("GET "+route.Path, httpHandlers.GetCustomRoute(route))
+	}
+
+	// extra endpoints are served from cache once cached, and proxied until then. Paths already served above keep their route
+	for _, path := range cfg.GetExtraEndpoints() {
+		if !servesPath(mux, path) {
+			handle("GET "+path, httpHandlers.GetCachedExtraEndpoint())
+		}
+	}
+
+	// admin routes, only reachable from the allowed networks and / or with the admin credentials. The admin credentials
+	// take the Authorization header a bearer token would, so when they're required they replace the authorizer
+	handleAdmin := func(pattern string, handler http.Handler) {
+		if adminGuard.RequiresCredentials() {
+			handleOperationalUnprotected(pattern, adminGuard.Protect(handler))
+			return
+		}
+
+		handleOperationalUnprotected(pattern, adminGuard.Protect(auth.Protect(authorizer, logger, registry, handler)))
+	}
+
+	handleAdmin("/admin/loglevel", httpHandlers.ManageLogLevel())
+	handleAdmin("GET /admin/maintenance", httpHandlers.ManageMaintenanceMode())
+	handleAdmin("POST /admin/maintenance", httpHandlers.ManageMaintenanceMode())
+	handleAdmin("GET /admin/cache/export", httpHandlers.ExportCache())
+	handleAdmin("POST /admin/cache/import", httpHandlers.ImportCache())
+	handleAdmin("POST /admin/cache/refresh", httpHandlers.RefreshCacheDataset())
+
+	// catch all, proxies request to github API. When the proxy is disabled, non-cached paths fall through to the mux's 404
+	if !cfg.GetDisableProxy() {
+		handle("/", httpHandlers.ProxyRequestToGithubAPI())
+	}
 
-	return mux
+	return mux, adminMux
 }
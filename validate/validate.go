@@ -0,0 +1,173 @@
+package validate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/adamjeanlaurent/github-api-read-cache-service/cache"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/config"
+	githubclient "github.com/adamjeanlaurent/github-api-read-cache-service/github-client"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/metrics"
+	"github.com/adamjeanlaurent/github-api-read-cache-service/replication"
+	"go.uber.org/zap"
+)
+
+// Outcome of a validation hydration
+type Summary struct {
+	Ok                 bool                               `json:"ok"`
+	Status             int                                `json:"status"`
+	Error              string                             `json:"error,omitempty"`
+	DurationMs         int64                              `json:"duration_ms"`
+	QuotaConsumed      *int                               `json:"quota_consumed,omitempty"` // omitted when the rate limit couldn't be read
+	QuotaRemaining     *int                               `json:"quota_remaining,omitempty"`
+	Datasets           map[string]cache.DatasetSyncReport `json:"datasets"`
+	ReposMissingFields map[string][]string                `json:"repos_missing_fields,omitempty"` // by repo name
+}
+
+// Configuration of a validation run, snapshots are neither written nor replicated so validating has no side effects
+type dryRunConfiguration struct {
+	config.Configuration
+}
+
+func (cfg dryRunConfiguration) GetSnapshotFile() string {
+	return ""
+}
+
+func (cfg dryRunConfiguration) GetSnapshotReplicaUrl() string {
+	return ""
+}
+
+func (cfg dryRunConfiguration) GetSnapshotBucket() replication.Bucket {
+	return replication.Bucket{}
+}
+
+// Client that keeps the repos it fetched, so repos missing fields can be reported even when computing views fails
+type recordingClient struct {
+	githubclient.GithubClient
+	repos []githubclient.JsonObject
+}
+
+func (client *recordingClient) GetNetflixRepos(ctx context.Context) (*githubclient.Response[[]githubclient.JsonObject], error) {
+	resp, err := client.GithubClient.GetNetflixRepos(ctx)
+	client.repos = resp.Body
+	return resp, err
+}
+
+// Hydrates the cache once and writes a summary of the hydration to w as indented JSON, returning an error if it failed
+func Run(cfg config.Configuration, logger *zap.Logger, w io.Writer) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cfg = dryRunConfiguration{cfg}
+	registry := metrics.NewRegistry()
+	client := &recordingClient{GithubClient: githubclient.NewGithubClient(cfg, logger, registry)}
+	dataCache := cache.NewCache(cfg, client, ctx, logger, registry)
+
+	// the rate limit endpoint doesn't count against the rate limit
+	before, beforeOk := coreRateLimit(ctx, client)
+
+	start := time.Now()
+	statusCode, err := dataCache.HydrateCache(ctx)
+
+	summary := Summary{
+		Ok:                 err == nil,
+		Status:             statusCode,
+		DurationMs:         time.Since(start).Milliseconds(),
+		Datasets:           dataCache.GetLastSyncReport().Datasets,
+		ReposMissingFields: map[string][]string{},
+	}
+
+	if err != nil {
+		summary.Error = err.Error()
+	}
+
+	if after, ok := coreRateLimit(ctx, client); ok {
+		summary.QuotaRemaining = &after.remaining
+
+		// the window reset mid hydration, so the quota used in the new window is all that's known
+		consumed := after.used
+		if beforeOk && before.reset == after.reset {
+			consumed = before.remaining - after.remaining
+		}
+		summary.QuotaConsumed = &consumed
+	}
+
+	for _, repo := range client.repos {
+		if missing := cache.MissingRepoFields(repo, dataCache.GetViewDefinitions()); len(missing) > 0 {
+			name, _ := repo["name"].(string)
+			summary.ReposMissingFields[name] = missing
+		}
+	}
+
+	if len(summary.ReposMissingFields) > 0 {
+		summary.Ok = false
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(summary); err != nil {
+		return err
+	}
+
+	if !summary.Ok {
+		return fmt.Errorf("Validation failed, %s", failureReason(summary))
+	}
+
+	return nil
+}
+
+// Core rate limit reported by GitHub
+type rateLimit struct {
+	remaining int
+	used      int
+	reset     float64
+}
+
+// Get the core rate limit, false if it couldn't be read
+func coreRateLimit(ctx context.Context, client githubclient.GithubClient) (rateLimit, bool) {
+	resp, err := client.GetRateLimit(ctx)
+	if err != nil {
+		return rateLimit{}, false
+	}
+	response := resp.Body
+
+	core, ok := response["rate"].(map[string]interface{})
+	if resources, hasResources := response["resources"].(map[string]interface{}); hasResources {
+		if resourceCore, hasCore := resources["core"].(map[string]interface{}); hasCore {
+			core, ok = resourceCore, true
+		}
+	}
+
+	remaining, hasRemaining := core["remaining"].(float64)
+	if !ok || !hasRemaining {
+		return rateLimit{}, false
+	}
+
+	used, _ := core["used"].(float64)
+	reset, _ := core["reset"].(float64)
+
+	return rateLimit{remaining: int(remaining), used: int(used), reset: reset}, true
+}
+
+// Describes why a validation failed
+func failureReason(summary Summary) string {
+	if summary.Error != "" {
+		return fmt.Sprintf("hydration failed (status %d): %s", summary.Status, summary.Error)
+	}
+
+	repos := make([]string, 0, len(summary.ReposMissingFields))
+	for repo := range summary.ReposMissingFields {
+		repos = append(repos, repo)
+	}
+	sort.Strings(repos)
+
+	return fmt.Sprintf("repos missing fields: %v", repos)
+}